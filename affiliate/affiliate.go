@@ -0,0 +1,182 @@
+// Package affiliate はECサイトごとの商品URLをアフィリエイトリンクに変換する。
+package affiliate
+
+import (
+	"errors"
+	"fmt"
+	"net/url"
+	"strings"
+
+	"github.com/h-hiwatashi/super-business-book-ranking-backend/repository"
+)
+
+// サイト名。sites.name と一致させる。
+const (
+	SiteRakuten = "rakuten"
+	SiteAmazon  = "amazon"
+	SiteYahoo   = "yahoo"
+)
+
+// Builder は商品URLとアフィリエイトIDからアフィリエイトリンクを組み立てる。
+type Builder func(productURL, affiliateID string) (string, error)
+
+// builders はサイト名ごとのリンクの形式である。
+var builders = map[string]Builder{
+	SiteRakuten: Rakuten,
+	SiteAmazon:  Amazon,
+	SiteYahoo:   ValueCommerce,
+}
+
+// Rakuten は楽天アフィリエイトのリダイレクトURLを返す。
+// affiliateID は楽天アフィリエイトのアフィリエイトIDである。
+func Rakuten(productURL, affiliateID string) (string, error) {
+	if _, err := parseProductURL(productURL, "rakuten.co.jp"); err != nil {
+		return "", err
+	}
+	if affiliateID == "" {
+		return "", errors.New("アフィリエイトIDが空です")
+	}
+
+	params := url.Values{}
+	params.Set("pc", productURL)
+	params.Set("m", productURL)
+	return "https://hb.afl.rakuten.co.jp/hgc/" + url.PathEscape(affiliateID) + "/?" + params.Encode(), nil
+}
+
+// Amazon は商品URLにAmazonアソシエイトのトラッキングID（tag）を付けたURLを返す。
+// 既に tag が付いている場合は置き換える。
+func Amazon(productURL, tag string) (string, error) {
+	u, err := parseProductURL(productURL, "amazon.co.jp")
+	if err != nil {
+		return "", err
+	}
+	if tag == "" {
+		return "", errors.New("トラッキングIDが空です")
+	}
+
+	params := u.Query()
+	params.Set("tag", tag)
+	u.RawQuery = params.Encode()
+	return u.String(), nil
+}
+
+// ValueCommerce はバリューコマース経由のYahoo!ショッピングのリンクを返す。
+// id は "サイトID:広告ID"（sid:pid）の形式である。
+func ValueCommerce(productURL, id string) (string, error) {
+	if _, err := parseProductURL(productURL, "yahoo.co.jp"); err != nil {
+		return "", err
+	}
+	sid, pid, err := ParseValueCommerceID(id)
+	if err != nil {
+		return "", err
+	}
+
+	params := url.Values{}
+	params.Set("sid", sid)
+	params.Set("pid", pid)
+	params.Set("vc_url", productURL)
+	return "https://ck.jp.ap.valuecommerce.com/servlet/referral?" + params.Encode(), nil
+}
+
+// ParseValueCommerceID は "sid:pid" 形式のバリューコマースのIDを分割する。
+func ParseValueCommerceID(id string) (sid, pid string, err error) {
+	sid, pid, ok := strings.Cut(id, ":")
+	if !ok || !isDigits(sid) || !isDigits(pid) {
+		return "", "", fmt.Errorf("バリューコマースのIDは sid:pid の形式で指定してください: %q", id)
+	}
+	return sid, pid, nil
+}
+
+// parseProductURL は productURL が domain またはそのサブドメインの http(s) のURLかを検証する。
+// 別サイトのURLにIDを付けて誤った成果を計上しないようにする。
+func parseProductURL(productURL, domain string) (*url.URL, error) {
+	u, err := url.Parse(productURL)
+	if err != nil {
+		return nil, err
+	}
+	host := strings.ToLower(u.Hostname())
+	if (u.Scheme != "http" && u.Scheme != "https") || (host != domain && !strings.HasSuffix(host, "."+domain)) {
+		return nil, fmt.Errorf("%s の商品URLではありません: %q", domain, productURL)
+	}
+	return u, nil
+}
+
+func isDigits(s string) bool {
+	if s == "" {
+		return false
+	}
+	for _, c := range s {
+		if c < '0' || c > '9' {
+			return false
+		}
+	}
+	return true
+}
+
+// URLLinker は商品URLをアフィリエイトリンクに変換する。*Linker が満たす。
+type URLLinker interface {
+	Link(site, productURL string) string
+}
+
+// LinkURL は l で site の商品URLをアフィリエイトリンクに変換する。l が nil の場合はそのまま返す。
+func LinkURL(l URLLinker, site, productURL string) string {
+	if l == nil {
+		return productURL
+	}
+	return l.Link(site, productURL)
+}
+
+// LinkRanking は書籍の商品URLを l でアフィリエイトリンクに置き換えたランキングを返す。
+// キャッシュしたランキングを書き換えないよう、書籍はコピーする。l が nil の場合は ranking をそのまま返す。
+func LinkRanking(l URLLinker, ranking *repository.Ranking) *repository.Ranking {
+	if l == nil || len(ranking.Books) == 0 {
+		return ranking
+	}
+
+	linked := *ranking
+	linked.Books = make([]repository.RankedBook, len(ranking.Books))
+	for i, book := range ranking.Books {
+		book.URL = l.Link(book.Site, book.URL)
+		linked.Books[i] = book
+	}
+	return &linked
+}
+
+// Linker はサイトごとのアフィリエイトIDを保持し、商品URLをアフィリエイトリンクに変換する。
+type Linker struct {
+	ids map[string]string
+}
+
+// NewLinker はサイト名をキーとしたアフィリエイトIDから Linker を作る。
+// IDが空のサイトの商品URLは変換しない。
+func NewLinker(ids map[string]string) *Linker {
+	l := &Linker{ids: make(map[string]string, len(ids))}
+	for site, id := range ids {
+		if id != "" {
+			l.ids[site] = id
+		}
+	}
+	return l
+}
+
+// Link は site の商品URLをアフィリエイトリンクに変換する。
+// アフィリエイトIDがないサイトや、変換できないURLはそのまま返す。
+func (l *Linker) Link(site, productURL string) string {
+	if l == nil || productURL == "" {
+		return productURL
+	}
+	id, ok := l.ids[site]
+	if !ok {
+		return productURL
+	}
+	build, ok := builders[site]
+	if !ok {
+		return productURL
+	}
+
+	link, err := build(productURL, id)
+	if err != nil {
+		return productURL
+	}
+	return link
+}
@@ -0,0 +1,234 @@
+package affiliate
+
+import (
+	"testing"
+
+	"github.com/h-hiwatashi/super-business-book-ranking-backend/repository"
+)
+
+func TestRakuten(t *testing.T) {
+	testCases := []struct {
+		name        string
+		productURL  string
+		affiliateID string
+		want        string
+		wantErr     bool
+	}{
+		{
+			name:        "正常系：楽天ブックスの商品URL",
+			productURL:  "https://books.rakuten.co.jp/rb/12345678/",
+			affiliateID: "1a2b3c4d.5e6f7a8b.1a2b3c4d.5e6f7a8b",
+			want:        "https://hb.afl.rakuten.co.jp/hgc/1a2b3c4d.5e6f7a8b.1a2b3c4d.5e6f7a8b/?m=https%3A%2F%2Fbooks.rakuten.co.jp%2Frb%2F12345678%2F&pc=https%3A%2F%2Fbooks.rakuten.co.jp%2Frb%2F12345678%2F",
+		},
+		{
+			name:        "正常系：クエリ付きの商品URLもエスケープする",
+			productURL:  "https://item.rakuten.co.jp/book/123/?s-id=top&l-id=a",
+			affiliateID: "abc",
+			want:        "https://hb.afl.rakuten.co.jp/hgc/abc/?m=https%3A%2F%2Fitem.rakuten.co.jp%2Fbook%2F123%2F%3Fs-id%3Dtop%26l-id%3Da&pc=https%3A%2F%2Fitem.rakuten.co.jp%2Fbook%2F123%2F%3Fs-id%3Dtop%26l-id%3Da",
+		},
+		{
+			name:        "異常系：楽天以外の商品URL",
+			productURL:  "https://www.amazon.co.jp/dp/4123456789",
+			affiliateID: "abc",
+			wantErr:     true,
+		},
+		{
+			name:        "異常系：末尾だけが一致する別ドメイン",
+			productURL:  "https://evilrakuten.co.jp/item",
+			affiliateID: "abc",
+			wantErr:     true,
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			got, err := Rakuten(tc.productURL, tc.affiliateID)
+			if (err != nil) != tc.wantErr {
+				t.Fatalf("Rakuten() error = %v, wantErr %v", err, tc.wantErr)
+			}
+			if got != tc.want {
+				t.Errorf("Rakuten() got %v want %v", got, tc.want)
+			}
+		})
+	}
+}
+
+func TestAmazon(t *testing.T) {
+	testCases := []struct {
+		name       string
+		productURL string
+		tag        string
+		want       string
+		wantErr    bool
+	}{
+		{
+			name:       "正常系：商品URLにタグを付ける",
+			productURL: "https://www.amazon.co.jp/dp/4123456789",
+			tag:        "bookranking-22",
+			want:       "https://www.amazon.co.jp/dp/4123456789?tag=bookranking-22",
+		},
+		{
+			name:       "正常系：既存のタグを置き換え、他のパラメータは残す",
+			productURL: "https://www.amazon.co.jp/dp/4123456789?tag=other-22&psc=1",
+			tag:        "bookranking-22",
+			want:       "https://www.amazon.co.jp/dp/4123456789?psc=1&tag=bookranking-22",
+		},
+		{
+			name:       "異常系：Amazon以外の商品URL",
+			productURL: "https://books.rakuten.co.jp/rb/12345678/",
+			tag:        "bookranking-22",
+			wantErr:    true,
+		},
+		{
+			name:       "異常系：相対URL",
+			productURL: "/dp/4123456789",
+			tag:        "bookranking-22",
+			wantErr:    true,
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			got, err := Amazon(tc.productURL, tc.tag)
+			if (err != nil) != tc.wantErr {
+				t.Fatalf("Amazon() error = %v, wantErr %v", err, tc.wantErr)
+			}
+			if got != tc.want {
+				t.Errorf("Amazon() got %v want %v", got, tc.want)
+			}
+		})
+	}
+}
+
+func TestValueCommerce(t *testing.T) {
+	testCases := []struct {
+		name       string
+		productURL string
+		id         string
+		want       string
+		wantErr    bool
+	}{
+		{
+			name:       "正常系：Yahoo!ショッピングの商品URL",
+			productURL: "https://store.shopping.yahoo.co.jp/bookstore/4123456789.html",
+			id:         "3123456:887654321",
+			want:       "https://ck.jp.ap.valuecommerce.com/servlet/referral?pid=887654321&sid=3123456&vc_url=https%3A%2F%2Fstore.shopping.yahoo.co.jp%2Fbookstore%2F4123456789.html",
+		},
+		{
+			name:       "異常系：pid がない",
+			productURL: "https://store.shopping.yahoo.co.jp/bookstore/4123456789.html",
+			id:         "3123456",
+			wantErr:    true,
+		},
+		{
+			name:       "異常系：数字以外を含むID",
+			productURL: "https://store.shopping.yahoo.co.jp/bookstore/4123456789.html",
+			id:         "3123456:abc",
+			wantErr:    true,
+		},
+		{
+			name:       "異常系：Yahoo以外の商品URL",
+			productURL: "https://www.amazon.co.jp/dp/4123456789",
+			id:         "3123456:887654321",
+			wantErr:    true,
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			got, err := ValueCommerce(tc.productURL, tc.id)
+			if (err != nil) != tc.wantErr {
+				t.Fatalf("ValueCommerce() error = %v, wantErr %v", err, tc.wantErr)
+			}
+			if got != tc.want {
+				t.Errorf("ValueCommerce() got %v want %v", got, tc.want)
+			}
+		})
+	}
+}
+
+func TestLinker(t *testing.T) {
+	linker := NewLinker(map[string]string{
+		SiteAmazon:  "bookranking-22",
+		SiteRakuten: "",
+		"unknown":   "abc",
+	})
+
+	testCases := []struct {
+		name       string
+		site       string
+		productURL string
+		want       string
+	}{
+		{
+			name:       "正常系：IDのあるサイト",
+			site:       SiteAmazon,
+			productURL: "https://www.amazon.co.jp/dp/4123456789",
+			want:       "https://www.amazon.co.jp/dp/4123456789?tag=bookranking-22",
+		},
+		{
+			name:       "正常系：IDが空のサイトはそのまま",
+			site:       SiteRakuten,
+			productURL: "https://books.rakuten.co.jp/rb/12345678/",
+			want:       "https://books.rakuten.co.jp/rb/12345678/",
+		},
+		{
+			name:       "正常系：形式のないサイトはそのまま",
+			site:       "unknown",
+			productURL: "https://example.com/item",
+			want:       "https://example.com/item",
+		},
+		{
+			name:       "正常系：変換できないURLはそのまま",
+			site:       SiteAmazon,
+			productURL: "https://books.rakuten.co.jp/rb/12345678/",
+			want:       "https://books.rakuten.co.jp/rb/12345678/",
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			if got := linker.Link(tc.site, tc.productURL); got != tc.want {
+				t.Errorf("Link() got %v want %v", got, tc.want)
+			}
+		})
+	}
+
+	var disabled *Linker
+	if got := disabled.Link(SiteAmazon, "https://www.amazon.co.jp/dp/4123456789"); got != "https://www.amazon.co.jp/dp/4123456789" {
+		t.Errorf("nil Linker changed the URL: %v", got)
+	}
+}
+
+func TestLinkRanking(t *testing.T) {
+	ranking := &repository.Ranking{
+		CategoryID: "001",
+		Books: []repository.RankedBook{
+			{Rank: 1, ID: "book-1", Site: SiteAmazon, URL: "https://www.amazon.co.jp/dp/4123456789"},
+			{Rank: 2, ID: "book-2", Site: SiteRakuten, URL: "https://books.rakuten.co.jp/rb/12345678/"},
+		},
+	}
+	linker := NewLinker(map[string]string{SiteAmazon: "bookranking-22"})
+
+	linked := LinkRanking(linker, ranking)
+	if linked.CategoryID != "001" || len(linked.Books) != 2 {
+		t.Fatalf("LinkRanking() = %+v", linked)
+	}
+	if got := linked.Books[0].URL; got != "https://www.amazon.co.jp/dp/4123456789?tag=bookranking-22" {
+		t.Errorf("books[0].url = %v", got)
+	}
+	if got := linked.Books[1].URL; got != "https://books.rakuten.co.jp/rb/12345678/" {
+		t.Errorf("books[1].url = %v", got)
+	}
+	// 元のランキングは書き換えない
+	if got := ranking.Books[0].URL; got != "https://www.amazon.co.jp/dp/4123456789" {
+		t.Errorf("original ranking was modified: %v", got)
+	}
+
+	if got := LinkRanking(nil, ranking); got != ranking {
+		t.Errorf("LinkRanking(nil) = %p, want the same ranking %p", got, ranking)
+	}
+	if got := LinkURL(nil, SiteAmazon, "https://www.amazon.co.jp/dp/4123456789"); got != "https://www.amazon.co.jp/dp/4123456789" {
+		t.Errorf("LinkURL(nil) changed the URL: %v", got)
+	}
+}
@@ -0,0 +1,241 @@
+// Package admin は運用者向けの管理APIを提供する。
+package admin
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/gorilla/mux"
+
+	"github.com/h-hiwatashi/super-business-book-ranking-backend/api/httperror"
+	"github.com/h-hiwatashi/super-business-book-ranking-backend/auth"
+	"github.com/h-hiwatashi/super-business-book-ranking-backend/logging"
+	"github.com/h-hiwatashi/super-business-book-ranking-backend/repository"
+)
+
+// 日付パラメータの書式
+const dateLayout = "2006-01-02"
+
+// リクエストボディの上限
+const maxBodyBytes = 64 << 10
+
+// クリック集計の既定の期間（日数）と、指定できる期間の上限
+const (
+	defaultClickReportDays = 7
+	maxClickReportDays     = 366
+)
+
+// IngestTrigger はランキング取り込みの即時実行を要求する。*ingest.Job が満たす。
+type IngestTrigger interface {
+	Trigger()
+}
+
+// KeyStore は発行したAPIキーを管理するリポジトリである。
+type KeyStore interface {
+	Create(ctx context.Context, key *repository.APIKey) error
+	List(ctx context.Context) ([]repository.APIKey, error)
+	Revoke(ctx context.Context, id string) error
+}
+
+// ClickReporter はクリック数を集計するリポジトリである。
+type ClickReporter interface {
+	Report(ctx context.Context, q repository.ClickReportQuery) ([]repository.ClickStat, error)
+}
+
+// WebhookStore は Webhook の登録と配信キューを管理するリポジトリである。
+type WebhookStore interface {
+	CreateSubscription(ctx context.Context, sub *repository.WebhookSubscription) error
+	ListSubscriptions(ctx context.Context) ([]repository.WebhookSubscription, error)
+	DisableSubscription(ctx context.Context, id string) error
+	ListDeliveries(ctx context.Context, q repository.WebhookDeliveryQuery) ([]repository.WebhookDelivery, error)
+	Redeliver(ctx context.Context, id int64) error
+}
+
+type AdminHandler struct {
+	// Ingest は取り込みジョブである。取り込みが無効な場合は nil とする。
+	Ingest   IngestTrigger
+	Keys     KeyStore
+	Clicks   ClickReporter
+	Webhooks WebhookStore
+
+	now func() time.Time
+}
+
+func NewAdminHandler(ingest IngestTrigger, keys KeyStore, clicks ClickReporter, webhooks WebhookStore) *AdminHandler {
+	return &AdminHandler{
+		Ingest:   ingest,
+		Keys:     keys,
+		Clicks:   clicks,
+		Webhooks: webhooks,
+		now:      time.Now,
+	}
+}
+
+// CreateAPIKeyRequest はAPIキーの発行リクエストである。
+type CreateAPIKeyRequest struct {
+	Name   string   `json:"name"`
+	Role   string   `json:"role"`
+	Scopes []string `json:"scopes"`
+}
+
+// CreateAPIKeyResponse は発行したAPIキーである。キー本体はこのレスポンスでのみ返す。
+type CreateAPIKeyResponse struct {
+	repository.APIKey
+	Key string `json:"key"`
+}
+
+// 取り込み実行ハンドラー
+// 取り込みは非同期に行うため、要求を受け付けた時点で 202 を返す。
+func (h *AdminHandler) RunIngestHandler(w http.ResponseWriter, r *http.Request) {
+	if h.Ingest == nil {
+		http.Error(w, "ランキング取り込みは無効です", http.StatusServiceUnavailable)
+		return
+	}
+
+	h.Ingest.Trigger()
+	logging.FromContext(r.Context()).Info("ランキング取り込みを要求しました")
+	w.WriteHeader(http.StatusAccepted)
+}
+
+// APIキー一覧取得ハンドラー
+func (h *AdminHandler) ListAPIKeysHandler(w http.ResponseWriter, r *http.Request) {
+	keys, err := h.Keys.List(r.Context())
+	if err != nil {
+		httperror.Write(w, r, err, "データベースクエリエラー")
+		return
+	}
+	writeJSON(w, http.StatusOK, keys)
+}
+
+// APIキー発行ハンドラー
+func (h *AdminHandler) CreateAPIKeyHandler(w http.ResponseWriter, r *http.Request) {
+	var req CreateAPIKeyRequest
+	if err := json.NewDecoder(http.MaxBytesReader(w, r.Body, maxBodyBytes)).Decode(&req); err != nil {
+		http.Error(w, "リクエストボディが不正です", http.StatusBadRequest)
+		return
+	}
+	req.Name = strings.TrimSpace(req.Name)
+	if req.Name == "" {
+		http.Error(w, "name は必須です", http.StatusBadRequest)
+		return
+	}
+	role, err := auth.ParseRole(req.Role)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	if req.Scopes == nil {
+		req.Scopes = []string{}
+	}
+	if err := auth.ValidateScopes(req.Scopes); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	key, prefix, hash, err := auth.GenerateKey()
+	if err != nil {
+		httperror.Write(w, r, err, "APIキーの生成エラー")
+		return
+	}
+	apiKey := repository.APIKey{
+		Name:    req.Name,
+		Prefix:  prefix,
+		KeyHash: hash,
+		Role:    string(role),
+		Scopes:  req.Scopes,
+	}
+	if err := h.Keys.Create(r.Context(), &apiKey); err != nil {
+		httperror.Write(w, r, err, "データベースクエリエラー")
+		return
+	}
+
+	logging.FromContext(r.Context()).Info("APIキーを発行しました", "keyId", apiKey.ID, "role", apiKey.Role)
+	writeJSON(w, http.StatusCreated, CreateAPIKeyResponse{APIKey: apiKey, Key: key})
+}
+
+// APIキー失効ハンドラー
+func (h *AdminHandler) RevokeAPIKeyHandler(w http.ResponseWriter, r *http.Request) {
+	keyID := mux.Vars(r)["keyId"]
+
+	err := h.Keys.Revoke(r.Context(), keyID)
+	if errors.Is(err, sql.ErrNoRows) {
+		http.Error(w, "APIキーが見つかりません", http.StatusNotFound)
+		return
+	}
+	if err != nil {
+		httperror.Write(w, r, err, "データベースクエリエラー", "keyId", keyID)
+		return
+	}
+
+	logging.FromContext(r.Context()).Info("APIキーを失効させました", "keyId", keyID)
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// クリック集計ハンドラー
+// from と to（YYYY-MM-DD）の期間のクリック数を日・書籍・サイトごとに返す。省略時は今日までの7日間とする。
+func (h *AdminHandler) ClickReportHandler(w http.ResponseWriter, r *http.Request) {
+	query, err := h.parseClickReportQuery(r)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	stats, err := h.Clicks.Report(r.Context(), query)
+	if err != nil {
+		httperror.Write(w, r, err, "データベースクエリエラー")
+		return
+	}
+	writeJSON(w, http.StatusOK, stats)
+}
+
+// parseClickReportQuery はクエリパラメータからクリック集計の取得条件を組み立てる。
+func (h *AdminHandler) parseClickReportQuery(r *http.Request) (repository.ClickReportQuery, error) {
+	params := r.URL.Query()
+	query := repository.ClickReportQuery{
+		Site:   params.Get("site"),
+		BookID: params.Get("bookId"),
+	}
+
+	today := h.now()
+	to := time.Date(today.Year(), today.Month(), today.Day(), 0, 0, 0, 0, time.UTC)
+	if v := params.Get("to"); v != "" {
+		t, err := time.Parse(dateLayout, v)
+		if err != nil {
+			return query, errors.New("to は YYYY-MM-DD 形式で指定してください")
+		}
+		to = t
+	}
+	from := to.AddDate(0, 0, -(defaultClickReportDays - 1))
+	if v := params.Get("from"); v != "" {
+		t, err := time.Parse(dateLayout, v)
+		if err != nil {
+			return query, errors.New("from は YYYY-MM-DD 形式で指定してください")
+		}
+		from = t
+	}
+
+	if from.After(to) {
+		return query, errors.New("from は to 以前の日付を指定してください")
+	}
+	if to.Sub(from) >= maxClickReportDays*24*time.Hour {
+		return query, fmt.Errorf("期間は%d日以内で指定してください", maxClickReportDays)
+	}
+
+	query.From = from.Format(dateLayout)
+	query.To = to.Format(dateLayout)
+	return query, nil
+}
+
+// 管理APIのレスポンスは利用者ごとに異なり、キー本体を含むこともあるためキャッシュさせない
+func writeJSON(w http.ResponseWriter, status int, v any) {
+	w.Header().Set("Content-Type", "application/json")
+	w.Header().Set("Cache-Control", "no-store")
+	w.WriteHeader(status)
+	json.NewEncoder(w).Encode(v)
+}
@@ -0,0 +1,269 @@
+package admin
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/gorilla/mux"
+
+	"github.com/h-hiwatashi/super-business-book-ranking-backend/auth"
+	"github.com/h-hiwatashi/super-business-book-ranking-backend/repository"
+)
+
+type fakeTrigger struct {
+	count int
+}
+
+func (t *fakeTrigger) Trigger() { t.count++ }
+
+type fakeKeyStore struct {
+	keys []repository.APIKey
+}
+
+func (s *fakeKeyStore) Create(ctx context.Context, key *repository.APIKey) error {
+	key.ID = "key-1"
+	key.CreatedAt = time.Date(2024, 3, 15, 0, 0, 0, 0, time.UTC)
+	s.keys = append(s.keys, *key)
+	return nil
+}
+
+func (s *fakeKeyStore) List(ctx context.Context) ([]repository.APIKey, error) {
+	return s.keys, nil
+}
+
+func (s *fakeKeyStore) Revoke(ctx context.Context, id string) error {
+	for i := range s.keys {
+		if s.keys[i].ID == id && s.keys[i].RevokedAt == nil {
+			now := time.Now()
+			s.keys[i].RevokedAt = &now
+			return nil
+		}
+	}
+	return sql.ErrNoRows
+}
+
+type fakeClickReporter struct {
+	got   repository.ClickReportQuery
+	calls int
+}
+
+func (f *fakeClickReporter) Report(ctx context.Context, q repository.ClickReportQuery) ([]repository.ClickStat, error) {
+	f.got = q
+	f.calls++
+	return []repository.ClickStat{{Date: q.From, BookID: "book-1", Site: "amazon", Clicks: 3}}, nil
+}
+
+func TestRunIngestHandler(t *testing.T) {
+	trigger := &fakeTrigger{}
+	handler := NewAdminHandler(trigger, &fakeKeyStore{}, &fakeClickReporter{}, &fakeWebhookStore{})
+
+	rr := httptest.NewRecorder()
+	handler.RunIngestHandler(rr, httptest.NewRequest("POST", "/admin/ingest", nil))
+
+	if rr.Code != http.StatusAccepted {
+		t.Errorf("status = %v, want %v", rr.Code, http.StatusAccepted)
+	}
+	if trigger.count != 1 {
+		t.Errorf("Trigger() called %d times, want 1", trigger.count)
+	}
+}
+
+func TestRunIngestHandlerDisabled(t *testing.T) {
+	handler := NewAdminHandler(nil, &fakeKeyStore{}, &fakeClickReporter{}, &fakeWebhookStore{})
+
+	rr := httptest.NewRecorder()
+	handler.RunIngestHandler(rr, httptest.NewRequest("POST", "/admin/ingest", nil))
+
+	if rr.Code != http.StatusServiceUnavailable {
+		t.Errorf("status = %v, want %v", rr.Code, http.StatusServiceUnavailable)
+	}
+}
+
+func TestCreateAPIKeyHandler(t *testing.T) {
+	testCases := []struct {
+		name           string
+		body           string
+		wantStatusCode int
+	}{
+		{
+			name:           "正常系：スコープ付きの管理者キー",
+			body:           `{"name":"運用","role":"admin","scopes":["ingest:run","keys:manage"]}`,
+			wantStatusCode: http.StatusCreated,
+		},
+		{
+			name:           "正常系：スコープなしの提携先キー",
+			body:           `{"name":"提携先","role":"partner"}`,
+			wantStatusCode: http.StatusCreated,
+		},
+		{
+			name:           "異常系：名前なし",
+			body:           `{"name":" ","role":"public"}`,
+			wantStatusCode: http.StatusBadRequest,
+		},
+		{
+			name:           "異常系：不正なロール",
+			body:           `{"name":"運用","role":"root"}`,
+			wantStatusCode: http.StatusBadRequest,
+		},
+		{
+			name:           "異常系：不正なスコープ",
+			body:           `{"name":"運用","role":"admin","scopes":["everything"]}`,
+			wantStatusCode: http.StatusBadRequest,
+		},
+		{
+			name:           "異常系：JSONでないボディ",
+			body:           `name=運用`,
+			wantStatusCode: http.StatusBadRequest,
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			store := &fakeKeyStore{}
+			handler := NewAdminHandler(nil, store, &fakeClickReporter{}, &fakeWebhookStore{})
+
+			rr := httptest.NewRecorder()
+			handler.CreateAPIKeyHandler(rr, httptest.NewRequest("POST", "/admin/api-keys", strings.NewReader(tc.body)))
+
+			if rr.Code != tc.wantStatusCode {
+				t.Fatalf("status = %v, want %v: %s", rr.Code, tc.wantStatusCode, rr.Body.String())
+			}
+			if rr.Code != http.StatusCreated {
+				if len(store.keys) != 0 {
+					t.Errorf("stored %d keys, want 0", len(store.keys))
+				}
+				return
+			}
+
+			var got CreateAPIKeyResponse
+			if err := json.NewDecoder(rr.Body).Decode(&got); err != nil {
+				t.Fatal(err)
+			}
+			if got.ID != "key-1" || !strings.HasPrefix(got.Key, got.Prefix) {
+				t.Errorf("unexpected response: %+v", got)
+			}
+			// キー本体は保存せず、ハッシュで照合できる
+			if stored := store.keys[0]; stored.KeyHash != auth.HashKey(got.Key) {
+				t.Errorf("stored hash = %q, want hash of issued key", stored.KeyHash)
+			}
+			if got := rr.Header().Get("Cache-Control"); got != "no-store" {
+				t.Errorf("Cache-Control = %q, want no-store", got)
+			}
+		})
+	}
+}
+
+func TestRevokeAPIKeyHandler(t *testing.T) {
+	testCases := []struct {
+		name           string
+		keyID          string
+		wantStatusCode int
+	}{
+		{
+			name:           "正常系：有効なキー",
+			keyID:          "key-1",
+			wantStatusCode: http.StatusNoContent,
+		},
+		{
+			name:           "異常系：存在しないキー",
+			keyID:          "key-2",
+			wantStatusCode: http.StatusNotFound,
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			store := &fakeKeyStore{keys: []repository.APIKey{{ID: "key-1", Name: "運用", Role: "admin"}}}
+			handler := NewAdminHandler(nil, store, &fakeClickReporter{}, &fakeWebhookStore{})
+
+			req := httptest.NewRequest("DELETE", "/admin/api-keys/"+tc.keyID, nil)
+			req = mux.SetURLVars(req, map[string]string{"keyId": tc.keyID})
+			rr := httptest.NewRecorder()
+			handler.RevokeAPIKeyHandler(rr, req)
+
+			if rr.Code != tc.wantStatusCode {
+				t.Errorf("status = %v, want %v", rr.Code, tc.wantStatusCode)
+			}
+		})
+	}
+}
+
+func TestClickReportHandler(t *testing.T) {
+	testCases := []struct {
+		name           string
+		query          string
+		wantStatusCode int
+		wantQuery      repository.ClickReportQuery
+	}{
+		{
+			name:           "正常系：期間の指定なしは今日までの7日間",
+			wantStatusCode: http.StatusOK,
+			wantQuery:      repository.ClickReportQuery{From: "2024-03-09", To: "2024-03-15"},
+		},
+		{
+			name:           "正常系：期間とサイト・書籍の絞り込み",
+			query:          "?from=2024-03-01&to=2024-03-31&site=amazon&bookId=book-1",
+			wantStatusCode: http.StatusOK,
+			wantQuery:      repository.ClickReportQuery{From: "2024-03-01", To: "2024-03-31", Site: "amazon", BookID: "book-1"},
+		},
+		{
+			name:           "正常系：終了日のみの指定",
+			query:          "?to=2024-02-10",
+			wantStatusCode: http.StatusOK,
+			wantQuery:      repository.ClickReportQuery{From: "2024-02-04", To: "2024-02-10"},
+		},
+		{
+			name:           "異常系：不正な日付",
+			query:          "?from=2024/03/01",
+			wantStatusCode: http.StatusBadRequest,
+		},
+		{
+			name:           "異常系：開始日が終了日より後",
+			query:          "?from=2024-03-10&to=2024-03-01",
+			wantStatusCode: http.StatusBadRequest,
+		},
+		{
+			name:           "異常系：期間が長すぎる",
+			query:          "?from=2023-01-01&to=2024-03-01",
+			wantStatusCode: http.StatusBadRequest,
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			clicks := &fakeClickReporter{}
+			handler := NewAdminHandler(nil, &fakeKeyStore{}, clicks, &fakeWebhookStore{})
+			handler.now = func() time.Time { return time.Date(2024, 3, 15, 10, 0, 0, 0, time.UTC) }
+
+			rr := httptest.NewRecorder()
+			handler.ClickReportHandler(rr, httptest.NewRequest("GET", "/admin/clicks"+tc.query, nil))
+
+			if rr.Code != tc.wantStatusCode {
+				t.Fatalf("status = %v, want %v: %s", rr.Code, tc.wantStatusCode, rr.Body.String())
+			}
+			if rr.Code != http.StatusOK {
+				if clicks.calls != 0 {
+					t.Errorf("Report() called %d times, want 0", clicks.calls)
+				}
+				return
+			}
+			if clicks.got != tc.wantQuery {
+				t.Errorf("query got %+v want %+v", clicks.got, tc.wantQuery)
+			}
+
+			var stats []repository.ClickStat
+			if err := json.NewDecoder(rr.Body).Decode(&stats); err != nil {
+				t.Fatal(err)
+			}
+			if len(stats) != 1 || stats[0].Clicks != 3 {
+				t.Errorf("unexpected stats: %+v", stats)
+			}
+		})
+	}
+}
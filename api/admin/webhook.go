@@ -0,0 +1,207 @@
+package admin
+
+import (
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+
+	"github.com/gorilla/mux"
+
+	"github.com/h-hiwatashi/super-business-book-ranking-backend/api/httperror"
+	"github.com/h-hiwatashi/super-business-book-ranking-backend/logging"
+	"github.com/h-hiwatashi/super-business-book-ranking-backend/repository"
+	"github.com/h-hiwatashi/super-business-book-ranking-backend/webhook"
+)
+
+// 配信ログの既定の取得件数と、指定できる件数の上限
+const (
+	defaultDeliveryLimit = 50
+	maxDeliveryLimit     = 500
+)
+
+// 通知先URLの長さの上限。webhook_subscriptions.url の長さに合わせる
+const maxWebhookURLLength = 2048
+
+// 登録時に省略した場合の通知の対象にする順位
+const defaultTopN = 10
+
+var validPeriods = map[string]bool{"daily": true, "weekly": true, "monthly": true, "yearly": true}
+
+var validDeliveryStatuses = map[string]bool{
+	repository.WebhookPending:   true,
+	repository.WebhookSucceeded: true,
+	repository.WebhookDead:      true,
+}
+
+// CreateWebhookRequest は Webhook の登録リクエストである。
+type CreateWebhookRequest struct {
+	Name string `json:"name"`
+	URL  string `json:"url"`
+	// CategoryID を省略した場合はすべてのカテゴリを通知する。
+	CategoryID string   `json:"categoryId"`
+	PeriodType string   `json:"periodType"`
+	Events     []string `json:"events"`
+	TopN       int      `json:"topN"`
+}
+
+// CreateWebhookResponse は登録した Webhook である。署名の鍵はこのレスポンスでのみ返す。
+type CreateWebhookResponse struct {
+	repository.WebhookSubscription
+	Secret string `json:"secret"`
+}
+
+// Webhook 一覧取得ハンドラー
+func (h *AdminHandler) ListWebhooksHandler(w http.ResponseWriter, r *http.Request) {
+	subs, err := h.Webhooks.ListSubscriptions(r.Context())
+	if err != nil {
+		httperror.Write(w, r, err, "データベースクエリエラー")
+		return
+	}
+	writeJSON(w, http.StatusOK, subs)
+}
+
+// Webhook 登録ハンドラー
+// 署名の鍵はサーバーで生成し、レスポンスで一度だけ返す。
+func (h *AdminHandler) CreateWebhookHandler(w http.ResponseWriter, r *http.Request) {
+	var req CreateWebhookRequest
+	if err := json.NewDecoder(http.MaxBytesReader(w, r.Body, maxBodyBytes)).Decode(&req); err != nil {
+		http.Error(w, "リクエストボディが不正です", http.StatusBadRequest)
+		return
+	}
+	sub, err := newWebhookSubscription(req)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	secret, err := webhook.GenerateSecret()
+	if err != nil {
+		httperror.Write(w, r, err, "署名の鍵の生成エラー")
+		return
+	}
+	sub.Secret = secret
+	err = h.Webhooks.CreateSubscription(r.Context(), &sub)
+	if errors.Is(err, sql.ErrNoRows) {
+		http.Error(w, "カテゴリが見つかりません", http.StatusBadRequest)
+		return
+	}
+	if err != nil {
+		httperror.Write(w, r, err, "データベースクエリエラー")
+		return
+	}
+
+	logging.FromContext(r.Context()).Info("Webhook を登録しました", "subscriptionId", sub.ID, "events", sub.Events)
+	writeJSON(w, http.StatusCreated, CreateWebhookResponse{WebhookSubscription: sub, Secret: secret})
+}
+
+// newWebhookSubscription は登録リクエストを検証し、省略された項目に既定値を設定した登録を返す。
+func newWebhookSubscription(req CreateWebhookRequest) (repository.WebhookSubscription, error) {
+	sub := repository.WebhookSubscription{
+		Name:       strings.TrimSpace(req.Name),
+		URL:        strings.TrimSpace(req.URL),
+		CategoryID: strings.TrimSpace(req.CategoryID),
+		PeriodType: req.PeriodType,
+		Events:     req.Events,
+		TopN:       req.TopN,
+	}
+	if sub.Name == "" {
+		return sub, errors.New("name は必須です")
+	}
+	u, err := url.Parse(sub.URL)
+	if err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" || len(sub.URL) > maxWebhookURLLength {
+		return sub, errors.New("url は http または https のURLを指定してください")
+	}
+	if sub.PeriodType == "" {
+		sub.PeriodType = "daily"
+	}
+	if !validPeriods[sub.PeriodType] {
+		return sub, errors.New("periodType は daily、weekly、monthly、yearly のいずれかを指定してください")
+	}
+	if err := webhook.ValidateEvents(sub.Events); err != nil {
+		return sub, err
+	}
+	if sub.TopN == 0 {
+		sub.TopN = defaultTopN
+	}
+	if sub.TopN < 1 || sub.TopN > webhook.MaxTopN {
+		return sub, fmt.Errorf("topN は1から%dの範囲で指定してください", webhook.MaxTopN)
+	}
+	return sub, nil
+}
+
+// Webhook 無効化ハンドラー
+// 配信ログを残すため登録は削除せず、配信待ちの通知は dead にする。
+func (h *AdminHandler) DisableWebhookHandler(w http.ResponseWriter, r *http.Request) {
+	subscriptionID := mux.Vars(r)["subscriptionId"]
+
+	err := h.Webhooks.DisableSubscription(r.Context(), subscriptionID)
+	if errors.Is(err, sql.ErrNoRows) {
+		http.Error(w, "Webhook が見つかりません", http.StatusNotFound)
+		return
+	}
+	if err != nil {
+		httperror.Write(w, r, err, "データベースクエリエラー", "subscriptionId", subscriptionID)
+		return
+	}
+
+	logging.FromContext(r.Context()).Info("Webhook を無効にしました", "subscriptionId", subscriptionID)
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// Webhook 配信ログ取得ハンドラー
+// subscriptionId と status（pending・succeeded・dead）で絞り込み、新しい順に limit 件返す。
+func (h *AdminHandler) ListWebhookDeliveriesHandler(w http.ResponseWriter, r *http.Request) {
+	params := r.URL.Query()
+	query := repository.WebhookDeliveryQuery{
+		SubscriptionID: params.Get("subscriptionId"),
+		Status:         params.Get("status"),
+		Limit:          defaultDeliveryLimit,
+	}
+	if query.Status != "" && !validDeliveryStatuses[query.Status] {
+		http.Error(w, "status は pending、succeeded、dead のいずれかを指定してください", http.StatusBadRequest)
+		return
+	}
+	if v := params.Get("limit"); v != "" {
+		limit, err := strconv.Atoi(v)
+		if err != nil || limit < 1 || limit > maxDeliveryLimit {
+			http.Error(w, fmt.Sprintf("limit は1から%dの範囲で指定してください", maxDeliveryLimit), http.StatusBadRequest)
+			return
+		}
+		query.Limit = limit
+	}
+
+	deliveries, err := h.Webhooks.ListDeliveries(r.Context(), query)
+	if err != nil {
+		httperror.Write(w, r, err, "データベースクエリエラー")
+		return
+	}
+	writeJSON(w, http.StatusOK, deliveries)
+}
+
+// Webhook 再配信ハンドラー
+// dead になった通知を配信待ちに戻す。送信は非同期に行うため、受け付けた時点で 202 を返す。
+func (h *AdminHandler) RedeliverWebhookHandler(w http.ResponseWriter, r *http.Request) {
+	deliveryID, err := strconv.ParseInt(mux.Vars(r)["deliveryId"], 10, 64)
+	if err != nil {
+		http.Error(w, "配信が見つかりません", http.StatusNotFound)
+		return
+	}
+
+	err = h.Webhooks.Redeliver(r.Context(), deliveryID)
+	if errors.Is(err, sql.ErrNoRows) {
+		http.Error(w, "再配信できる配信が見つかりません", http.StatusNotFound)
+		return
+	}
+	if err != nil {
+		httperror.Write(w, r, err, "データベースクエリエラー", "deliveryId", deliveryID)
+		return
+	}
+
+	logging.FromContext(r.Context()).Info("Webhook の再配信を要求しました", "deliveryId", deliveryID)
+	w.WriteHeader(http.StatusAccepted)
+}
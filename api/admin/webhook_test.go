@@ -0,0 +1,275 @@
+package admin
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/gorilla/mux"
+
+	"github.com/h-hiwatashi/super-business-book-ranking-backend/repository"
+)
+
+type fakeWebhookStore struct {
+	subs       []repository.WebhookSubscription
+	deliveries []repository.WebhookDelivery
+	query      repository.WebhookDeliveryQuery
+}
+
+func (s *fakeWebhookStore) CreateSubscription(ctx context.Context, sub *repository.WebhookSubscription) error {
+	if sub.CategoryID == "999" {
+		return sql.ErrNoRows
+	}
+	sub.ID = "sub-1"
+	sub.CreatedAt = time.Date(2024, 3, 15, 0, 0, 0, 0, time.UTC)
+	s.subs = append(s.subs, *sub)
+	return nil
+}
+
+func (s *fakeWebhookStore) ListSubscriptions(ctx context.Context) ([]repository.WebhookSubscription, error) {
+	return s.subs, nil
+}
+
+func (s *fakeWebhookStore) DisableSubscription(ctx context.Context, id string) error {
+	for i := range s.subs {
+		if s.subs[i].ID == id && s.subs[i].DisabledAt == nil {
+			now := time.Now()
+			s.subs[i].DisabledAt = &now
+			return nil
+		}
+	}
+	return sql.ErrNoRows
+}
+
+func (s *fakeWebhookStore) ListDeliveries(ctx context.Context, q repository.WebhookDeliveryQuery) ([]repository.WebhookDelivery, error) {
+	s.query = q
+	return s.deliveries, nil
+}
+
+func (s *fakeWebhookStore) Redeliver(ctx context.Context, id int64) error {
+	for i := range s.deliveries {
+		if s.deliveries[i].ID == id && s.deliveries[i].Status == repository.WebhookDead {
+			s.deliveries[i].Status = repository.WebhookPending
+			return nil
+		}
+	}
+	return sql.ErrNoRows
+}
+
+func TestCreateWebhookHandler(t *testing.T) {
+	testCases := []struct {
+		name           string
+		body           string
+		wantStatusCode int
+		wantPeriod     string
+		wantTopN       int
+	}{
+		{
+			name:           "正常系：カテゴリと上位を指定",
+			body:           `{"name":"提携先","url":"https://partner.example.com/hooks","categoryId":"001","periodType":"weekly","events":["new_entry","price_drop"],"topN":20}`,
+			wantStatusCode: http.StatusCreated,
+			wantPeriod:     "weekly",
+			wantTopN:       20,
+		},
+		{
+			name:           "正常系：期間と上位の省略",
+			body:           `{"name":"提携先","url":"http://localhost:9000/hooks","events":["dropped_out"]}`,
+			wantStatusCode: http.StatusCreated,
+			wantPeriod:     "daily",
+			wantTopN:       defaultTopN,
+		},
+		{
+			name:           "異常系：名前なし",
+			body:           `{"url":"https://partner.example.com/hooks","events":["new_entry"]}`,
+			wantStatusCode: http.StatusBadRequest,
+		},
+		{
+			name:           "異常系：http でないURL",
+			body:           `{"name":"提携先","url":"ftp://partner.example.com/hooks","events":["new_entry"]}`,
+			wantStatusCode: http.StatusBadRequest,
+		},
+		{
+			name:           "異常系：イベントなし",
+			body:           `{"name":"提携先","url":"https://partner.example.com/hooks"}`,
+			wantStatusCode: http.StatusBadRequest,
+		},
+		{
+			name:           "異常系：不明なイベント",
+			body:           `{"name":"提携先","url":"https://partner.example.com/hooks","events":["rank_down"]}`,
+			wantStatusCode: http.StatusBadRequest,
+		},
+		{
+			name:           "異常系：上位の上限超過",
+			body:           `{"name":"提携先","url":"https://partner.example.com/hooks","events":["new_entry"],"topN":101}`,
+			wantStatusCode: http.StatusBadRequest,
+		},
+		{
+			name:           "異常系：不正な期間",
+			body:           `{"name":"提携先","url":"https://partner.example.com/hooks","events":["new_entry"],"periodType":"hourly"}`,
+			wantStatusCode: http.StatusBadRequest,
+		},
+		{
+			name:           "異常系：存在しないカテゴリ",
+			body:           `{"name":"提携先","url":"https://partner.example.com/hooks","events":["new_entry"],"categoryId":"999"}`,
+			wantStatusCode: http.StatusBadRequest,
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			store := &fakeWebhookStore{}
+			handler := NewAdminHandler(nil, &fakeKeyStore{}, &fakeClickReporter{}, store)
+
+			rr := httptest.NewRecorder()
+			handler.CreateWebhookHandler(rr, httptest.NewRequest("POST", "/admin/webhooks", strings.NewReader(tc.body)))
+
+			if rr.Code != tc.wantStatusCode {
+				t.Fatalf("status = %v, want %v: %s", rr.Code, tc.wantStatusCode, rr.Body.String())
+			}
+			if rr.Code != http.StatusCreated {
+				if len(store.subs) != 0 {
+					t.Errorf("stored %d subscriptions, want 0", len(store.subs))
+				}
+				return
+			}
+
+			var got CreateWebhookResponse
+			if err := json.NewDecoder(rr.Body).Decode(&got); err != nil {
+				t.Fatal(err)
+			}
+			if got.ID != "sub-1" || got.PeriodType != tc.wantPeriod || got.TopN != tc.wantTopN {
+				t.Errorf("unexpected response: %+v", got)
+			}
+			if !strings.HasPrefix(got.Secret, "whsec_") || store.subs[0].Secret != got.Secret {
+				t.Errorf("secret = %q, stored %q", got.Secret, store.subs[0].Secret)
+			}
+		})
+	}
+}
+
+func TestListWebhooksHandlerHidesSecret(t *testing.T) {
+	store := &fakeWebhookStore{subs: []repository.WebhookSubscription{{ID: "sub-1", Name: "提携先", Secret: "whsec_secret"}}}
+	handler := NewAdminHandler(nil, &fakeKeyStore{}, &fakeClickReporter{}, store)
+
+	rr := httptest.NewRecorder()
+	handler.ListWebhooksHandler(rr, httptest.NewRequest("GET", "/admin/webhooks", nil))
+
+	if rr.Code != http.StatusOK {
+		t.Fatalf("status = %v, want %v", rr.Code, http.StatusOK)
+	}
+	if strings.Contains(rr.Body.String(), "whsec_secret") {
+		t.Errorf("一覧に署名の鍵が含まれています: %s", rr.Body.String())
+	}
+}
+
+func TestDisableWebhookHandler(t *testing.T) {
+	testCases := []struct {
+		name           string
+		subscriptionID string
+		wantStatusCode int
+	}{
+		{name: "正常系：有効な登録", subscriptionID: "sub-1", wantStatusCode: http.StatusNoContent},
+		{name: "異常系：存在しない登録", subscriptionID: "sub-2", wantStatusCode: http.StatusNotFound},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			store := &fakeWebhookStore{subs: []repository.WebhookSubscription{{ID: "sub-1"}}}
+			handler := NewAdminHandler(nil, &fakeKeyStore{}, &fakeClickReporter{}, store)
+
+			req := httptest.NewRequest("DELETE", "/admin/webhooks/"+tc.subscriptionID, nil)
+			req = mux.SetURLVars(req, map[string]string{"subscriptionId": tc.subscriptionID})
+			rr := httptest.NewRecorder()
+			handler.DisableWebhookHandler(rr, req)
+
+			if rr.Code != tc.wantStatusCode {
+				t.Errorf("status = %v, want %v", rr.Code, tc.wantStatusCode)
+			}
+		})
+	}
+}
+
+func TestListWebhookDeliveriesHandler(t *testing.T) {
+	testCases := []struct {
+		name           string
+		query          string
+		wantStatusCode int
+		wantQuery      repository.WebhookDeliveryQuery
+	}{
+		{
+			name:           "正常系：指定なし",
+			query:          "",
+			wantStatusCode: http.StatusOK,
+			wantQuery:      repository.WebhookDeliveryQuery{Limit: defaultDeliveryLimit},
+		},
+		{
+			name:           "正常系：dead の絞り込み",
+			query:          "?subscriptionId=sub-1&status=dead&limit=10",
+			wantStatusCode: http.StatusOK,
+			wantQuery:      repository.WebhookDeliveryQuery{SubscriptionID: "sub-1", Status: "dead", Limit: 10},
+		},
+		{
+			name:           "異常系：不正な状態",
+			query:          "?status=failed",
+			wantStatusCode: http.StatusBadRequest,
+		},
+		{
+			name:           "異常系：件数の上限超過",
+			query:          "?limit=501",
+			wantStatusCode: http.StatusBadRequest,
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			store := &fakeWebhookStore{deliveries: []repository.WebhookDelivery{}}
+			handler := NewAdminHandler(nil, &fakeKeyStore{}, &fakeClickReporter{}, store)
+
+			rr := httptest.NewRecorder()
+			handler.ListWebhookDeliveriesHandler(rr, httptest.NewRequest("GET", "/admin/webhooks/deliveries"+tc.query, nil))
+
+			if rr.Code != tc.wantStatusCode {
+				t.Fatalf("status = %v, want %v: %s", rr.Code, tc.wantStatusCode, rr.Body.String())
+			}
+			if rr.Code == http.StatusOK && store.query != tc.wantQuery {
+				t.Errorf("query = %+v, want %+v", store.query, tc.wantQuery)
+			}
+		})
+	}
+}
+
+func TestRedeliverWebhookHandler(t *testing.T) {
+	testCases := []struct {
+		name           string
+		deliveryID     string
+		wantStatusCode int
+	}{
+		{name: "正常系：dead の配信", deliveryID: "1", wantStatusCode: http.StatusAccepted},
+		{name: "異常系：成功済みの配信", deliveryID: "2", wantStatusCode: http.StatusNotFound},
+		{name: "異常系：不正なID", deliveryID: "abc", wantStatusCode: http.StatusNotFound},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			store := &fakeWebhookStore{deliveries: []repository.WebhookDelivery{
+				{ID: 1, Status: repository.WebhookDead},
+				{ID: 2, Status: repository.WebhookSucceeded},
+			}}
+			handler := NewAdminHandler(nil, &fakeKeyStore{}, &fakeClickReporter{}, store)
+
+			req := httptest.NewRequest("POST", "/admin/webhooks/deliveries/"+tc.deliveryID+"/redeliver", nil)
+			req = mux.SetURLVars(req, map[string]string{"deliveryId": tc.deliveryID})
+			rr := httptest.NewRecorder()
+			handler.RedeliverWebhookHandler(rr, req)
+
+			if rr.Code != tc.wantStatusCode {
+				t.Errorf("status = %v, want %v", rr.Code, tc.wantStatusCode)
+			}
+		})
+	}
+}
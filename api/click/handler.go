@@ -0,0 +1,115 @@
+// Package click は商品リンクのクリックを記録し、ECサイトへ転送するハンドラーを提供する。
+package click
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"net/http"
+	"strconv"
+	"time"
+	"unicode/utf8"
+
+	"github.com/gorilla/mux"
+
+	"github.com/h-hiwatashi/super-business-book-ranking-backend/affiliate"
+	"github.com/h-hiwatashi/super-business-book-ranking-backend/api/httperror"
+	"github.com/h-hiwatashi/super-business-book-ranking-backend/middleware"
+	"github.com/h-hiwatashi/super-business-book-ranking-backend/repository"
+)
+
+// clicks.referrer に保存するリファラーの最大長
+const maxReferrerLength = 512
+
+// LinkFinder は商品ページへのリンクを取得するリポジトリである。
+type LinkFinder interface {
+	FindLink(ctx context.Context, bookSiteMappingID string) (*repository.SiteLink, error)
+}
+
+// Recorder はクリックを非同期に保存する。*clicklog.Writer が満たす。
+type Recorder interface {
+	Record(click repository.Click) bool
+}
+
+// IPHasher は送信元IPアドレスをハッシュに変換する。*clicklog.IPHasher が満たす。
+type IPHasher interface {
+	Hash(ip string) string
+}
+
+type ClickHandler struct {
+	Links  LinkFinder
+	Clicks Recorder
+	IPs    IPHasher
+	// Affiliate が nil の場合は商品URLへそのまま転送する。
+	Affiliate affiliate.URLLinker
+
+	now func() time.Time
+}
+
+func NewClickHandler(links LinkFinder, clicks Recorder, ips IPHasher) *ClickHandler {
+	return &ClickHandler{
+		Links:  links,
+		Clicks: clicks,
+		IPs:    ips,
+		now:    time.Now,
+	}
+}
+
+// クリック転送ハンドラー
+// クリック元のランキングは category, period, rank のクエリパラメータで受け取る。
+// クリックの保存を待たずに転送し、保存できなかった場合も転送は行う。
+func (h *ClickHandler) RedirectHandler(w http.ResponseWriter, r *http.Request) {
+	mappingID := mux.Vars(r)["bookSiteMappingId"]
+
+	link, err := h.Links.FindLink(r.Context(), mappingID)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			http.Error(w, "リンクが見つかりません", http.StatusNotFound)
+		} else {
+			httperror.Write(w, r, err, "データベースクエリエラー", "bookSiteMappingId", mappingID)
+		}
+		return
+	}
+
+	h.Clicks.Record(h.newClick(r, link))
+
+	target := affiliate.LinkURL(h.Affiliate, link.Site, link.URL)
+	// クリックごとに記録するため、ブラウザーや中継サーバーに転送をキャッシュさせない
+	w.Header().Set("Cache-Control", "no-store")
+	http.Redirect(w, r, target, http.StatusFound)
+}
+
+func (h *ClickHandler) newClick(r *http.Request, link *repository.SiteLink) repository.Click {
+	params := r.URL.Query()
+	click := repository.Click{
+		BookSiteMappingID: link.BookSiteMappingID,
+		ClickedAt:         h.now(),
+		Referrer:          truncate(r.Referer(), maxReferrerLength),
+		CategoryID:        params.Get("category"),
+		IPHash:            h.IPs.Hash(middleware.ClientIP(r)),
+	}
+	// 不正な値は保存時のエラーでクリック全体を失わないよう、記録しない
+	switch period := params.Get("period"); period {
+	case "daily", "weekly", "monthly", "yearly":
+		click.PeriodType = period
+	}
+	if rank, err := strconv.Atoi(params.Get("rank")); err == nil && rank > 0 {
+		click.Rank = rank
+	}
+	if len(click.CategoryID) > 36 {
+		click.CategoryID = ""
+	}
+	return click
+}
+
+// truncate は s を UTF-8 の文字境界で n バイト以下に切り詰める。
+func truncate(s string, n int) string {
+	if len(s) <= n {
+		return s
+	}
+	s = s[:n]
+	for len(s) > 0 && !utf8.ValidString(s) {
+		s = s[:len(s)-1]
+	}
+	return s
+}
@@ -0,0 +1,139 @@
+package click
+
+import (
+	"context"
+	"database/sql"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/gorilla/mux"
+
+	"github.com/h-hiwatashi/super-business-book-ranking-backend/affiliate"
+	"github.com/h-hiwatashi/super-business-book-ranking-backend/repository"
+)
+
+type fakeLinks map[string]*repository.SiteLink
+
+func (f fakeLinks) FindLink(ctx context.Context, bookSiteMappingID string) (*repository.SiteLink, error) {
+	if link, ok := f[bookSiteMappingID]; ok {
+		return link, nil
+	}
+	return nil, sql.ErrNoRows
+}
+
+type fakeRecorder struct {
+	clicks []repository.Click
+}
+
+func (f *fakeRecorder) Record(click repository.Click) bool {
+	f.clicks = append(f.clicks, click)
+	return true
+}
+
+type fakeHasher struct{}
+
+func (fakeHasher) Hash(ip string) string { return "hash:" + ip }
+
+func TestRedirectHandler(t *testing.T) {
+	links := fakeLinks{
+		"bsm-1": {BookSiteMappingID: "bsm-1", BookID: "book-1", Site: affiliate.SiteAmazon, URL: "https://www.amazon.co.jp/dp/4123456789"},
+	}
+	clickedAt := time.Date(2024, 3, 15, 10, 0, 0, 0, time.UTC)
+
+	testCases := []struct {
+		name           string
+		path           string
+		referrer       string
+		wantStatusCode int
+		wantLocation   string
+		wantClick      *repository.Click
+	}{
+		{
+			name:           "正常系：クリック元のランキング付き",
+			path:           "/go/bsm-1?category=001&period=weekly&rank=3",
+			referrer:       "https://front.example.com/rankings/001",
+			wantStatusCode: http.StatusFound,
+			wantLocation:   "https://www.amazon.co.jp/dp/4123456789?tag=bookranking-22",
+			wantClick: &repository.Click{
+				BookSiteMappingID: "bsm-1",
+				ClickedAt:         clickedAt,
+				Referrer:          "https://front.example.com/rankings/001",
+				CategoryID:        "001",
+				PeriodType:        "weekly",
+				Rank:              3,
+				IPHash:            "hash:192.0.2.1",
+			},
+		},
+		{
+			name:           "正常系：不正なクリック元は記録しない",
+			path:           "/go/bsm-1?period=hourly&rank=-1",
+			wantStatusCode: http.StatusFound,
+			wantLocation:   "https://www.amazon.co.jp/dp/4123456789?tag=bookranking-22",
+			wantClick: &repository.Click{
+				BookSiteMappingID: "bsm-1",
+				ClickedAt:         clickedAt,
+				IPHash:            "hash:192.0.2.1",
+			},
+		},
+		{
+			name:           "異常系：存在しないリンク",
+			path:           "/go/bsm-unknown",
+			wantStatusCode: http.StatusNotFound,
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			recorder := &fakeRecorder{}
+			handler := NewClickHandler(links, recorder, fakeHasher{})
+			handler.Affiliate = affiliate.NewLinker(map[string]string{affiliate.SiteAmazon: "bookranking-22"})
+			handler.now = func() time.Time { return clickedAt }
+
+			router := mux.NewRouter()
+			router.HandleFunc("/go/{bookSiteMappingId}", handler.RedirectHandler).Methods("GET")
+
+			req := httptest.NewRequest("GET", tc.path, nil)
+			req.RemoteAddr = "192.0.2.1:54321"
+			if tc.referrer != "" {
+				req.Header.Set("Referer", tc.referrer)
+			}
+			rr := httptest.NewRecorder()
+			router.ServeHTTP(rr, req)
+
+			if rr.Code != tc.wantStatusCode {
+				t.Fatalf("status = %v, want %v", rr.Code, tc.wantStatusCode)
+			}
+			if got := rr.Header().Get("Location"); got != tc.wantLocation {
+				t.Errorf("Location got %v want %v", got, tc.wantLocation)
+			}
+			if tc.wantClick == nil {
+				if len(recorder.clicks) != 0 {
+					t.Errorf("recorded %d clicks, want 0", len(recorder.clicks))
+				}
+				return
+			}
+			if len(recorder.clicks) != 1 {
+				t.Fatalf("recorded %d clicks, want 1", len(recorder.clicks))
+			}
+			if got := recorder.clicks[0]; got != *tc.wantClick {
+				t.Errorf("click got %+v want %+v", got, *tc.wantClick)
+			}
+			if got := rr.Header().Get("Cache-Control"); got != "no-store" {
+				t.Errorf("Cache-Control = %q, want no-store", got)
+			}
+		})
+	}
+}
+
+func TestTruncate(t *testing.T) {
+	// 3バイトの文字の途中で切らない
+	if got := truncate("ランキング", 7); got != "ラン" {
+		t.Errorf("truncate() got %q want %q", got, "ラン")
+	}
+	if got := truncate(strings.Repeat("a", 10), 20); got != strings.Repeat("a", 10) {
+		t.Errorf("truncate() changed a short string: %q", got)
+	}
+}
@@ -0,0 +1,197 @@
+// Package docs は API 仕様（openapi.yaml・openapi.json）と、それを表示する Swagger UI を提供する。
+// Swagger UI はバイナリに埋め込むため、外部のネットワークに接続できない環境でも表示できる。
+package docs
+
+import (
+	"bytes"
+	"embed"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/fs"
+	"net/http"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/gorilla/mux"
+	swaggerfiles "github.com/swaggo/files/v2"
+	"gopkg.in/yaml.v3"
+
+	"github.com/h-hiwatashi/super-business-book-ranking-backend/api/httpcache"
+	"github.com/h-hiwatashi/super-business-book-ranking-backend/api/httperror"
+)
+
+// API 仕様と Swagger UI のページのキャッシュ期間
+const specMaxAge = 5 * time.Minute
+
+// Swagger UI のスクリプトなどのキャッシュ期間。更新はサーバーの更新時に限られる。
+const assetMaxAge = 24 * time.Hour
+
+//go:embed ui
+var ui embed.FS
+
+// asset は /docs と /docs/{file} で返すファイルである。
+type asset struct {
+	content     []byte
+	contentType string
+	etag        string
+	maxAge      time.Duration
+}
+
+// Swagger UI の配布物のうち、ページが読み込むファイル
+var swaggerAssets = map[string]string{
+	"swagger-ui.css":       "text/css; charset=utf-8",
+	"swagger-ui-bundle.js": "text/javascript; charset=utf-8",
+	"favicon-16x16.png":    "image/png",
+	"favicon-32x32.png":    "image/png",
+}
+
+type DocsHandler struct {
+	// BaseURL は API 仕様の servers に記載する公開URLである。空の場合はリクエストのホストから組み立てる。
+	BaseURL string
+
+	// spec は API 仕様の最上位のマッピングである。リクエストごとに servers だけを差し替える。
+	spec     *yaml.Node
+	specETag string
+	page     asset
+	assets   map[string]asset
+}
+
+// NewDocsHandler は spec（openapi.yaml の内容）を返すハンドラーを返す。
+func NewDocsHandler(spec []byte) (*DocsHandler, error) {
+	var doc yaml.Node
+	if err := yaml.Unmarshal(spec, &doc); err != nil {
+		return nil, fmt.Errorf("API 仕様の読み込みエラー: %w", err)
+	}
+	if doc.Kind != yaml.DocumentNode || len(doc.Content) == 0 || doc.Content[0].Kind != yaml.MappingNode {
+		return nil, errors.New("API 仕様の最上位がマッピングではありません")
+	}
+
+	h := &DocsHandler{
+		spec:     doc.Content[0],
+		specETag: httpcache.ETag(string(spec)),
+		assets:   make(map[string]asset),
+	}
+	var err error
+	if h.page, err = loadAsset(ui, "ui/index.html", "text/html; charset=utf-8", specMaxAge); err != nil {
+		return nil, err
+	}
+	if h.assets["initializer.js"], err = loadAsset(ui, "ui/initializer.js", "text/javascript; charset=utf-8", specMaxAge); err != nil {
+		return nil, err
+	}
+	for name, contentType := range swaggerAssets {
+		if h.assets[name], err = loadAsset(swaggerfiles.FS, name, contentType, assetMaxAge); err != nil {
+			return nil, err
+		}
+	}
+	return h, nil
+}
+
+func loadAsset(fsys fs.FS, name, contentType string, maxAge time.Duration) (asset, error) {
+	content, err := fs.ReadFile(fsys, name)
+	if err != nil {
+		return asset{}, fmt.Errorf("Swagger UI のファイルの読み込みエラー: %w", err)
+	}
+	return asset{content: content, contentType: contentType, etag: httpcache.ETag(string(content)), maxAge: maxAge}, nil
+}
+
+// API 仕様（YAML）取得ハンドラー
+func (h *DocsHandler) SpecYAMLHandler(w http.ResponseWriter, r *http.Request) {
+	h.writeSpec(w, r, "application/yaml; charset=utf-8", func(spec *yaml.Node) ([]byte, error) {
+		var buf bytes.Buffer
+		enc := yaml.NewEncoder(&buf)
+		enc.SetIndent(2)
+		if err := enc.Encode(spec); err != nil {
+			return nil, err
+		}
+		return buf.Bytes(), enc.Close()
+	})
+}
+
+// API 仕様（JSON）取得ハンドラー
+func (h *DocsHandler) SpecJSONHandler(w http.ResponseWriter, r *http.Request) {
+	h.writeSpec(w, r, "application/json", func(spec *yaml.Node) ([]byte, error) {
+		var v interface{}
+		if err := spec.Decode(&v); err != nil {
+			return nil, err
+		}
+		return json.Marshal(v)
+	})
+}
+
+// writeSpec は servers をこのサーバーのURLに差し替えた API 仕様を encode で変換して返す。
+func (h *DocsHandler) writeSpec(w http.ResponseWriter, r *http.Request, contentType string, encode func(*yaml.Node) ([]byte, error)) {
+	base := h.baseURL(r)
+	validators := httpcache.Validators{ETag: httpcache.ETag(h.specETag, base, contentType)}
+	w.Header().Set("Cache-Control", httpcache.MaxAge(specMaxAge))
+	if httpcache.CheckNotModified(w, r, validators) {
+		return
+	}
+
+	body, err := encode(h.withServer(base))
+	if err != nil {
+		httperror.Write(w, r, err, "API 仕様の変換エラー")
+		return
+	}
+	w.Header().Set("Content-Type", contentType)
+	w.Write(body)
+}
+
+// withServer は servers を base だけにした API 仕様を返す。元の仕様は変更しない。
+func (h *DocsHandler) withServer(base string) *yaml.Node {
+	servers := &yaml.Node{Kind: yaml.SequenceNode, Content: []*yaml.Node{{
+		Kind: yaml.MappingNode,
+		Content: []*yaml.Node{
+			{Kind: yaml.ScalarNode, Value: "url"}, {Kind: yaml.ScalarNode, Value: base},
+			{Kind: yaml.ScalarNode, Value: "description"}, {Kind: yaml.ScalarNode, Value: "このサーバー"},
+		},
+	}}}
+
+	spec := *h.spec
+	spec.Content = slices.Clone(h.spec.Content)
+	for i := 0; i+1 < len(spec.Content); i += 2 {
+		if spec.Content[i].Value == "servers" {
+			spec.Content[i+1] = servers
+			return &spec
+		}
+	}
+	spec.Content = append(spec.Content, &yaml.Node{Kind: yaml.ScalarNode, Value: "servers"}, servers)
+	return &spec
+}
+
+// baseURL は API 仕様の servers に記載する公開URLを返す。
+func (h *DocsHandler) baseURL(r *http.Request) string {
+	if h.BaseURL != "" {
+		return strings.TrimSuffix(h.BaseURL, "/")
+	}
+	scheme := "http"
+	if r.TLS != nil {
+		scheme = "https"
+	}
+	return scheme + "://" + r.Host
+}
+
+// Swagger UI のページ取得ハンドラー
+func (h *DocsHandler) UIHandler(w http.ResponseWriter, r *http.Request) {
+	serveAsset(w, r, h.page)
+}
+
+// Swagger UI のファイル取得ハンドラー
+func (h *DocsHandler) AssetHandler(w http.ResponseWriter, r *http.Request) {
+	a, ok := h.assets[mux.Vars(r)["file"]]
+	if !ok {
+		http.Error(w, "ファイルが見つかりません", http.StatusNotFound)
+		return
+	}
+	serveAsset(w, r, a)
+}
+
+func serveAsset(w http.ResponseWriter, r *http.Request, a asset) {
+	w.Header().Set("Cache-Control", httpcache.MaxAge(a.maxAge))
+	if httpcache.CheckNotModified(w, r, httpcache.Validators{ETag: a.etag}) {
+		return
+	}
+	w.Header().Set("Content-Type", a.contentType)
+	w.Write(a.content)
+}
@@ -0,0 +1,256 @@
+package docs
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/gorilla/mux"
+	"gopkg.in/yaml.v3"
+)
+
+const testSpec = `openapi: 3.0.0
+info:
+  title: 書籍ランキングAPI
+  version: 1.0.0
+servers:
+  - url: http://localhost:8080
+    description: 開発環境
+paths: {}
+`
+
+func newTestRouter(h *DocsHandler) *mux.Router {
+	router := mux.NewRouter()
+	router.HandleFunc("/openapi.yaml", h.SpecYAMLHandler).Methods("GET")
+	router.HandleFunc("/openapi.json", h.SpecJSONHandler).Methods("GET")
+	router.HandleFunc("/docs", h.UIHandler).Methods("GET")
+	router.HandleFunc("/docs/{file}", h.AssetHandler).Methods("GET")
+	return router
+}
+
+// testDocument は返された API 仕様のうち、テストで確かめる項目である。
+type testDocument struct {
+	Info struct {
+		Title string `json:"title" yaml:"title"`
+	} `json:"info" yaml:"info"`
+	Servers []struct {
+		URL string `json:"url" yaml:"url"`
+	} `json:"servers" yaml:"servers"`
+}
+
+func TestSpecHandlers(t *testing.T) {
+	testCases := []struct {
+		name            string
+		spec            string
+		baseURL         string
+		target          string
+		tls             bool
+		wantContentType string
+		wantServer      string
+	}{
+		{
+			name:            "正常系：YAML の servers をリクエストのホストに置き換える",
+			spec:            testSpec,
+			target:          "/openapi.yaml",
+			wantContentType: "application/yaml; charset=utf-8",
+			wantServer:      "http://api.example.com",
+		},
+		{
+			name:            "正常系：JSON の servers をリクエストのホストに置き換える",
+			spec:            testSpec,
+			target:          "/openapi.json",
+			wantContentType: "application/json",
+			wantServer:      "http://api.example.com",
+		},
+		{
+			name:            "正常系：TLS の場合は https",
+			spec:            testSpec,
+			target:          "/openapi.json",
+			tls:             true,
+			wantContentType: "application/json",
+			wantServer:      "https://api.example.com",
+		},
+		{
+			name:            "正常系：公開URLを設定した場合は公開URL",
+			spec:            testSpec,
+			baseURL:         "https://ranking.example.com/",
+			target:          "/openapi.json",
+			wantContentType: "application/json",
+			wantServer:      "https://ranking.example.com",
+		},
+		{
+			name:            "正常系：servers のない仕様に追加する",
+			spec:            "openapi: 3.0.0\ninfo:\n  title: 書籍ランキングAPI\n  version: 1.0.0\npaths: {}\n",
+			target:          "/openapi.yaml",
+			wantContentType: "application/yaml; charset=utf-8",
+			wantServer:      "http://api.example.com",
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			h, err := NewDocsHandler([]byte(tc.spec))
+			if err != nil {
+				t.Fatal(err)
+			}
+			h.BaseURL = tc.baseURL
+
+			scheme := "http"
+			if tc.tls {
+				scheme = "https"
+			}
+			req := httptest.NewRequest("GET", scheme+"://api.example.com"+tc.target, nil)
+			rr := httptest.NewRecorder()
+			newTestRouter(h).ServeHTTP(rr, req)
+
+			if rr.Code != http.StatusOK {
+				t.Fatalf("status = %v, want %v", rr.Code, http.StatusOK)
+			}
+			if got := rr.Header().Get("Content-Type"); got != tc.wantContentType {
+				t.Errorf("Content-Type = %q, want %q", got, tc.wantContentType)
+			}
+
+			var doc testDocument
+			if strings.HasSuffix(tc.target, ".json") {
+				err = json.Unmarshal(rr.Body.Bytes(), &doc)
+			} else {
+				err = yaml.Unmarshal(rr.Body.Bytes(), &doc)
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+			if doc.Info.Title != "書籍ランキングAPI" {
+				t.Errorf("info.title = %q", doc.Info.Title)
+			}
+			if len(doc.Servers) != 1 || doc.Servers[0].URL != tc.wantServer {
+				t.Errorf("servers = %+v, want %q", doc.Servers, tc.wantServer)
+			}
+		})
+	}
+}
+
+func TestSpecHandlerNotModified(t *testing.T) {
+	h, err := NewDocsHandler([]byte(testSpec))
+	if err != nil {
+		t.Fatal(err)
+	}
+	router := newTestRouter(h)
+
+	rr := httptest.NewRecorder()
+	router.ServeHTTP(rr, httptest.NewRequest("GET", "http://api.example.com/openapi.json", nil))
+	etag := rr.Header().Get("ETag")
+	if etag == "" {
+		t.Fatal("ETag がありません")
+	}
+
+	// ホストが変わると servers も変わるため、同じ ETag でも一致しない
+	testCases := []struct {
+		name           string
+		target         string
+		wantStatusCode int
+	}{
+		{name: "正常系：同じホストは変更なし", target: "http://api.example.com/openapi.json", wantStatusCode: http.StatusNotModified},
+		{name: "正常系：別のホストは返し直す", target: "http://localhost:8080/openapi.json", wantStatusCode: http.StatusOK},
+		{name: "正常系：別の形式は返し直す", target: "http://api.example.com/openapi.yaml", wantStatusCode: http.StatusOK},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			req := httptest.NewRequest("GET", tc.target, nil)
+			req.Header.Set("If-None-Match", etag)
+			rr := httptest.NewRecorder()
+			router.ServeHTTP(rr, req)
+			if rr.Code != tc.wantStatusCode {
+				t.Errorf("status = %v, want %v", rr.Code, tc.wantStatusCode)
+			}
+		})
+	}
+}
+
+func TestUIHandlers(t *testing.T) {
+	h, err := NewDocsHandler([]byte(testSpec))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	testCases := []struct {
+		name            string
+		target          string
+		wantStatusCode  int
+		wantContentType string
+		wantBody        string
+	}{
+		{
+			name:            "正常系：ページ",
+			target:          "/docs",
+			wantStatusCode:  http.StatusOK,
+			wantContentType: "text/html; charset=utf-8",
+			wantBody:        `<div id="swagger-ui">`,
+		},
+		{
+			name:            "正常系：ページの初期化スクリプト",
+			target:          "/docs/initializer.js",
+			wantStatusCode:  http.StatusOK,
+			wantContentType: "text/javascript; charset=utf-8",
+			wantBody:        `url: "/openapi.json"`,
+		},
+		{
+			name:            "正常系：Swagger UI のスクリプト",
+			target:          "/docs/swagger-ui-bundle.js",
+			wantStatusCode:  http.StatusOK,
+			wantContentType: "text/javascript; charset=utf-8",
+			wantBody:        "SwaggerUIBundle",
+		},
+		{
+			name:            "正常系：Swagger UI のスタイルシート",
+			target:          "/docs/swagger-ui.css",
+			wantStatusCode:  http.StatusOK,
+			wantContentType: "text/css; charset=utf-8",
+			wantBody:        ".swagger-ui",
+		},
+		{
+			name:           "異常系：配布物にないファイル",
+			target:         "/docs/index.html",
+			wantStatusCode: http.StatusNotFound,
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			rr := httptest.NewRecorder()
+			newTestRouter(h).ServeHTTP(rr, httptest.NewRequest("GET", tc.target, nil))
+
+			if rr.Code != tc.wantStatusCode {
+				t.Fatalf("status = %v, want %v", rr.Code, tc.wantStatusCode)
+			}
+			if tc.wantStatusCode != http.StatusOK {
+				return
+			}
+			if got := rr.Header().Get("Content-Type"); got != tc.wantContentType {
+				t.Errorf("Content-Type = %q, want %q", got, tc.wantContentType)
+			}
+			if !strings.Contains(rr.Body.String(), tc.wantBody) {
+				t.Errorf("body に %q がありません", tc.wantBody)
+			}
+		})
+	}
+}
+
+func TestNewDocsHandlerInvalidSpec(t *testing.T) {
+	testCases := []struct {
+		name string
+		spec string
+	}{
+		{name: "異常系：YAML として読めない", spec: "openapi: [3.0.0"},
+		{name: "異常系：最上位がマッピングでない", spec: "- openapi"},
+		{name: "異常系：空", spec: ""},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			if _, err := NewDocsHandler([]byte(tc.spec)); err == nil {
+				t.Error("エラーになりません")
+			}
+		})
+	}
+}
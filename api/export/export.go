@@ -0,0 +1,143 @@
+// Package export はランキングを CSV・TSV で返すための形式の判定と書き出しを行う。
+package export
+
+import (
+	"encoding/csv"
+	"errors"
+	"fmt"
+	"mime"
+	"net/http"
+	"strconv"
+	"strings"
+)
+
+// Format はレスポンスの形式である。
+type Format string
+
+const (
+	FormatJSON Format = "json"
+	FormatCSV  Format = "csv"
+	FormatTSV  Format = "tsv"
+)
+
+// 形式ごとのメディアタイプ
+const (
+	mediaTypeCSV = "text/csv"
+	mediaTypeTSV = "text/tab-separated-values"
+)
+
+// Excel が UTF-8 と判定するためのバイト順マーク
+const bom = "\xef\xbb\xbf"
+
+// Negotiate は format クエリパラメータ、なければ Accept ヘッダーからレスポンスの形式を決める。
+// Accept は q 値を考慮せず、CSV・TSV・JSON のうち最初に現れたものを選ぶ。いずれもない場合は JSON とする。
+func Negotiate(r *http.Request) (Format, error) {
+	if v := r.URL.Query().Get("format"); v != "" {
+		switch format := Format(strings.ToLower(v)); format {
+		case FormatJSON, FormatCSV, FormatTSV:
+			return format, nil
+		default:
+			return "", errors.New("format は json, csv, tsv のいずれかで指定してください")
+		}
+	}
+
+	for _, value := range r.Header.Values("Accept") {
+		for _, part := range strings.Split(value, ",") {
+			mediaType, params, err := mime.ParseMediaType(strings.TrimSpace(part))
+			if err != nil || params["q"] == "0" {
+				continue
+			}
+			switch mediaType {
+			case mediaTypeCSV:
+				return FormatCSV, nil
+			case mediaTypeTSV:
+				return FormatTSV, nil
+			case "application/json":
+				return FormatJSON, nil
+			}
+		}
+	}
+	return FormatJSON, nil
+}
+
+// Writer は CSV・TSV の行をレスポンスに書き出す。
+// 行はバッファに溜めず、Flush のたびにクライアントへ送る。
+type Writer struct {
+	w   http.ResponseWriter
+	csv *csv.Writer
+}
+
+// NewWriter は format の Content-Type と、filename で保存させる Content-Disposition を設定した Writer を返す。
+// bom クエリパラメータが true の場合は、Excel で文字化けしないよう先頭にバイト順マークを書く。
+// format は FormatCSV か FormatTSV である。
+func NewWriter(w http.ResponseWriter, r *http.Request, format Format, filename string) *Writer {
+	mediaType := mediaTypeCSV
+	if format == FormatTSV {
+		mediaType = mediaTypeTSV
+	}
+	w.Header().Set("Content-Type", mediaType+"; charset=utf-8")
+	w.Header().Set("Content-Disposition", fmt.Sprintf(`attachment; filename="%s.%s"`, filename, format))
+
+	if withBOM, _ := strconv.ParseBool(r.URL.Query().Get("bom")); withBOM {
+		w.Write([]byte(bom))
+	}
+
+	cw := csv.NewWriter(w)
+	if format == FormatTSV {
+		cw.Comma = '\t'
+	}
+	return &Writer{w: w, csv: cw}
+}
+
+// Write は1行を書き出す。区切り文字や引用符、改行を含む値は引用符で囲む。
+// 表計算ソフトで数式として解釈される値は、先頭に ' を付けて文字列として扱わせる。
+func (w *Writer) Write(record []string) error {
+	escaped := make([]string, len(record))
+	for i, field := range record {
+		escaped[i] = escapeFormula(field)
+	}
+	return w.csv.Write(escaped)
+}
+
+// Flush は書き出した行をクライアントへ送る。
+func (w *Writer) Flush() error {
+	w.csv.Flush()
+	if err := w.csv.Error(); err != nil {
+		return err
+	}
+	// ミドルウェアが ResponseWriter を包んでいても送れるよう ResponseController を使う
+	if err := http.NewResponseController(w.w).Flush(); err != nil && !errors.Is(err, http.ErrNotSupported) {
+		return err
+	}
+	return nil
+}
+
+// escapeFormula は =, +, -, @ などで始まる値の先頭に ' を付ける。数値はそのまま返す。
+func escapeFormula(s string) string {
+	if s == "" || !strings.ContainsRune("=+-@\t\r", rune(s[0])) {
+		return s
+	}
+	if _, err := strconv.ParseFloat(s, 64); err == nil {
+		return s
+	}
+	return "'" + s
+}
+
+// Filename は識別子をファイル名に使える文字（英数字、-、_）だけにして "-" で連結する。
+func Filename(parts ...string) string {
+	cleaned := make([]string, 0, len(parts))
+	for _, part := range parts {
+		part = strings.Map(func(r rune) rune {
+			switch {
+			case r >= 'a' && r <= 'z', r >= 'A' && r <= 'Z', r >= '0' && r <= '9', r == '-', r == '_':
+				return r
+			default:
+				return -1
+			}
+		}, part)
+		if part != "" {
+			cleaned = append(cleaned, part)
+		}
+	}
+	return strings.Join(cleaned, "-")
+}
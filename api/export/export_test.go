@@ -0,0 +1,162 @@
+package export
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestNegotiate(t *testing.T) {
+	testCases := []struct {
+		name    string
+		query   string
+		accept  string
+		want    Format
+		wantErr bool
+	}{
+		{
+			name: "正常系：指定なしは JSON",
+			want: FormatJSON,
+		},
+		{
+			name:  "正常系：format=csv",
+			query: "?format=csv",
+			want:  FormatCSV,
+		},
+		{
+			name:   "正常系：format は Accept より優先する",
+			query:  "?format=TSV",
+			accept: "text/csv",
+			want:   FormatTSV,
+		},
+		{
+			name:   "正常系：Accept: text/csv",
+			accept: "text/csv; charset=utf-8",
+			want:   FormatCSV,
+		},
+		{
+			name:   "正常系：Accept の最初の対応形式",
+			accept: "text/html, text/tab-separated-values, text/csv",
+			want:   FormatTSV,
+		},
+		{
+			name:   "正常系：q=0 の形式は選ばない",
+			accept: "text/csv;q=0, application/json",
+			want:   FormatJSON,
+		},
+		{
+			name:   "正常系：ブラウザーの Accept は JSON",
+			accept: "text/html,application/xhtml+xml,*/*;q=0.8",
+			want:   FormatJSON,
+		},
+		{
+			name:    "異常系：未対応の format",
+			query:   "?format=xlsx",
+			wantErr: true,
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			req := httptest.NewRequest("GET", "/api/rankings/001"+tc.query, nil)
+			if tc.accept != "" {
+				req.Header.Set("Accept", tc.accept)
+			}
+			got, err := Negotiate(req)
+			if (err != nil) != tc.wantErr {
+				t.Fatalf("Negotiate() error = %v, wantErr %v", err, tc.wantErr)
+			}
+			if got != tc.want {
+				t.Errorf("Negotiate() got %v want %v", got, tc.want)
+			}
+		})
+	}
+}
+
+func TestWriter(t *testing.T) {
+	record := []string{"1", "「なぜ」から始めよ, 新版", `著者 "A"`, "=HYPERLINK(\"x\")", "-5", "1480.5"}
+
+	testCases := []struct {
+		name            string
+		query           string
+		format          Format
+		wantBody        string
+		wantContentType string
+	}{
+		{
+			name:            "正常系：CSV は区切り文字と引用符を含む値を引用符で囲む",
+			format:          FormatCSV,
+			wantBody:        "1,\"「なぜ」から始めよ, 新版\",\"著者 \"\"A\"\"\",\"'=HYPERLINK(\"\"x\"\")\",-5,1480.5\n",
+			wantContentType: "text/csv; charset=utf-8",
+		},
+		{
+			name:            "正常系：TSV",
+			format:          FormatTSV,
+			wantBody:        "1\t「なぜ」から始めよ, 新版\t\"著者 \"\"A\"\"\"\t\"'=HYPERLINK(\"\"x\"\")\"\t-5\t1480.5\n",
+			wantContentType: "text/tab-separated-values; charset=utf-8",
+		},
+		{
+			name:            "正常系：bom=true は先頭にバイト順マークを付ける",
+			query:           "?bom=true",
+			format:          FormatCSV,
+			wantBody:        "\xef\xbb\xbf1,\"「なぜ」から始めよ, 新版\",\"著者 \"\"A\"\"\",\"'=HYPERLINK(\"\"x\"\")\",-5,1480.5\n",
+			wantContentType: "text/csv; charset=utf-8",
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			rr := httptest.NewRecorder()
+			req := httptest.NewRequest("GET", "/api/rankings/001"+tc.query, nil)
+
+			out := NewWriter(rr, req, tc.format, "ranking-001")
+			if err := out.Write(record); err != nil {
+				t.Fatal(err)
+			}
+			if err := out.Flush(); err != nil {
+				t.Fatal(err)
+			}
+
+			if got := rr.Body.String(); got != tc.wantBody {
+				t.Errorf("body got %q want %q", got, tc.wantBody)
+			}
+			if got := rr.Header().Get("Content-Type"); got != tc.wantContentType {
+				t.Errorf("Content-Type got %v want %v", got, tc.wantContentType)
+			}
+			wantDisposition := `attachment; filename="ranking-001.` + string(tc.format) + `"`
+			if got := rr.Header().Get("Content-Disposition"); got != wantDisposition {
+				t.Errorf("Content-Disposition got %v want %v", got, wantDisposition)
+			}
+		})
+	}
+}
+
+func TestFilename(t *testing.T) {
+	if got := Filename("ranking", "../001", "daily", "2024-03-15"); got != "ranking-001-daily-2024-03-15" {
+		t.Errorf("Filename() got %v", got)
+	}
+	if got := Filename("ranking", "ビジネス", "daily"); got != "ranking-daily" {
+		t.Errorf("Filename() got %v", got)
+	}
+}
+
+// wrappedWriter はミドルウェアが包んだ ResponseWriter である。
+type wrappedWriter struct {
+	http.ResponseWriter
+}
+
+func (w *wrappedWriter) Unwrap() http.ResponseWriter { return w.ResponseWriter }
+
+func TestWriterFlushThroughMiddleware(t *testing.T) {
+	rr := httptest.NewRecorder()
+	req := httptest.NewRequest("GET", "/api/export/rankings", nil)
+
+	out := NewWriter(&wrappedWriter{rr}, req, FormatCSV, "rankings")
+	out.Write([]string{"1"})
+	if err := out.Flush(); err != nil {
+		t.Fatal(err)
+	}
+	if !rr.Flushed {
+		t.Error("Flush() did not reach the underlying ResponseWriter")
+	}
+}
@@ -0,0 +1,198 @@
+// Package feed はランキングを Atom・RSS のフィードとして配信するハンドラーを提供する。
+package feed
+
+import (
+	"bytes"
+	"encoding/xml"
+	"time"
+)
+
+// Content-Type
+const (
+	atomContentType = "application/atom+xml; charset=utf-8"
+	rssContentType  = "application/rss+xml; charset=utf-8"
+)
+
+// 書影に使う Media RSS の名前空間
+const mediaNamespace = "http://search.yahoo.com/mrss/"
+
+// フィードの発行者名。Atom では著者のないエントリーがあるためフィードに必須となる。
+const publisher = "ビジネス書ランキング"
+
+// Feed は Atom と RSS に共通するフィードの内容である。
+type Feed struct {
+	// ID はフィードを識別する変わらないURLである。
+	ID       string
+	Title    string
+	Subtitle string
+	// Link はフィードの元になったランキングのURLである。
+	Link string
+	// SelfURL はフィード自身のURLである。
+	SelfURL string
+	Updated time.Time
+	Entries []Entry
+}
+
+// Entry はフィードの1書籍分の項目である。
+type Entry struct {
+	ID    string
+	Title string
+	// Link は商品ページ（アフィリエイトリンク）である。
+	Link   string
+	Author string
+	// Summary は書影と書籍情報を含む HTML である。
+	Summary  string
+	ImageURL string
+	Updated  time.Time
+}
+
+type atomFeed struct {
+	XMLName    xml.Name    `xml:"http://www.w3.org/2005/Atom feed"`
+	XMLNSMedia string      `xml:"xmlns:media,attr"`
+	ID         string      `xml:"id"`
+	Title      string      `xml:"title"`
+	Subtitle   string      `xml:"subtitle,omitempty"`
+	Updated    string      `xml:"updated"`
+	Links      []atomLink  `xml:"link"`
+	Author     atomPerson  `xml:"author"`
+	Entries    []atomEntry `xml:"entry"`
+}
+
+type atomLink struct {
+	Rel  string `xml:"rel,attr,omitempty"`
+	Type string `xml:"type,attr,omitempty"`
+	Href string `xml:"href,attr"`
+}
+
+type atomPerson struct {
+	Name string `xml:"name"`
+}
+
+type atomText struct {
+	Type string `xml:"type,attr"`
+	Body string `xml:",chardata"`
+}
+
+type atomEntry struct {
+	ID        string          `xml:"id"`
+	Title     string          `xml:"title"`
+	Updated   string          `xml:"updated"`
+	Link      *atomLink       `xml:"link"`
+	Author    *atomPerson     `xml:"author"`
+	Summary   atomText        `xml:"summary"`
+	Thumbnail *mediaThumbnail `xml:"media:thumbnail"`
+}
+
+type mediaThumbnail struct {
+	URL string `xml:"url,attr"`
+}
+
+type rssFeed struct {
+	XMLName    xml.Name   `xml:"rss"`
+	Version    string     `xml:"version,attr"`
+	XMLNSAtom  string     `xml:"xmlns:atom,attr"`
+	XMLNSMedia string     `xml:"xmlns:media,attr"`
+	Channel    rssChannel `xml:"channel"`
+}
+
+type rssChannel struct {
+	Title         string    `xml:"title"`
+	Link          string    `xml:"link"`
+	Description   string    `xml:"description"`
+	AtomLink      atomLink  `xml:"atom:link"`
+	LastBuildDate string    `xml:"lastBuildDate"`
+	Items         []rssItem `xml:"item"`
+}
+
+type rssItem struct {
+	Title       string          `xml:"title"`
+	Link        string          `xml:"link,omitempty"`
+	Description string          `xml:"description"`
+	GUID        rssGUID         `xml:"guid"`
+	PubDate     string          `xml:"pubDate"`
+	Thumbnail   *mediaThumbnail `xml:"media:thumbnail"`
+}
+
+type rssGUID struct {
+	IsPermaLink bool   `xml:"isPermaLink,attr"`
+	ID          string `xml:",chardata"`
+}
+
+// Atom はフィードを Atom 1.0（RFC 4287）の XML に変換する。
+func (f *Feed) Atom() ([]byte, error) {
+	feed := atomFeed{
+		XMLNSMedia: mediaNamespace,
+		ID:         f.ID,
+		Title:      f.Title,
+		Subtitle:   f.Subtitle,
+		Updated:    f.Updated.Format(time.RFC3339),
+		Links: []atomLink{
+			{Rel: "self", Type: "application/atom+xml", Href: f.SelfURL},
+			{Rel: "alternate", Type: "application/json", Href: f.Link},
+		},
+		Author: atomPerson{Name: publisher},
+	}
+	for _, e := range f.Entries {
+		entry := atomEntry{
+			ID:        e.ID,
+			Title:     e.Title,
+			Updated:   e.Updated.Format(time.RFC3339),
+			Summary:   atomText{Type: "html", Body: e.Summary},
+			Thumbnail: thumbnail(e.ImageURL),
+		}
+		if e.Link != "" {
+			entry.Link = &atomLink{Rel: "alternate", Type: "text/html", Href: e.Link}
+		}
+		if e.Author != "" {
+			entry.Author = &atomPerson{Name: e.Author}
+		}
+		feed.Entries = append(feed.Entries, entry)
+	}
+	return marshal(feed)
+}
+
+// RSS はフィードを RSS 2.0 の XML に変換する。
+func (f *Feed) RSS() ([]byte, error) {
+	feed := rssFeed{
+		Version:    "2.0",
+		XMLNSAtom:  "http://www.w3.org/2005/Atom",
+		XMLNSMedia: mediaNamespace,
+		Channel: rssChannel{
+			Title:         f.Title,
+			Link:          f.Link,
+			Description:   f.Subtitle,
+			AtomLink:      atomLink{Rel: "self", Type: "application/rss+xml", Href: f.SelfURL},
+			LastBuildDate: f.Updated.Format(time.RFC1123Z),
+		},
+	}
+	for _, e := range f.Entries {
+		feed.Channel.Items = append(feed.Channel.Items, rssItem{
+			Title:       e.Title,
+			Link:        e.Link,
+			Description: e.Summary,
+			GUID:        rssGUID{ID: e.ID},
+			PubDate:     e.Updated.Format(time.RFC1123Z),
+			Thumbnail:   thumbnail(e.ImageURL),
+		})
+	}
+	return marshal(feed)
+}
+
+func thumbnail(imageURL string) *mediaThumbnail {
+	if imageURL == "" {
+		return nil
+	}
+	return &mediaThumbnail{URL: imageURL}
+}
+
+func marshal(v interface{}) ([]byte, error) {
+	var buf bytes.Buffer
+	buf.WriteString(xml.Header)
+	enc := xml.NewEncoder(&buf)
+	enc.Indent("", "  ")
+	if err := enc.Encode(v); err != nil {
+		return nil, err
+	}
+	buf.WriteByte('\n')
+	return buf.Bytes(), nil
+}
@@ -0,0 +1,291 @@
+package feed
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"html"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/gorilla/mux"
+
+	"github.com/h-hiwatashi/super-business-book-ranking-backend/affiliate"
+	"github.com/h-hiwatashi/super-business-book-ranking-backend/api/httpcache"
+	"github.com/h-hiwatashi/super-business-book-ranking-backend/api/httperror"
+	"github.com/h-hiwatashi/super-business-book-ranking-backend/repository"
+)
+
+// フィードのキャッシュ期間
+const feedMaxAge = 10 * time.Minute
+
+// 前回のスナップショットから比較のために取得する書籍数
+const compareLimit = 100
+
+// フィードの種類
+const (
+	// KindTop は最新のスナップショットの上位である。
+	KindTop = "top"
+	// KindNew は前回の上位になかった書籍である。
+	KindNew = "new"
+	// KindMovers は前回から順位を大きく上げた書籍である。
+	KindMovers = "movers"
+)
+
+// 期間の種類ごとの表示名
+var periodLabels = map[string]string{
+	"daily":   "日間",
+	"weekly":  "週間",
+	"monthly": "月間",
+	"yearly":  "年間",
+}
+
+// RankingFinder はランキングを取得するリポジトリである。
+type RankingFinder interface {
+	Find(ctx context.Context, q repository.RankingQuery) (*repository.Ranking, error)
+}
+
+// SnapshotDates はスナップショットの日付を取得するリポジトリである。
+type SnapshotDates interface {
+	PreviousDate(ctx context.Context, categoryID, periodType string) (string, error)
+}
+
+type FeedHandler struct {
+	Rankings  RankingFinder
+	Snapshots SnapshotDates
+	// Links が nil の場合は商品URLをそのまま載せる。
+	Links affiliate.URLLinker
+	// BaseURL はフィードのIDとリンクに使う公開URLである。空の場合はリクエストのホストから組み立てる。
+	BaseURL string
+	// Size はフィードに載せる上位の書籍数である。
+	Size int
+	// MoverThreshold は急上昇フィードに載せる順位の上昇幅の下限である。
+	MoverThreshold int
+
+	now func() time.Time
+}
+
+func NewFeedHandler(rankings RankingFinder, snapshots SnapshotDates, size, moverThreshold int) *FeedHandler {
+	return &FeedHandler{
+		Rankings:       rankings,
+		Snapshots:      snapshots,
+		Size:           size,
+		MoverThreshold: moverThreshold,
+		now:            time.Now,
+	}
+}
+
+// ランキングフィードハンドラー
+// パスの kind（new, movers。省略時は上位）と format（atom, rss）でフィードを選ぶ。
+// new と movers は最新と1つ前のスナップショットを比べ、前回がない場合は項目のないフィードを返す。
+func (h *FeedHandler) RankingFeedHandler(w http.ResponseWriter, r *http.Request) {
+	vars := mux.Vars(r)
+	categoryID := vars["categoryId"]
+	format := vars["format"]
+	kind := vars["kind"]
+	if kind == "" {
+		kind = KindTop
+	}
+	periodType := r.URL.Query().Get("period")
+	if periodType == "" {
+		periodType = "daily" // デフォルト値
+	}
+	if _, ok := periodLabels[periodType]; !ok {
+		http.Error(w, "period は daily, weekly, monthly, yearly のいずれかで指定してください", http.StatusBadRequest)
+		return
+	}
+
+	current, err := h.Rankings.Find(r.Context(), repository.RankingQuery{
+		CategoryID: categoryID,
+		PeriodType: periodType,
+		Page:       1,
+		Limit:      h.Size,
+	})
+	if err != nil {
+		httperror.Write(w, r, err, "データベースクエリエラー", "categoryId", categoryID)
+		return
+	}
+	if len(current.Books) == 0 {
+		http.Error(w, "ランキングが見つかりません", http.StatusNotFound)
+		return
+	}
+
+	var previous map[string]int
+	if kind != KindTop {
+		previous, err = h.previousRanks(r.Context(), categoryID, periodType)
+		if err != nil {
+			httperror.Write(w, r, err, "データベースクエリエラー", "categoryId", categoryID)
+			return
+		}
+	}
+
+	base := h.baseURL(r)
+	feed := h.newFeed(base, r, kind, current, previous)
+
+	var body []byte
+	contentType := atomContentType
+	if format == "rss" {
+		body, err = feed.RSS()
+		contentType = rssContentType
+	} else {
+		body, err = feed.Atom()
+	}
+	if err != nil {
+		httperror.Write(w, r, err, "フィードの生成エラー", "categoryId", categoryID)
+		return
+	}
+
+	validators := httpcache.Validators{
+		ETag:         httpcache.ETag(string(body)),
+		LastModified: current.LastModified,
+	}
+	w.Header().Set("Cache-Control", httpcache.MaxAge(feedMaxAge))
+	if httpcache.CheckNotModified(w, r, validators) {
+		return
+	}
+	w.Header().Set("Content-Type", contentType)
+	w.Write(body)
+}
+
+// previousRanks は1つ前のスナップショットの書籍IDごとの順位を返す。
+// 前回のスナップショットがない場合は nil を返す。
+func (h *FeedHandler) previousRanks(ctx context.Context, categoryID, periodType string) (map[string]int, error) {
+	date, err := h.Snapshots.PreviousDate(ctx, categoryID, periodType)
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	previous, err := h.Rankings.Find(ctx, repository.RankingQuery{
+		CategoryID: categoryID,
+		PeriodType: periodType,
+		Date:       date,
+		Page:       1,
+		Limit:      compareLimit,
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	ranks := make(map[string]int, len(previous.Books))
+	for _, book := range previous.Books {
+		// 複数のサイトで同じ書籍がある場合は最も高い順位を使う
+		if rank, ok := ranks[book.ID]; !ok || book.Rank < rank {
+			ranks[book.ID] = book.Rank
+		}
+	}
+	return ranks, nil
+}
+
+// newFeed はランキングからフィードの種類に応じた項目を選んでフィードを組み立てる。
+func (h *FeedHandler) newFeed(base string, r *http.Request, kind string, ranking *repository.Ranking, previous map[string]int) *Feed {
+	date := ranking.DateTo
+	updated := ranking.LastModified
+	if updated.IsZero() {
+		updated = h.now()
+	}
+
+	name := ranking.CategoryName
+	if name == "" {
+		name = ranking.CategoryID
+	}
+	label := periodLabels[ranking.PeriodType]
+	feed := &Feed{
+		ID:      fmt.Sprintf("%s/feeds/rankings/%s/%s/%s", base, url.PathEscape(ranking.CategoryID), ranking.PeriodType, kind),
+		Link:    fmt.Sprintf("%s/api/v1/rankings/%s?period=%s", base, url.PathEscape(ranking.CategoryID), ranking.PeriodType),
+		SelfURL: base + r.URL.RequestURI(),
+		Updated: updated,
+	}
+	switch kind {
+	case KindNew:
+		feed.Title = fmt.Sprintf("%sの%sランキング 新着", name, label)
+		feed.Subtitle = fmt.Sprintf("前回の上位%d位になかった書籍", h.Size)
+	case KindMovers:
+		feed.Title = fmt.Sprintf("%sの%sランキング 急上昇", name, label)
+		feed.Subtitle = fmt.Sprintf("前回から%d位以上順位を上げた書籍", h.MoverThreshold)
+	default:
+		feed.Title = fmt.Sprintf("%sの%sランキング TOP%d", name, label, h.Size)
+		feed.Subtitle = fmt.Sprintf("%sの%sランキングの上位%d位", name, label, h.Size)
+	}
+
+	seen := make(map[string]bool)
+	for _, book := range ranking.Books {
+		// 複数のサイトで同じ書籍がある場合は最も高い順位だけを載せる
+		if seen[book.ID] {
+			continue
+		}
+		seen[book.ID] = true
+
+		title := fmt.Sprintf("%d位 %s", book.Rank, book.Title)
+		previousRank, ranked := previous[book.ID]
+		switch kind {
+		case KindNew:
+			if previous == nil || (ranked && previousRank <= h.Size) {
+				continue
+			}
+			if ranked {
+				title += fmt.Sprintf("（前回%d位）", previousRank)
+			} else {
+				title += "（前回ランク外）"
+			}
+		case KindMovers:
+			if !ranked || previousRank-book.Rank < h.MoverThreshold {
+				continue
+			}
+			title += fmt.Sprintf("（前回%d位から%d位上昇）", previousRank, previousRank-book.Rank)
+		}
+
+		link := affiliate.LinkURL(h.Links, book.Site, book.URL)
+		feed.Entries = append(feed.Entries, Entry{
+			// 同じ書籍でもスナップショットとフィードごとに別の項目とする
+			ID:       fmt.Sprintf("%s/%s/%s", feed.ID, date, url.PathEscape(book.ID)),
+			Title:    title,
+			Link:     link,
+			Author:   book.Author,
+			Summary:  summary(book),
+			ImageURL: book.ImageURL,
+			Updated:  updated,
+		})
+	}
+	return feed
+}
+
+// baseURL はフィードのIDとリンクに使う公開URLを返す。
+func (h *FeedHandler) baseURL(r *http.Request) string {
+	if h.BaseURL != "" {
+		return strings.TrimSuffix(h.BaseURL, "/")
+	}
+	scheme := "http"
+	if r.TLS != nil {
+		scheme = "https"
+	}
+	return scheme + "://" + r.Host
+}
+
+// summary は書影と書籍情報の HTML を返す。
+func summary(book repository.RankedBook) string {
+	var b strings.Builder
+	if book.ImageURL != "" {
+		fmt.Fprintf(&b, `<p><img src="%s" alt="%s"></p>`, html.EscapeString(book.ImageURL), html.EscapeString(book.Title))
+	}
+	var details []string
+	if book.Author != "" {
+		details = append(details, "著者: "+html.EscapeString(book.Author))
+	}
+	if book.Publisher != "" {
+		details = append(details, "出版社: "+html.EscapeString(book.Publisher))
+	}
+	if book.Price > 0 {
+		details = append(details, "価格: "+strconv.FormatFloat(book.Price, 'f', -1, 64)+"円")
+	}
+	if len(details) > 0 {
+		b.WriteString("<p>" + strings.Join(details, " / ") + "</p>")
+	}
+	return b.String()
+}
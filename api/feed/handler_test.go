@@ -0,0 +1,274 @@
+package feed
+
+import (
+	"context"
+	"database/sql"
+	"encoding/xml"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/gorilla/mux"
+
+	"github.com/h-hiwatashi/super-business-book-ranking-backend/affiliate"
+	"github.com/h-hiwatashi/super-business-book-ranking-backend/repository"
+)
+
+// fakeRankings は終了日ごとのランキングを返す。空の終了日は最新を表す。
+type fakeRankings struct {
+	rankings map[string]*repository.Ranking
+}
+
+func (f *fakeRankings) Find(ctx context.Context, q repository.RankingQuery) (*repository.Ranking, error) {
+	if ranking, ok := f.rankings[q.Date]; ok && q.CategoryID == "cat-1" {
+		return ranking, nil
+	}
+	return &repository.Ranking{}, nil
+}
+
+type fakeSnapshotDates struct {
+	date string
+}
+
+func (f *fakeSnapshotDates) PreviousDate(ctx context.Context, categoryID, periodType string) (string, error) {
+	if f.date == "" {
+		return "", sql.ErrNoRows
+	}
+	return f.date, nil
+}
+
+func newTestRouter(h *FeedHandler) *mux.Router {
+	router := mux.NewRouter()
+	router.HandleFunc("/feeds/rankings/{categoryId}.{format:atom|rss}", h.RankingFeedHandler).Methods("GET")
+	router.HandleFunc("/feeds/rankings/{categoryId}/{kind:new|movers}.{format:atom|rss}", h.RankingFeedHandler).Methods("GET")
+	return router
+}
+
+func newTestHandler(previousDate string) *FeedHandler {
+	latest := &repository.Ranking{
+		CategoryID:   "cat-1",
+		CategoryName: "ビジネス",
+		PeriodType:   "daily",
+		DateTo:       "2024-03-15",
+		LastModified: time.Date(2024, 3, 15, 1, 0, 0, 0, time.UTC),
+		Books: []repository.RankedBook{
+			{Rank: 1, ID: "book-1", Title: "イシューからはじめよ", Author: "安宅和人", ImageURL: "https://example.com/1.jpg", Price: 1980,
+				Site: affiliate.SiteAmazon, URL: "https://www.amazon.co.jp/dp/4862760856"},
+			{Rank: 2, ID: "book-2", Title: "入門 考える技術・書く技術", Site: affiliate.SiteRakuten, URL: "https://books.rakuten.co.jp/rb/2/"},
+			{Rank: 3, ID: "book-3", Title: "ゼロ秒思考 <新版>"},
+		},
+	}
+	previous := &repository.Ranking{
+		Books: []repository.RankedBook{
+			{Rank: 1, ID: "book-2"},
+			{Rank: 2, ID: "book-4"},
+			{Rank: 4, ID: "book-5"},
+			{Rank: 9, ID: "book-3"},
+		},
+	}
+	rankings := &fakeRankings{rankings: map[string]*repository.Ranking{"": latest, "2024-03-14": previous}}
+
+	h := NewFeedHandler(rankings, &fakeSnapshotDates{date: previousDate}, 3, 5)
+	h.BaseURL = "https://ranking.example.com/"
+	h.Links = affiliate.NewLinker(map[string]string{affiliate.SiteAmazon: "bookranking-22"})
+	return h
+}
+
+func TestRankingFeedHandlerAtom(t *testing.T) {
+	testCases := []struct {
+		name         string
+		path         string
+		previousDate string
+		wantTitles   []string
+	}{
+		{
+			name:       "正常系：上位",
+			path:       "/feeds/rankings/cat-1.atom",
+			wantTitles: []string{"1位 イシューからはじめよ", "2位 入門 考える技術・書く技術", "3位 ゼロ秒思考 <新版>"},
+		},
+		{
+			name:         "正常系：新着は前回の上位になかった書籍",
+			path:         "/feeds/rankings/cat-1/new.atom",
+			previousDate: "2024-03-14",
+			wantTitles:   []string{"1位 イシューからはじめよ（前回ランク外）", "3位 ゼロ秒思考 <新版>（前回9位）"},
+		},
+		{
+			name:         "正常系：急上昇は順位の上昇幅が下限以上の書籍",
+			path:         "/feeds/rankings/cat-1/movers.atom",
+			previousDate: "2024-03-14",
+			wantTitles:   []string{"3位 ゼロ秒思考 <新版>（前回9位から6位上昇）"},
+		},
+		{
+			name:       "正常系：前回のスナップショットがない場合は項目なし",
+			path:       "/feeds/rankings/cat-1/new.atom",
+			wantTitles: nil,
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			rr := httptest.NewRecorder()
+			newTestRouter(newTestHandler(tc.previousDate)).ServeHTTP(rr, httptest.NewRequest("GET", tc.path, nil))
+
+			if rr.Code != http.StatusOK {
+				t.Fatalf("status = %d, want 200", rr.Code)
+			}
+			if got := rr.Header().Get("Content-Type"); got != atomContentType {
+				t.Errorf("Content-Type got %v want %v", got, atomContentType)
+			}
+
+			var feed atomFeed
+			if err := xml.Unmarshal(rr.Body.Bytes(), &feed); err != nil {
+				t.Fatalf("invalid Atom: %v", err)
+			}
+			if feed.XMLName.Space != "http://www.w3.org/2005/Atom" {
+				t.Errorf("namespace got %v", feed.XMLName.Space)
+			}
+			if feed.Updated != "2024-03-15T01:00:00Z" {
+				t.Errorf("updated got %v", feed.Updated)
+			}
+			var titles []string
+			for _, entry := range feed.Entries {
+				titles = append(titles, entry.Title)
+			}
+			if strings.Join(titles, "\n") != strings.Join(tc.wantTitles, "\n") {
+				t.Errorf("titles got %q want %q", titles, tc.wantTitles)
+			}
+		})
+	}
+}
+
+func TestRankingFeedHandlerAtomEntry(t *testing.T) {
+	rr := httptest.NewRecorder()
+	newTestRouter(newTestHandler("")).ServeHTTP(rr, httptest.NewRequest("GET", "/feeds/rankings/cat-1.atom", nil))
+
+	var feed atomFeed
+	if err := xml.Unmarshal(rr.Body.Bytes(), &feed); err != nil {
+		t.Fatalf("invalid Atom: %v", err)
+	}
+	if feed.ID != "https://ranking.example.com/feeds/rankings/cat-1/daily/top" {
+		t.Errorf("feed id got %v", feed.ID)
+	}
+	if feed.Links[0].Href != "https://ranking.example.com/feeds/rankings/cat-1.atom" {
+		t.Errorf("self link got %v", feed.Links[0].Href)
+	}
+
+	entry := feed.Entries[0]
+	if entry.ID != "https://ranking.example.com/feeds/rankings/cat-1/daily/top/2024-03-15/book-1" {
+		t.Errorf("entry id got %v", entry.ID)
+	}
+	if entry.Link == nil || entry.Link.Href != "https://www.amazon.co.jp/dp/4862760856?tag=bookranking-22" {
+		t.Errorf("entry link got %v", entry.Link)
+	}
+	if entry.Author == nil || entry.Author.Name != "安宅和人" {
+		t.Errorf("entry author got %v", entry.Author)
+	}
+	wantSummary := `<p><img src="https://example.com/1.jpg" alt="イシューからはじめよ"></p><p>著者: 安宅和人 / 価格: 1980円</p>`
+	if entry.Summary.Type != "html" || entry.Summary.Body != wantSummary {
+		t.Errorf("entry summary got %+v", entry.Summary)
+	}
+	if !strings.Contains(rr.Body.String(), `<media:thumbnail url="https://example.com/1.jpg"></media:thumbnail>`) {
+		t.Error("entry has no thumbnail")
+	}
+	// 著者のない書籍はフィードの著者を使う
+	if feed.Entries[1].Author != nil {
+		t.Errorf("entry author got %v, want nil", feed.Entries[1].Author)
+	}
+}
+
+func TestRankingFeedHandlerRSS(t *testing.T) {
+	rr := httptest.NewRecorder()
+	newTestRouter(newTestHandler("")).ServeHTTP(rr, httptest.NewRequest("GET", "/feeds/rankings/cat-1.rss?period=daily", nil))
+
+	if rr.Code != http.StatusOK {
+		t.Fatalf("status = %d, want 200", rr.Code)
+	}
+	if got := rr.Header().Get("Content-Type"); got != rssContentType {
+		t.Errorf("Content-Type got %v want %v", got, rssContentType)
+	}
+
+	var feed rssFeed
+	if err := xml.Unmarshal(rr.Body.Bytes(), &feed); err != nil {
+		t.Fatalf("invalid RSS: %v", err)
+	}
+	if feed.Version != "2.0" || feed.Channel.Title != "ビジネスの日間ランキング TOP3" {
+		t.Errorf("unexpected channel: %+v", feed.Channel)
+	}
+	if feed.Channel.LastBuildDate != "Fri, 15 Mar 2024 01:00:00 +0000" {
+		t.Errorf("lastBuildDate got %v", feed.Channel.LastBuildDate)
+	}
+	if len(feed.Channel.Items) != 3 {
+		t.Fatalf("items got %d want 3", len(feed.Channel.Items))
+	}
+	item := feed.Channel.Items[0]
+	if item.GUID.IsPermaLink || item.GUID.ID != "https://ranking.example.com/feeds/rankings/cat-1/daily/top/2024-03-15/book-1" {
+		t.Errorf("guid got %+v", item.GUID)
+	}
+	if item.Link != "https://www.amazon.co.jp/dp/4862760856?tag=bookranking-22" {
+		t.Errorf("link got %v", item.Link)
+	}
+}
+
+func TestRankingFeedHandlerErrors(t *testing.T) {
+	testCases := []struct {
+		name           string
+		path           string
+		wantStatusCode int
+	}{
+		{
+			name:           "異常系：ランキングがないカテゴリ",
+			path:           "/feeds/rankings/cat-2.atom",
+			wantStatusCode: http.StatusNotFound,
+		},
+		{
+			name:           "異常系：不正な期間",
+			path:           "/feeds/rankings/cat-1.atom?period=hourly",
+			wantStatusCode: http.StatusBadRequest,
+		},
+		{
+			name:           "異常系：未対応の形式",
+			path:           "/feeds/rankings/cat-1.json",
+			wantStatusCode: http.StatusNotFound,
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			rr := httptest.NewRecorder()
+			newTestRouter(newTestHandler("")).ServeHTTP(rr, httptest.NewRequest("GET", tc.path, nil))
+			if rr.Code != tc.wantStatusCode {
+				t.Errorf("status = %d, want %d", rr.Code, tc.wantStatusCode)
+			}
+		})
+	}
+}
+
+func TestRankingFeedHandlerConditional(t *testing.T) {
+	router := newTestRouter(newTestHandler(""))
+
+	rr := httptest.NewRecorder()
+	router.ServeHTTP(rr, httptest.NewRequest("GET", "/feeds/rankings/cat-1.atom", nil))
+	etag := rr.Header().Get("ETag")
+	if etag == "" {
+		t.Fatal("ETag is not set")
+	}
+
+	req := httptest.NewRequest("GET", "/feeds/rankings/cat-1.atom", nil)
+	req.Header.Set("If-None-Match", etag)
+	rr = httptest.NewRecorder()
+	router.ServeHTTP(rr, req)
+	if rr.Code != http.StatusNotModified {
+		t.Errorf("status = %d, want 304", rr.Code)
+	}
+}
+
+func TestBaseURLFromRequest(t *testing.T) {
+	h := NewFeedHandler(nil, nil, 10, 5)
+	req := httptest.NewRequest("GET", "/feeds/rankings/cat-1.atom", nil)
+	req.Host = "localhost:8080"
+	if got := h.baseURL(req); got != "http://localhost:8080" {
+		t.Errorf("baseURL() got %v", got)
+	}
+}
@@ -0,0 +1,89 @@
+package graphql
+
+import (
+	"strings"
+
+	"github.com/vektah/gqlparser/v2/ast"
+)
+
+// 要素数を指定できないリストの要素数の見積もり
+const defaultListSize = 10
+
+// フィールドごとのリストの要素数の見積もり。ECサイトやサブカテゴリは数が限られる。
+var listSizes = map[string]int{
+	"Book.offers":       3,
+	"Category.children": 5,
+}
+
+// complexity はクエリの複雑さを見積もる。
+// フィールドごとに1を数え、リストを返すフィールドは子の複雑さを要素数の見積もり倍する。
+// limit 引数のあるフィールドでは、その値を子のリストの要素数とする。
+// イントロスペクションのフィールドは数えない。
+func complexity(set ast.SelectionSet, vars map[string]interface{}, listSize int) int {
+	total := 0
+	for _, selection := range set {
+		switch s := selection.(type) {
+		case *ast.Field:
+			if strings.HasPrefix(s.Name, "__") || s.Definition == nil {
+				continue
+			}
+			childListSize := 0
+			if limit, ok := limitArgument(s, vars); ok {
+				childListSize = limit
+			}
+			total += 1 + complexity(s.SelectionSet, vars, childListSize)*multiplier(s, listSize)
+		case *ast.FragmentSpread:
+			total += complexity(s.Definition.SelectionSet, vars, listSize)
+		case *ast.InlineFragment:
+			total += complexity(s.SelectionSet, vars, listSize)
+		}
+	}
+	return total
+}
+
+// multiplier はフィールドが返す要素数の見積もりを返す。リストでない場合は1である。
+func multiplier(field *ast.Field, listSize int) int {
+	if field.Definition.Type.Elem == nil {
+		return 1
+	}
+	if listSize > 0 {
+		return listSize
+	}
+	if size, ok := listSizes[field.ObjectDefinition.Name+"."+field.Name]; ok {
+		return size
+	}
+	return defaultListSize
+}
+
+// limitArgument はフィールドの limit 引数の値を 1 から maxLimit の範囲に収めて返す。
+// 範囲外の値はリゾルバーがエラーにする。
+func limitArgument(field *ast.Field, vars map[string]interface{}) (int, bool) {
+	var value interface{}
+	if arg := field.Arguments.ForName("limit"); arg != nil {
+		if arg.Value.Kind == ast.Variable {
+			v, ok := vars[arg.Value.Raw]
+			if !ok && arg.Value.VariableDefinition != nil && arg.Value.VariableDefinition.DefaultValue != nil {
+				v, _ = arg.Value.VariableDefinition.DefaultValue.Value(nil)
+			}
+			value = v
+		} else {
+			value, _ = arg.Value.Value(vars)
+		}
+	} else if def := field.Definition.Arguments.ForName("limit"); def != nil && def.DefaultValue != nil {
+		value, _ = def.DefaultValue.Value(nil)
+	} else {
+		return 0, false
+	}
+
+	var n int
+	switch v := value.(type) {
+	case int64:
+		n = int(min(max(v, 1), maxLimit))
+	case float64:
+		// JSON の変数は float64 になる
+		n = int(min(max(v, 1), maxLimit))
+	default:
+		return 0, false
+	}
+	return n, true
+}
@@ -0,0 +1,162 @@
+// Package graphql は書籍・カテゴリ・ランキングを GraphQL で取得するエンドポイントを提供する。
+package graphql
+
+import (
+	"context"
+	_ "embed"
+	"encoding/json"
+	"fmt"
+	"net/http"
+
+	graphqlgo "github.com/graph-gophers/graphql-go"
+	"github.com/vektah/gqlparser/v2"
+	"github.com/vektah/gqlparser/v2/ast"
+
+	"github.com/h-hiwatashi/super-business-book-ranking-backend/affiliate"
+	"github.com/h-hiwatashi/super-business-book-ranking-backend/api/httperror"
+	"github.com/h-hiwatashi/super-business-book-ranking-backend/repository"
+)
+
+// リクエスト本文の上限
+const maxBodyBytes = 1 << 20
+
+//go:embed schema.graphql
+var schemaSDL string
+
+// CategoryLister はカテゴリ一覧を取得するリポジトリである。
+type CategoryLister interface {
+	List(ctx context.Context) ([]repository.Category, error)
+}
+
+// BookFinder は書籍と販売情報を取得するリポジトリである。
+type BookFinder interface {
+	FindByID(ctx context.Context, bookID string) (*repository.Book, error)
+	FindOffers(ctx context.Context, bookIDs []string) (map[string][]repository.Offer, error)
+}
+
+// RankingFinder はランキングを取得するリポジトリである。
+type RankingFinder interface {
+	Find(ctx context.Context, q repository.RankingQuery) (*repository.Ranking, error)
+}
+
+// LatestRankingFinder は複数のカテゴリの最新のランキングをまとめて取得するリポジトリである。
+type LatestRankingFinder interface {
+	FindLatest(ctx context.Context, categoryIDs []string, periodType string, limit int) (map[string]*repository.Ranking, error)
+}
+
+// Limits はクエリの制限である。
+type Limits struct {
+	// MaxDepth はフィールドの入れ子の深さの上限である。
+	MaxDepth int
+	// MaxComplexity は complexity で見積もったクエリの複雑さの上限である。
+	MaxComplexity int
+	// Introspection はスキーマのイントロスペクションを許可するかである。
+	Introspection bool
+}
+
+type GraphQLHandler struct {
+	Categories CategoryLister
+	Books      BookFinder
+	Rankings   RankingFinder
+	Latest     LatestRankingFinder
+	// Links が nil の場合は商品URLをそのまま返す。
+	Links affiliate.URLLinker
+
+	schema        *graphqlgo.Schema
+	validation    *ast.Schema
+	maxComplexity int
+}
+
+func NewGraphQLHandler(categories CategoryLister, books BookFinder, rankings RankingFinder, latest LatestRankingFinder, limits Limits) (*GraphQLHandler, error) {
+	opts := []graphqlgo.SchemaOpt{
+		graphqlgo.UseStringDescriptions(),
+		graphqlgo.MaxDepth(limits.MaxDepth),
+	}
+	if !limits.Introspection {
+		opts = append(opts, graphqlgo.DisableIntrospection())
+	}
+	schema, err := graphqlgo.ParseSchema(schemaSDL, &rootResolver{}, opts...)
+	if err != nil {
+		return nil, fmt.Errorf("GraphQL スキーマの読み込みエラー: %w", err)
+	}
+	// graphql-go は解析したクエリを公開しないため、複雑さの見積もりには別のパーサーを使う
+	validation, err := gqlparser.LoadSchema(&ast.Source{Name: "schema.graphql", Input: schemaSDL})
+	if err != nil {
+		return nil, fmt.Errorf("GraphQL スキーマの読み込みエラー: %w", err)
+	}
+
+	return &GraphQLHandler{
+		Categories:    categories,
+		Books:         books,
+		Rankings:      rankings,
+		Latest:        latest,
+		schema:        schema,
+		validation:    validation,
+		maxComplexity: limits.MaxComplexity,
+	}, nil
+}
+
+// graphQLRequest は GraphQL over HTTP のリクエスト本文である。
+type graphQLRequest struct {
+	Query         string                 `json:"query"`
+	OperationName string                 `json:"operationName"`
+	Variables     map[string]interface{} `json:"variables"`
+}
+
+// graphQLError はリゾルバーを実行する前に返すエラーである。
+type graphQLError struct {
+	Message string `json:"message"`
+}
+
+// GraphQL クエリハンドラー
+// クエリの深さと複雑さが上限を超える場合は、リゾルバーを実行せずにエラーを返す。
+// 構文や型の誤りがあるクエリは複雑さを見積もれないため、実行せずに 400 を返す。
+func (h *GraphQLHandler) QueryHandler(w http.ResponseWriter, r *http.Request) {
+	var params graphQLRequest
+	if err := json.NewDecoder(http.MaxBytesReader(w, r.Body, maxBodyBytes)).Decode(&params); err != nil {
+		http.Error(w, "リクエストの形式が不正です", http.StatusBadRequest)
+		return
+	}
+	if params.Query == "" {
+		http.Error(w, "query を指定してください", http.StatusBadRequest)
+		return
+	}
+
+	doc, errs := gqlparser.LoadQuery(h.validation, params.Query)
+	if len(errs) > 0 {
+		queryErrors := make([]graphQLError, len(errs))
+		for i, err := range errs {
+			queryErrors[i] = graphQLError{Message: err.Message}
+		}
+		writeResponse(w, r, http.StatusBadRequest, map[string]interface{}{"errors": queryErrors})
+		return
+	}
+	op := doc.Operations.ForName(params.OperationName)
+	if op == nil {
+		writeResponse(w, r, http.StatusBadRequest, map[string]interface{}{
+			"errors": []graphQLError{{Message: "実行する操作を operationName で指定してください"}},
+		})
+		return
+	}
+	if cost := complexity(op.SelectionSet, params.Variables, 0); cost > h.maxComplexity {
+		writeResponse(w, r, http.StatusOK, map[string]interface{}{
+			"errors": []graphQLError{{Message: fmt.Sprintf("クエリの複雑さ %d が上限 %d を超えています", cost, h.maxComplexity)}},
+		})
+		return
+	}
+
+	ctx := context.WithValue(r.Context(), requestKey{}, newRequest(h))
+	writeResponse(w, r, http.StatusOK, h.schema.Exec(ctx, params.Query, params.OperationName, params.Variables))
+}
+
+func writeResponse(w http.ResponseWriter, r *http.Request, status int, v interface{}) {
+	body, err := json.Marshal(v)
+	if err != nil {
+		httperror.Write(w, r, err, "GraphQL レスポンスの生成エラー")
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	w.Header().Set("Cache-Control", "no-store")
+	w.WriteHeader(status)
+	w.Write(body)
+}
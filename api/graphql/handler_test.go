@@ -0,0 +1,372 @@
+package graphql
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/h-hiwatashi/super-business-book-ranking-backend/affiliate"
+	"github.com/h-hiwatashi/super-business-book-ranking-backend/repository"
+)
+
+type fakeCategories struct {
+	calls int
+}
+
+func (f *fakeCategories) List(ctx context.Context) ([]repository.Category, error) {
+	f.calls++
+	business := "cat-1"
+	return []repository.Category{
+		{ID: "cat-1", Name: "ビジネス"},
+		{ID: "cat-2", Name: "マーケティング", ParentID: &business},
+		{ID: "cat-3", Name: "経営戦略", ParentID: &business},
+	}, nil
+}
+
+// fakeBooks は FindOffers の呼び出し回数を数える。
+type fakeBooks struct {
+	mu         sync.Mutex
+	offerCalls int
+	offerKeys  [][]string
+}
+
+func (f *fakeBooks) FindByID(ctx context.Context, bookID string) (*repository.Book, error) {
+	if bookID != "book-1" {
+		return nil, sql.ErrNoRows
+	}
+	return &repository.Book{ID: "book-1", Title: "イシューからはじめよ", Author: "安宅和人", Publisher: "英治出版", ISBN: "9784862760852"}, nil
+}
+
+func (f *fakeBooks) FindOffers(ctx context.Context, bookIDs []string) (map[string][]repository.Offer, error) {
+	f.mu.Lock()
+	f.offerCalls++
+	f.offerKeys = append(f.offerKeys, bookIDs)
+	f.mu.Unlock()
+
+	offers := make(map[string][]repository.Offer, len(bookIDs))
+	for _, id := range bookIDs {
+		offers[id] = []repository.Offer{
+			{ID: "map-" + id, BookID: id, Site: affiliate.SiteAmazon, Price: 1980, URL: "https://www.amazon.co.jp/dp/" + id},
+		}
+	}
+	return offers, nil
+}
+
+type fakeRankings struct{}
+
+func (fakeRankings) Find(ctx context.Context, q repository.RankingQuery) (*repository.Ranking, error) {
+	if q.CategoryID != "cat-1" {
+		return &repository.Ranking{}, nil
+	}
+	return testRanking("cat-1", q.Limit), nil
+}
+
+// fakeLatest は FindLatest の呼び出し回数を数える。
+type fakeLatest struct {
+	mu    sync.Mutex
+	calls int
+}
+
+func (f *fakeLatest) FindLatest(ctx context.Context, categoryIDs []string, periodType string, limit int) (map[string]*repository.Ranking, error) {
+	f.mu.Lock()
+	f.calls++
+	f.mu.Unlock()
+
+	rankings := make(map[string]*repository.Ranking, len(categoryIDs))
+	for _, id := range categoryIDs {
+		rankings[id] = testRanking(id, limit)
+	}
+	return rankings, nil
+}
+
+func testRanking(categoryID string, limit int) *repository.Ranking {
+	ranking := &repository.Ranking{
+		CategoryID: categoryID,
+		PeriodType: "daily",
+		DateFrom:   "2024-03-15",
+		DateTo:     "2024-03-15",
+	}
+	for i := 1; i <= limit; i++ {
+		id := categoryID + "-book-" + string(rune('0'+i))
+		ranking.Books = append(ranking.Books, repository.RankedBook{
+			Rank: i, ID: id, Title: "書籍" + id, BookSiteMappingID: "map-" + id,
+			Site: affiliate.SiteRakuten, Price: 1650, URL: "https://books.rakuten.co.jp/rb/" + id + "/",
+		})
+	}
+	return ranking
+}
+
+type testHandler struct {
+	*GraphQLHandler
+	categories *fakeCategories
+	books      *fakeBooks
+	latest     *fakeLatest
+}
+
+func newTestHandler(t *testing.T, limits Limits) testHandler {
+	t.Helper()
+	categories, books, latest := &fakeCategories{}, &fakeBooks{}, &fakeLatest{}
+	h, err := NewGraphQLHandler(categories, books, fakeRankings{}, latest, limits)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return testHandler{h, categories, books, latest}
+}
+
+var defaultLimits = Limits{MaxDepth: 8, MaxComplexity: 5000, Introspection: true}
+
+type response struct {
+	Data   json.RawMessage `json:"data"`
+	Errors []struct {
+		Message string `json:"message"`
+	} `json:"errors"`
+}
+
+func query(t *testing.T, h *GraphQLHandler, body string) response {
+	t.Helper()
+	req := httptest.NewRequest("POST", "/graphql", strings.NewReader(body))
+	rec := httptest.NewRecorder()
+	h.QueryHandler(rec, req)
+	if rec.Code != http.StatusOK {
+		t.Fatalf("status = %d, want %d: %s", rec.Code, http.StatusOK, rec.Body.String())
+	}
+	if got := rec.Header().Get("Cache-Control"); got != "no-store" {
+		t.Errorf("Cache-Control = %q, want %q", got, "no-store")
+	}
+	var resp response
+	if err := json.Unmarshal(rec.Body.Bytes(), &resp); err != nil {
+		t.Fatal(err)
+	}
+	return resp
+}
+
+func TestQueryHandler(t *testing.T) {
+	testCases := []struct {
+		name  string
+		body  string
+		want  string
+		error string
+	}{
+		{
+			name: "正常系：カテゴリの子と親",
+			body: `{"query":"{ category(id: \"cat-2\") { name parent { name children { id } } } }"}`,
+			want: `{"category":{"name":"マーケティング","parent":{"name":"ビジネス","children":[{"id":"cat-2"},{"id":"cat-3"}]}}}`,
+		},
+		{
+			name: "正常系：書籍と販売情報",
+			body: `{"query":"query($id: ID!) { book(id: $id) { title isbn publicationDate offers { id site price url } } }","variables":{"id":"book-1"}}`,
+			want: `{"book":{"title":"イシューからはじめよ","isbn":"9784862760852","publicationDate":null,"offers":[{"id":"map-book-1","site":"amazon","price":1980,"url":"https://www.amazon.co.jp/dp/book-1?tag=bookranking-22"}]}}`,
+		},
+		{
+			name: "正常系：存在しない書籍",
+			body: `{"query":"{ book(id: \"book-9\") { title } }"}`,
+			want: `{"book":null}`,
+		},
+		{
+			name: "正常系：日付を指定したランキング",
+			body: `{"query":"{ ranking(categoryId: \"cat-1\", period: DAILY, date: \"2024-03-15\", limit: 1) { period dateFrom dateTo category { name } entries { rank book { title } offer { id site } } } }"}`,
+			want: `{"ranking":{"period":"DAILY","dateFrom":"2024-03-15","dateTo":"2024-03-15","category":{"name":"ビジネス"},"entries":[{"rank":1,"book":{"title":"書籍cat-1-book-1"},"offer":{"id":"map-cat-1-book-1","site":"rakuten"}}]}}`,
+		},
+		{
+			name: "正常系：ランキングがないカテゴリ",
+			body: `{"query":"{ ranking(categoryId: \"cat-9\") { period } }"}`,
+			want: `{"ranking":null}`,
+		},
+		{
+			name:  "異常系：不正な日付",
+			body:  `{"query":"{ ranking(categoryId: \"cat-1\", date: \"20240315\") { period } }"}`,
+			error: "date は YYYY-MM-DD 形式で指定してください",
+		},
+		{
+			name:  "異常系：limit が上限を超える",
+			body:  `{"query":"{ ranking(categoryId: \"cat-1\", limit: 101) { period } }"}`,
+			error: "limit は1以上100以下で指定してください",
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			h := newTestHandler(t, defaultLimits)
+			h.Links = affiliate.NewLinker(map[string]string{affiliate.SiteAmazon: "bookranking-22"})
+
+			resp := query(t, h.GraphQLHandler, tc.body)
+			if tc.error != "" {
+				if len(resp.Errors) == 0 || !strings.Contains(resp.Errors[0].Message, tc.error) {
+					t.Fatalf("errors = %+v, want %q", resp.Errors, tc.error)
+				}
+				return
+			}
+			if len(resp.Errors) > 0 {
+				t.Fatalf("errors = %+v", resp.Errors)
+			}
+			if string(resp.Data) != tc.want {
+				t.Errorf("data = %s, want %s", resp.Data, tc.want)
+			}
+		})
+	}
+}
+
+func TestQueryHandlerBatching(t *testing.T) {
+	h := newTestHandler(t, defaultLimits)
+
+	// 3カテゴリ × 5位の書籍の販売情報を取得しても、ランキングは1回、販売情報はランキングごとに1回以内で読み込む
+	resp := query(t, h.GraphQLHandler, `{"query":"{ categories { id ranking(limit: 5) { entries { rank book { id offers { site } } } } } }"}`)
+	if len(resp.Errors) > 0 {
+		t.Fatalf("errors = %+v", resp.Errors)
+	}
+
+	var data struct {
+		Categories []struct {
+			ID      string
+			Ranking struct {
+				Entries []struct {
+					Book struct {
+						ID     string
+						Offers []struct{ Site string }
+					}
+				}
+			}
+		}
+	}
+	if err := json.Unmarshal(resp.Data, &data); err != nil {
+		t.Fatal(err)
+	}
+	if len(data.Categories) != 3 {
+		t.Fatalf("len(categories) = %d, want 3", len(data.Categories))
+	}
+	for _, category := range data.Categories {
+		if len(category.Ranking.Entries) != 5 {
+			t.Errorf("%s: len(entries) = %d, want 5", category.ID, len(category.Ranking.Entries))
+		}
+		for _, entry := range category.Ranking.Entries {
+			if len(entry.Book.Offers) != 1 {
+				t.Errorf("%s: len(offers) = %d, want 1", entry.Book.ID, len(entry.Book.Offers))
+			}
+		}
+	}
+
+	if h.categories.calls != 1 {
+		t.Errorf("List の呼び出し = %d, want 1", h.categories.calls)
+	}
+	if h.latest.calls != 1 {
+		t.Errorf("FindLatest の呼び出し = %d, want 1", h.latest.calls)
+	}
+	if h.books.offerCalls > 3 {
+		t.Errorf("FindOffers の呼び出し = %d, want <= 3 (%v)", h.books.offerCalls, h.books.offerKeys)
+	}
+}
+
+func TestQueryHandlerLimits(t *testing.T) {
+	testCases := []struct {
+		name   string
+		limits Limits
+		body   string
+		error  string
+	}{
+		{
+			name:   "異常系：深さが上限を超える",
+			limits: Limits{MaxDepth: 3, MaxComplexity: 5000},
+			body:   `{"query":"{ categories { children { children { id } } } }"}`,
+			error:  "exceeds max depth 3",
+		},
+		{
+			name:   "異常系：複雑さが上限を超える",
+			limits: Limits{MaxDepth: 8, MaxComplexity: 100},
+			body:   `{"query":"{ categories { ranking(limit: 100) { entries { rank } } } }"}`,
+			error:  "クエリの複雑さ",
+		},
+		{
+			name:   "異常系：変数で指定した limit も複雑さに数える",
+			limits: Limits{MaxDepth: 8, MaxComplexity: 100},
+			body:   `{"query":"query($n: Int) { categories { ranking(limit: $n) { entries { rank } } } }","variables":{"n":100}}`,
+			error:  "クエリの複雑さ",
+		},
+		{
+			name:   "異常系：イントロスペクションの無効化",
+			limits: Limits{MaxDepth: 8, MaxComplexity: 5000},
+			body:   `{"query":"{ __schema { types { name } } }"}`,
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			h := newTestHandler(t, tc.limits)
+
+			resp := query(t, h.GraphQLHandler, tc.body)
+			if tc.error == "" {
+				if string(resp.Data) != `{}` {
+					t.Errorf("data = %s, want {}", resp.Data)
+				}
+				return
+			}
+			if len(resp.Errors) == 0 || !strings.Contains(resp.Errors[0].Message, tc.error) {
+				t.Fatalf("errors = %+v, want %q", resp.Errors, tc.error)
+			}
+			if h.latest.calls != 0 || h.categories.calls != 0 {
+				t.Errorf("上限を超えたクエリでリポジトリが呼ばれました")
+			}
+		})
+	}
+}
+
+func TestQueryHandlerBadRequest(t *testing.T) {
+	testCases := []struct {
+		name  string
+		body  string
+		error string
+	}{
+		{name: "異常系：JSON でない本文", body: `query { categories { id } }`},
+		{name: "異常系：query がない", body: `{"variables":{}}`},
+		{
+			name:  "異常系：存在しないフィールド",
+			body:  `{"query":"{ books { id } }"}`,
+			error: `Cannot query field "books"`,
+		},
+		{
+			name:  "異常系：構文の誤り",
+			body:  `{"query":"{ categories { id "}`,
+			error: "Expected Name",
+		},
+		{
+			name:  "異常系：複雑さの上限を超える構文の誤りのあるクエリも実行しない",
+			body:  `{"query":"{ categories { ranking(limit: 100) { entries { rank } } } unknown }"}`,
+			error: `Cannot query field "unknown"`,
+		},
+		{
+			name:  "異常系：operationName に一致する操作がない",
+			body:  `{"query":"query a { categories { id } } query b { categories { name } }"}`,
+			error: "operationName",
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			h := newTestHandler(t, Limits{MaxDepth: 8, MaxComplexity: 100})
+
+			req := httptest.NewRequest("POST", "/graphql", strings.NewReader(tc.body))
+			rec := httptest.NewRecorder()
+			h.QueryHandler(rec, req)
+			if rec.Code != http.StatusBadRequest {
+				t.Fatalf("status = %d, want %d", rec.Code, http.StatusBadRequest)
+			}
+			if h.latest.calls != 0 || h.categories.calls != 0 {
+				t.Errorf("誤りのあるクエリでリポジトリが呼ばれました")
+			}
+			if tc.error == "" {
+				return
+			}
+			var resp response
+			if err := json.Unmarshal(rec.Body.Bytes(), &resp); err != nil {
+				t.Fatal(err)
+			}
+			if len(resp.Errors) == 0 || !strings.Contains(resp.Errors[0].Message, tc.error) {
+				t.Errorf("errors = %+v, want %q", resp.Errors, tc.error)
+			}
+		})
+	}
+}
@@ -0,0 +1,90 @@
+package graphql
+
+import (
+	"context"
+	"sync"
+)
+
+// Loader はキーごとの値をまとめて読み込み、リクエストの間は読み込んだ値を再利用する。
+//
+// リストの要素は並列数の上限ごとに分けて解決されるため、時間で区切るとまとめきれない。
+// そのため、要素のリゾルバーが Enqueue で兄弟要素のキーを登録し、
+// 最初の Load で登録済みのキーをまとめて1回で読み込む。
+type Loader[K comparable, V any] struct {
+	fetch func(ctx context.Context, keys []K) (map[K]V, error)
+
+	mu      sync.Mutex
+	pending []K
+	queued  map[K]bool
+	results map[K]*loadResult[V]
+}
+
+type loadResult[V any] struct {
+	done  chan struct{}
+	value V
+	err   error
+}
+
+// NewLoader は fetch で値を読み込む Loader を返す。
+// fetch が返さなかったキーの値はゼロ値とする。
+func NewLoader[K comparable, V any](fetch func(ctx context.Context, keys []K) (map[K]V, error)) *Loader[K, V] {
+	return &Loader[K, V]{
+		fetch:   fetch,
+		queued:  make(map[K]bool),
+		results: make(map[K]*loadResult[V]),
+	}
+}
+
+// Enqueue は keys を次の読み込みに加える。読み込み済みまたは登録済みのキーは無視する。
+func (l *Loader[K, V]) Enqueue(keys ...K) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	for _, key := range keys {
+		l.enqueue(key)
+	}
+}
+
+func (l *Loader[K, V]) enqueue(key K) {
+	if _, ok := l.results[key]; ok || l.queued[key] {
+		return
+	}
+	l.queued[key] = true
+	l.pending = append(l.pending, key)
+}
+
+// Load は key の値を返す。読み込み中の場合は完了を待ち、未読み込みの場合は登録済みのキーとまとめて読み込む。
+func (l *Loader[K, V]) Load(ctx context.Context, key K) (V, error) {
+	l.mu.Lock()
+	if result, ok := l.results[key]; ok {
+		l.mu.Unlock()
+		return result.wait(ctx)
+	}
+
+	l.enqueue(key)
+	keys := l.pending
+	batch := make(map[K]*loadResult[V], len(keys))
+	for _, k := range keys {
+		batch[k] = &loadResult[V]{done: make(chan struct{})}
+		l.results[k] = batch[k]
+	}
+	l.pending = nil
+	l.queued = make(map[K]bool)
+	l.mu.Unlock()
+
+	values, err := l.fetch(ctx, keys)
+	for k, result := range batch {
+		result.value, result.err = values[k], err
+		close(result.done)
+	}
+	return batch[key].value, batch[key].err
+}
+
+func (r *loadResult[V]) wait(ctx context.Context) (V, error) {
+	select {
+	case <-r.done:
+		return r.value, r.err
+	case <-ctx.Done():
+		var zero V
+		return zero, ctx.Err()
+	}
+}
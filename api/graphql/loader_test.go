@@ -0,0 +1,85 @@
+package graphql
+
+import (
+	"context"
+	"errors"
+	"reflect"
+	"sort"
+	"sync"
+	"testing"
+)
+
+func TestLoader(t *testing.T) {
+	var mu sync.Mutex
+	var batches [][]int
+	loader := NewLoader(func(ctx context.Context, keys []int) (map[int]string, error) {
+		mu.Lock()
+		defer mu.Unlock()
+		batches = append(batches, append([]int(nil), keys...))
+		values := make(map[int]string, len(keys))
+		for _, k := range keys {
+			if k != 3 {
+				values[k] = string(rune('a' + k))
+			}
+		}
+		return values, nil
+	})
+
+	ctx := context.Background()
+	loader.Enqueue(1, 2, 3)
+
+	var wg sync.WaitGroup
+	got := make([]string, 4)
+	for k := 0; k < 4; k++ {
+		wg.Add(1)
+		go func(k int) {
+			defer wg.Done()
+			v, err := loader.Load(ctx, k)
+			if err != nil {
+				t.Error(err)
+			}
+			got[k] = v
+		}(k)
+	}
+	wg.Wait()
+
+	if want := []string{"a", "b", "c", ""}; !reflect.DeepEqual(got, want) {
+		t.Errorf("values = %q, want %q", got, want)
+	}
+	var keys []int
+	for _, batch := range batches {
+		keys = append(keys, batch...)
+	}
+	sort.Ints(keys)
+	if want := []int{0, 1, 2, 3}; !reflect.DeepEqual(keys, want) {
+		t.Errorf("読み込んだキー = %v, want %v（重複なし）", keys, want)
+	}
+	// 登録済みのキーは最初の Load でまとめて読み込む
+	if len(batches) > 2 {
+		t.Errorf("読み込み回数 = %d, want <= 2", len(batches))
+	}
+
+	// 読み込み済みのキーは再度読み込まない
+	loader.Enqueue(1, 2)
+	if _, err := loader.Load(ctx, 1); err != nil {
+		t.Fatal(err)
+	}
+	if n := len(batches); n > 2 {
+		t.Errorf("読み込み済みのキーを再度読み込みました: %v", batches)
+	}
+}
+
+func TestLoaderError(t *testing.T) {
+	fetchErr := errors.New("接続エラー")
+	loader := NewLoader(func(ctx context.Context, keys []string) (map[string]int, error) {
+		return nil, fetchErr
+	})
+
+	loader.Enqueue("a", "b")
+	if _, err := loader.Load(context.Background(), "a"); !errors.Is(err, fetchErr) {
+		t.Errorf("err = %v, want %v", err, fetchErr)
+	}
+	if _, err := loader.Load(context.Background(), "b"); !errors.Is(err, fetchErr) {
+		t.Errorf("err = %v, want %v", err, fetchErr)
+	}
+}
@@ -0,0 +1,375 @@
+package graphql
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"strings"
+	"sync"
+	"time"
+
+	graphqlgo "github.com/graph-gophers/graphql-go"
+
+	"github.com/h-hiwatashi/super-business-book-ranking-backend/affiliate"
+	"github.com/h-hiwatashi/super-business-book-ranking-backend/repository"
+)
+
+// ランキングの取得数の上限
+const maxLimit = 100
+
+// snapshotKey は最新のスナップショットを読み込むキーである。
+type snapshotKey struct {
+	CategoryID string
+	PeriodType string
+	Limit      int
+}
+
+// request は1リクエストの間に共有する依存と Loader である。
+type request struct {
+	h *GraphQLHandler
+
+	categoriesOnce sync.Once
+	categories     []repository.Category
+	categoriesByID map[string]repository.Category
+	categoriesErr  error
+
+	offers    *Loader[string, []repository.Offer]
+	snapshots *Loader[snapshotKey, *repository.Ranking]
+}
+
+func newRequest(h *GraphQLHandler) *request {
+	req := &request{h: h}
+	req.offers = NewLoader(func(ctx context.Context, bookIDs []string) (map[string][]repository.Offer, error) {
+		return h.Books.FindOffers(ctx, bookIDs)
+	})
+	req.snapshots = NewLoader(req.fetchSnapshots)
+	return req
+}
+
+type requestKey struct{}
+
+func requestFrom(ctx context.Context) *request {
+	return ctx.Value(requestKey{}).(*request)
+}
+
+// fetchSnapshots は期間と取得数の組ごとに最新のスナップショットをまとめて取得する。
+func (req *request) fetchSnapshots(ctx context.Context, keys []snapshotKey) (map[snapshotKey]*repository.Ranking, error) {
+	type group struct {
+		periodType string
+		limit      int
+	}
+	categoryIDs := make(map[group][]string)
+	for _, key := range keys {
+		g := group{key.PeriodType, key.Limit}
+		categoryIDs[g] = append(categoryIDs[g], key.CategoryID)
+	}
+
+	result := make(map[snapshotKey]*repository.Ranking, len(keys))
+	for g, ids := range categoryIDs {
+		rankings, err := req.h.Latest.FindLatest(ctx, ids, g.periodType, g.limit)
+		if err != nil {
+			return nil, err
+		}
+		for categoryID, ranking := range rankings {
+			result[snapshotKey{categoryID, g.periodType, g.limit}] = ranking
+		}
+	}
+	return result, nil
+}
+
+// loadCategories はカテゴリ一覧を1リクエストにつき1回だけ取得する。
+func (req *request) loadCategories(ctx context.Context) ([]repository.Category, map[string]repository.Category, error) {
+	req.categoriesOnce.Do(func() {
+		req.categories, req.categoriesErr = req.h.Categories.List(ctx)
+		req.categoriesByID = make(map[string]repository.Category, len(req.categories))
+		for _, category := range req.categories {
+			req.categoriesByID[category.ID] = category
+		}
+	})
+	return req.categories, req.categoriesByID, req.categoriesErr
+}
+
+// newCategoryResolvers は兄弟要素のIDを共有するカテゴリのリゾルバーを返す。
+func (req *request) newCategoryResolvers(categories []repository.Category) []*categoryResolver {
+	ids := make([]string, len(categories))
+	for i, category := range categories {
+		ids[i] = category.ID
+	}
+	resolvers := make([]*categoryResolver, len(categories))
+	for i, category := range categories {
+		resolvers[i] = &categoryResolver{req: req, category: category, siblings: ids}
+	}
+	return resolvers
+}
+
+// rootResolver は Query 型のリゾルバーである。
+type rootResolver struct{}
+
+func (*rootResolver) Categories(ctx context.Context) ([]*categoryResolver, error) {
+	req := requestFrom(ctx)
+	categories, _, err := req.loadCategories(ctx)
+	if err != nil {
+		return nil, err
+	}
+	return req.newCategoryResolvers(categories), nil
+}
+
+func (*rootResolver) Category(ctx context.Context, args struct{ ID graphqlgo.ID }) (*categoryResolver, error) {
+	req := requestFrom(ctx)
+	_, byID, err := req.loadCategories(ctx)
+	if err != nil {
+		return nil, err
+	}
+	category, ok := byID[string(args.ID)]
+	if !ok {
+		return nil, nil
+	}
+	return req.newCategoryResolvers([]repository.Category{category})[0], nil
+}
+
+func (*rootResolver) Book(ctx context.Context, args struct{ ID graphqlgo.ID }) (*bookResolver, error) {
+	req := requestFrom(ctx)
+	book, err := req.h.Books.FindByID(ctx, string(args.ID))
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	return &bookResolver{req: req, book: *book, siblings: []string{book.ID}}, nil
+}
+
+func (*rootResolver) Ranking(ctx context.Context, args struct {
+	CategoryID graphqlgo.ID
+	Period     string
+	Date       *string
+	Limit      int32
+}) (*snapshotResolver, error) {
+	req := requestFrom(ctx)
+	if err := validLimit(args.Limit); err != nil {
+		return nil, err
+	}
+	query := repository.RankingQuery{
+		CategoryID: string(args.CategoryID),
+		PeriodType: strings.ToLower(args.Period),
+		Page:       1,
+		Limit:      int(args.Limit),
+	}
+	if args.Date != nil {
+		if _, err := time.Parse("2006-01-02", *args.Date); err != nil {
+			return nil, errors.New("date は YYYY-MM-DD 形式で指定してください")
+		}
+		query.Date = *args.Date
+	}
+
+	ranking, err := req.h.Rankings.Find(ctx, query)
+	if err != nil {
+		return nil, err
+	}
+	if len(ranking.Books) == 0 {
+		return nil, nil
+	}
+	return &snapshotResolver{req: req, ranking: ranking}, nil
+}
+
+type categoryResolver struct {
+	req      *request
+	category repository.Category
+	// siblings は同じリストのカテゴリIDで、ランキングをまとめて読み込むために使う。
+	siblings []string
+}
+
+func (r *categoryResolver) ID() graphqlgo.ID { return graphqlgo.ID(r.category.ID) }
+
+func (r *categoryResolver) Name() string { return r.category.Name }
+
+func (r *categoryResolver) Parent(ctx context.Context) (*categoryResolver, error) {
+	if r.category.ParentID == nil {
+		return nil, nil
+	}
+	_, byID, err := r.req.loadCategories(ctx)
+	if err != nil {
+		return nil, err
+	}
+	parent, ok := byID[*r.category.ParentID]
+	if !ok {
+		return nil, nil
+	}
+	return r.req.newCategoryResolvers([]repository.Category{parent})[0], nil
+}
+
+func (r *categoryResolver) Children(ctx context.Context) ([]*categoryResolver, error) {
+	categories, _, err := r.req.loadCategories(ctx)
+	if err != nil {
+		return nil, err
+	}
+	var children []repository.Category
+	for _, category := range categories {
+		if category.ParentID != nil && *category.ParentID == r.category.ID {
+			children = append(children, category)
+		}
+	}
+	return r.req.newCategoryResolvers(children), nil
+}
+
+func (r *categoryResolver) Ranking(ctx context.Context, args struct {
+	Period string
+	Limit  int32
+}) (*snapshotResolver, error) {
+	if err := validLimit(args.Limit); err != nil {
+		return nil, err
+	}
+	periodType := strings.ToLower(args.Period)
+
+	keys := make([]snapshotKey, len(r.siblings))
+	for i, id := range r.siblings {
+		keys[i] = snapshotKey{id, periodType, int(args.Limit)}
+	}
+	r.req.snapshots.Enqueue(keys...)
+
+	ranking, err := r.req.snapshots.Load(ctx, snapshotKey{r.category.ID, periodType, int(args.Limit)})
+	if err != nil {
+		return nil, err
+	}
+	if ranking == nil || len(ranking.Books) == 0 {
+		return nil, nil
+	}
+	return &snapshotResolver{req: r.req, ranking: ranking}, nil
+}
+
+type snapshotResolver struct {
+	req     *request
+	ranking *repository.Ranking
+}
+
+func (r *snapshotResolver) Category(ctx context.Context) (*categoryResolver, error) {
+	_, byID, err := r.req.loadCategories(ctx)
+	if err != nil {
+		return nil, err
+	}
+	category, ok := byID[r.ranking.CategoryID]
+	if !ok {
+		category = repository.Category{ID: r.ranking.CategoryID, Name: r.ranking.CategoryName}
+	}
+	return r.req.newCategoryResolvers([]repository.Category{category})[0], nil
+}
+
+func (r *snapshotResolver) Period() string { return strings.ToUpper(r.ranking.PeriodType) }
+
+func (r *snapshotResolver) DateFrom() string { return r.ranking.DateFrom }
+
+func (r *snapshotResolver) DateTo() string { return r.ranking.DateTo }
+
+func (r *snapshotResolver) Entries() []*entryResolver {
+	seen := make(map[string]bool)
+	var bookIDs []string
+	for _, book := range r.ranking.Books {
+		if !seen[book.ID] {
+			seen[book.ID] = true
+			bookIDs = append(bookIDs, book.ID)
+		}
+	}
+
+	entries := make([]*entryResolver, len(r.ranking.Books))
+	for i, book := range r.ranking.Books {
+		entries[i] = &entryResolver{req: r.req, book: book, siblings: bookIDs}
+	}
+	return entries
+}
+
+type entryResolver struct {
+	req      *request
+	book     repository.RankedBook
+	siblings []string
+}
+
+func (r *entryResolver) Rank() int32 { return int32(r.book.Rank) }
+
+func (r *entryResolver) Book() *bookResolver {
+	return &bookResolver{
+		req: r.req,
+		book: repository.Book{
+			ID:              r.book.ID,
+			Title:           r.book.Title,
+			Author:          r.book.Author,
+			Publisher:       r.book.Publisher,
+			ISBN:            r.book.ISBN,
+			PublicationDate: r.book.PublicationDate,
+			ImageURL:        r.book.ImageURL,
+		},
+		siblings: r.siblings,
+	}
+}
+
+func (r *entryResolver) Offer() *offerResolver {
+	return &offerResolver{req: r.req, offer: repository.Offer{
+		ID:     r.book.BookSiteMappingID,
+		BookID: r.book.ID,
+		Site:   r.book.Site,
+		Price:  r.book.Price,
+		URL:    r.book.URL,
+	}}
+}
+
+type bookResolver struct {
+	req  *request
+	book repository.Book
+	// siblings は同じリストの書籍IDで、販売情報をまとめて読み込むために使う。
+	siblings []string
+}
+
+func (r *bookResolver) ID() graphqlgo.ID { return graphqlgo.ID(r.book.ID) }
+
+func (r *bookResolver) Title() string { return r.book.Title }
+
+func (r *bookResolver) Author() string { return r.book.Author }
+
+func (r *bookResolver) Publisher() string { return r.book.Publisher }
+
+func (r *bookResolver) ISBN() string { return r.book.ISBN }
+
+func (r *bookResolver) PublicationDate() *string { return optional(r.book.PublicationDate) }
+
+func (r *bookResolver) ImageURL() *string { return optional(r.book.ImageURL) }
+
+func (r *bookResolver) Offers(ctx context.Context) ([]*offerResolver, error) {
+	r.req.offers.Enqueue(r.siblings...)
+	offers, err := r.req.offers.Load(ctx, r.book.ID)
+	if err != nil {
+		return nil, err
+	}
+	resolvers := make([]*offerResolver, len(offers))
+	for i, offer := range offers {
+		resolvers[i] = &offerResolver{req: r.req, offer: offer}
+	}
+	return resolvers, nil
+}
+
+type offerResolver struct {
+	req   *request
+	offer repository.Offer
+}
+
+func (r *offerResolver) ID() graphqlgo.ID { return graphqlgo.ID(r.offer.ID) }
+
+func (r *offerResolver) Site() string { return r.offer.Site }
+
+func (r *offerResolver) Price() float64 { return r.offer.Price }
+
+func (r *offerResolver) URL() string {
+	return affiliate.LinkURL(r.req.h.Links, r.offer.Site, r.offer.URL)
+}
+
+func validLimit(limit int32) error {
+	if limit < 1 || limit > maxLimit {
+		return errors.New("limit は1以上100以下で指定してください")
+	}
+	return nil
+}
+
+func optional(s string) *string {
+	if s == "" {
+		return nil
+	}
+	return &s
+}
@@ -0,0 +1,197 @@
+package health
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"sync/atomic"
+	"time"
+
+	"github.com/h-hiwatashi/super-business-book-ranking-backend/logging"
+	"github.com/h-hiwatashi/super-business-book-ranking-backend/version"
+)
+
+// コンポーネントおよび全体の状態
+const (
+	StatusOK          = "ok"
+	StatusStale       = "stale"
+	StatusDegraded    = "degraded"
+	StatusUnavailable = "unavailable"
+)
+
+// Pinger はデータベースへの疎通確認を行う。*sql.DB が満たす。
+type Pinger interface {
+	PingContext(ctx context.Context) error
+}
+
+// IngestionStatus は取得元ごとの最終取り込み時刻を返す。
+type IngestionStatus interface {
+	LastIngestedAt(ctx context.Context) (map[string]time.Time, error)
+}
+
+// ヘルスチェックレスポンス
+type HealthResponse struct {
+	Status  string `json:"status"`
+	Version string `json:"version"`
+	Time    string `json:"time"`
+}
+
+// レディネスチェックレスポンス
+type ReadinessResponse struct {
+	Status     string                     `json:"status"`
+	Version    string                     `json:"version"`
+	Time       string                     `json:"time"`
+	Components map[string]ComponentStatus `json:"components"`
+}
+
+// コンポーネントごとの状態
+type ComponentStatus struct {
+	Status      string   `json:"status"`
+	LatencyMs   *float64 `json:"latencyMs,omitempty"`
+	LastSuccess string   `json:"lastSuccess,omitempty"`
+	AgeSeconds  *int64   `json:"ageSeconds,omitempty"`
+	Error       string   `json:"error,omitempty"`
+}
+
+type HealthHandler struct {
+	DB        Pinger
+	Ingestion IngestionStatus
+	// Sources は鮮度を確認する取得元の名前である。
+	Sources []string
+	// Timeout は依存先の確認に使うタイムアウトである。
+	Timeout time.Duration
+	// MaxIngestionAge はこれを超えて取り込みがない取得元を stale と判定するしきい値である。
+	MaxIngestionAge time.Duration
+
+	shuttingDown atomic.Bool
+	now          func() time.Time
+}
+
+func NewHealthHandler(db Pinger, ingestion IngestionStatus, sources []string, timeout, maxIngestionAge time.Duration) *HealthHandler {
+	return &HealthHandler{
+		DB:              db,
+		Ingestion:       ingestion,
+		Sources:         sources,
+		Timeout:         timeout,
+		MaxIngestionAge: maxIngestionAge,
+		now:             time.Now,
+	}
+}
+
+// ライブネスチェック用ハンドラー
+// プロセスが応答できる限り常に ok を返し、依存先の状態は確認しない。
+func (h *HealthHandler) LivenessHandler(w http.ResponseWriter, r *http.Request) {
+	response := HealthResponse{
+		Status:  StatusOK,
+		Version: version.String(),
+		Time:    h.now().Format(time.RFC3339),
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.Header().Set("Cache-Control", "no-store")
+	json.NewEncoder(w).Encode(response)
+}
+
+// SetShuttingDown はシャットダウン開始を記録する。
+// 以降のレディネスチェックは 503 を返し、ロードバランサーに振り分け対象から外させる。
+func (h *HealthHandler) SetShuttingDown() {
+	h.shuttingDown.Store(true)
+}
+
+// レディネスチェック用ハンドラー
+// データベースに接続できない場合はトラフィックを受けられないため 503 を返す。
+// 取り込みの遅延は保存済みのランキングで応答を続けられるため、
+// 該当コンポーネントを stale とし全体を degraded として 200 を返す。
+func (h *HealthHandler) ReadinessHandler(w http.ResponseWriter, r *http.Request) {
+	ctx, cancel := context.WithTimeout(r.Context(), h.Timeout)
+	defer cancel()
+
+	response := ReadinessResponse{
+		Status:     StatusOK,
+		Version:    version.String(),
+		Time:       h.now().Format(time.RFC3339),
+		Components: make(map[string]ComponentStatus),
+	}
+
+	if h.shuttingDown.Load() {
+		response.Status = StatusUnavailable
+		response.Components["server"] = ComponentStatus{Status: StatusUnavailable, Error: "シャットダウン中です"}
+		writeJSON(w, http.StatusServiceUnavailable, response)
+		return
+	}
+
+	database := h.checkDatabase(ctx)
+	response.Components["database"] = database
+
+	if database.Status == StatusOK {
+		for name, component := range h.checkIngestion(ctx) {
+			response.Components["ingestion:"+name] = component
+			if component.Status != StatusOK {
+				response.Status = StatusDegraded
+			}
+		}
+	}
+
+	statusCode := http.StatusOK
+	if database.Status != StatusOK {
+		response.Status = StatusUnavailable
+		statusCode = http.StatusServiceUnavailable
+		logging.FromContext(r.Context()).Warn("レディネスチェック失敗")
+	}
+
+	writeJSON(w, statusCode, response)
+}
+
+func writeJSON(w http.ResponseWriter, statusCode int, response ReadinessResponse) {
+	w.Header().Set("Content-Type", "application/json")
+	w.Header().Set("Cache-Control", "no-store")
+	w.WriteHeader(statusCode)
+	json.NewEncoder(w).Encode(response)
+}
+
+func (h *HealthHandler) checkDatabase(ctx context.Context) ComponentStatus {
+	start := h.now()
+	err := h.DB.PingContext(ctx)
+	latency := float64(h.now().Sub(start).Microseconds()) / 1000
+
+	if err != nil {
+		// エラーには接続先やドライバーの情報が含まれるため、公開するレスポンスには含めずログにだけ記録する
+		logging.FromContext(ctx).Error("データベース疎通確認エラー", "error", err)
+		return ComponentStatus{Status: StatusUnavailable, LatencyMs: &latency, Error: "データベースに接続できません"}
+	}
+	return ComponentStatus{Status: StatusOK, LatencyMs: &latency}
+}
+
+func (h *HealthHandler) checkIngestion(ctx context.Context) map[string]ComponentStatus {
+	components := make(map[string]ComponentStatus, len(h.Sources))
+
+	lastIngested, err := h.Ingestion.LastIngestedAt(ctx)
+	if err != nil {
+		logging.FromContext(ctx).Error("取り込み状況の取得エラー", "error", err)
+		for _, name := range h.Sources {
+			components[name] = ComponentStatus{Status: StatusUnavailable, Error: "取り込み状況を取得できません"}
+		}
+		return components
+	}
+
+	now := h.now()
+	for _, name := range h.Sources {
+		last, ok := lastIngested[name]
+		if !ok {
+			components[name] = ComponentStatus{Status: StatusStale, Error: "取り込み実績がありません"}
+			continue
+		}
+
+		age := int64(now.Sub(last).Seconds())
+		component := ComponentStatus{
+			Status:      StatusOK,
+			LastSuccess: last.Format(time.RFC3339),
+			AgeSeconds:  &age,
+		}
+		if now.Sub(last) > h.MaxIngestionAge {
+			component.Status = StatusStale
+		}
+		components[name] = component
+	}
+	return components
+}
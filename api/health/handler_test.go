@@ -0,0 +1,162 @@
+package health
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+)
+
+type fakePinger struct {
+	err error
+}
+
+func (p *fakePinger) PingContext(ctx context.Context) error {
+	return p.err
+}
+
+type fakeIngestion struct {
+	last map[string]time.Time
+	err  error
+}
+
+func (f *fakeIngestion) LastIngestedAt(ctx context.Context) (map[string]time.Time, error) {
+	return f.last, f.err
+}
+
+func TestLivenessHandler(t *testing.T) {
+	handler := NewHealthHandler(&fakePinger{err: errors.New("down")}, &fakeIngestion{}, nil, time.Second, time.Hour)
+
+	rr := httptest.NewRecorder()
+	handler.LivenessHandler(rr, httptest.NewRequest("GET", "/health/live", nil))
+
+	// データベースが停止していてもライブネスは ok を返す
+	if rr.Code != http.StatusOK {
+		t.Errorf("handler returned wrong status code: got %v want %v", rr.Code, http.StatusOK)
+	}
+
+	var response HealthResponse
+	if err := json.Unmarshal(rr.Body.Bytes(), &response); err != nil {
+		t.Fatalf("handler returned invalid JSON: %v", err)
+	}
+	if response.Status != StatusOK {
+		t.Errorf("status = %q, want %q", response.Status, StatusOK)
+	}
+	if response.Version == "" {
+		t.Error("version is empty")
+	}
+}
+
+func TestReadinessHandler(t *testing.T) {
+	now := time.Date(2024, 3, 15, 12, 0, 0, 0, time.UTC)
+
+	testCases := []struct {
+		name           string
+		dbErr          error
+		last           map[string]time.Time
+		wantStatusCode int
+		wantStatus     string
+		wantSource     string
+	}{
+		{
+			name:           "正常系：すべて正常",
+			last:           map[string]time.Time{"rakuten": now.Add(-30 * time.Minute)},
+			wantStatusCode: http.StatusOK,
+			wantStatus:     StatusOK,
+			wantSource:     StatusOK,
+		},
+		{
+			name:           "取り込みが古い場合は degraded",
+			last:           map[string]time.Time{"rakuten": now.Add(-5 * time.Hour)},
+			wantStatusCode: http.StatusOK,
+			wantStatus:     StatusDegraded,
+			wantSource:     StatusStale,
+		},
+		{
+			name:           "取り込み実績がない場合は degraded",
+			last:           map[string]time.Time{},
+			wantStatusCode: http.StatusOK,
+			wantStatus:     StatusDegraded,
+			wantSource:     StatusStale,
+		},
+		{
+			name:           "データベース停止時は 503",
+			dbErr:          errors.New("connection refused"),
+			wantStatusCode: http.StatusServiceUnavailable,
+			wantStatus:     StatusUnavailable,
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			handler := NewHealthHandler(&fakePinger{err: tc.dbErr}, &fakeIngestion{last: tc.last}, []string{"rakuten"}, time.Second, time.Hour)
+			handler.now = func() time.Time { return now }
+
+			rr := httptest.NewRecorder()
+			handler.ReadinessHandler(rr, httptest.NewRequest("GET", "/health/ready", nil))
+
+			if rr.Code != tc.wantStatusCode {
+				t.Errorf("handler returned wrong status code: got %v want %v", rr.Code, tc.wantStatusCode)
+			}
+
+			var response ReadinessResponse
+			if err := json.Unmarshal(rr.Body.Bytes(), &response); err != nil {
+				t.Fatalf("handler returned invalid JSON: %v", err)
+			}
+			if response.Status != tc.wantStatus {
+				t.Errorf("status = %q, want %q", response.Status, tc.wantStatus)
+			}
+			if tc.wantSource != "" {
+				if got := response.Components["ingestion:rakuten"].Status; got != tc.wantSource {
+					t.Errorf("ingestion:rakuten status = %q, want %q", got, tc.wantSource)
+				}
+			}
+		})
+	}
+}
+
+func TestReadinessHandlerHidesErrors(t *testing.T) {
+	testCases := []struct {
+		name         string
+		dbErr        error
+		ingestionErr error
+	}{
+		{
+			name:  "異常系：データベースの接続エラー",
+			dbErr: errors.New("dial tcp 10.0.0.5:3306: connect: connection refused"),
+		},
+		{
+			name:         "異常系：取り込み状況の取得エラー",
+			ingestionErr: errors.New("Error 1045 (28000): Access denied for user 'app'@'10.0.0.5'"),
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			handler := NewHealthHandler(&fakePinger{err: tc.dbErr}, &fakeIngestion{err: tc.ingestionErr}, []string{"rakuten"}, time.Second, time.Hour)
+
+			rr := httptest.NewRecorder()
+			handler.ReadinessHandler(rr, httptest.NewRequest("GET", "/health/ready", nil))
+
+			if body := rr.Body.String(); strings.Contains(body, "10.0.0.5") {
+				t.Errorf("response leaks the error: %s", body)
+			}
+		})
+	}
+}
+
+func TestReadinessHandlerShuttingDown(t *testing.T) {
+	handler := NewHealthHandler(&fakePinger{}, &fakeIngestion{}, nil, time.Second, time.Hour)
+	handler.SetShuttingDown()
+
+	rr := httptest.NewRecorder()
+	handler.ReadinessHandler(rr, httptest.NewRequest("GET", "/health/ready", nil))
+
+	if rr.Code != http.StatusServiceUnavailable {
+		t.Errorf("handler returned wrong status code: got %v want %v", rr.Code, http.StatusServiceUnavailable)
+	}
+}
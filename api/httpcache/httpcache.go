@@ -0,0 +1,127 @@
+// Package httpcache は ETag と Last-Modified による条件付きリクエストと
+// Cache-Control ヘッダーを扱う。
+package httpcache
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Cache-Control の値
+const (
+	NoStore = "no-store"
+)
+
+// Validators はレスポンスの検証子である。
+type Validators struct {
+	// ETag はレスポンスの ETag である。空の場合は本文から計算する。
+	ETag string
+	// LastModified はレスポンスの最終更新時刻である。ゼロ値の場合は送らない。
+	LastModified time.Time
+}
+
+// ETag は識別子の組から弱い ETag を生成する。
+// 表現の細部（JSON の整形など）が変わっても同じ内容なら一致させるため弱い比較を前提とする。
+func ETag(parts ...string) string {
+	h := sha256.New()
+	for _, part := range parts {
+		h.Write([]byte(part))
+		h.Write([]byte{0})
+	}
+	return `W/"` + hex.EncodeToString(h.Sum(nil)[:16]) + `"`
+}
+
+// MaxAge は public なレスポンスの Cache-Control を返す。
+// 期限切れ後も再検証の間は古いレスポンスを返してよいよう stale-while-revalidate を付ける。
+func MaxAge(d time.Duration) string {
+	seconds := strconv.Itoa(int(d.Seconds()))
+	return "public, max-age=" + seconds + ", stale-while-revalidate=" + seconds
+}
+
+// CheckNotModified は検証子をレスポンスヘッダーに設定し、
+// 条件付きリクエストに一致する場合は 304 を返して true を返す。
+// If-None-Match がある場合は If-Modified-Since より優先する（RFC 9110 13.2.2）。
+func CheckNotModified(w http.ResponseWriter, r *http.Request, v Validators) bool {
+	if v.ETag != "" {
+		w.Header().Set("ETag", v.ETag)
+	}
+	if !v.LastModified.IsZero() {
+		w.Header().Set("Last-Modified", v.LastModified.UTC().Format(http.TimeFormat))
+	}
+
+	if r.Method != http.MethodGet && r.Method != http.MethodHead {
+		return false
+	}
+
+	if inm := r.Header.Get("If-None-Match"); inm != "" {
+		if v.ETag == "" || !etagMatches(inm, v.ETag) {
+			return false
+		}
+		writeNotModified(w)
+		return true
+	}
+
+	if ims := r.Header.Get("If-Modified-Since"); ims != "" && !v.LastModified.IsZero() {
+		t, err := http.ParseTime(ims)
+		if err != nil {
+			return false
+		}
+		// HTTP の日付は秒単位のため、秒未満を切り捨てて比較する
+		if v.LastModified.Truncate(time.Second).After(t) {
+			return false
+		}
+		writeNotModified(w)
+		return true
+	}
+
+	return false
+}
+
+// WriteJSON は v を JSON として返す。ETag が指定されていない場合は本文のハッシュを ETag とし、
+// 条件付きリクエストに一致する場合は本文を送らずに 304 を返す。
+func WriteJSON(w http.ResponseWriter, r *http.Request, v interface{}, validators Validators, cacheControl string) error {
+	body, err := json.Marshal(v)
+	if err != nil {
+		return err
+	}
+	body = append(body, '\n')
+
+	if validators.ETag == "" {
+		validators.ETag = ETag(string(body))
+	}
+	w.Header().Set("Cache-Control", cacheControl)
+	if CheckNotModified(w, r, validators) {
+		return nil
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	_, err = w.Write(body)
+	return err
+}
+
+func writeNotModified(w http.ResponseWriter) {
+	// 304 では本文に関するヘッダーを送らない
+	h := w.Header()
+	h.Del("Content-Type")
+	h.Del("Content-Length")
+	w.WriteHeader(http.StatusNotModified)
+}
+
+// etagMatches は If-None-Match の値と ETag を弱い比較で照合する。
+func etagMatches(header, etag string) bool {
+	if strings.TrimSpace(header) == "*" {
+		return true
+	}
+	want := strings.TrimPrefix(etag, "W/")
+	for _, candidate := range strings.Split(header, ",") {
+		if strings.TrimPrefix(strings.TrimSpace(candidate), "W/") == want {
+			return true
+		}
+	}
+	return false
+}
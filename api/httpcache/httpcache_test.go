@@ -0,0 +1,117 @@
+package httpcache
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+)
+
+func TestCheckNotModified(t *testing.T) {
+	lastModified := time.Date(2024, 3, 15, 9, 30, 15, 500, time.UTC)
+	v := Validators{ETag: ETag("ranking", "001", "daily"), LastModified: lastModified}
+
+	testCases := []struct {
+		name    string
+		headers map[string]string
+		want    bool
+	}{
+		{
+			name: "条件なし",
+			want: false,
+		},
+		{
+			name:    "ETag が一致",
+			headers: map[string]string{"If-None-Match": v.ETag},
+			want:    true,
+		},
+		{
+			name:    "弱い比較で一致",
+			headers: map[string]string{"If-None-Match": `"other", ` + v.ETag[2:]},
+			want:    true,
+		},
+		{
+			name:    "ワイルドカード",
+			headers: map[string]string{"If-None-Match": "*"},
+			want:    true,
+		},
+		{
+			name:    "ETag が不一致",
+			headers: map[string]string{"If-None-Match": `W/"other"`},
+			want:    false,
+		},
+		{
+			name:    "更新されていない",
+			headers: map[string]string{"If-Modified-Since": lastModified.Format(http.TimeFormat)},
+			want:    true,
+		},
+		{
+			name:    "更新されている",
+			headers: map[string]string{"If-Modified-Since": lastModified.Add(-time.Second).Format(http.TimeFormat)},
+			want:    false,
+		},
+		{
+			name: "If-None-Match は If-Modified-Since より優先される",
+			headers: map[string]string{
+				"If-None-Match":     `W/"other"`,
+				"If-Modified-Since": lastModified.Format(http.TimeFormat),
+			},
+			want: false,
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			req := httptest.NewRequest("GET", "/api/rankings/001", nil)
+			for k, v := range tc.headers {
+				req.Header.Set(k, v)
+			}
+			rr := httptest.NewRecorder()
+
+			got := CheckNotModified(rr, req, v)
+			if got != tc.want {
+				t.Errorf("CheckNotModified() = %v, want %v", got, tc.want)
+			}
+			if got && rr.Code != http.StatusNotModified {
+				t.Errorf("status = %d, want 304", rr.Code)
+			}
+			if rr.Header().Get("ETag") != v.ETag {
+				t.Errorf("ETag header = %q, want %q", rr.Header().Get("ETag"), v.ETag)
+			}
+			if rr.Header().Get("Last-Modified") != "Fri, 15 Mar 2024 09:30:15 GMT" {
+				t.Errorf("Last-Modified header = %q", rr.Header().Get("Last-Modified"))
+			}
+		})
+	}
+}
+
+func TestWriteJSON(t *testing.T) {
+	body := map[string]string{"id": "001"}
+
+	rr := httptest.NewRecorder()
+	if err := WriteJSON(rr, httptest.NewRequest("GET", "/api/categories", nil), body, Validators{}, MaxAge(time.Hour)); err != nil {
+		t.Fatalf("WriteJSON() error = %v", err)
+	}
+	if rr.Code != http.StatusOK {
+		t.Fatalf("status = %d, want 200", rr.Code)
+	}
+	if got := rr.Header().Get("Cache-Control"); got != "public, max-age=3600, stale-while-revalidate=3600" {
+		t.Errorf("Cache-Control = %q", got)
+	}
+	etag := rr.Header().Get("ETag")
+	if etag == "" {
+		t.Fatal("ETag was not computed from body")
+	}
+
+	req := httptest.NewRequest("GET", "/api/categories", nil)
+	req.Header.Set("If-None-Match", etag)
+	rr = httptest.NewRecorder()
+	WriteJSON(rr, req, body, Validators{}, MaxAge(time.Hour))
+
+	if rr.Code != http.StatusNotModified {
+		t.Errorf("status = %d, want 304", rr.Code)
+	}
+	if rr.Body.Len() != 0 {
+		t.Errorf("304 response has body %q", rr.Body.String())
+	}
+}
@@ -0,0 +1,54 @@
+// Package httperror はハンドラーで発生したエラーを HTTP レスポンスに変換する。
+package httperror
+
+import (
+	"context"
+	"errors"
+	"net/http"
+
+	"github.com/h-hiwatashi/super-business-book-ranking-backend/breaker"
+	"github.com/h-hiwatashi/super-business-book-ranking-backend/logging"
+)
+
+// StatusClientClosedRequest はクライアントがレスポンスを待たずに切断したことを表す。
+// nginx に倣った非標準のステータスコードで、アクセスログとメトリクスで区別するために使う。
+const StatusClientClosedRequest = 499
+
+// Status はエラーに対応するステータスコードを返す。
+// タイムアウトは 504、クライアントの切断は 499、サーキットブレーカーが開いている場合は 503 とし、
+// それ以外は fallback を返す。
+func Status(r *http.Request, err error, fallback int) int {
+	switch {
+	case errors.Is(err, breaker.ErrOpen):
+		return http.StatusServiceUnavailable
+	case errors.Is(err, context.DeadlineExceeded):
+		return http.StatusGatewayTimeout
+	case errors.Is(err, context.Canceled) && r.Context().Err() != nil:
+		return StatusClientClosedRequest
+	default:
+		return fallback
+	}
+}
+
+// Write はエラーをログに記録し、対応するステータスコードでレスポンスを返す。
+// message はタイムアウトやクライアントの切断以外のエラーでクライアントに返す文言である。
+func Write(w http.ResponseWriter, r *http.Request, err error, message string, attrs ...any) {
+	logger := logging.FromContext(r.Context())
+	attrs = append(attrs, "error", err)
+
+	switch status := Status(r, err, http.StatusInternalServerError); status {
+	case http.StatusGatewayTimeout:
+		logger.Warn("処理がタイムアウトしました", attrs...)
+		http.Error(w, "処理がタイムアウトしました", status)
+	case http.StatusServiceUnavailable:
+		logger.Warn("外部サービスへの呼び出しを停止しています", attrs...)
+		http.Error(w, "外部サービスが一時的に利用できません", status)
+	case StatusClientClosedRequest:
+		// 切断済みのため本文は届かないが、ステータスはアクセスログに残す
+		logger.Info("クライアントが切断しました", attrs...)
+		w.WriteHeader(status)
+	default:
+		logger.Error(message, attrs...)
+		http.Error(w, message, status)
+	}
+}
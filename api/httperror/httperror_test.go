@@ -0,0 +1,66 @@
+package httperror
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/h-hiwatashi/super-business-book-ranking-backend/breaker"
+)
+
+func TestWrite(t *testing.T) {
+	testCases := []struct {
+		name           string
+		err            error
+		cancelRequest  bool
+		wantStatusCode int
+	}{
+		{
+			name:           "一般的なエラーは 500",
+			err:            errors.New("connection refused"),
+			wantStatusCode: http.StatusInternalServerError,
+		},
+		{
+			name:           "期限切れは 504",
+			err:            fmt.Errorf("query: %w", context.DeadlineExceeded),
+			wantStatusCode: http.StatusGatewayTimeout,
+		},
+		{
+			name:           "サーキットブレーカーが開いている場合は 503",
+			err:            fmt.Errorf("rakuten: %w", breaker.ErrOpen),
+			wantStatusCode: http.StatusServiceUnavailable,
+		},
+		{
+			name:           "クライアントの切断は 499",
+			err:            context.Canceled,
+			cancelRequest:  true,
+			wantStatusCode: StatusClientClosedRequest,
+		},
+		{
+			name:           "リクエストが生きている場合のキャンセルは 500",
+			err:            context.Canceled,
+			wantStatusCode: http.StatusInternalServerError,
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			req := httptest.NewRequest("GET", "/api/categories", nil)
+			if tc.cancelRequest {
+				ctx, cancel := context.WithCancel(req.Context())
+				cancel()
+				req = req.WithContext(ctx)
+			}
+			rr := httptest.NewRecorder()
+
+			Write(rr, req, tc.err, "データベースクエリエラー")
+
+			if rr.Code != tc.wantStatusCode {
+				t.Errorf("status = %v, want %v", rr.Code, tc.wantStatusCode)
+			}
+		})
+	}
+}
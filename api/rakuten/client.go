@@ -1,12 +1,37 @@
 package rakuten
 
 import (
+	"context"
+	"database/sql"
 	"encoding/json"
+	"errors"
 	"fmt"
+	"io"
 	"math/rand"
+	"net/http"
+	"net/url"
+	"strconv"
 	"time"
+
+	"github.com/h-hiwatashi/super-business-book-ranking-backend/backoff"
+	"github.com/h-hiwatashi/super-business-book-ranking-backend/breaker"
+	"github.com/h-hiwatashi/super-business-book-ranking-backend/clock"
+	"github.com/h-hiwatashi/super-business-book-ranking-backend/logging"
+	"github.com/h-hiwatashi/super-business-book-ranking-backend/repository"
 )
 
+// 楽天ブックス書籍検索APIのエンドポイント
+const DefaultBaseURL = "https://app.rakuten.co.jp/services/api/BooksBook/Search/20170404"
+
+// source_responses に保存する取得元の名前
+const sourceName = "rakuten"
+
+// 1回のリクエストで取得する件数
+const rankingHits = 30
+
+// ClientConfig.Timeout を指定しない場合の、楽天APIへの1回のリクエストにかける時間の上限
+const requestTimeout = 10 * time.Second
+
 type RakutenBookRankingResponse struct {
 	Items []RakutenBookItem `json:"Items"`
 	Count int               `json:"count"`
@@ -14,6 +39,10 @@ type RakutenBookRankingResponse struct {
 	First int               `json:"first"`
 	Last  int               `json:"last"`
 	Hits  int               `json:"hits"`
+	// Stale は楽天APIの障害のため、前回取得したランキングを返していることを表す。
+	Stale bool `json:"stale"`
+	// FetchedAt は Stale の場合に、そのランキングを取得した時刻である。
+	FetchedAt *time.Time `json:"fetchedAt,omitempty"`
 }
 
 type RakutenBookItem struct {
@@ -35,20 +64,317 @@ type RakutenBook struct {
 	ItemCaption   string  `json:"itemCaption"`
 }
 
+// APIError は楽天APIがエラーを返したことを表す。
+type APIError struct {
+	StatusCode  int
+	Code        string `json:"error"`
+	Description string `json:"error_description"`
+}
+
+func (e *APIError) Error() string {
+	if e.Code == "" {
+		return fmt.Sprintf("楽天APIがステータス %d を返しました", e.StatusCode)
+	}
+	return fmt.Sprintf("楽天APIがステータス %d を返しました: %s: %s", e.StatusCode, e.Code, e.Description)
+}
+
+// ClientConfig は楽天APIクライアントの設定である。
+type ClientConfig struct {
+	ApplicationID string
+	BaseURL       string
+	// Timeout は1回のリクエストの期限である。0 の場合は10秒とする。
+	Timeout time.Duration
+	// RateLimit は1秒あたりのリクエスト数の上限である。
+	RateLimit float64
+	Burst     int
+	// MaxRetries は 429 や 5xx を受け取ったときに再試行する最大回数である。
+	MaxRetries     int
+	RetryBaseDelay time.Duration
+	RetryMaxDelay  time.Duration
+}
+
+// ResponseStore は楽天APIから最後に取得できたランキングを保存するストアである。
+type ResponseStore interface {
+	Save(ctx context.Context, response repository.SourceResponse) error
+	Find(ctx context.Context, source, categoryID, periodType string) (*repository.SourceResponse, error)
+}
+
 type RakutenClient struct {
+	// ApplicationID が空の場合は楽天APIを呼び出さず、モックデータを返す。
+	ApplicationID string
+	BaseURL       string
+	HTTPClient    *http.Client
+	// Limiter はこのクライアントを使うハンドラーと取り込みジョブのすべてで共有される。
+	Limiter        *TokenBucket
+	MaxRetries     int
+	RetryBaseDelay time.Duration
+	RetryMaxDelay  time.Duration
+	// Breaker は楽天APIの障害が続いたときに呼び出しを止める。nil の場合は常に呼び出す。
+	Breaker *breaker.Breaker
+	// Responses は取得できたランキングを保存し、障害時に返すために使う。nil の場合は保存しない。
+	Responses ResponseStore
+
+	clock  clock.Clock
+	jitter func(time.Duration) time.Duration
 }
 
 func NewRakutenClient() *RakutenClient {
 	return &RakutenClient{}
 }
 
-func (c *RakutenClient) GetBookRanking(categoryID string, periodType string) (*RakutenBookRankingResponse, error) {
-	mockData := generateMockBookRanking(categoryID, periodType)
-	return mockData, nil
+// NewRakutenAPIClient は楽天APIを呼び出すクライアントを返す。
+func NewRakutenAPIClient(cfg ClientConfig) *RakutenClient {
+	return newAPIClient(cfg, clock.Real{})
+}
+
+func newAPIClient(cfg ClientConfig, clk clock.Clock) *RakutenClient {
+	baseURL := cfg.BaseURL
+	if baseURL == "" {
+		baseURL = DefaultBaseURL
+	}
+	timeout := cfg.Timeout
+	if timeout <= 0 {
+		timeout = requestTimeout
+	}
+	return &RakutenClient{
+		ApplicationID:  cfg.ApplicationID,
+		BaseURL:        baseURL,
+		HTTPClient:     &http.Client{Timeout: timeout},
+		Limiter:        NewTokenBucket(cfg.RateLimit, cfg.Burst, clk),
+		MaxRetries:     cfg.MaxRetries,
+		RetryBaseDelay: cfg.RetryBaseDelay,
+		RetryMaxDelay:  cfg.RetryMaxDelay,
+		clock:          clk,
+		jitter:         backoff.FullJitter,
+	}
+}
+
+func (c *RakutenClient) GetBookRanking(ctx context.Context, categoryID string, periodType string) (*RakutenBookRankingResponse, error) {
+	logger := logging.FromContext(ctx)
+	logger.Debug("楽天ランキング取得開始", "categoryId", categoryID, "period", periodType)
+
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+
+	var ranking *RakutenBookRankingResponse
+	if c.ApplicationID == "" {
+		ranking = generateMockBookRanking(categoryID, periodType)
+	} else {
+		// 流量制限の待ち時間は楽天APIの障害ではないため、サーキットブレーカーの外で待つ。
+		// 開いている間は待たずに失敗させる。
+		if c.Breaker != nil && c.Breaker.State() == breaker.Open {
+			return nil, breaker.ErrOpen
+		}
+		if err := c.Limiter.Wait(ctx); err != nil {
+			return nil, err
+		}
+		fetch := func() error {
+			var err error
+			ranking, err = c.fetchBookRanking(ctx, categoryID)
+			if err != nil && ctx.Err() != nil {
+				return &callerError{err: err}
+			}
+			return err
+		}
+		var err error
+		if c.Breaker != nil {
+			err = c.Breaker.Do(fetch)
+		} else {
+			err = fetch()
+		}
+		if err != nil {
+			return nil, err
+		}
+		c.saveResponse(ctx, categoryID, periodType, ranking)
+	}
+
+	logger.Debug("楽天ランキング取得完了", "categoryId", categoryID, "period", periodType, "items", len(ranking.Items))
+	return ranking, nil
+}
+
+// LastBookRanking は最後に取得できたランキングを Stale を付けて返す。
+// 保存されていない場合は sql.ErrNoRows を返す。
+func (c *RakutenClient) LastBookRanking(ctx context.Context, categoryID string, periodType string) (*RakutenBookRankingResponse, error) {
+	if c.Responses == nil {
+		return nil, sql.ErrNoRows
+	}
+	saved, err := c.Responses.Find(ctx, sourceName, categoryID, periodType)
+	if err != nil {
+		return nil, err
+	}
+
+	var ranking RakutenBookRankingResponse
+	if err := json.Unmarshal(saved.Body, &ranking); err != nil {
+		return nil, fmt.Errorf("保存済みのランキングを解析できません: %w", err)
+	}
+	ranking.Stale = true
+	ranking.FetchedAt = &saved.FetchedAt
+	return &ranking, nil
+}
+
+// saveResponse は取得できたランキングを障害時のために保存する。
+// 保存に失敗してもランキングは返せるため、ログに残すだけにする。
+func (c *RakutenClient) saveResponse(ctx context.Context, categoryID string, periodType string, ranking *RakutenBookRankingResponse) {
+	if c.Responses == nil {
+		return
+	}
+	logger := logging.FromContext(ctx)
+
+	body, err := json.Marshal(ranking)
+	if err != nil {
+		logger.Warn("楽天ランキングの保存に失敗しました", "categoryId", categoryID, "period", periodType, "error", err)
+		return
+	}
+	err = c.Responses.Save(ctx, repository.SourceResponse{
+		Source:     sourceName,
+		CategoryID: categoryID,
+		PeriodType: periodType,
+		Body:       body,
+		FetchedAt:  c.clock.Now(),
+	})
+	if err != nil {
+		logger.Warn("楽天ランキングの保存に失敗しました", "categoryId", categoryID, "period", periodType, "error", err)
+	}
+}
+
+// callerError は呼び出し元の期限切れや切断によって取得を中断したことを表す。
+type callerError struct {
+	err error
+}
+
+func (e *callerError) Error() string { return e.err.Error() }
+
+func (e *callerError) Unwrap() error { return e.err }
+
+// IsSourceFailure は err が楽天API側の障害によるものかを返す。
+// 呼び出し元の期限切れや切断、リクエスト内容の誤り（4xx）は障害として扱わない。
+// サーキットブレーカーの失敗の判定と、前回のランキングを返すかの判定に使う。
+func IsSourceFailure(err error) bool {
+	var callerErr *callerError
+	if err == nil || errors.Is(err, context.Canceled) || errors.As(err, &callerErr) {
+		return false
+	}
+	var apiErr *APIError
+	if errors.As(err, &apiErr) {
+		return retryable(apiErr.StatusCode)
+	}
+	return true
+}
+
+// fetchBookRanking は楽天ブックス書籍検索APIからジャンル内の売上順の書籍を取得する。
+// 書籍検索APIは集計期間を指定できないため、期間によらず同じ結果になる。
+//
+// 429 と 5xx、通信エラーの場合は指数バックオフで MaxRetries 回まで再試行する。
+// Retry-After が返された場合はそれに従う。最初のリクエストの流量制限は呼び出し元で待ち、
+// 再試行は Limiter で間隔を空けて送る。
+func (c *RakutenClient) fetchBookRanking(ctx context.Context, genreID string) (*RakutenBookRankingResponse, error) {
+	logger := logging.FromContext(ctx)
+
+	query := url.Values{}
+	query.Set("applicationId", c.ApplicationID)
+	query.Set("format", "json")
+	query.Set("booksGenreId", genreID)
+	query.Set("sort", "sales")
+	query.Set("hits", strconv.Itoa(rankingHits))
+	endpoint := c.BaseURL + "?" + query.Encode()
+
+	for attempt := 0; ; attempt++ {
+		ranking, retryAfter, err := c.do(ctx, endpoint)
+		if err == nil {
+			return ranking, nil
+		}
+		if ctx.Err() != nil {
+			return nil, ctx.Err()
+		}
+
+		var apiErr *APIError
+		if errors.As(err, &apiErr) && !retryable(apiErr.StatusCode) {
+			return nil, err
+		}
+		if attempt >= c.MaxRetries {
+			return nil, fmt.Errorf("楽天APIの再試行回数の上限に達しました: %w", err)
+		}
+
+		delay := backoff.Delay(attempt, c.RetryBaseDelay, c.RetryMaxDelay, c.jitter)
+		if retryAfter > 0 {
+			// 同じアプリIDを使う他の呼び出しも止める
+			c.Limiter.Backoff(retryAfter)
+			delay = max(delay, retryAfter)
+		}
+		if deadline, ok := ctx.Deadline(); ok && time.Until(deadline) < delay {
+			return nil, fmt.Errorf("楽天APIの再試行を待つ時間がありません: %w", err)
+		}
+
+		logger.Warn("楽天APIリクエストを再試行します", "attempt", attempt+1, "delay", delay, "error", err)
+		select {
+		case <-c.clock.After(delay):
+		case <-ctx.Done():
+			return nil, ctx.Err()
+		}
+		if err := c.Limiter.Wait(ctx); err != nil {
+			return nil, err
+		}
+	}
+}
+
+// do はリクエストを1回送信する。429 などで Retry-After が返された場合はその待ち時間も返す。
+func (c *RakutenClient) do(ctx context.Context, endpoint string) (*RakutenBookRankingResponse, time.Duration, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, endpoint, nil)
+	if err != nil {
+		return nil, 0, redactURL(err)
+	}
+	resp, err := c.HTTPClient.Do(req)
+	if err != nil {
+		return nil, 0, redactURL(err)
+	}
+	defer resp.Body.Close()
+
+	body, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return nil, 0, err
+	}
+
+	if resp.StatusCode != http.StatusOK {
+		apiErr := &APIError{StatusCode: resp.StatusCode}
+		// エラー本文が JSON でない場合もステータスコードだけで判断する
+		_ = json.Unmarshal(body, apiErr)
+		retryAfter, _ := parseRetryAfter(resp.Header.Get("Retry-After"), c.clock.Now())
+		return nil, retryAfter, apiErr
+	}
+
+	var ranking RakutenBookRankingResponse
+	if err := json.Unmarshal(body, &ranking); err != nil {
+		return nil, 0, fmt.Errorf("楽天APIのレスポンスを解析できません: %w", err)
+	}
+	// 書籍検索APIは順位を返さないため、売上順の並びから付与する
+	first := max(ranking.First, 1)
+	for i := range ranking.Items {
+		ranking.Items[i].Item.Rank = first + i
+	}
+	return &ranking, 0, nil
+}
+
+// redactURL は err に含まれるURLからクエリを除く。
+// クエリにはアプリIDが含まれ、エラーはログに残るため、ホストとパスだけにする。
+func redactURL(err error) error {
+	var urlErr *url.Error
+	if !errors.As(err, &urlErr) {
+		return err
+	}
+	if u, parseErr := url.Parse(urlErr.URL); parseErr == nil {
+		u.User = nil
+		u.RawQuery = ""
+		u.Fragment = ""
+		urlErr.URL = u.String()
+	} else {
+		urlErr.URL = ""
+	}
+	return err
 }
 
-func (c *RakutenClient) GetBookRankingJSON(categoryID string, periodType string) (string, error) {
-	ranking, err := c.GetBookRanking(categoryID, periodType)
+func (c *RakutenClient) GetBookRankingJSON(ctx context.Context, categoryID string, periodType string) (string, error) {
+	ranking, err := c.GetBookRanking(ctx, categoryID, periodType)
 	if err != nil {
 		return "", err
 	}
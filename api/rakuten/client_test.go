@@ -1,8 +1,23 @@
 package rakuten
 
 import (
+	"bytes"
+	"context"
+	"database/sql"
 	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync/atomic"
 	"testing"
+	"time"
+
+	"github.com/h-hiwatashi/super-business-book-ranking-backend/breaker"
+	"github.com/h-hiwatashi/super-business-book-ranking-backend/clock"
+	"github.com/h-hiwatashi/super-business-book-ranking-backend/logging"
+	"github.com/h-hiwatashi/super-business-book-ranking-backend/repository"
 )
 
 func TestNewRakutenClient(t *testing.T) {
@@ -43,7 +58,7 @@ func TestGetBookRanking(t *testing.T) {
 	
 	for _, tc := range testCases {
 		t.Run(tc.name, func(t *testing.T) {
-			result, err := client.GetBookRanking(tc.categoryID, tc.periodType)
+			result, err := client.GetBookRanking(context.Background(), tc.categoryID, tc.periodType)
 			
 			if err != nil {
 				t.Errorf("GetBookRanking() error = %v", err)
@@ -87,7 +102,7 @@ func TestGetBookRanking(t *testing.T) {
 func TestGetBookRankingJSON(t *testing.T) {
 	client := NewRakutenClient()
 	
-	jsonStr, err := client.GetBookRankingJSON("001", "daily")
+	jsonStr, err := client.GetBookRankingJSON(context.Background(), "001", "daily")
 	
 	if err != nil {
 		t.Errorf("GetBookRankingJSON() error = %v", err)
@@ -114,3 +129,399 @@ func TestGetBookRankingJSON(t *testing.T) {
 		t.Error("GetBookRankingJSON() returned empty Items array")
 	}
 }
+
+const testRankingBody = `{"Items":[{"Item":{"title":"成功する習慣","isbn":"9784123456789"}},{"Item":{"title":"リーダーシップの極意","isbn":"9784123456790"}}],"count":2,"page":1,"first":1,"last":2,"hits":30}`
+
+// newTestAPIClient は server に接続し、偽の時計で待機するクライアントを返す。
+// 待ち時間を検証しやすいよう、バックオフのばらつきはなくしている。
+func newTestAPIClient(server *httptest.Server, clk clock.Clock) *RakutenClient {
+	client := newAPIClient(ClientConfig{
+		ApplicationID:  "test-app",
+		BaseURL:        server.URL,
+		RateLimit:      1,
+		Burst:          10,
+		MaxRetries:     3,
+		RetryBaseDelay: time.Second,
+		RetryMaxDelay:  10 * time.Second,
+	}, clk)
+	client.jitter = func(d time.Duration) time.Duration { return d }
+	return client
+}
+
+type rankingResult struct {
+	ranking *RakutenBookRankingResponse
+	err     error
+}
+
+func getBookRankingAsync(client *RakutenClient) <-chan rankingResult {
+	done := make(chan rankingResult, 1)
+	go func() {
+		ranking, err := client.GetBookRanking(context.Background(), "001006", "daily")
+		done <- rankingResult{ranking, err}
+	}()
+	return done
+}
+
+func receive(t *testing.T, done <-chan rankingResult) rankingResult {
+	t.Helper()
+	select {
+	case result := <-done:
+		return result
+	case <-time.After(5 * time.Second):
+		t.Fatal("GetBookRanking() did not return")
+		return rankingResult{}
+	}
+}
+
+func TestGetBookRankingAPI(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		q := r.URL.Query()
+		if q.Get("applicationId") != "test-app" || q.Get("booksGenreId") != "001006" || q.Get("sort") != "sales" {
+			t.Errorf("unexpected query: %s", r.URL.RawQuery)
+		}
+		fmt.Fprint(w, testRankingBody)
+	}))
+	defer server.Close()
+
+	client := newTestAPIClient(server, clock.NewFake(testStart))
+	result, err := client.GetBookRanking(context.Background(), "001006", "daily")
+	if err != nil {
+		t.Fatalf("GetBookRanking() error = %v", err)
+	}
+
+	if len(result.Items) != 2 {
+		t.Fatalf("GetBookRanking() returned %d items, want 2", len(result.Items))
+	}
+	for i, item := range result.Items {
+		if item.Item.Rank != i+1 {
+			t.Errorf("Item %d has rank %d, want %d", i, item.Item.Rank, i+1)
+		}
+	}
+}
+
+func TestGetBookRankingAPIRetry(t *testing.T) {
+	testCases := []struct {
+		name       string
+		statuses   []int
+		retryAfter string
+		delays     []time.Duration
+		wantErr    bool
+		wantStatus int
+		wantCalls  int32
+	}{
+		{
+			name:      "5xx は指数バックオフで再試行する",
+			statuses:  []int{http.StatusServiceUnavailable, http.StatusBadGateway, http.StatusOK},
+			delays:    []time.Duration{time.Second, 2 * time.Second},
+			wantCalls: 3,
+		},
+		{
+			name:       "429 は Retry-After に従う",
+			statuses:   []int{http.StatusTooManyRequests, http.StatusOK},
+			retryAfter: "5",
+			// Retry-After の間はトークンの補充も止まるため、その後さらに1秒待つ
+			delays:    []time.Duration{5 * time.Second, time.Second},
+			wantCalls: 2,
+		},
+		{
+			name:       "再試行回数の上限で諦める",
+			statuses:   []int{500, 500, 500, 500},
+			delays:     []time.Duration{time.Second, 2 * time.Second, 4 * time.Second},
+			wantErr:    true,
+			wantStatus: 500,
+			wantCalls:  4,
+		},
+		{
+			name:       "4xx は再試行しない",
+			statuses:   []int{http.StatusBadRequest},
+			wantErr:    true,
+			wantStatus: http.StatusBadRequest,
+			wantCalls:  1,
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			var calls atomic.Int32
+			server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				status := tc.statuses[calls.Add(1)-1]
+				if status != http.StatusOK {
+					if tc.retryAfter != "" {
+						w.Header().Set("Retry-After", tc.retryAfter)
+					}
+					w.WriteHeader(status)
+					fmt.Fprint(w, `{"error":"error","error_description":"test"}`)
+					return
+				}
+				fmt.Fprint(w, testRankingBody)
+			}))
+			defer server.Close()
+
+			clk := clock.NewFake(testStart)
+			client := newTestAPIClient(server, clk)
+			done := getBookRankingAsync(client)
+
+			for _, delay := range tc.delays {
+				clk.BlockUntil(1)
+				clk.Advance(delay)
+			}
+			result := receive(t, done)
+
+			if got := calls.Load(); got != tc.wantCalls {
+				t.Errorf("server called %d times, want %d", got, tc.wantCalls)
+			}
+			if !tc.wantErr {
+				if result.err != nil {
+					t.Fatalf("GetBookRanking() error = %v", result.err)
+				}
+				if len(result.ranking.Items) != 2 {
+					t.Errorf("GetBookRanking() returned %d items, want 2", len(result.ranking.Items))
+				}
+				return
+			}
+
+			var apiErr *APIError
+			if !errors.As(result.err, &apiErr) {
+				t.Fatalf("GetBookRanking() error = %v, want *APIError", result.err)
+			}
+			if apiErr.StatusCode != tc.wantStatus {
+				t.Errorf("StatusCode = %d, want %d", apiErr.StatusCode, tc.wantStatus)
+			}
+		})
+	}
+}
+
+func TestGetBookRankingAPIRedactsApplicationID(t *testing.T) {
+	// 接続できないサーバーに送り、URL を含む通信エラーを起こす
+	server := httptest.NewServer(http.NotFoundHandler())
+	server.Close()
+
+	var buf bytes.Buffer
+	ctx := logging.WithLogger(context.Background(), logging.New(&buf, "debug"))
+
+	clk := clock.NewFake(testStart)
+	client := newTestAPIClient(server, clk)
+	client.MaxRetries = 1
+	done := make(chan rankingResult, 1)
+	go func() {
+		ranking, err := client.GetBookRanking(ctx, "001006", "daily")
+		done <- rankingResult{ranking, err}
+	}()
+	clk.BlockUntil(1)
+	clk.Advance(time.Second)
+
+	result := receive(t, done)
+	if result.err == nil {
+		t.Fatal("GetBookRanking() error = nil, want error")
+	}
+	if strings.Contains(result.err.Error(), "test-app") {
+		t.Errorf("error %q contains the application ID", result.err)
+	}
+	if !strings.Contains(result.err.Error(), server.URL) {
+		t.Errorf("error %q does not contain the host", result.err)
+	}
+	if !strings.Contains(buf.String(), "再試行します") {
+		t.Fatalf("retry was not logged: %s", buf.String())
+	}
+	if strings.Contains(buf.String(), "test-app") {
+		t.Errorf("log contains the application ID: %s", buf.String())
+	}
+}
+
+func TestGetBookRankingAPIRateLimit(t *testing.T) {
+	var calls atomic.Int32
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		calls.Add(1)
+		fmt.Fprint(w, testRankingBody)
+	}))
+	defer server.Close()
+
+	clk := clock.NewFake(testStart)
+	client := newTestAPIClient(server, clk)
+	client.Limiter = NewTokenBucket(1, 1, clk)
+
+	receive(t, getBookRankingAsync(client))
+
+	// 2回目は1秒経過するまで送信されない
+	done := getBookRankingAsync(client)
+	clk.BlockUntil(1)
+	if got := calls.Load(); got != 1 {
+		t.Fatalf("server called %d times before a token was available, want 1", got)
+	}
+	clk.Advance(time.Second)
+	if result := receive(t, done); result.err != nil {
+		t.Fatalf("GetBookRanking() error = %v", result.err)
+	}
+	if got := calls.Load(); got != 2 {
+		t.Errorf("server called %d times, want 2", got)
+	}
+}
+
+type fakeResponseStore struct {
+	responses map[string]repository.SourceResponse
+}
+
+func newFakeResponseStore() *fakeResponseStore {
+	return &fakeResponseStore{responses: map[string]repository.SourceResponse{}}
+}
+
+func (s *fakeResponseStore) Save(ctx context.Context, response repository.SourceResponse) error {
+	s.responses[response.Source+"/"+response.CategoryID+"/"+response.PeriodType] = response
+	return nil
+}
+
+func (s *fakeResponseStore) Find(ctx context.Context, source, categoryID, periodType string) (*repository.SourceResponse, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+	response, ok := s.responses[source+"/"+categoryID+"/"+periodType]
+	if !ok {
+		return nil, sql.ErrNoRows
+	}
+	return &response, nil
+}
+
+func TestGetBookRankingAPIBreaker(t *testing.T) {
+	var calls atomic.Int32
+	var failing atomic.Bool
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		calls.Add(1)
+		if failing.Load() {
+			w.WriteHeader(http.StatusInternalServerError)
+			return
+		}
+		fmt.Fprint(w, testRankingBody)
+	}))
+	defer server.Close()
+
+	clk := clock.NewFake(testStart)
+	store := newFakeResponseStore()
+	client := newTestAPIClient(server, clk)
+	client.MaxRetries = 0
+	client.Responses = store
+	client.Breaker = breaker.New("test-rakuten", breaker.Settings{
+		FailureThreshold: 2,
+		OpenTimeout:      time.Minute,
+		IsFailure:        IsSourceFailure,
+		Clock:            clk,
+	})
+	ctx := context.Background()
+
+	// 取得できたランキングは保存される
+	if _, err := client.GetBookRanking(ctx, "001006", "daily"); err != nil {
+		t.Fatalf("GetBookRanking() error = %v", err)
+	}
+
+	failing.Store(true)
+	for i := 0; i < 2; i++ {
+		if _, err := client.GetBookRanking(ctx, "001006", "daily"); err == nil {
+			t.Fatal("GetBookRanking() error = nil, want error")
+		}
+	}
+
+	// ブレーカーが開くと楽天APIを呼び出さない
+	if _, err := client.GetBookRanking(ctx, "001006", "daily"); !errors.Is(err, breaker.ErrOpen) {
+		t.Errorf("GetBookRanking() error = %v, want %v", err, breaker.ErrOpen)
+	}
+	if got := calls.Load(); got != 3 {
+		t.Errorf("server called %d times, want 3", got)
+	}
+
+	stale, err := client.LastBookRanking(ctx, "001006", "daily")
+	if err != nil {
+		t.Fatalf("LastBookRanking() error = %v", err)
+	}
+	if !stale.Stale || stale.FetchedAt == nil || !stale.FetchedAt.Equal(testStart) {
+		t.Errorf("LastBookRanking() stale = %v, fetchedAt = %v, want true, %v", stale.Stale, stale.FetchedAt, testStart)
+	}
+	if len(stale.Items) != 2 {
+		t.Errorf("LastBookRanking() returned %d items, want 2", len(stale.Items))
+	}
+
+	if _, err := client.LastBookRanking(ctx, "001006", "weekly"); !errors.Is(err, sql.ErrNoRows) {
+		t.Errorf("LastBookRanking() error = %v, want %v", err, sql.ErrNoRows)
+	}
+}
+
+func TestGetBookRankingAPIBreakerIgnoresCallerDeadline(t *testing.T) {
+	var calls atomic.Int32
+	release := make(chan struct{})
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if calls.Add(1) == 1 {
+			// 呼び出し元の期限が切れるまで応答しない
+			select {
+			case <-r.Context().Done():
+			case <-release:
+			}
+			return
+		}
+		fmt.Fprint(w, testRankingBody)
+	}))
+	defer server.Close()
+	defer close(release)
+
+	clk := clock.NewFake(testStart)
+	client := newTestAPIClient(server, clk)
+	client.MaxRetries = 0
+	client.Limiter = NewTokenBucket(1, 1, clk)
+	client.Breaker = breaker.New("test-rakuten-deadline", breaker.Settings{
+		FailureThreshold: 1,
+		OpenTimeout:      time.Minute,
+		IsFailure:        IsSourceFailure,
+		Clock:            clk,
+	})
+
+	// 楽天APIの応答待ちで期限が切れた
+	ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+	defer cancel()
+	if _, err := client.GetBookRanking(ctx, "001006", "daily"); !errors.Is(err, context.DeadlineExceeded) {
+		t.Fatalf("GetBookRanking() error = %v, want %v", err, context.DeadlineExceeded)
+	}
+	if got := client.Breaker.State(); got != breaker.Closed {
+		t.Fatalf("breaker state = %v after the caller's deadline, want %v", got, breaker.Closed)
+	}
+
+	// 流量制限の待機中に期限が切れた
+	ctx, cancel = context.WithTimeout(context.Background(), 50*time.Millisecond)
+	defer cancel()
+	if _, err := client.GetBookRanking(ctx, "001006", "daily"); !errors.Is(err, context.DeadlineExceeded) {
+		t.Fatalf("GetBookRanking() error = %v, want %v", err, context.DeadlineExceeded)
+	}
+	if got := client.Breaker.State(); got != breaker.Closed {
+		t.Fatalf("breaker state = %v after waiting on the limiter, want %v", got, breaker.Closed)
+	}
+
+	clk.Advance(time.Second)
+	if _, err := client.GetBookRanking(context.Background(), "001006", "daily"); err != nil {
+		t.Errorf("GetBookRanking() error = %v", err)
+	}
+	if got := calls.Load(); got != 2 {
+		t.Errorf("server called %d times, want 2", got)
+	}
+}
+
+func TestIsSourceFailure(t *testing.T) {
+	testCases := []struct {
+		name string
+		err  error
+		want bool
+	}{
+		{name: "5xx", err: &APIError{StatusCode: http.StatusBadGateway}, want: true},
+		{name: "429", err: &APIError{StatusCode: http.StatusTooManyRequests}, want: true},
+		{name: "4xx", err: &APIError{StatusCode: http.StatusBadRequest}, want: false},
+		{name: "通信エラー", err: errors.New("connection refused"), want: true},
+		{name: "期限切れ", err: context.DeadlineExceeded, want: true},
+		{name: "キャンセル", err: context.Canceled, want: false},
+		{name: "呼び出し元の期限切れ", err: &callerError{err: context.DeadlineExceeded}, want: false},
+		{name: "呼び出し元の期限切れ（再試行の上限）", err: fmt.Errorf("楽天APIの再試行回数の上限に達しました: %w", &callerError{err: errors.New("timeout")}), want: false},
+		{name: "ブレーカーが開いている", err: breaker.ErrOpen, want: true},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			if got := IsSourceFailure(tc.err); got != tc.want {
+				t.Errorf("IsSourceFailure(%v) = %v, want %v", tc.err, got, tc.want)
+			}
+		})
+	}
+}
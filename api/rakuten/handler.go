@@ -1,14 +1,39 @@
 package rakuten
 
 import (
+	"context"
+	"database/sql"
 	"encoding/json"
+	"errors"
 	"net/http"
+	"strconv"
+	"time"
 
 	"github.com/gorilla/mux"
+
+	"github.com/h-hiwatashi/super-business-book-ranking-backend/affiliate"
+	"github.com/h-hiwatashi/super-business-book-ranking-backend/api/export"
+	"github.com/h-hiwatashi/super-business-book-ranking-backend/api/httpcache"
+	"github.com/h-hiwatashi/super-business-book-ranking-backend/api/httperror"
+	"github.com/h-hiwatashi/super-business-book-ranking-backend/logging"
 )
 
+// 楽天ランキングのキャッシュ期間
+const cacheMaxAge = 5 * time.Minute
+
+// 障害時に返す古いランキングのキャッシュ期間。回復後すぐに新しいランキングを返せるよう短くする。
+const staleMaxAge = 30 * time.Second
+
+// 障害時に前回のランキングを読み出す期限。リクエストの期限が切れた後に読み出すため、別に設ける。
+const staleLookupTimeout = 2 * time.Second
+
+// アフィリエイトリンクを生成する際のサイト名（sites.name）
+const siteName = "rakuten"
+
 type RakutenHandler struct {
 	Client *RakutenClient
+	// Links が nil の場合は商品URLをそのまま返す。
+	Links affiliate.URLLinker
 }
 
 func NewRakutenHandler() *RakutenHandler {
@@ -25,13 +50,90 @@ func (h *RakutenHandler) GetRakutenBookRankingHandler(w http.ResponseWriter, r *
 	if periodType == "" {
 		periodType = "daily" // デフォルト値
 	}
+	format, err := export.Negotiate(r)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	// 形式を Accept で選ぶため、共有キャッシュに Accept ごとに保存させる
+	w.Header().Add("Vary", "Accept")
 	
-	ranking, err := h.Client.GetBookRanking(categoryID, periodType)
+	ranking, err := h.Client.GetBookRanking(r.Context(), categoryID, periodType)
+	if err != nil && (IsSourceFailure(err) || errors.Is(err, context.DeadlineExceeded)) {
+		// 楽天APIの障害時や応答が期限に間に合わない場合は前回取得できたランキングを返す。
+		// リクエストの context は期限切れのことがあるため、切り離して読み出す。
+		ctx, cancel := context.WithTimeout(context.WithoutCancel(r.Context()), staleLookupTimeout)
+		defer cancel()
+		stale, staleErr := h.Client.LastBookRanking(ctx, categoryID, periodType)
+		if staleErr == nil {
+			logging.FromContext(r.Context()).Warn("楽天APIの障害のため前回取得したランキングを返します",
+				"categoryId", categoryID, "period", periodType, "fetchedAt", stale.FetchedAt, "error", err)
+			h.write(w, r, format, categoryID, periodType, h.withAffiliateLinks(stale), httpcache.Validators{LastModified: *stale.FetchedAt}, httpcache.MaxAge(staleMaxAge))
+			return
+		}
+		if !errors.Is(staleErr, sql.ErrNoRows) {
+			logging.FromContext(r.Context()).Error("保存済みの楽天ランキング取得エラー", "categoryId", categoryID, "period", periodType, "error", staleErr)
+		}
+	}
 	if err != nil {
-		http.Error(w, "楽天APIエラー: "+err.Error(), http.StatusInternalServerError)
+		httperror.Write(w, r, err, "楽天APIエラー", "categoryId", categoryID, "period", periodType)
 		return
 	}
 	
-	w.Header().Set("Content-Type", "application/json")
-	json.NewEncoder(w).Encode(ranking)
+	h.write(w, r, format, categoryID, periodType, h.withAffiliateLinks(ranking), httpcache.Validators{}, httpcache.MaxAge(cacheMaxAge))
+}
+
+// CSV・TSV の見出し行
+var rankingHeader = []string{
+	"順位", "タイトル", "著者", "出版社", "ISBN", "価格", "発売日", "レビュー数", "平均評価", "URL",
+}
+
+// write はランキングを format の形式で返す。
+// CSV・TSV の ETag は JSON と同じくランキングの内容から作る。
+func (h *RakutenHandler) write(w http.ResponseWriter, r *http.Request, format export.Format, categoryID, periodType string,
+	ranking *RakutenBookRankingResponse, validators httpcache.Validators, cacheControl string) {
+	if format == export.FormatJSON {
+		httpcache.WriteJSON(w, r, ranking, validators, cacheControl)
+		return
+	}
+
+	content, err := json.Marshal(ranking)
+	if err != nil {
+		httperror.Write(w, r, err, "楽天ランキングの書き出しエラー", "categoryId", categoryID, "period", periodType)
+		return
+	}
+	validators.ETag = httpcache.ETag("rakuten", string(content), string(format), r.URL.Query().Get("bom"))
+	w.Header().Set("Cache-Control", cacheControl)
+	if httpcache.CheckNotModified(w, r, validators) {
+		return
+	}
+
+	out := export.NewWriter(w, r, format, export.Filename("rakuten", categoryID, periodType))
+	out.Write(rankingHeader)
+	for _, item := range ranking.Items {
+		book := item.Item
+		out.Write([]string{
+			strconv.Itoa(book.Rank), book.Title, book.Author, book.PublisherName, book.ISBN,
+			strconv.Itoa(book.ItemPrice), book.SalesDate, strconv.Itoa(book.ReviewCount),
+			strconv.FormatFloat(book.ReviewAverage, 'f', -1, 64), book.ItemURL,
+		})
+	}
+	if err := out.Flush(); err != nil {
+		logging.FromContext(r.Context()).Error("楽天ランキングの書き出しエラー", "categoryId", categoryID, "error", err)
+	}
+}
+
+// withAffiliateLinks は商品URLをアフィリエイトリンクに置き換えたランキングを返す。
+func (h *RakutenHandler) withAffiliateLinks(ranking *RakutenBookRankingResponse) *RakutenBookRankingResponse {
+	if h.Links == nil || len(ranking.Items) == 0 {
+		return ranking
+	}
+
+	linked := *ranking
+	linked.Items = make([]RakutenBookItem, len(ranking.Items))
+	for i, item := range ranking.Items {
+		item.Item.ItemURL = h.Links.Link(siteName, item.Item.ItemURL)
+		linked.Items[i] = item
+	}
+	return &linked
 }
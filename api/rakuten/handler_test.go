@@ -1,12 +1,21 @@
 package rakuten
 
 import (
+	"context"
+	"encoding/csv"
 	"encoding/json"
 	"net/http"
 	"net/http/httptest"
+	"strings"
 	"testing"
+	"time"
 
 	"github.com/gorilla/mux"
+
+	"github.com/h-hiwatashi/super-business-book-ranking-backend/affiliate"
+	"github.com/h-hiwatashi/super-business-book-ranking-backend/breaker"
+	"github.com/h-hiwatashi/super-business-book-ranking-backend/clock"
+	"github.com/h-hiwatashi/super-business-book-ranking-backend/repository"
 )
 
 func TestNewRakutenHandler(t *testing.T) {
@@ -102,3 +111,186 @@ func TestGetRakutenBookRankingHandler(t *testing.T) {
 		})
 	}
 }
+
+func TestGetRakutenBookRankingHandlerStale(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusServiceUnavailable)
+	}))
+	defer server.Close()
+
+	fetchedAt := time.Date(2024, 3, 14, 12, 0, 0, 0, time.UTC)
+	store := newFakeResponseStore()
+	store.Save(context.Background(), repository.SourceResponse{
+		Source:     "rakuten",
+		CategoryID: "001006",
+		PeriodType: "daily",
+		Body:       []byte(testRankingBody),
+		FetchedAt:  fetchedAt,
+	})
+
+	clk := clock.NewFake(testStart)
+	client := newTestAPIClient(server, clk)
+	client.MaxRetries = 0
+	client.Responses = store
+	client.Breaker = breaker.New("test-rakuten-handler", breaker.Settings{
+		FailureThreshold: 1,
+		OpenTimeout:      time.Minute,
+		IsFailure:        IsSourceFailure,
+		Clock:            clk,
+	})
+
+	router := mux.NewRouter()
+	handler := &RakutenHandler{Client: client}
+	router.HandleFunc("/api/rakuten/rankings/{categoryId}", handler.GetRakutenBookRankingHandler).Methods("GET")
+
+	testCases := []struct {
+		name           string
+		categoryID     string
+		wantStatusCode int
+		wantStale      bool
+	}{
+		{
+			name:           "楽天APIのエラー時は前回のランキングを返す",
+			categoryID:     "001006",
+			wantStatusCode: http.StatusOK,
+			wantStale:      true,
+		},
+		{
+			name:           "ブレーカーが開いている間も前回のランキングを返す",
+			categoryID:     "001006",
+			wantStatusCode: http.StatusOK,
+			wantStale:      true,
+		},
+		{
+			name:           "保存されたランキングがなければ 503",
+			categoryID:     "001001",
+			wantStatusCode: http.StatusServiceUnavailable,
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			req := httptest.NewRequest("GET", "/api/rakuten/rankings/"+tc.categoryID, nil)
+			rr := httptest.NewRecorder()
+			router.ServeHTTP(rr, req)
+
+			if status := rr.Code; status != tc.wantStatusCode {
+				t.Fatalf("handler returned wrong status code: got %v want %v", status, tc.wantStatusCode)
+			}
+			if !tc.wantStale {
+				return
+			}
+
+			var response RakutenBookRankingResponse
+			if err := json.Unmarshal(rr.Body.Bytes(), &response); err != nil {
+				t.Fatalf("handler returned invalid JSON: %v", err)
+			}
+			if !response.Stale || response.FetchedAt == nil || !response.FetchedAt.Equal(fetchedAt) {
+				t.Errorf("stale = %v, fetchedAt = %v, want true, %v", response.Stale, response.FetchedAt, fetchedAt)
+			}
+			if got := rr.Header().Get("Last-Modified"); got != fetchedAt.Format(http.TimeFormat) {
+				t.Errorf("Last-Modified = %q, want %q", got, fetchedAt.Format(http.TimeFormat))
+			}
+		})
+	}
+}
+
+func TestGetRakutenBookRankingHandlerStaleAfterDeadline(t *testing.T) {
+	release := make(chan struct{})
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		// 楽天APIの応答がリクエストの期限に間に合わない
+		select {
+		case <-r.Context().Done():
+		case <-release:
+		}
+	}))
+	defer server.Close()
+	defer close(release)
+
+	fetchedAt := time.Date(2024, 3, 14, 12, 0, 0, 0, time.UTC)
+	store := newFakeResponseStore()
+	store.Save(context.Background(), repository.SourceResponse{
+		Source:     "rakuten",
+		CategoryID: "001006",
+		PeriodType: "daily",
+		Body:       []byte(testRankingBody),
+		FetchedAt:  fetchedAt,
+	})
+
+	client := newTestAPIClient(server, clock.NewFake(testStart))
+	client.MaxRetries = 0
+	client.Responses = store
+
+	router := mux.NewRouter()
+	handler := &RakutenHandler{Client: client}
+	router.HandleFunc("/api/rakuten/rankings/{categoryId}", handler.GetRakutenBookRankingHandler).Methods("GET")
+
+	ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+	defer cancel()
+	req := httptest.NewRequest("GET", "/api/rakuten/rankings/001006", nil).WithContext(ctx)
+	rr := httptest.NewRecorder()
+	router.ServeHTTP(rr, req)
+
+	if status := rr.Code; status != http.StatusOK {
+		t.Fatalf("handler returned wrong status code: got %v want %v", status, http.StatusOK)
+	}
+	var response RakutenBookRankingResponse
+	if err := json.Unmarshal(rr.Body.Bytes(), &response); err != nil {
+		t.Fatalf("handler returned invalid JSON: %v", err)
+	}
+	if !response.Stale || response.FetchedAt == nil || !response.FetchedAt.Equal(fetchedAt) {
+		t.Errorf("stale = %v, fetchedAt = %v, want true, %v", response.Stale, response.FetchedAt, fetchedAt)
+	}
+}
+
+func TestGetRakutenBookRankingHandlerAffiliateLinks(t *testing.T) {
+	handler := NewRakutenHandler()
+	handler.Links = affiliate.NewLinker(map[string]string{affiliate.SiteRakuten: "abc"})
+
+	router := mux.NewRouter()
+	router.HandleFunc("/api/rakuten/rankings/{categoryId}", handler.GetRakutenBookRankingHandler).Methods("GET")
+	rr := httptest.NewRecorder()
+	router.ServeHTTP(rr, httptest.NewRequest("GET", "/api/rakuten/rankings/001", nil))
+
+	var response RakutenBookRankingResponse
+	if err := json.Unmarshal(rr.Body.Bytes(), &response); err != nil {
+		t.Fatal(err)
+	}
+	want := "https://hb.afl.rakuten.co.jp/hgc/abc/?m=https%3A%2F%2Fbooks.rakuten.co.jp%2Fmock%2Fbook1&pc=https%3A%2F%2Fbooks.rakuten.co.jp%2Fmock%2Fbook1"
+	if got := response.Items[0].Item.ItemURL; got != want {
+		t.Errorf("itemUrl got %v want %v", got, want)
+	}
+}
+
+func TestGetRakutenBookRankingHandlerCSV(t *testing.T) {
+	handler := NewRakutenHandler()
+	router := mux.NewRouter()
+	router.HandleFunc("/api/rakuten/rankings/{categoryId}", handler.GetRakutenBookRankingHandler).Methods("GET")
+
+	rr := httptest.NewRecorder()
+	router.ServeHTTP(rr, httptest.NewRequest("GET", "/api/rakuten/rankings/001?format=csv&bom=true", nil))
+
+	if rr.Code != http.StatusOK {
+		t.Fatalf("status = %d, want 200", rr.Code)
+	}
+	if got := rr.Header().Get("Content-Type"); got != "text/csv; charset=utf-8" {
+		t.Errorf("Content-Type got %v want text/csv; charset=utf-8", got)
+	}
+	if got := rr.Header().Get("Content-Disposition"); got != `attachment; filename="rakuten-001-daily.csv"` {
+		t.Errorf("Content-Disposition got %v", got)
+	}
+
+	records, err := csv.NewReader(strings.NewReader(strings.TrimPrefix(rr.Body.String(), "\xef\xbb\xbf"))).ReadAll()
+	if err != nil {
+		t.Fatalf("invalid CSV: %v", err)
+	}
+	if !strings.HasPrefix(rr.Body.String(), "\xef\xbb\xbf") {
+		t.Error("bom=true did not write a byte order mark")
+	}
+	if len(records) < 2 || records[0][0] != "順位" {
+		t.Fatalf("unexpected records: %v", records)
+	}
+	if records[1][0] != "1" || records[1][1] == "" {
+		t.Errorf("unexpected first row: %v", records[1])
+	}
+}
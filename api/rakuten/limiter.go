@@ -0,0 +1,92 @@
+package rakuten
+
+import (
+	"context"
+	"sync"
+	"time"
+
+	"github.com/h-hiwatashi/super-business-book-ranking-backend/clock"
+)
+
+// TokenBucket は楽天APIへのリクエスト数を制限するトークンバケットである。
+// 楽天APIの制限はアプリIDごとに掛かるため、同じアプリIDを使う呼び出し元はすべて
+// 1つの TokenBucket を共有する。
+type TokenBucket struct {
+	mu    sync.Mutex
+	rate  float64 // 1秒あたりに補充するトークン数
+	burst float64
+	// tokens は負の値を取り、その場合は予約済みの待ち分を表す。
+	tokens float64
+	last   time.Time
+	clock  clock.Clock
+}
+
+// NewTokenBucket は1秒あたり rate 回、最大 burst 回まで連続してリクエストできる TokenBucket を返す。
+func NewTokenBucket(rate float64, burst int, clk clock.Clock) *TokenBucket {
+	if burst < 1 {
+		burst = 1
+	}
+	return &TokenBucket{
+		rate:   rate,
+		burst:  float64(burst),
+		tokens: float64(burst),
+		last:   clk.Now(),
+		clock:  clk,
+	}
+}
+
+// Wait はトークンを1つ消費できるまで待つ。
+// 待機中に ctx が終了した場合は予約したトークンを戻し、ctx のエラーを返す。
+func (b *TokenBucket) Wait(ctx context.Context) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+
+	b.mu.Lock()
+	delay := b.reserve(b.clock.Now())
+	b.mu.Unlock()
+	if delay <= 0 {
+		return nil
+	}
+
+	select {
+	case <-b.clock.After(delay):
+		return nil
+	case <-ctx.Done():
+		b.mu.Lock()
+		b.tokens = min(b.tokens+1, b.burst)
+		b.mu.Unlock()
+		return ctx.Err()
+	}
+}
+
+// Backoff は手元のトークンを捨て、d の間トークンの補充を止める。
+// 楽天APIから 429 が返ったときに、共有しているすべての呼び出し元の送信を止めるために使う。
+func (b *TokenBucket) Backoff(d time.Duration) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	b.refill(b.clock.Now())
+	if b.tokens > 0 {
+		b.tokens = 0
+	}
+	b.tokens -= d.Seconds() * b.rate
+}
+
+// reserve はトークンを1つ予約し、使えるようになるまでの待ち時間を返す。
+func (b *TokenBucket) reserve(now time.Time) time.Duration {
+	b.refill(now)
+	b.tokens--
+	if b.tokens >= 0 {
+		return 0
+	}
+	return time.Duration(-b.tokens / b.rate * float64(time.Second))
+}
+
+func (b *TokenBucket) refill(now time.Time) {
+	if !now.After(b.last) {
+		return
+	}
+	b.tokens = min(b.tokens+now.Sub(b.last).Seconds()*b.rate, b.burst)
+	b.last = now
+}
@@ -0,0 +1,121 @@
+package rakuten
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/h-hiwatashi/super-business-book-ranking-backend/clock"
+)
+
+var testStart = time.Date(2024, 3, 15, 0, 0, 0, 0, time.UTC)
+
+// waitAsync は Wait を別のゴルーチンで呼び出し、その結果を返すチャネルを返す。
+func waitAsync(ctx context.Context, b *TokenBucket) <-chan error {
+	done := make(chan error, 1)
+	go func() { done <- b.Wait(ctx) }()
+	return done
+}
+
+func assertPending(t *testing.T, done <-chan error) {
+	t.Helper()
+	select {
+	case err := <-done:
+		t.Fatalf("Wait() returned %v before a token was available", err)
+	default:
+	}
+}
+
+func assertDone(t *testing.T, done <-chan error) {
+	t.Helper()
+	select {
+	case err := <-done:
+		if err != nil {
+			t.Fatalf("Wait() error = %v", err)
+		}
+	case <-time.After(time.Second):
+		t.Fatal("Wait() did not return")
+	}
+}
+
+func TestTokenBucketBurst(t *testing.T) {
+	clk := clock.NewFake(testStart)
+	b := NewTokenBucket(1, 2, clk)
+	ctx := context.Background()
+
+	// バースト分はすぐに取得できる
+	for i := 0; i < 2; i++ {
+		if err := b.Wait(ctx); err != nil {
+			t.Fatalf("Wait() error = %v", err)
+		}
+	}
+
+	done := waitAsync(ctx, b)
+	clk.BlockUntil(1)
+	clk.Advance(999 * time.Millisecond)
+	assertPending(t, done)
+
+	clk.Advance(time.Millisecond)
+	assertDone(t, done)
+}
+
+func TestTokenBucketSharedAcrossWorkers(t *testing.T) {
+	clk := clock.NewFake(testStart)
+	b := NewTokenBucket(1, 1, clk)
+	ctx := context.Background()
+
+	if err := b.Wait(ctx); err != nil {
+		t.Fatalf("Wait() error = %v", err)
+	}
+
+	// 2つのワーカーが同時に待つと、1秒ごとに1つずつ解放される
+	first := waitAsync(ctx, b)
+	clk.BlockUntil(1)
+	second := waitAsync(ctx, b)
+	clk.BlockUntil(2)
+
+	clk.Advance(time.Second)
+	assertDone(t, first)
+	assertPending(t, second)
+
+	clk.Advance(time.Second)
+	assertDone(t, second)
+}
+
+func TestTokenBucketCancel(t *testing.T) {
+	clk := clock.NewFake(testStart)
+	b := NewTokenBucket(1, 1, clk)
+
+	if err := b.Wait(context.Background()); err != nil {
+		t.Fatalf("Wait() error = %v", err)
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	done := waitAsync(ctx, b)
+	clk.BlockUntil(1)
+	cancel()
+	if err := <-done; err != context.Canceled {
+		t.Fatalf("Wait() error = %v, want %v", err, context.Canceled)
+	}
+
+	// キャンセルした予約は戻されるため、次の呼び出しは1秒後に取得できる
+	done = waitAsync(context.Background(), b)
+	clk.BlockUntil(2)
+	clk.Advance(time.Second)
+	assertDone(t, done)
+}
+
+func TestTokenBucketBackoff(t *testing.T) {
+	clk := clock.NewFake(testStart)
+	b := NewTokenBucket(1, 1, clk)
+
+	b.Backoff(5 * time.Second)
+
+	done := waitAsync(context.Background(), b)
+	clk.BlockUntil(1)
+	clk.Advance(5 * time.Second)
+	assertPending(t, done)
+
+	clk.Advance(time.Second)
+	assertDone(t, done)
+}
@@ -0,0 +1,32 @@
+package rakuten
+
+import (
+	"testing"
+	"time"
+)
+
+func TestParseRetryAfter(t *testing.T) {
+	now := time.Date(2024, 3, 15, 0, 0, 0, 0, time.UTC)
+
+	testCases := []struct {
+		name   string
+		value  string
+		want   time.Duration
+		wantOK bool
+	}{
+		{name: "秒数", value: "3", want: 3 * time.Second, wantOK: true},
+		{name: "HTTP日付", value: "Fri, 15 Mar 2024 00:00:10 GMT", want: 10 * time.Second, wantOK: true},
+		{name: "過去の日付", value: "Thu, 14 Mar 2024 00:00:00 GMT", want: 0, wantOK: true},
+		{name: "空", value: "", wantOK: false},
+		{name: "不正な値", value: "soon", wantOK: false},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			got, ok := parseRetryAfter(tc.value, now)
+			if ok != tc.wantOK || got != tc.want {
+				t.Errorf("parseRetryAfter(%q) = %v, %v want %v, %v", tc.value, got, ok, tc.want, tc.wantOK)
+			}
+		})
+	}
+}
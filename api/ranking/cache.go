@@ -0,0 +1,99 @@
+package ranking
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/h-hiwatashi/super-business-book-ranking-backend/cache"
+	"github.com/h-hiwatashi/super-business-book-ranking-backend/logging"
+	"github.com/h-hiwatashi/super-business-book-ranking-backend/metrics"
+	"github.com/h-hiwatashi/super-business-book-ranking-backend/repository"
+)
+
+// メトリクスに使うキャッシュ名
+const rankingCacheName = "rankings"
+
+// cachedRanking はキャッシュに保存する形式である。
+// LastModified は JSON の応答には含めないため、ランキングとは別に保存する。
+type cachedRanking struct {
+	Ranking      *repository.Ranking `json:"ranking"`
+	LastModified time.Time           `json:"lastModified"`
+}
+
+// CachedRankingFinder はランキングの取得結果をキャッシュする RankingFinder である。
+// 取り込みジョブのリスナーとして登録すると、新しいスナップショットの保存時に
+// 該当するカテゴリ・期間のキャッシュを破棄する。
+// プロセス内キャッシュを複数のサーバーで使う場合、他のサーバーの取り込みでは破棄されないため、
+// 古いランキングを返す時間は TTL で制限する。
+type CachedRankingFinder struct {
+	Next  RankingFinder
+	Store cache.Store
+	TTL   time.Duration
+}
+
+func NewCachedRankingFinder(next RankingFinder, store cache.Store, ttl time.Duration) *CachedRankingFinder {
+	return &CachedRankingFinder{Next: next, Store: store, TTL: ttl}
+}
+
+// Find はキャッシュにあればそれを返し、なければ Next から取得してキャッシュする。
+// キャッシュの読み書きに失敗してもランキングの取得は継続する。
+func (c *CachedRankingFinder) Find(ctx context.Context, q repository.RankingQuery) (*repository.Ranking, error) {
+	logger := logging.FromContext(ctx)
+	key := rankingCacheKey(q)
+
+	if data, ok, err := c.Store.Get(ctx, key); err != nil {
+		logger.Warn("キャッシュ読み込みエラー", "key", key, "error", err)
+	} else if ok {
+		var cached cachedRanking
+		if err := json.Unmarshal(data, &cached); err == nil && cached.Ranking != nil {
+			metrics.CacheHits.WithLabelValues(rankingCacheName).Inc()
+			cached.Ranking.LastModified = cached.LastModified
+			return cached.Ranking, nil
+		}
+		logger.Warn("キャッシュのデコードエラー", "key", key, "error", err)
+	}
+	metrics.CacheMisses.WithLabelValues(rankingCacheName).Inc()
+
+	ranking, err := c.Next.Find(ctx, q)
+	if err != nil {
+		return nil, err
+	}
+
+	if data, err := json.Marshal(cachedRanking{Ranking: ranking, LastModified: ranking.LastModified}); err == nil {
+		if err := c.Store.Set(ctx, key, data, c.TTL); err != nil {
+			logger.Warn("キャッシュ書き込みエラー", "key", key, "error", err)
+		}
+	}
+	return ranking, nil
+}
+
+// SnapshotSaved は保存されたスナップショットのカテゴリ・期間のキャッシュを破棄する。
+func (c *CachedRankingFinder) SnapshotSaved(ctx context.Context, snapshot repository.Snapshot) {
+	prefix := rankingCachePrefix(snapshot.CategoryID, snapshot.PeriodType)
+	if err := c.Store.DeletePrefix(ctx, prefix); err != nil {
+		logging.FromContext(ctx).Error("キャッシュ無効化エラー", "prefix", prefix, "error", err)
+	}
+}
+
+func rankingCachePrefix(categoryID, periodType string) string {
+	return fmt.Sprintf("rankings:%s:%s:", categoryID, periodType)
+}
+
+func rankingCacheKey(q repository.RankingQuery) string {
+	date := q.Date
+	if date == "" {
+		date = "latest"
+	}
+	key := fmt.Sprintf("%s%s:%d:%d", rankingCachePrefix(q.CategoryID, q.PeriodType), date, q.Page, q.Limit)
+	if q.Columns != nil {
+		// 項目を絞り込んだ取得結果は、すべての項目の取得結果とは別に保存する
+		fields := slices.Clone(q.Columns.BookFields)
+		slices.Sort(fields)
+		key += fmt.Sprintf(":%s:%t", strings.Join(fields, ","), q.Columns.Offers)
+	}
+	return key
+}
@@ -0,0 +1,96 @@
+package ranking
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/h-hiwatashi/super-business-book-ranking-backend/cache"
+	"github.com/h-hiwatashi/super-business-book-ranking-backend/repository"
+)
+
+func TestCachedRankingFinder(t *testing.T) {
+	ctx := context.Background()
+	lastModified := time.Date(2024, 3, 15, 1, 0, 0, 0, time.UTC)
+	next := &fakeRankings{ranking: &repository.Ranking{
+		CategoryID:   "001",
+		PeriodType:   "daily",
+		Books:        []repository.RankedBook{{ID: "book-1", Rank: 1}},
+		LastModified: lastModified,
+	}}
+	finder := NewCachedRankingFinder(next, cache.NewMemory(10), time.Minute)
+
+	daily := repository.RankingQuery{CategoryID: "001", PeriodType: "daily", Page: 1, Limit: defaultLimit}
+	weekly := repository.RankingQuery{CategoryID: "001", PeriodType: "weekly", Page: 1, Limit: defaultLimit}
+
+	for i := 0; i < 3; i++ {
+		got, err := finder.Find(ctx, daily)
+		if err != nil {
+			t.Fatalf("Find() error = %v", err)
+		}
+		if len(got.Books) != 1 || got.Books[0].ID != "book-1" {
+			t.Errorf("Find() returned unexpected books: %+v", got.Books)
+		}
+		// 応答に含めない更新時刻もキャッシュから復元される
+		if !got.LastModified.Equal(lastModified) {
+			t.Errorf("LastModified = %v, want %v", got.LastModified, lastModified)
+		}
+	}
+	if next.calls != 1 {
+		t.Errorf("repository called %d times, want 1", next.calls)
+	}
+
+	finder.Find(ctx, weekly)
+	if next.calls != 2 {
+		t.Errorf("repository called %d times, want 2 after a different query", next.calls)
+	}
+
+	// 日次ランキングの保存で日次のキャッシュだけが破棄される
+	finder.SnapshotSaved(ctx, repository.Snapshot{CategoryID: "001", PeriodType: "daily"})
+	finder.Find(ctx, daily)
+	finder.Find(ctx, weekly)
+	if next.calls != 3 {
+		t.Errorf("repository called %d times, want 3 after invalidation", next.calls)
+	}
+}
+
+func TestCachedRankingFinderColumns(t *testing.T) {
+	ctx := context.Background()
+	next := &fakeRankings{ranking: &repository.Ranking{CategoryID: "001", PeriodType: "daily"}}
+	finder := NewCachedRankingFinder(next, cache.NewMemory(10), time.Minute)
+
+	all := repository.RankingQuery{CategoryID: "001", PeriodType: "daily", Page: 1, Limit: defaultLimit}
+	titles := all
+	titles.Columns = &repository.RankingColumns{BookFields: []string{"title", "imageUrl"}}
+	reordered := all
+	reordered.Columns = &repository.RankingColumns{BookFields: []string{"imageUrl", "title"}}
+	withOffers := all
+	withOffers.Columns = &repository.RankingColumns{BookFields: []string{"title", "imageUrl"}, Offers: true}
+
+	for _, q := range []repository.RankingQuery{all, titles, reordered, withOffers} {
+		if _, err := finder.Find(ctx, q); err != nil {
+			t.Fatalf("Find() error = %v", err)
+		}
+	}
+	// 項目の順序だけが違う取得は同じキャッシュを使う
+	if next.calls != 3 {
+		t.Errorf("repository called %d times, want 3", next.calls)
+	}
+}
+
+func TestCachedRankingFinderDoesNotCacheErrors(t *testing.T) {
+	ctx := context.Background()
+	next := &fakeRankings{err: errors.New("connection refused")}
+	finder := NewCachedRankingFinder(next, cache.NewMemory(10), time.Minute)
+
+	q := repository.RankingQuery{CategoryID: "001", PeriodType: "daily", Page: 1, Limit: defaultLimit}
+	for i := 0; i < 2; i++ {
+		if _, err := finder.Find(ctx, q); err == nil {
+			t.Error("Find() error = nil, want error")
+		}
+	}
+	if next.calls != 2 {
+		t.Errorf("repository called %d times, want 2", next.calls)
+	}
+}
@@ -0,0 +1,97 @@
+package ranking
+
+import (
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/h-hiwatashi/super-business-book-ranking-backend/affiliate"
+	"github.com/h-hiwatashi/super-business-book-ranking-backend/api/export"
+	"github.com/h-hiwatashi/super-business-book-ranking-backend/api/httperror"
+	"github.com/h-hiwatashi/super-business-book-ranking-backend/logging"
+	"github.com/h-hiwatashi/super-business-book-ranking-backend/repository"
+)
+
+// CSV・TSV で1カテゴリから書き出す書籍数の上限
+const exportLimit = 1000
+
+// CSV・TSV の見出し行
+var rankingHeader = []string{
+	"カテゴリID", "カテゴリ名", "期間", "開始日", "終了日",
+	"順位", "書籍ID", "タイトル", "著者", "出版社", "ISBN", "発売日", "価格", "サイト", "URL",
+}
+
+// writeRankingRows はランキングの書籍を1冊1行で書き出す。
+// 書き込みのエラーは Flush で返る。
+func writeRankingRows(out *export.Writer, ranking *repository.Ranking) {
+	for _, book := range ranking.Books {
+		out.Write([]string{
+			ranking.CategoryID, ranking.CategoryName, ranking.PeriodType, ranking.DateFrom, ranking.DateTo,
+			strconv.Itoa(book.Rank), book.ID, book.Title, book.Author, book.Publisher, book.ISBN,
+			book.PublicationDate, strconv.FormatFloat(book.Price, 'f', -1, 64), book.Site, book.URL,
+		})
+	}
+}
+
+// ランキング一括書き出しハンドラー
+// 指定期間のすべてのカテゴリのランキングを CSV（format=tsv または Accept で TSV）で返す。
+// 書き出す前にすべてのランキングを取得し、取得に失敗した場合はエラーのステータスを返す。
+// 書き出しの途中で失敗した場合は、途中までの CSV を完全なものと誤解させないよう接続を切る。
+func (h *RankingHandler) ExportRankingsHandler(w http.ResponseWriter, r *http.Request) {
+	params := r.URL.Query()
+	periodType := params.Get("period")
+	if periodType == "" {
+		periodType = "daily" // デフォルト値
+	}
+	date := params.Get("date")
+	if date != "" {
+		if _, err := time.Parse("2006-01-02", date); err != nil {
+			http.Error(w, "date は YYYY-MM-DD 形式で指定してください", http.StatusBadRequest)
+			return
+		}
+	}
+	format, err := export.Negotiate(r)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	if format == export.FormatJSON {
+		if params.Get("format") != "" {
+			http.Error(w, "format は csv または tsv で指定してください", http.StatusBadRequest)
+			return
+		}
+		format = export.FormatCSV
+	}
+
+	categories, err := h.Categories.List(r.Context())
+	if err != nil {
+		httperror.Write(w, r, err, "データベースクエリエラー")
+		return
+	}
+	rankings, err := h.Exports.FindAll(r.Context(), periodType, date, exportLimit)
+	if err != nil {
+		httperror.Write(w, r, err, "データベースクエリエラー", "period", periodType, "date", date)
+		return
+	}
+
+	if date == "" {
+		date = "latest"
+	}
+	w.Header().Add("Vary", "Accept")
+	w.Header().Set("Cache-Control", rankingCacheControl(periodType))
+	out := export.NewWriter(w, r, format, export.Filename("rankings", periodType, date))
+	out.Write(rankingHeader)
+
+	// カテゴリ一覧の順に書き出す
+	for _, category := range categories {
+		ranking, ok := rankings[category.ID]
+		if !ok {
+			continue
+		}
+		writeRankingRows(out, affiliate.LinkRanking(h.Links, ranking))
+		if err := out.Flush(); err != nil {
+			logging.FromContext(r.Context()).Error("ランキングの書き出しエラー", "categoryId", category.ID, "error", err)
+			panic(http.ErrAbortHandler)
+		}
+	}
+}
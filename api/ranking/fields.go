@@ -0,0 +1,110 @@
+package ranking
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"slices"
+	"strings"
+
+	"github.com/h-hiwatashi/super-business-book-ranking-backend/repository"
+)
+
+// fields= で選べる書籍の項目。書籍IDは常に返す。
+var bookFieldNames = []string{"title", "author", "publisher", "isbn", "publicationDate", "imageUrl"}
+
+// include= で含められる関連情報
+const (
+	IncludeOffers   = "offers"
+	IncludeCategory = "category"
+	IncludeHistory  = "history"
+)
+
+var includeNames = []string{IncludeOffers, IncludeCategory, IncludeHistory}
+
+// SparseBook は fields= で選んだ項目だけを JSON に書き出す書籍である。
+type SparseBook struct {
+	repository.Book
+	// Fields は書き出す項目（JSON の名前）である。nil の場合はすべての項目を書き出す。
+	Fields []string `json:"-"`
+}
+
+// MarshalJSON は書籍IDと Fields の項目だけを書き出す。
+func (b SparseBook) MarshalJSON() ([]byte, error) {
+	data, err := json.Marshal(b.Book)
+	if err != nil || b.Fields == nil {
+		return data, err
+	}
+	var all map[string]json.RawMessage
+	if err := json.Unmarshal(data, &all); err != nil {
+		return nil, err
+	}
+	selected := map[string]json.RawMessage{"id": all["id"]}
+	for _, field := range b.Fields {
+		selected[field] = all[field]
+	}
+	return json.Marshal(selected)
+}
+
+// parseFields は fields= で指定された書籍の項目を返す。指定がない場合は nil を返す。
+func parseFields(r *http.Request) ([]string, error) {
+	if !r.URL.Query().Has("fields") {
+		return nil, nil
+	}
+	fields := []string{}
+	for _, field := range splitList(r.URL.Query().Get("fields")) {
+		if field == "id" {
+			continue
+		}
+		if !slices.Contains(bookFieldNames, field) {
+			return nil, errors.New("fields は " + strings.Join(bookFieldNames, ", ") + " から選んでカンマ区切りで指定してください")
+		}
+		if !slices.Contains(fields, field) {
+			fields = append(fields, field)
+		}
+	}
+	return fields, nil
+}
+
+// parseInclude は include= で指定された関連情報を返す。指定がない場合は defaults を返す。
+func parseInclude(r *http.Request, defaults ...string) (map[string]bool, error) {
+	names := defaults
+	if r.URL.Query().Has("include") {
+		names = splitList(r.URL.Query().Get("include"))
+	}
+	include := make(map[string]bool)
+	for _, name := range names {
+		if !slices.Contains(includeNames, name) {
+			return nil, errors.New("include は " + strings.Join(includeNames, ", ") + " から選んでカンマ区切りで指定してください")
+		}
+		include[name] = true
+	}
+	return include, nil
+}
+
+// splitList はカンマ区切りの値を空白を除いて分割する。空の要素は無視する。
+func splitList(value string) []string {
+	var items []string
+	for _, item := range strings.Split(value, ",") {
+		if item = strings.TrimSpace(item); item != "" {
+			items = append(items, item)
+		}
+	}
+	return items
+}
+
+// selectionKey は fields= と include= の組を ETag に使う文字列にする。指定の順序には依存しない。
+func selectionKey(fields []string, include map[string]bool) string {
+	key := "all"
+	if fields != nil {
+		sorted := slices.Clone(fields)
+		slices.Sort(sorted)
+		key = strings.Join(sorted, ",")
+	}
+	for _, name := range includeNames {
+		if include[name] {
+			key += "+" + name
+		}
+	}
+	return key
+}
@@ -0,0 +1,202 @@
+package ranking
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/gorilla/mux"
+
+	"github.com/h-hiwatashi/super-business-book-ranking-backend/affiliate"
+	"github.com/h-hiwatashi/super-business-book-ranking-backend/api/export"
+	"github.com/h-hiwatashi/super-business-book-ranking-backend/api/httpcache"
+	"github.com/h-hiwatashi/super-business-book-ranking-backend/api/httperror"
+	"github.com/h-hiwatashi/super-business-book-ranking-backend/logging"
+	"github.com/h-hiwatashi/super-business-book-ranking-backend/repository"
+)
+
+// デフォルト取得数
+const defaultLimit = 10
+
+// 書籍とカテゴリのキャッシュ期間
+const catalogMaxAge = time.Hour
+
+// RankingFinder はランキングを取得するリポジトリである。
+type RankingFinder interface {
+	Find(ctx context.Context, q repository.RankingQuery) (*repository.Ranking, error)
+}
+
+// BookFinder は書籍を取得するリポジトリである。
+type BookFinder interface {
+	FindByID(ctx context.Context, bookID string) (*repository.Book, error)
+	// FindFields は fields の項目だけを読み込んだ書籍を返す。fields が nil の場合はすべての項目を読み込む。
+	FindFields(ctx context.Context, bookID string, fields []string) (*repository.Book, error)
+	FindOffers(ctx context.Context, bookIDs []string) (map[string][]repository.Offer, error)
+	FindCategories(ctx context.Context, bookID string) ([]repository.Category, error)
+}
+
+// RankingExporter はすべてのカテゴリのランキングをまとめて取得するリポジトリである。
+type RankingExporter interface {
+	FindAll(ctx context.Context, periodType, date string, limit int) (map[string]*repository.Ranking, error)
+}
+
+// CategoryLister はカテゴリ一覧を取得するリポジトリである。
+type CategoryLister interface {
+	List(ctx context.Context) ([]repository.Category, error)
+}
+
+type RankingHandler struct {
+	Rankings   RankingFinder
+	Books      BookFinder
+	Categories CategoryLister
+	// Links が nil の場合は商品URLをそのまま返す。
+	Links affiliate.URLLinker
+	// Snapshots が nil の場合、v2 のランキングは前回の順位と比べず、順位の履歴も返さない。
+	Snapshots SnapshotHistory
+	// MoverThreshold は v2 のランキングで急上昇とする順位の上昇幅の下限である。
+	MoverThreshold int
+	// Exports はランキングの一括書き出しで使う。
+	Exports RankingExporter
+}
+
+func NewRankingHandler(rankings RankingFinder, books BookFinder, categories CategoryLister) *RankingHandler {
+	return &RankingHandler{
+		Rankings:       rankings,
+		Books:          books,
+		Categories:     categories,
+		MoverThreshold: defaultMoverThreshold,
+	}
+}
+
+// ランキング取得ハンドラー
+// format=csv・tsv または Accept で CSV・TSV を要求された場合は、ページに分けずスナップショット全体を返す。
+func (h *RankingHandler) GetRankingsHandler(w http.ResponseWriter, r *http.Request) {
+	query, err := parseRankingQuery(r)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	format, err := export.Negotiate(r)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	if format != export.FormatJSON {
+		query.Page = 1
+		query.Limit = exportLimit
+	}
+
+	response, err := h.Rankings.Find(r.Context(), query)
+	if err != nil {
+		httperror.Write(w, r, err, "データベースクエリエラー", "categoryId", query.CategoryID)
+		return
+	}
+	response = affiliate.LinkRanking(h.Links, response)
+
+	// スナップショットが同じなら同じ内容のため、その識別子から ETag を作る
+	validators := httpcache.Validators{
+		ETag: httpcache.ETag("ranking", query.CategoryID, query.PeriodType, response.DateTo,
+			response.LastModified.UTC().Format(time.RFC3339Nano),
+			strconv.Itoa(query.Page), strconv.Itoa(query.Limit), string(format), r.URL.Query().Get("bom")),
+		LastModified: response.LastModified,
+	}
+	// 形式を Accept で選ぶため、共有キャッシュに Accept ごとに保存させる
+	w.Header().Add("Vary", "Accept")
+	if format == export.FormatJSON {
+		httpcache.WriteJSON(w, r, response, validators, rankingCacheControl(query.PeriodType))
+		return
+	}
+
+	w.Header().Set("Cache-Control", rankingCacheControl(query.PeriodType))
+	if httpcache.CheckNotModified(w, r, validators) {
+		return
+	}
+	out := export.NewWriter(w, r, format, export.Filename("ranking", query.CategoryID, query.PeriodType, response.DateTo))
+	out.Write(rankingHeader)
+	writeRankingRows(out, response)
+	if err := out.Flush(); err != nil {
+		logging.FromContext(r.Context()).Error("ランキングの書き出しエラー", "categoryId", query.CategoryID, "error", err)
+	}
+}
+
+// 書籍詳細取得ハンドラー
+func (h *RankingHandler) GetBookDetailsHandler(w http.ResponseWriter, r *http.Request) {
+	vars := mux.Vars(r)
+	bookID := vars["bookId"]
+
+	book, err := h.Books.FindByID(r.Context(), bookID)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			http.Error(w, "書籍が見つかりません", http.StatusNotFound)
+		} else {
+			httperror.Write(w, r, err, "データベースクエリエラー", "bookId", bookID)
+		}
+		return
+	}
+
+	validators := httpcache.Validators{
+		ETag:         httpcache.ETag("book", book.ID, book.UpdatedAt.UTC().Format(time.RFC3339Nano)),
+		LastModified: book.UpdatedAt,
+	}
+	httpcache.WriteJSON(w, r, book, validators, httpcache.MaxAge(catalogMaxAge))
+}
+
+// カテゴリ一覧取得ハンドラー
+func (h *RankingHandler) GetCategoriesHandler(w http.ResponseWriter, r *http.Request) {
+	categories, err := h.Categories.List(r.Context())
+	if err != nil {
+		httperror.Write(w, r, err, "データベースクエリエラー")
+		return
+	}
+
+	httpcache.WriteJSON(w, r, categories, httpcache.Validators{}, httpcache.MaxAge(catalogMaxAge))
+}
+
+// rankingCacheControl は期間の種類に応じた Cache-Control を返す。
+// 取り込みは定期的に最新のスナップショットを置き換えるため、
+// 更新頻度の高い日次ほど短い期間にする。
+func rankingCacheControl(periodType string) string {
+	switch periodType {
+	case "weekly":
+		return httpcache.MaxAge(time.Hour)
+	case "monthly", "yearly":
+		return httpcache.MaxAge(6 * time.Hour)
+	default:
+		return httpcache.MaxAge(10 * time.Minute)
+	}
+}
+
+// parseRankingQuery はパスとクエリパラメータからランキングの取得条件を組み立てる。
+func parseRankingQuery(r *http.Request) (repository.RankingQuery, error) {
+	params := r.URL.Query()
+
+	query := repository.RankingQuery{
+		CategoryID: mux.Vars(r)["categoryId"],
+		PeriodType: params.Get("period"),
+		Date:       params.Get("date"),
+		Page:       1,
+		Limit:      defaultLimit,
+	}
+	if query.PeriodType == "" {
+		query.PeriodType = "daily" // デフォルト値
+	}
+
+	if query.Date != "" {
+		if _, err := time.Parse("2006-01-02", query.Date); err != nil {
+			return query, errors.New("date は YYYY-MM-DD 形式で指定してください")
+		}
+	}
+
+	if page := params.Get("page"); page != "" {
+		n, err := strconv.Atoi(page)
+		if err != nil || n < 1 {
+			return query, errors.New("page は1以上の整数で指定してください")
+		}
+		query.Page = n
+	}
+
+	return query, nil
+}
@@ -0,0 +1,542 @@
+package ranking
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/gorilla/mux"
+
+	"github.com/h-hiwatashi/super-business-book-ranking-backend/affiliate"
+	"github.com/h-hiwatashi/super-business-book-ranking-backend/repository"
+)
+
+type fakeRankings struct {
+	ranking *repository.Ranking
+	err     error
+	calls   int
+	got     repository.RankingQuery
+}
+
+func (f *fakeRankings) Find(ctx context.Context, q repository.RankingQuery) (*repository.Ranking, error) {
+	f.calls++
+	f.got = q
+	return f.ranking, f.err
+}
+
+type fakeBooks struct {
+	books      map[string]*repository.Book
+	offers     map[string][]repository.Offer
+	categories []repository.Category
+	err        error
+	// fields は FindFields に渡された項目である。
+	fields []string
+}
+
+func (f *fakeBooks) FindByID(ctx context.Context, bookID string) (*repository.Book, error) {
+	if book, ok := f.books[bookID]; ok {
+		return book, nil
+	}
+	return nil, sql.ErrNoRows
+}
+
+func (f *fakeBooks) FindFields(ctx context.Context, bookID string, fields []string) (*repository.Book, error) {
+	f.fields = fields
+	return f.FindByID(ctx, bookID)
+}
+
+func (f *fakeBooks) FindOffers(ctx context.Context, bookIDs []string) (map[string][]repository.Offer, error) {
+	return f.offers, f.err
+}
+
+func (f *fakeBooks) FindCategories(ctx context.Context, bookID string) ([]repository.Category, error) {
+	return f.categories, f.err
+}
+
+type fakeCategories struct {
+	categories []repository.Category
+}
+
+func (f *fakeCategories) List(ctx context.Context) ([]repository.Category, error) {
+	return f.categories, nil
+}
+
+func newTestRouter(h *RankingHandler) *mux.Router {
+	router := mux.NewRouter()
+	router.HandleFunc("/api/rankings/{categoryId}", h.GetRankingsHandler).Methods("GET")
+	router.HandleFunc("/api/v2/rankings/{categoryId}", h.GetRankingsV2Handler).Methods("GET")
+	router.HandleFunc("/api/books/{bookId}", h.GetBookDetailsHandler).Methods("GET")
+	router.HandleFunc("/api/v2/books/{bookId}", h.GetBookV2Handler).Methods("GET")
+	router.HandleFunc("/api/categories", h.GetCategoriesHandler).Methods("GET")
+	return router
+}
+
+func TestGetRankingsHandler(t *testing.T) {
+	testCases := []struct {
+		name           string
+		query          string
+		err            error
+		wantPeriod     string
+		wantDate       string
+		wantPage       int
+		wantStatusCode int
+	}{
+		{
+			name:           "正常系：期間パラメータなし（デフォルト値が使用される）",
+			query:          "",
+			wantPeriod:     "daily",
+			wantPage:       1,
+			wantStatusCode: http.StatusOK,
+		},
+		{
+			name:           "正常系：週間ランキング",
+			query:          "?period=weekly",
+			wantPeriod:     "weekly",
+			wantPage:       1,
+			wantStatusCode: http.StatusOK,
+		},
+		{
+			name:           "正常系：日付とページを指定",
+			query:          "?date=2024-03-15&page=2",
+			wantPeriod:     "daily",
+			wantDate:       "2024-03-15",
+			wantPage:       2,
+			wantStatusCode: http.StatusOK,
+		},
+		{
+			name:           "異常系：不正な日付",
+			query:          "?date=20240315",
+			wantStatusCode: http.StatusBadRequest,
+		},
+		{
+			name:           "異常系：不正なページ",
+			query:          "?page=0",
+			wantStatusCode: http.StatusBadRequest,
+		},
+		{
+			name:           "異常系：タイムアウト",
+			query:          "",
+			err:            context.DeadlineExceeded,
+			wantPeriod:     "daily",
+			wantPage:       1,
+			wantStatusCode: http.StatusGatewayTimeout,
+		},
+		{
+			name:           "異常系：データベースエラー",
+			query:          "",
+			err:            errors.New("connection refused"),
+			wantPeriod:     "daily",
+			wantPage:       1,
+			wantStatusCode: http.StatusInternalServerError,
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			rankings := &fakeRankings{
+				ranking: &repository.Ranking{
+					CategoryID: "001",
+					Books:      []repository.RankedBook{{ID: "book-1", Rank: 1, Title: "成功する習慣"}},
+				},
+				err: tc.err,
+			}
+			router := newTestRouter(NewRankingHandler(rankings, &fakeBooks{}, &fakeCategories{}))
+
+			req := httptest.NewRequest("GET", "/api/rankings/001"+tc.query, nil)
+			rr := httptest.NewRecorder()
+			router.ServeHTTP(rr, req)
+
+			if rr.Code != tc.wantStatusCode {
+				t.Errorf("handler returned wrong status code: got %v want %v", rr.Code, tc.wantStatusCode)
+			}
+			if tc.wantStatusCode == http.StatusBadRequest {
+				if rankings.calls != 0 {
+					t.Error("repository was called for an invalid request")
+				}
+				return
+			}
+			want := repository.RankingQuery{
+				CategoryID: "001",
+				PeriodType: tc.wantPeriod,
+				Date:       tc.wantDate,
+				Page:       tc.wantPage,
+				Limit:      defaultLimit,
+			}
+			if rankings.got != want {
+				t.Errorf("query = %+v, want %+v", rankings.got, want)
+			}
+			if tc.wantStatusCode != http.StatusOK {
+				return
+			}
+
+			var response repository.Ranking
+			if err := json.Unmarshal(rr.Body.Bytes(), &response); err != nil {
+				t.Fatalf("handler returned invalid JSON: %v", err)
+			}
+			if len(response.Books) != 1 || response.Books[0].ID != "book-1" {
+				t.Errorf("unexpected books: %+v", response.Books)
+			}
+		})
+	}
+}
+
+func TestGetBookDetailsHandler(t *testing.T) {
+	books := &fakeBooks{books: map[string]*repository.Book{
+		"book-1": {ID: "book-1", Title: "成功する習慣"},
+	}}
+	router := newTestRouter(NewRankingHandler(&fakeRankings{}, books, &fakeCategories{}))
+
+	testCases := []struct {
+		bookID         string
+		wantStatusCode int
+	}{
+		{bookID: "book-1", wantStatusCode: http.StatusOK},
+		{bookID: "unknown", wantStatusCode: http.StatusNotFound},
+	}
+
+	for _, tc := range testCases {
+		req := httptest.NewRequest("GET", "/api/books/"+tc.bookID, nil)
+		rr := httptest.NewRecorder()
+		router.ServeHTTP(rr, req)
+
+		if rr.Code != tc.wantStatusCode {
+			t.Errorf("GET /api/books/%s status = %v, want %v", tc.bookID, rr.Code, tc.wantStatusCode)
+		}
+	}
+}
+
+func TestGetCategoriesHandler(t *testing.T) {
+	categories := &fakeCategories{categories: []repository.Category{
+		{ID: "001", Name: "ビジネス書"},
+		{ID: "002", Name: "自己啓発"},
+	}}
+	router := newTestRouter(NewRankingHandler(&fakeRankings{}, &fakeBooks{}, categories))
+
+	req := httptest.NewRequest("GET", "/api/categories", nil)
+	rr := httptest.NewRecorder()
+	router.ServeHTTP(rr, req)
+
+	if rr.Code != http.StatusOK {
+		t.Fatalf("handler returned wrong status code: got %v want %v", rr.Code, http.StatusOK)
+	}
+
+	var response []repository.Category
+	if err := json.Unmarshal(rr.Body.Bytes(), &response); err != nil {
+		t.Fatalf("handler returned invalid JSON: %v", err)
+	}
+	if len(response) != 2 {
+		t.Errorf("returned %d categories, want 2", len(response))
+	}
+}
+
+func TestGetRankingsHandlerConditional(t *testing.T) {
+	rankings := &fakeRankings{ranking: &repository.Ranking{
+		CategoryID:   "001",
+		PeriodType:   "weekly",
+		DateTo:       "2024-03-15",
+		Books:        []repository.RankedBook{{ID: "book-1", Rank: 1}},
+		LastModified: time.Date(2024, 3, 15, 1, 0, 0, 0, time.UTC),
+	}}
+	router := newTestRouter(NewRankingHandler(rankings, &fakeBooks{}, &fakeCategories{}))
+
+	rr := httptest.NewRecorder()
+	router.ServeHTTP(rr, httptest.NewRequest("GET", "/api/rankings/001?period=weekly", nil))
+
+	etag := rr.Header().Get("ETag")
+	if etag == "" {
+		t.Fatal("response has no ETag")
+	}
+	if got := rr.Header().Get("Last-Modified"); got != "Fri, 15 Mar 2024 01:00:00 GMT" {
+		t.Errorf("Last-Modified = %q", got)
+	}
+	if got := rr.Header().Get("Cache-Control"); got != rankingCacheControl("weekly") {
+		t.Errorf("Cache-Control = %q, want %q", got, rankingCacheControl("weekly"))
+	}
+
+	// 別ページは別の ETag になる
+	rr = httptest.NewRecorder()
+	router.ServeHTTP(rr, httptest.NewRequest("GET", "/api/rankings/001?period=weekly&page=2", nil))
+	if rr.Header().Get("ETag") == etag {
+		t.Error("different pages share the same ETag")
+	}
+
+	req := httptest.NewRequest("GET", "/api/rankings/001?period=weekly", nil)
+	req.Header.Set("If-None-Match", etag)
+	rr = httptest.NewRecorder()
+	router.ServeHTTP(rr, req)
+	if rr.Code != http.StatusNotModified {
+		t.Errorf("status = %d, want 304", rr.Code)
+	}
+
+	req = httptest.NewRequest("GET", "/api/rankings/001?period=weekly", nil)
+	req.Header.Set("If-Modified-Since", "Fri, 15 Mar 2024 01:00:00 GMT")
+	rr = httptest.NewRecorder()
+	router.ServeHTTP(rr, req)
+	if rr.Code != http.StatusNotModified {
+		t.Errorf("status = %d, want 304 for If-Modified-Since", rr.Code)
+	}
+}
+
+func TestGetRankingsHandlerAffiliateLinks(t *testing.T) {
+	ranking := &repository.Ranking{
+		CategoryID: "cat-1",
+		PeriodType: "daily",
+		Books: []repository.RankedBook{
+			{Rank: 1, URL: "https://www.amazon.co.jp/dp/4123456789", Site: affiliate.SiteAmazon},
+			{Rank: 2, URL: "https://books.rakuten.co.jp/rb/12345678/", Site: affiliate.SiteRakuten},
+		},
+	}
+	handler := NewRankingHandler(&fakeRankings{ranking: ranking}, &fakeBooks{}, &fakeCategories{})
+	handler.Links = affiliate.NewLinker(map[string]string{affiliate.SiteAmazon: "bookranking-22"})
+
+	rr := httptest.NewRecorder()
+	newTestRouter(handler).ServeHTTP(rr, httptest.NewRequest("GET", "/api/rankings/cat-1", nil))
+
+	var got repository.Ranking
+	if err := json.NewDecoder(rr.Body).Decode(&got); err != nil {
+		t.Fatal(err)
+	}
+	wantURLs := []string{
+		"https://www.amazon.co.jp/dp/4123456789?tag=bookranking-22",
+		// アフィリエイトIDのないサイトはそのまま返す
+		"https://books.rakuten.co.jp/rb/12345678/",
+	}
+	for i, want := range wantURLs {
+		if got.Books[i].URL != want {
+			t.Errorf("books[%d].url got %v want %v", i, got.Books[i].URL, want)
+		}
+	}
+	// キャッシュされたランキングは書き換えない
+	if ranking.Books[0].URL != "https://www.amazon.co.jp/dp/4123456789" {
+		t.Errorf("cached ranking was modified: %v", ranking.Books[0].URL)
+	}
+}
+
+func TestGetRankingsHandlerCSV(t *testing.T) {
+	rankings := &fakeRankings{ranking: &repository.Ranking{
+		CategoryID:   "cat-1",
+		CategoryName: "ビジネス",
+		PeriodType:   "daily",
+		DateFrom:     "2024-03-15",
+		DateTo:       "2024-03-15",
+		Books: []repository.RankedBook{
+			{Rank: 1, ID: "book-1", Title: "夢をかなえるゾウ, 新版", Author: `水野 "敬也"`, Price: 1760, Site: "rakuten", URL: "https://books.rakuten.co.jp/rb/1/"},
+		},
+	}}
+	router := newTestRouter(NewRankingHandler(rankings, &fakeBooks{}, &fakeCategories{}))
+
+	testCases := []struct {
+		name            string
+		query           string
+		accept          string
+		wantStatusCode  int
+		wantContentType string
+		wantBody        string
+	}{
+		{
+			name:            "正常系：format=csv",
+			query:           "?format=csv&page=3",
+			wantStatusCode:  http.StatusOK,
+			wantContentType: "text/csv; charset=utf-8",
+			wantBody: "カテゴリID,カテゴリ名,期間,開始日,終了日,順位,書籍ID,タイトル,著者,出版社,ISBN,発売日,価格,サイト,URL\n" +
+				"cat-1,ビジネス,daily,2024-03-15,2024-03-15,1,book-1,\"夢をかなえるゾウ, 新版\",\"水野 \"\"敬也\"\"\",,,,1760,rakuten,https://books.rakuten.co.jp/rb/1/\n",
+		},
+		{
+			name:            "正常系：Accept: text/tab-separated-values",
+			accept:          "text/tab-separated-values",
+			wantStatusCode:  http.StatusOK,
+			wantContentType: "text/tab-separated-values; charset=utf-8",
+			wantBody: "カテゴリID\tカテゴリ名\t期間\t開始日\t終了日\t順位\t書籍ID\tタイトル\t著者\t出版社\tISBN\t発売日\t価格\tサイト\tURL\n" +
+				"cat-1\tビジネス\tdaily\t2024-03-15\t2024-03-15\t1\tbook-1\t夢をかなえるゾウ, 新版\t\"水野 \"\"敬也\"\"\"\t\t\t\t1760\trakuten\thttps://books.rakuten.co.jp/rb/1/\n",
+		},
+		{
+			name:           "異常系：未対応の形式",
+			query:          "?format=xml",
+			wantStatusCode: http.StatusBadRequest,
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			req := httptest.NewRequest("GET", "/api/rankings/cat-1"+tc.query, nil)
+			if tc.accept != "" {
+				req.Header.Set("Accept", tc.accept)
+			}
+			rr := httptest.NewRecorder()
+			router.ServeHTTP(rr, req)
+
+			if rr.Code != tc.wantStatusCode {
+				t.Fatalf("status = %d, want %d", rr.Code, tc.wantStatusCode)
+			}
+			if tc.wantStatusCode != http.StatusOK {
+				return
+			}
+			if got := rr.Header().Get("Content-Type"); got != tc.wantContentType {
+				t.Errorf("Content-Type got %v want %v", got, tc.wantContentType)
+			}
+			if got := rr.Body.String(); got != tc.wantBody {
+				t.Errorf("body got %q want %q", got, tc.wantBody)
+			}
+			// 書き出しはページに分けない
+			if rankings.got.Page != 1 || rankings.got.Limit != exportLimit {
+				t.Errorf("query got page=%d limit=%d", rankings.got.Page, rankings.got.Limit)
+			}
+		})
+	}
+}
+
+func TestGetRankingsHandlerCSVConditional(t *testing.T) {
+	rankings := &fakeRankings{ranking: &repository.Ranking{
+		CategoryID:   "cat-1",
+		DateTo:       "2024-03-15",
+		LastModified: time.Date(2024, 3, 15, 1, 0, 0, 0, time.UTC),
+	}}
+	router := newTestRouter(NewRankingHandler(rankings, &fakeBooks{}, &fakeCategories{}))
+
+	rr := httptest.NewRecorder()
+	router.ServeHTTP(rr, httptest.NewRequest("GET", "/api/rankings/cat-1", nil))
+	jsonETag := rr.Header().Get("ETag")
+
+	rr = httptest.NewRecorder()
+	router.ServeHTTP(rr, httptest.NewRequest("GET", "/api/rankings/cat-1?format=csv", nil))
+	csvETag := rr.Header().Get("ETag")
+	if csvETag == "" || csvETag == jsonETag {
+		t.Fatalf("CSV ETag %q must differ from JSON ETag %q", csvETag, jsonETag)
+	}
+
+	req := httptest.NewRequest("GET", "/api/rankings/cat-1?format=csv", nil)
+	req.Header.Set("If-None-Match", csvETag)
+	rr = httptest.NewRecorder()
+	router.ServeHTTP(rr, req)
+	if rr.Code != http.StatusNotModified {
+		t.Errorf("status = %d, want 304", rr.Code)
+	}
+}
+
+// fakeExports はすべてのカテゴリのランキングをまとめて返す。
+type fakeExports struct {
+	rankings map[string]*repository.Ranking
+	err      error
+	calls    int
+	// 呼び出されたときの引数
+	periodType, date string
+	limit            int
+}
+
+func (f *fakeExports) FindAll(ctx context.Context, periodType, date string, limit int) (map[string]*repository.Ranking, error) {
+	f.calls++
+	f.periodType, f.date, f.limit = periodType, date, limit
+	if f.err != nil {
+		return nil, f.err
+	}
+	return f.rankings, nil
+}
+
+// failingWriter は本文の書き込みに失敗する ResponseWriter である。
+type failingWriter struct {
+	*httptest.ResponseRecorder
+}
+
+func (w failingWriter) Write(b []byte) (int, error) {
+	return 0, errors.New("connection reset")
+}
+
+func TestExportRankingsHandler(t *testing.T) {
+	categories := &fakeCategories{categories: []repository.Category{{ID: "cat-1"}, {ID: "cat-2"}, {ID: "cat-3"}}}
+	ranking := func(categoryID string) *repository.Ranking {
+		return &repository.Ranking{
+			CategoryID: categoryID, PeriodType: "weekly", DateFrom: "2024-03-09", DateTo: "2024-03-15",
+			Books: []repository.RankedBook{{Rank: 1, ID: "book-" + categoryID, Title: "本", Price: 1500}},
+		}
+	}
+	header := "カテゴリID,カテゴリ名,期間,開始日,終了日,順位,書籍ID,タイトル,著者,出版社,ISBN,発売日,価格,サイト,URL\n"
+
+	testCases := []struct {
+		name            string
+		query           string
+		err             error
+		wantStatusCode  int
+		wantBody        string
+		wantDisposition string
+	}{
+		{
+			name:           "正常系：すべてのカテゴリを CSV で書き出す",
+			query:          "?period=weekly&date=2024-03-15",
+			wantStatusCode: http.StatusOK,
+			wantBody: header +
+				"cat-1,,weekly,2024-03-09,2024-03-15,1,book-cat-1,本,,,,,1500,,\n" +
+				"cat-2,,weekly,2024-03-09,2024-03-15,1,book-cat-2,本,,,,,1500,,\n",
+			wantDisposition: `attachment; filename="rankings-weekly-2024-03-15.csv"`,
+		},
+		{
+			name:           "異常系：取得に失敗した場合は何も書き出さずに 500",
+			query:          "?period=weekly",
+			err:            errors.New("db down"),
+			wantStatusCode: http.StatusInternalServerError,
+		},
+		{
+			name:           "異常系：format=json",
+			query:          "?format=json",
+			wantStatusCode: http.StatusBadRequest,
+		},
+		{
+			name:           "異常系：不正な日付",
+			query:          "?date=2024/03/15",
+			wantStatusCode: http.StatusBadRequest,
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			exports := &fakeExports{err: tc.err, rankings: map[string]*repository.Ranking{
+				"cat-2": ranking("cat-2"),
+				"cat-1": ranking("cat-1"),
+			}}
+			handler := NewRankingHandler(&fakeRankings{}, &fakeBooks{}, categories)
+			handler.Exports = exports
+
+			rr := httptest.NewRecorder()
+			handler.ExportRankingsHandler(rr, httptest.NewRequest("GET", "/api/export/rankings"+tc.query, nil))
+
+			if rr.Code != tc.wantStatusCode {
+				t.Fatalf("status = %d, want %d", rr.Code, tc.wantStatusCode)
+			}
+			if tc.wantStatusCode != http.StatusOK {
+				if rr.Header().Get("Content-Disposition") != "" {
+					t.Error("Content-Disposition is set on an error response")
+				}
+				return
+			}
+			if got := rr.Body.String(); got != tc.wantBody {
+				t.Errorf("body got %q want %q", got, tc.wantBody)
+			}
+			if got := rr.Header().Get("Content-Disposition"); got != tc.wantDisposition {
+				t.Errorf("Content-Disposition got %v want %v", got, tc.wantDisposition)
+			}
+			// すべてのカテゴリを1回の問い合わせで取得する
+			if exports.calls != 1 || exports.limit != exportLimit || exports.periodType != "weekly" || exports.date != "2024-03-15" {
+				t.Errorf("FindAll called %d times with (%q, %q, %d)", exports.calls, exports.periodType, exports.date, exports.limit)
+			}
+		})
+	}
+}
+
+func TestExportRankingsHandlerAbortsOnWriteError(t *testing.T) {
+	categories := &fakeCategories{categories: []repository.Category{{ID: "cat-1"}}}
+	handler := NewRankingHandler(&fakeRankings{}, &fakeBooks{}, categories)
+	handler.Exports = &fakeExports{rankings: map[string]*repository.Ranking{
+		"cat-1": {CategoryID: "cat-1", Books: []repository.RankedBook{{Rank: 1, ID: "book-1", Title: "本"}}},
+	}}
+
+	defer func() {
+		if v := recover(); v != http.ErrAbortHandler {
+			t.Errorf("recover() = %v, want %v", v, http.ErrAbortHandler)
+		}
+	}()
+	handler.ExportRankingsHandler(failingWriter{httptest.NewRecorder()}, httptest.NewRequest("GET", "/api/export/rankings", nil))
+}
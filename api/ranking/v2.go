@@ -0,0 +1,376 @@
+package ranking
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"net/http"
+	"slices"
+	"strconv"
+	"time"
+
+	"github.com/gorilla/mux"
+
+	"github.com/h-hiwatashi/super-business-book-ranking-backend/affiliate"
+	"github.com/h-hiwatashi/super-business-book-ranking-backend/api/httpcache"
+	"github.com/h-hiwatashi/super-business-book-ranking-backend/api/httperror"
+	"github.com/h-hiwatashi/super-business-book-ranking-backend/repository"
+)
+
+// 前回の順位を調べる上位の件数
+const compareLimit = 100
+
+// 急上昇とする順位の上昇幅の既定値
+const defaultMoverThreshold = 5
+
+// include=history で返す順位の履歴の件数
+const (
+	rankHistoryLimit = 10
+	bookHistoryLimit = 30
+)
+
+// SnapshotHistory は過去のスナップショットを参照するリポジトリである。*repository.RankingRepository が満たす。
+type SnapshotHistory interface {
+	PreviousDate(ctx context.Context, categoryID, periodType string) (string, error)
+	FindRankHistory(ctx context.Context, categoryID, periodType, date string, bookIDs []string, limit int) (map[string][]repository.RankPoint, error)
+	FindBookHistory(ctx context.Context, bookID string, limit int) ([]repository.BookRankPoint, error)
+}
+
+// RankingV2 は v2 のランキングである。
+// v1 ではECサイトごとに1件だった書籍を1件にまとめて販売情報を offers に並べ、前回からの順位の変化を加える。
+type RankingV2 struct {
+	CategoryID   string `json:"categoryId"`
+	CategoryName string `json:"categoryName"`
+	PeriodType   string `json:"periodType"`
+	DateFrom     string `json:"dateFrom"`
+	DateTo       string `json:"dateTo"`
+	// PreviousDateTo は順位を比べた前回のスナップショットの終了日である。比べていない場合は null とする。
+	PreviousDateTo *string `json:"previousDateTo"`
+	// Category は include=category の場合のカテゴリである。
+	Category *repository.Category `json:"category,omitempty"`
+	Entries  []RankingEntryV2     `json:"entries"`
+	// Movers は Entries のうち、前回から急上昇とする幅以上に順位を上げた書籍である。
+	Movers []MoverV2 `json:"movers"`
+}
+
+// RankingEntryV2 は v2 のランキングの1書籍である。
+type RankingEntryV2 struct {
+	// Rank は書籍を販売するECサイトのうち最も高い順位である。
+	Rank int `json:"rank"`
+	// PreviousRank は前回の順位で、前回のランキングにない場合や比べていない場合は null とする。
+	PreviousRank *int `json:"previousRank"`
+	// RankChange は前回からの順位の上昇幅で、下がった場合は負の値とする。PreviousRank が null の場合は null とする。
+	RankChange *int `json:"rankChange"`
+	// IsNew は前回のランキングになかった書籍かである。前回と比べていない場合は false とする。
+	IsNew bool       `json:"isNew"`
+	Book  SparseBook `json:"book"`
+	// Offers は include=offers の場合のECサイトごとの販売情報である。
+	Offers []OfferV2 `json:"offers,omitempty"`
+	// History は include=history の場合の、このスナップショットまでの直近の順位で、新しい順に並ぶ。
+	History []repository.RankPoint `json:"history,omitempty"`
+}
+
+// OfferV2 はランキングに載ったECサイトごとの販売情報である。
+type OfferV2 struct {
+	Site  string  `json:"site"`
+	Rank  int     `json:"rank"`
+	Price float64 `json:"price"`
+	URL   string  `json:"url"`
+	// BookSiteMappingID はクリックを計測するリンク（/go/{bookSiteMappingId}）に使う。
+	BookSiteMappingID string `json:"bookSiteMappingId"`
+}
+
+// MoverV2 は前回から順位を大きく上げた書籍である。
+type MoverV2 struct {
+	BookID       string `json:"bookId"`
+	Title        string `json:"title"`
+	Rank         int    `json:"rank"`
+	PreviousRank int    `json:"previousRank"`
+	RankChange   int    `json:"rankChange"`
+}
+
+// BookV2 は v2 の書籍詳細である。関連情報は include= で指定した場合だけ返す。
+type BookV2 struct {
+	Book SparseBook `json:"book"`
+	// Offers は include=offers の場合のECサイトごとの販売情報である。
+	Offers []repository.Offer `json:"offers,omitempty"`
+	// Categories は include=category の場合の、書籍がランキングに載ったことのあるカテゴリである。
+	Categories []repository.Category `json:"categories,omitempty"`
+	// History は include=history の場合の、すべてのカテゴリ・期間での直近の順位で、新しい順に並ぶ。
+	History []repository.BookRankPoint `json:"history,omitempty"`
+}
+
+// ランキング取得ハンドラー（v2）
+// 日付を指定しない場合は最新と1つ前のスナップショットを比べ、順位の変化と急上昇の書籍を返す。
+// fields= で書籍の項目を、include= で含める関連情報（省略時は offers）を選び、使わない項目のための結合や取得は行わない。
+func (h *RankingHandler) GetRankingsV2Handler(w http.ResponseWriter, r *http.Request) {
+	query, err := parseRankingQuery(r)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	fields, err := parseFields(r)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	include, err := parseInclude(r, IncludeOffers)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	if fields != nil || !include[IncludeOffers] {
+		query.Columns = &repository.RankingColumns{BookFields: fields, Offers: include[IncludeOffers]}
+		if fields == nil {
+			query.Columns.BookFields = bookFieldNames
+		} else if query.Date == "" && !slices.Contains(fields, "title") {
+			// 急上昇の書籍は fields によらず書名を返すため、前回と比べる場合は書名も読み込む
+			query.Columns.BookFields = append(slices.Clone(fields), "title")
+		}
+	}
+
+	ranking, err := h.Rankings.Find(r.Context(), query)
+	if err != nil {
+		httperror.Write(w, r, err, "データベースクエリエラー", "categoryId", query.CategoryID)
+		return
+	}
+
+	var previousDate string
+	var previous map[string]int
+	if query.Date == "" && len(ranking.Books) > 0 {
+		previousDate, previous, err = h.previousRanks(r.Context(), query.CategoryID, query.PeriodType)
+		if err != nil {
+			httperror.Write(w, r, err, "データベースクエリエラー", "categoryId", query.CategoryID)
+			return
+		}
+	}
+
+	response := h.newRankingV2(ranking, previousDate, previous, fields, include[IncludeOffers])
+	if err := h.includeInRanking(r.Context(), &response, query, include); err != nil {
+		httperror.Write(w, r, err, "データベースクエリエラー", "categoryId", query.CategoryID)
+		return
+	}
+
+	// カテゴリと過去のスナップショットは最新のスナップショットとは別に変わるため、含める場合は本文から ETag を作る
+	validators := httpcache.Validators{}
+	if !include[IncludeCategory] && !include[IncludeHistory] {
+		validators = httpcache.Validators{
+			ETag: httpcache.ETag("ranking-v2", query.CategoryID, query.PeriodType, ranking.DateTo, previousDate,
+				ranking.LastModified.UTC().Format(time.RFC3339Nano),
+				strconv.Itoa(query.Page), strconv.Itoa(query.Limit), selectionKey(fields, include)),
+			LastModified: ranking.LastModified,
+		}
+	}
+	httpcache.WriteJSON(w, r, response, validators, rankingCacheControl(query.PeriodType))
+}
+
+// includeInRanking は include= で指定されたカテゴリと順位の履歴を response に加える。
+func (h *RankingHandler) includeInRanking(ctx context.Context, response *RankingV2, query repository.RankingQuery, include map[string]bool) error {
+	if include[IncludeCategory] {
+		categories, err := h.Categories.List(ctx)
+		if err != nil {
+			return err
+		}
+		for _, category := range categories {
+			if category.ID == query.CategoryID {
+				category := category
+				response.Category = &category
+				break
+			}
+		}
+	}
+
+	if include[IncludeHistory] && h.Snapshots != nil && len(response.Entries) > 0 {
+		bookIDs := make([]string, len(response.Entries))
+		for i, entry := range response.Entries {
+			bookIDs[i] = entry.Book.ID
+		}
+		history, err := h.Snapshots.FindRankHistory(ctx, query.CategoryID, query.PeriodType, query.Date, bookIDs, rankHistoryLimit)
+		if err != nil {
+			return err
+		}
+		for i := range response.Entries {
+			response.Entries[i].History = history[response.Entries[i].Book.ID]
+		}
+	}
+	return nil
+}
+
+// 書籍詳細取得ハンドラー（v2）
+// fields= で書籍の項目を、include= で含める関連情報（省略時はなし）を選び、指定した項目と関連情報だけを取得する。
+func (h *RankingHandler) GetBookV2Handler(w http.ResponseWriter, r *http.Request) {
+	bookID := mux.Vars(r)["bookId"]
+	fields, err := parseFields(r)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	include, err := parseInclude(r)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	book, err := h.Books.FindFields(r.Context(), bookID, fields)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			http.Error(w, "書籍が見つかりません", http.StatusNotFound)
+		} else {
+			httperror.Write(w, r, err, "データベースクエリエラー", "bookId", bookID)
+		}
+		return
+	}
+
+	response := BookV2{Book: SparseBook{Book: *book, Fields: fields}}
+	if err := h.includeInBook(r.Context(), &response, include); err != nil {
+		httperror.Write(w, r, err, "データベースクエリエラー", "bookId", bookID)
+		return
+	}
+
+	// 関連情報は書籍の更新時刻とは別に変わるため、含める場合は本文から ETag を作る
+	validators := httpcache.Validators{}
+	if len(include) == 0 {
+		validators = httpcache.Validators{
+			ETag:         httpcache.ETag("book-v2", book.ID, book.UpdatedAt.UTC().Format(time.RFC3339Nano), selectionKey(fields, include)),
+			LastModified: book.UpdatedAt,
+		}
+	}
+	httpcache.WriteJSON(w, r, response, validators, httpcache.MaxAge(catalogMaxAge))
+}
+
+// includeInBook は include= で指定された販売情報・カテゴリ・順位の履歴を response に加える。
+func (h *RankingHandler) includeInBook(ctx context.Context, response *BookV2, include map[string]bool) error {
+	bookID := response.Book.ID
+	if include[IncludeOffers] {
+		offers, err := h.Books.FindOffers(ctx, []string{bookID})
+		if err != nil {
+			return err
+		}
+		// リポジトリが返すスライスは書き換えず、アフィリエイトリンクに変えたものを返す
+		response.Offers = make([]repository.Offer, len(offers[bookID]))
+		for i, offer := range offers[bookID] {
+			offer.URL = affiliate.LinkURL(h.Links, offer.Site, offer.URL)
+			response.Offers[i] = offer
+		}
+	}
+	if include[IncludeCategory] {
+		categories, err := h.Books.FindCategories(ctx, bookID)
+		if err != nil {
+			return err
+		}
+		response.Categories = categories
+	}
+	if include[IncludeHistory] && h.Snapshots != nil {
+		history, err := h.Snapshots.FindBookHistory(ctx, bookID, bookHistoryLimit)
+		if err != nil {
+			return err
+		}
+		response.History = history
+	}
+	return nil
+}
+
+// previousRanks は1つ前のスナップショットの終了日と、書籍IDごとの順位を返す。
+// 前回のスナップショットがない場合は空の終了日と nil を返す。
+func (h *RankingHandler) previousRanks(ctx context.Context, categoryID, periodType string) (string, map[string]int, error) {
+	if h.Snapshots == nil {
+		return "", nil, nil
+	}
+	date, err := h.Snapshots.PreviousDate(ctx, categoryID, periodType)
+	if errors.Is(err, sql.ErrNoRows) {
+		return "", nil, nil
+	}
+	if err != nil {
+		return "", nil, err
+	}
+
+	previous, err := h.Rankings.Find(ctx, repository.RankingQuery{
+		CategoryID: categoryID,
+		PeriodType: periodType,
+		Date:       date,
+		Page:       1,
+		Limit:      compareLimit,
+		// 比べるのは書籍IDと順位だけのため、書籍と販売情報は読み込まない
+		Columns: &repository.RankingColumns{},
+	})
+	if err != nil {
+		return "", nil, err
+	}
+
+	ranks := make(map[string]int, len(previous.Books))
+	for _, book := range previous.Books {
+		// 複数のサイトで同じ書籍がある場合は最も高い順位を使う
+		if rank, ok := ranks[book.ID]; !ok || book.Rank < rank {
+			ranks[book.ID] = book.Rank
+		}
+	}
+	return date, ranks, nil
+}
+
+// newRankingV2 はECサイトごとの書籍を書籍ごとにまとめ、前回の順位 previous と比べた v2 のランキングを組み立てる。
+// previous が nil の場合は前回と比べない。書籍は fields の項目だけを返し、offers が false の場合は販売情報を返さない。
+func (h *RankingHandler) newRankingV2(ranking *repository.Ranking, previousDate string, previous map[string]int, fields []string, offers bool) RankingV2 {
+	response := RankingV2{
+		CategoryID:   ranking.CategoryID,
+		CategoryName: ranking.CategoryName,
+		PeriodType:   ranking.PeriodType,
+		DateFrom:     ranking.DateFrom,
+		DateTo:       ranking.DateTo,
+		Entries:      []RankingEntryV2{},
+		Movers:       []MoverV2{},
+	}
+	if previous != nil {
+		response.PreviousDateTo = &previousDate
+	}
+
+	// ランキングは順位の順に並ぶため、最初に現れた行がその書籍の最も高い順位になる
+	index := make(map[string]int)
+	for _, book := range ranking.Books {
+		url := affiliate.LinkURL(h.Links, book.Site, book.URL)
+		offer := OfferV2{Site: book.Site, Rank: book.Rank, Price: book.Price, URL: url, BookSiteMappingID: book.BookSiteMappingID}
+		if i, ok := index[book.ID]; ok {
+			if offers {
+				response.Entries[i].Offers = append(response.Entries[i].Offers, offer)
+			}
+			continue
+		}
+
+		index[book.ID] = len(response.Entries)
+		entry := RankingEntryV2{
+			Rank: book.Rank,
+			Book: SparseBook{
+				Book: repository.Book{
+					ID:              book.ID,
+					Title:           book.Title,
+					Author:          book.Author,
+					Publisher:       book.Publisher,
+					ISBN:            book.ISBN,
+					PublicationDate: book.PublicationDate,
+					ImageURL:        book.ImageURL,
+				},
+				Fields: fields,
+			},
+		}
+		if offers {
+			entry.Offers = []OfferV2{offer}
+		}
+		if previousRank, ok := previous[book.ID]; ok {
+			change := previousRank - book.Rank
+			entry.PreviousRank = &previousRank
+			entry.RankChange = &change
+			if change >= h.MoverThreshold {
+				response.Movers = append(response.Movers, MoverV2{
+					BookID:       book.ID,
+					Title:        book.Title,
+					Rank:         book.Rank,
+					PreviousRank: previousRank,
+					RankChange:   change,
+				})
+			}
+		} else {
+			entry.IsNew = previous != nil
+		}
+		response.Entries = append(response.Entries, entry)
+	}
+	return response
+}
@@ -0,0 +1,528 @@
+package ranking
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"slices"
+	"strings"
+	"testing"
+
+	"github.com/h-hiwatashi/super-business-book-ranking-backend/affiliate"
+	"github.com/h-hiwatashi/super-business-book-ranking-backend/repository"
+)
+
+// fakeDatedRankings は終了日ごとのランキングを返す。空の終了日は最新を表す。
+type fakeDatedRankings struct {
+	rankings map[string]*repository.Ranking
+	// queries は Find に渡された条件である。
+	queries []repository.RankingQuery
+}
+
+func (f *fakeDatedRankings) Find(ctx context.Context, q repository.RankingQuery) (*repository.Ranking, error) {
+	f.queries = append(f.queries, q)
+	if ranking, ok := f.rankings[q.Date]; ok {
+		return ranking, nil
+	}
+	return &repository.Ranking{}, nil
+}
+
+type fakeSnapshotDates struct {
+	date        string
+	err         error
+	history     map[string][]repository.RankPoint
+	bookHistory []repository.BookRankPoint
+	// historyDate は FindRankHistory に渡された終了日である。
+	historyDate string
+}
+
+func (f *fakeSnapshotDates) PreviousDate(ctx context.Context, categoryID, periodType string) (string, error) {
+	if f.date == "" && f.err == nil {
+		return "", sql.ErrNoRows
+	}
+	return f.date, f.err
+}
+
+func (f *fakeSnapshotDates) FindRankHistory(ctx context.Context, categoryID, periodType, date string, bookIDs []string, limit int) (map[string][]repository.RankPoint, error) {
+	f.historyDate = date
+	return f.history, f.err
+}
+
+func (f *fakeSnapshotDates) FindBookHistory(ctx context.Context, bookID string, limit int) ([]repository.BookRankPoint, error) {
+	return f.bookHistory, f.err
+}
+
+func TestGetRankingsV2Handler(t *testing.T) {
+	latest := &repository.Ranking{
+		CategoryID:   "001",
+		CategoryName: "ビジネス書",
+		PeriodType:   "daily",
+		DateTo:       "2024-03-15",
+		Books: []repository.RankedBook{
+			{Rank: 1, ID: "book-1", Title: "イシューからはじめよ", Site: affiliate.SiteAmazon, URL: "https://www.amazon.co.jp/dp/4862760856", BookSiteMappingID: "bsm-1"},
+			{Rank: 1, ID: "book-2", Title: "入門 考える技術・書く技術", Site: affiliate.SiteRakuten, URL: "https://books.rakuten.co.jp/rb/2/", BookSiteMappingID: "bsm-2"},
+			{Rank: 2, ID: "book-1", Title: "イシューからはじめよ", Site: affiliate.SiteRakuten, URL: "https://books.rakuten.co.jp/rb/1/", BookSiteMappingID: "bsm-3"},
+			{Rank: 3, ID: "book-3", Title: "ゼロ秒思考"},
+		},
+	}
+	previous := &repository.Ranking{
+		Books: []repository.RankedBook{
+			{Rank: 2, ID: "book-1"},
+			{Rank: 7, ID: "book-2"},
+			{Rank: 9, ID: "book-2"},
+		},
+	}
+
+	type entry struct {
+		bookID       string
+		rank         int
+		previousRank int
+		isNew        bool
+		offers       int
+	}
+	testCases := []struct {
+		name             string
+		query            string
+		previousDate     string
+		wantPreviousDate string
+		wantEntries      []entry
+		wantMovers       []string
+	}{
+		{
+			name:             "正常系：前回と比べる",
+			previousDate:     "2024-03-14",
+			wantPreviousDate: "2024-03-14",
+			wantEntries: []entry{
+				{bookID: "book-1", rank: 1, previousRank: 2, offers: 2},
+				{bookID: "book-2", rank: 1, previousRank: 7, offers: 1},
+				{bookID: "book-3", rank: 3, isNew: true, offers: 1},
+			},
+			wantMovers: []string{"book-2"},
+		},
+		{
+			name: "正常系：前回のスナップショットがない",
+			wantEntries: []entry{
+				{bookID: "book-1", rank: 1, offers: 2},
+				{bookID: "book-2", rank: 1, offers: 1},
+				{bookID: "book-3", rank: 3, offers: 1},
+			},
+		},
+		{
+			name:         "正常系：日付を指定した場合は比べない",
+			query:        "?date=2024-03-14",
+			previousDate: "2024-03-13",
+			wantEntries: []entry{
+				{bookID: "book-1", rank: 2, offers: 1},
+				{bookID: "book-2", rank: 7, offers: 2},
+			},
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			rankings := &fakeDatedRankings{rankings: map[string]*repository.Ranking{"": latest, "2024-03-14": previous}}
+			handler := NewRankingHandler(rankings, &fakeBooks{}, &fakeCategories{})
+			handler.Snapshots = &fakeSnapshotDates{date: tc.previousDate}
+			handler.Links = affiliate.NewLinker(map[string]string{affiliate.SiteAmazon: "bookranking-22"})
+
+			rr := httptest.NewRecorder()
+			newTestRouter(handler).ServeHTTP(rr, httptest.NewRequest("GET", "/api/v2/rankings/001"+tc.query, nil))
+			if rr.Code != http.StatusOK {
+				t.Fatalf("status = %v, want %v: %s", rr.Code, http.StatusOK, rr.Body.String())
+			}
+
+			var got RankingV2
+			if err := json.Unmarshal(rr.Body.Bytes(), &got); err != nil {
+				t.Fatal(err)
+			}
+			gotPreviousDate := ""
+			if got.PreviousDateTo != nil {
+				gotPreviousDate = *got.PreviousDateTo
+			}
+			if gotPreviousDate != tc.wantPreviousDate {
+				t.Errorf("previousDateTo = %q, want %q", gotPreviousDate, tc.wantPreviousDate)
+			}
+			if len(got.Entries) != len(tc.wantEntries) {
+				t.Fatalf("entries = %+v, want %d entries", got.Entries, len(tc.wantEntries))
+			}
+			for i, want := range tc.wantEntries {
+				e := got.Entries[i]
+				previousRank := 0
+				if e.PreviousRank != nil {
+					previousRank = *e.PreviousRank
+					if e.RankChange == nil || *e.RankChange != previousRank-e.Rank {
+						t.Errorf("entries[%d].rankChange = %v, want %d", i, e.RankChange, previousRank-e.Rank)
+					}
+				}
+				if e.Book.ID != want.bookID || e.Rank != want.rank || previousRank != want.previousRank || e.IsNew != want.isNew || len(e.Offers) != want.offers {
+					t.Errorf("entries[%d] = %+v, want %+v", i, e, want)
+				}
+			}
+			var movers []string
+			for _, mover := range got.Movers {
+				movers = append(movers, mover.BookID)
+			}
+			if len(movers) != len(tc.wantMovers) || (len(movers) > 0 && movers[0] != tc.wantMovers[0]) {
+				t.Errorf("movers = %v, want %v", movers, tc.wantMovers)
+			}
+		})
+	}
+}
+
+func TestGetRankingsV2HandlerOffers(t *testing.T) {
+	latest := &repository.Ranking{
+		CategoryID: "001",
+		Books: []repository.RankedBook{
+			{Rank: 1, ID: "book-1", Site: affiliate.SiteAmazon, URL: "https://www.amazon.co.jp/dp/4862760856", Price: 1980, BookSiteMappingID: "bsm-1"},
+			{Rank: 3, ID: "book-1", Site: affiliate.SiteRakuten, URL: "https://books.rakuten.co.jp/rb/1/", Price: 1980, BookSiteMappingID: "bsm-2"},
+		},
+	}
+	handler := NewRankingHandler(&fakeDatedRankings{rankings: map[string]*repository.Ranking{"": latest}}, &fakeBooks{}, &fakeCategories{})
+	handler.Links = affiliate.NewLinker(map[string]string{affiliate.SiteAmazon: "bookranking-22"})
+
+	rr := httptest.NewRecorder()
+	newTestRouter(handler).ServeHTTP(rr, httptest.NewRequest("GET", "/api/v2/rankings/001", nil))
+
+	var got RankingV2
+	if err := json.Unmarshal(rr.Body.Bytes(), &got); err != nil {
+		t.Fatal(err)
+	}
+	want := []OfferV2{
+		{Site: affiliate.SiteAmazon, Rank: 1, Price: 1980, URL: "https://www.amazon.co.jp/dp/4862760856?tag=bookranking-22", BookSiteMappingID: "bsm-1"},
+		{Site: affiliate.SiteRakuten, Rank: 3, Price: 1980, URL: "https://books.rakuten.co.jp/rb/1/", BookSiteMappingID: "bsm-2"},
+	}
+	if len(got.Entries) != 1 || len(got.Entries[0].Offers) != len(want) {
+		t.Fatalf("entries = %+v", got.Entries)
+	}
+	for i := range want {
+		if got.Entries[0].Offers[i] != want[i] {
+			t.Errorf("offers[%d] = %+v, want %+v", i, got.Entries[0].Offers[i], want[i])
+		}
+	}
+	// キャッシュされたランキングは書き換えない
+	if latest.Books[0].URL != "https://www.amazon.co.jp/dp/4862760856" {
+		t.Errorf("cached ranking was modified: %v", latest.Books[0].URL)
+	}
+}
+
+func TestGetRankingsV2HandlerErrors(t *testing.T) {
+	latest := &repository.Ranking{CategoryID: "001", Books: []repository.RankedBook{{Rank: 1, ID: "book-1"}}}
+
+	testCases := []struct {
+		name           string
+		query          string
+		rankingsErr    error
+		snapshotsErr   error
+		wantStatusCode int
+	}{
+		{
+			name:           "異常系：不正なページ",
+			query:          "?page=0",
+			wantStatusCode: http.StatusBadRequest,
+		},
+		{
+			name:           "異常系：不明な項目",
+			query:          "?fields=title,price",
+			wantStatusCode: http.StatusBadRequest,
+		},
+		{
+			name:           "異常系：不明な関連情報",
+			query:          "?include=offers,reviews",
+			wantStatusCode: http.StatusBadRequest,
+		},
+		{
+			name:           "異常系：ランキングの取得エラー",
+			rankingsErr:    errors.New("connection refused"),
+			wantStatusCode: http.StatusInternalServerError,
+		},
+		{
+			name:           "異常系：前回の終了日の取得エラー",
+			snapshotsErr:   errors.New("connection refused"),
+			wantStatusCode: http.StatusInternalServerError,
+		},
+		{
+			name:           "異常系：順位の履歴の取得エラー",
+			query:          "?date=2024-03-15&include=history",
+			snapshotsErr:   errors.New("connection refused"),
+			wantStatusCode: http.StatusInternalServerError,
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			var rankings RankingFinder = &fakeDatedRankings{rankings: map[string]*repository.Ranking{"": latest, "2024-03-15": latest}}
+			if tc.rankingsErr != nil {
+				rankings = &fakeRankings{err: tc.rankingsErr}
+			}
+			handler := NewRankingHandler(rankings, &fakeBooks{}, &fakeCategories{})
+			handler.Snapshots = &fakeSnapshotDates{err: tc.snapshotsErr}
+
+			rr := httptest.NewRecorder()
+			newTestRouter(handler).ServeHTTP(rr, httptest.NewRequest("GET", "/api/v2/rankings/001"+tc.query, nil))
+			if rr.Code != tc.wantStatusCode {
+				t.Errorf("status = %v, want %v", rr.Code, tc.wantStatusCode)
+			}
+		})
+	}
+}
+
+func TestGetRankingsV2HandlerFieldsAndInclude(t *testing.T) {
+	latest := &repository.Ranking{
+		CategoryID: "001",
+		DateTo:     "2024-03-15",
+		Books: []repository.RankedBook{
+			{Rank: 1, ID: "book-1", Title: "イシューからはじめよ", Author: "安宅和人", ImageURL: "https://example.com/book-1.jpg", Site: affiliate.SiteAmazon, BookSiteMappingID: "bsm-1"},
+			{Rank: 2, ID: "book-2", Title: "ゼロ秒思考", Author: "赤羽雄二", ImageURL: "https://example.com/book-2.jpg", Site: affiliate.SiteAmazon, BookSiteMappingID: "bsm-2"},
+		},
+	}
+	history := map[string][]repository.RankPoint{
+		"book-1": {{DateTo: "2024-03-15", Rank: 1}, {DateTo: "2024-03-14", Rank: 3}},
+	}
+
+	testCases := []struct {
+		name         string
+		query        string
+		wantColumns  *repository.RankingColumns
+		wantBookKeys []string
+		wantOffers   bool
+		wantCategory bool
+		wantHistory  bool
+		wantDate     string
+	}{
+		{
+			name:         "正常系：指定なしはすべての項目と販売情報",
+			wantBookKeys: []string{"author", "id", "imageUrl", "isbn", "publicationDate", "publisher", "title"},
+			wantOffers:   true,
+		},
+		{
+			name:         "正常系：項目を絞り込み販売情報を含めない",
+			query:        "?fields=title,imageUrl&include=",
+			wantColumns:  &repository.RankingColumns{BookFields: []string{"title", "imageUrl"}},
+			wantBookKeys: []string{"id", "imageUrl", "title"},
+		},
+		{
+			name:         "正常系：書名を返さない場合も急上昇の書籍のために書名を読み込む",
+			query:        "?fields=imageUrl&include=",
+			wantColumns:  &repository.RankingColumns{BookFields: []string{"imageUrl", "title"}},
+			wantBookKeys: []string{"id", "imageUrl"},
+		},
+		{
+			name:         "正常系：日付を指定した場合は前回と比べないため書名を読み込まない",
+			query:        "?date=2024-03-15&fields=imageUrl&include=",
+			wantColumns:  &repository.RankingColumns{BookFields: []string{"imageUrl"}},
+			wantBookKeys: []string{"id", "imageUrl"},
+		},
+		{
+			name:         "正常系：関連情報をすべて含める",
+			query:        "?fields=id,title&include=offers,category,history",
+			wantColumns:  &repository.RankingColumns{BookFields: []string{"title"}, Offers: true},
+			wantBookKeys: []string{"id", "title"},
+			wantOffers:   true,
+			wantCategory: true,
+			wantHistory:  true,
+		},
+		{
+			name:         "正常系：日付を指定した履歴はその日付まで",
+			query:        "?date=2024-03-15&include=history",
+			wantColumns:  &repository.RankingColumns{BookFields: bookFieldNames},
+			wantBookKeys: []string{"author", "id", "imageUrl", "isbn", "publicationDate", "publisher", "title"},
+			wantHistory:  true,
+			wantDate:     "2024-03-15",
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			rankings := &fakeDatedRankings{rankings: map[string]*repository.Ranking{"": latest, "2024-03-15": latest}}
+			snapshots := &fakeSnapshotDates{history: history}
+			handler := NewRankingHandler(rankings, &fakeBooks{}, &fakeCategories{categories: []repository.Category{{ID: "001", Name: "ビジネス書"}}})
+			handler.Snapshots = snapshots
+
+			rr := httptest.NewRecorder()
+			newTestRouter(handler).ServeHTTP(rr, httptest.NewRequest("GET", "/api/v2/rankings/001"+tc.query, nil))
+			if rr.Code != http.StatusOK {
+				t.Fatalf("status = %v, want %v: %s", rr.Code, http.StatusOK, rr.Body.String())
+			}
+
+			if got := rankings.queries[0].Columns; !reflect.DeepEqual(got, tc.wantColumns) {
+				t.Errorf("Columns = %+v, want %+v", got, tc.wantColumns)
+			}
+
+			var got struct {
+				Category *repository.Category `json:"category"`
+				Entries  []struct {
+					Book    map[string]json.RawMessage `json:"book"`
+					Offers  []OfferV2                  `json:"offers"`
+					History []repository.RankPoint     `json:"history"`
+				} `json:"entries"`
+			}
+			if err := json.Unmarshal(rr.Body.Bytes(), &got); err != nil {
+				t.Fatal(err)
+			}
+			if len(got.Entries) != 2 {
+				t.Fatalf("entries = %+v", got.Entries)
+			}
+			var keys []string
+			for key := range got.Entries[0].Book {
+				keys = append(keys, key)
+			}
+			if !equalSorted(keys, tc.wantBookKeys) {
+				t.Errorf("book keys = %v, want %v", keys, tc.wantBookKeys)
+			}
+			if gotOffers := got.Entries[0].Offers != nil; gotOffers != tc.wantOffers {
+				t.Errorf("offers = %+v, want included = %v", got.Entries[0].Offers, tc.wantOffers)
+			}
+			if gotCategory := got.Category != nil; gotCategory != tc.wantCategory {
+				t.Errorf("category = %+v, want included = %v", got.Category, tc.wantCategory)
+			}
+			if tc.wantCategory && got.Category.Name != "ビジネス書" {
+				t.Errorf("category.name = %q", got.Category.Name)
+			}
+			if gotHistory := len(got.Entries[0].History) == 2; gotHistory != tc.wantHistory {
+				t.Errorf("history = %+v, want included = %v", got.Entries[0].History, tc.wantHistory)
+			}
+			if tc.wantHistory && snapshots.historyDate != tc.wantDate {
+				t.Errorf("history date = %q, want %q", snapshots.historyDate, tc.wantDate)
+			}
+			if got.Entries[1].History != nil {
+				t.Errorf("entries[1].history = %+v, want none", got.Entries[1].History)
+			}
+		})
+	}
+}
+
+func TestGetRankingsV2HandlerSelectionETag(t *testing.T) {
+	latest := &repository.Ranking{CategoryID: "001", DateTo: "2024-03-15", Books: []repository.RankedBook{{Rank: 1, ID: "book-1"}}}
+	router := newTestRouter(NewRankingHandler(&fakeDatedRankings{rankings: map[string]*repository.Ranking{"": latest}}, &fakeBooks{}, &fakeCategories{}))
+
+	etag := func(query string) string {
+		rr := httptest.NewRecorder()
+		router.ServeHTTP(rr, httptest.NewRequest("GET", "/api/v2/rankings/001"+query, nil))
+		return rr.Header().Get("ETag")
+	}
+	if etag("?fields=title,imageUrl") != etag("?fields=imageUrl,title") {
+		t.Error("項目の順序だけが違うリクエストの ETag が異なります")
+	}
+	if etag("") == etag("?fields=title") || etag("") == etag("?include=") {
+		t.Error("返す項目が異なるリクエストの ETag が同じです")
+	}
+}
+
+func TestGetBookV2Handler(t *testing.T) {
+	books := &fakeBooks{
+		books: map[string]*repository.Book{
+			"book-1": {ID: "book-1", Title: "イシューからはじめよ", Author: "安宅和人", ImageURL: "https://example.com/book-1.jpg"},
+		},
+		offers: map[string][]repository.Offer{
+			"book-1": {{ID: "bsm-1", BookID: "book-1", Site: affiliate.SiteAmazon, Price: 1980, URL: "https://www.amazon.co.jp/dp/4862760856"}},
+		},
+		categories: []repository.Category{{ID: "001", Name: "ビジネス書"}},
+	}
+	snapshots := &fakeSnapshotDates{bookHistory: []repository.BookRankPoint{{CategoryID: "001", PeriodType: "daily", DateTo: "2024-03-15", Rank: 1}}}
+
+	testCases := []struct {
+		name           string
+		bookID         string
+		query          string
+		wantStatusCode int
+		wantFields     []string
+		wantBookKeys   []string
+		wantOffers     int
+		wantCategories int
+		wantHistory    int
+	}{
+		{
+			name:           "正常系：指定なしは書籍のみ",
+			bookID:         "book-1",
+			wantStatusCode: http.StatusOK,
+			wantBookKeys:   []string{"author", "id", "imageUrl", "isbn", "publicationDate", "publisher", "title"},
+		},
+		{
+			name:           "正常系：項目を絞り込み関連情報を含める",
+			bookID:         "book-1",
+			query:          "?fields=title&include=offers,category,history",
+			wantStatusCode: http.StatusOK,
+			wantFields:     []string{"title"},
+			wantBookKeys:   []string{"id", "title"},
+			wantOffers:     1,
+			wantCategories: 1,
+			wantHistory:    1,
+		},
+		{
+			name:           "異常系：不明な項目",
+			bookID:         "book-1",
+			query:          "?fields=price",
+			wantStatusCode: http.StatusBadRequest,
+		},
+		{
+			name:           "異常系：不明な関連情報",
+			bookID:         "book-1",
+			query:          "?include=reviews",
+			wantStatusCode: http.StatusBadRequest,
+		},
+		{
+			name:           "異常系：存在しない書籍",
+			bookID:         "unknown",
+			wantStatusCode: http.StatusNotFound,
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			handler := NewRankingHandler(&fakeRankings{}, books, &fakeCategories{})
+			handler.Snapshots = snapshots
+			handler.Links = affiliate.NewLinker(map[string]string{affiliate.SiteAmazon: "bookranking-22"})
+
+			rr := httptest.NewRecorder()
+			newTestRouter(handler).ServeHTTP(rr, httptest.NewRequest("GET", "/api/v2/books/"+tc.bookID+tc.query, nil))
+			if rr.Code != tc.wantStatusCode {
+				t.Fatalf("status = %v, want %v: %s", rr.Code, tc.wantStatusCode, rr.Body.String())
+			}
+			if tc.wantStatusCode != http.StatusOK {
+				return
+			}
+			if !reflect.DeepEqual(books.fields, tc.wantFields) {
+				t.Errorf("fields = %v, want %v", books.fields, tc.wantFields)
+			}
+
+			var got struct {
+				Book       map[string]json.RawMessage `json:"book"`
+				Offers     []repository.Offer         `json:"offers"`
+				Categories []repository.Category      `json:"categories"`
+				History    []repository.BookRankPoint `json:"history"`
+			}
+			if err := json.Unmarshal(rr.Body.Bytes(), &got); err != nil {
+				t.Fatal(err)
+			}
+			var keys []string
+			for key := range got.Book {
+				keys = append(keys, key)
+			}
+			if !equalSorted(keys, tc.wantBookKeys) {
+				t.Errorf("book keys = %v, want %v", keys, tc.wantBookKeys)
+			}
+			if len(got.Offers) != tc.wantOffers || len(got.Categories) != tc.wantCategories || len(got.History) != tc.wantHistory {
+				t.Errorf("offers = %+v, categories = %+v, history = %+v", got.Offers, got.Categories, got.History)
+			}
+			if tc.wantOffers > 0 && !strings.Contains(got.Offers[0].URL, "tag=bookranking-22") {
+				t.Errorf("offers[0].url = %q, want an affiliate link", got.Offers[0].URL)
+			}
+		})
+	}
+	// キャッシュされうる販売情報は書き換えない
+	if books.offers["book-1"][0].URL != "https://www.amazon.co.jp/dp/4862760856" {
+		t.Errorf("offers were modified: %v", books.offers["book-1"][0].URL)
+	}
+}
+
+// equalSorted は順序を問わず got と want が同じ要素を持つかを返す。
+func equalSorted(got, want []string) bool {
+	got, want = slices.Clone(got), slices.Clone(want)
+	slices.Sort(got)
+	slices.Sort(want)
+	return slices.Equal(got, want)
+}
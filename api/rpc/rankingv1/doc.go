@@ -0,0 +1,6 @@
+// Package rankingv1 は ranking.proto から生成した gRPC のメッセージとサービスである。
+//
+// 生成には protoc、protoc-gen-go、protoc-gen-go-grpc が必要である。
+package rankingv1
+
+//go:generate protoc --go_out=. --go_opt=paths=source_relative --go-grpc_out=. --go-grpc_opt=paths=source_relative ranking.proto
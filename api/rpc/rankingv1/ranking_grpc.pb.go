@@ -0,0 +1,166 @@
+// 社内サービス向けのランキングの gRPC API。
+// メッセージは REST API の JSON（/api/rankings/{categoryId}、/api/categories）と同じ項目を持つ。
+
+// Code generated by protoc-gen-go-grpc. DO NOT EDIT.
+// versions:
+// - protoc-gen-go-grpc v1.5.1
+// - protoc             v5.27.1
+// source: ranking.proto
+
+package rankingv1
+
+import (
+	context "context"
+	grpc "google.golang.org/grpc"
+	codes "google.golang.org/grpc/codes"
+	status "google.golang.org/grpc/status"
+)
+
+// This is a compile-time assertion to ensure that this generated file
+// is compatible with the grpc package it is being compiled against.
+// Requires gRPC-Go v1.64.0 or later.
+const _ = grpc.SupportPackageIsVersion9
+
+const (
+	RankingService_GetRanking_FullMethodName     = "/bookranking.v1.RankingService/GetRanking"
+	RankingService_ListCategories_FullMethodName = "/bookranking.v1.RankingService/ListCategories"
+)
+
+// RankingServiceClient is the client API for RankingService service.
+//
+// For semantics around ctx use and closing/ending streaming RPCs, please refer to https://pkg.go.dev/google.golang.org/grpc/?tab=doc#ClientConn.NewStream.
+type RankingServiceClient interface {
+	// カテゴリのランキングを返す。ランキングがない場合は books が空の Ranking を返す。
+	GetRanking(ctx context.Context, in *GetRankingRequest, opts ...grpc.CallOption) (*Ranking, error)
+	// すべてのカテゴリを返す。
+	ListCategories(ctx context.Context, in *ListCategoriesRequest, opts ...grpc.CallOption) (*ListCategoriesResponse, error)
+}
+
+type rankingServiceClient struct {
+	cc grpc.ClientConnInterface
+}
+
+func NewRankingServiceClient(cc grpc.ClientConnInterface) RankingServiceClient {
+	return &rankingServiceClient{cc}
+}
+
+func (c *rankingServiceClient) GetRanking(ctx context.Context, in *GetRankingRequest, opts ...grpc.CallOption) (*Ranking, error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	out := new(Ranking)
+	err := c.cc.Invoke(ctx, RankingService_GetRanking_FullMethodName, in, out, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *rankingServiceClient) ListCategories(ctx context.Context, in *ListCategoriesRequest, opts ...grpc.CallOption) (*ListCategoriesResponse, error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	out := new(ListCategoriesResponse)
+	err := c.cc.Invoke(ctx, RankingService_ListCategories_FullMethodName, in, out, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+// RankingServiceServer is the server API for RankingService service.
+// All implementations must embed UnimplementedRankingServiceServer
+// for forward compatibility.
+type RankingServiceServer interface {
+	// カテゴリのランキングを返す。ランキングがない場合は books が空の Ranking を返す。
+	GetRanking(context.Context, *GetRankingRequest) (*Ranking, error)
+	// すべてのカテゴリを返す。
+	ListCategories(context.Context, *ListCategoriesRequest) (*ListCategoriesResponse, error)
+	mustEmbedUnimplementedRankingServiceServer()
+}
+
+// UnimplementedRankingServiceServer must be embedded to have
+// forward compatible implementations.
+//
+// NOTE: this should be embedded by value instead of pointer to avoid a nil
+// pointer dereference when methods are called.
+type UnimplementedRankingServiceServer struct{}
+
+func (UnimplementedRankingServiceServer) GetRanking(context.Context, *GetRankingRequest) (*Ranking, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method GetRanking not implemented")
+}
+func (UnimplementedRankingServiceServer) ListCategories(context.Context, *ListCategoriesRequest) (*ListCategoriesResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method ListCategories not implemented")
+}
+func (UnimplementedRankingServiceServer) mustEmbedUnimplementedRankingServiceServer() {}
+func (UnimplementedRankingServiceServer) testEmbeddedByValue()                        {}
+
+// UnsafeRankingServiceServer may be embedded to opt out of forward compatibility for this service.
+// Use of this interface is not recommended, as added methods to RankingServiceServer will
+// result in compilation errors.
+type UnsafeRankingServiceServer interface {
+	mustEmbedUnimplementedRankingServiceServer()
+}
+
+func RegisterRankingServiceServer(s grpc.ServiceRegistrar, srv RankingServiceServer) {
+	// If the following call pancis, it indicates UnimplementedRankingServiceServer was
+	// embedded by pointer and is nil.  This will cause panics if an
+	// unimplemented method is ever invoked, so we test this at initialization
+	// time to prevent it from happening at runtime later due to I/O.
+	if t, ok := srv.(interface{ testEmbeddedByValue() }); ok {
+		t.testEmbeddedByValue()
+	}
+	s.RegisterService(&RankingService_ServiceDesc, srv)
+}
+
+func _RankingService_GetRanking_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(GetRankingRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(RankingServiceServer).GetRanking(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: RankingService_GetRanking_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(RankingServiceServer).GetRanking(ctx, req.(*GetRankingRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _RankingService_ListCategories_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(ListCategoriesRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(RankingServiceServer).ListCategories(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: RankingService_ListCategories_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(RankingServiceServer).ListCategories(ctx, req.(*ListCategoriesRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+// RankingService_ServiceDesc is the grpc.ServiceDesc for RankingService service.
+// It's only intended for direct use with grpc.RegisterService,
+// and not to be introspected or modified (even as a copy)
+var RankingService_ServiceDesc = grpc.ServiceDesc{
+	ServiceName: "bookranking.v1.RankingService",
+	HandlerType: (*RankingServiceServer)(nil),
+	Methods: []grpc.MethodDesc{
+		{
+			MethodName: "GetRanking",
+			Handler:    _RankingService_GetRanking_Handler,
+		},
+		{
+			MethodName: "ListCategories",
+			Handler:    _RankingService_ListCategories_Handler,
+		},
+	},
+	Streams:  []grpc.StreamDesc{},
+	Metadata: "ranking.proto",
+}
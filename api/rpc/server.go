@@ -0,0 +1,109 @@
+package rpc
+
+import (
+	"context"
+	"net"
+	"time"
+
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/health"
+	healthpb "google.golang.org/grpc/health/grpc_health_v1"
+	"google.golang.org/grpc/peer"
+	"google.golang.org/grpc/reflection"
+	"google.golang.org/grpc/status"
+
+	"github.com/h-hiwatashi/super-business-book-ranking-backend/api/rpc/rankingv1"
+	"github.com/h-hiwatashi/super-business-book-ranking-backend/logging"
+)
+
+// Server は RankingService にヘルスチェックとリフレクションを加えた gRPC サーバーである。
+type Server struct {
+	grpc   *grpc.Server
+	health *health.Server
+}
+
+// NewServer は service を登録した Server を返す。
+// timeout は1回の呼び出しの処理時間の上限で、クライアントの期限の方が短い場合はそちらを使う。
+func NewServer(service *RankingService, timeout time.Duration) *Server {
+	s := &Server{
+		grpc: grpc.NewServer(
+			grpc.ChainUnaryInterceptor(accessLog, recoverPanic, withTimeout(timeout)),
+		),
+		health: health.NewServer(),
+	}
+	rankingv1.RegisterRankingServiceServer(s.grpc, service)
+	healthpb.RegisterHealthServer(s.grpc, s.health)
+	reflection.Register(s.grpc)
+
+	s.health.SetServingStatus(rankingv1.RankingService_ServiceDesc.ServiceName, healthpb.HealthCheckResponse_SERVING)
+	return s
+}
+
+// Serve は lis で呼び出しを受け付ける。Shutdown を呼ぶまで戻らない。
+func (s *Server) Serve(lis net.Listener) error {
+	return s.grpc.Serve(lis)
+}
+
+// SetShuttingDown はヘルスチェックを NOT_SERVING にし、クライアントが振り分け先から外せるようにする。
+func (s *Server) SetShuttingDown() {
+	s.health.Shutdown()
+}
+
+// Shutdown は新しい呼び出しの受け付けを止め、処理中の呼び出しの完了を待つ。
+// ctx の期限までに終わらない場合は接続を切断する。
+func (s *Server) Shutdown(ctx context.Context) error {
+	s.health.Shutdown()
+
+	done := make(chan struct{})
+	go func() {
+		s.grpc.GracefulStop()
+		close(done)
+	}()
+	select {
+	case <-done:
+		return nil
+	case <-ctx.Done():
+		s.grpc.Stop()
+		return ctx.Err()
+	}
+}
+
+// accessLog は呼び出しごとにメソッド、ステータス、処理時間を構造化ログとして出力する。
+// 呼び出し内のログにメソッドを含めるため、ロガーをコンテキストに格納する。
+func accessLog(ctx context.Context, req any, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (any, error) {
+	start := time.Now()
+	logger := logging.FromContext(ctx).With("grpcMethod", info.FullMethod)
+
+	resp, err := handler(logging.WithLogger(ctx, logger), req)
+
+	attrs := []any{
+		"code", status.Code(err).String(),
+		"latencyMs", float64(time.Since(start).Microseconds()) / 1000,
+	}
+	if p, ok := peer.FromContext(ctx); ok {
+		attrs = append(attrs, "remoteAddr", p.Addr.String())
+	}
+	logger.Info("gRPCリクエスト", attrs...)
+	return resp, err
+}
+
+// recoverPanic はハンドラーのパニックを Internal のエラーに変換し、サーバーを停止させない。
+func recoverPanic(ctx context.Context, req any, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (resp any, err error) {
+	defer func() {
+		if v := recover(); v != nil {
+			logging.FromContext(ctx).Error("gRPCハンドラーでパニックが発生しました", "panic", v)
+			err = status.Error(codes.Internal, "サーバーエラー")
+		}
+	}()
+	return handler(ctx, req)
+}
+
+// withTimeout は呼び出しのコンテキストに処理時間の上限を設定する。
+func withTimeout(timeout time.Duration) grpc.UnaryServerInterceptor {
+	return func(ctx context.Context, req any, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (any, error) {
+		ctx, cancel := context.WithTimeout(ctx, timeout)
+		defer cancel()
+		return handler(ctx, req)
+	}
+}
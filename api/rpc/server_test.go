@@ -0,0 +1,246 @@
+package rpc
+
+import (
+	"context"
+	"errors"
+	"net"
+	"sort"
+	"testing"
+	"time"
+
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/credentials/insecure"
+	healthpb "google.golang.org/grpc/health/grpc_health_v1"
+	reflectionpb "google.golang.org/grpc/reflection/grpc_reflection_v1"
+	"google.golang.org/grpc/status"
+	"google.golang.org/grpc/test/bufconn"
+
+	"github.com/h-hiwatashi/super-business-book-ranking-backend/affiliate"
+	"github.com/h-hiwatashi/super-business-book-ranking-backend/api/rpc/rankingv1"
+	"github.com/h-hiwatashi/super-business-book-ranking-backend/repository"
+)
+
+// fakeRankings は受け取った取得条件を記録する。
+type fakeRankings struct {
+	query repository.RankingQuery
+	err   error
+}
+
+func (f *fakeRankings) Find(ctx context.Context, q repository.RankingQuery) (*repository.Ranking, error) {
+	f.query = q
+	if f.err != nil {
+		return nil, f.err
+	}
+	return &repository.Ranking{
+		CategoryID:   q.CategoryID,
+		CategoryName: "ビジネス",
+		PeriodType:   q.PeriodType,
+		DateFrom:     "2024-03-15",
+		DateTo:       "2024-03-15",
+		Books: []repository.RankedBook{
+			{ID: "book-1", Rank: 1, Title: "イシューからはじめよ", Author: "安宅和人", ISBN: "9784862760852", Price: 1980,
+				Site: affiliate.SiteAmazon, URL: "https://www.amazon.co.jp/dp/4862760856", BookSiteMappingID: "map-1"},
+		},
+	}, nil
+}
+
+type fakeCategories struct{}
+
+func (fakeCategories) List(ctx context.Context) ([]repository.Category, error) {
+	business := "001"
+	return []repository.Category{
+		{ID: "001", Name: "ビジネス"},
+		{ID: "002", Name: "マーケティング", ParentID: &business},
+	}, nil
+}
+
+// newTestClient は bufconn で Server に接続したクライアントを返す。
+func newTestClient(t *testing.T, service *RankingService) *grpc.ClientConn {
+	t.Helper()
+	_, conn := newTestServer(t, service)
+	return conn
+}
+
+func newTestServer(t *testing.T, service *RankingService) (*Server, *grpc.ClientConn) {
+	t.Helper()
+	lis := bufconn.Listen(1 << 20)
+	srv := NewServer(service, time.Second)
+	go srv.Serve(lis)
+	t.Cleanup(func() { srv.Shutdown(context.Background()) })
+
+	conn, err := grpc.NewClient("passthrough:///bufnet",
+		grpc.WithContextDialer(func(ctx context.Context, _ string) (net.Conn, error) { return lis.DialContext(ctx) }),
+		grpc.WithTransportCredentials(insecure.NewCredentials()),
+	)
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { conn.Close() })
+	return srv, conn
+}
+
+func TestGetRanking(t *testing.T) {
+	rankings := &fakeRankings{}
+	service := NewRankingService(rankings, fakeCategories{})
+	service.Links = affiliate.NewLinker(map[string]string{affiliate.SiteAmazon: "bookranking-22"})
+	client := rankingv1.NewRankingServiceClient(newTestClient(t, service))
+
+	resp, err := client.GetRanking(context.Background(), &rankingv1.GetRankingRequest{
+		CategoryId: "001",
+		PeriodType: rankingv1.PeriodType_PERIOD_TYPE_WEEKLY,
+		Date:       "2024-03-15",
+		Page:       2,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	wantQuery := repository.RankingQuery{CategoryID: "001", PeriodType: "weekly", Date: "2024-03-15", Page: 2, Limit: 10}
+	if rankings.query != wantQuery {
+		t.Errorf("query = %+v, want %+v", rankings.query, wantQuery)
+	}
+	if resp.DateFrom != "2024-03-15" || resp.DateTo != "2024-03-15" {
+		t.Errorf("date = %s〜%s, want 2024-03-15", resp.DateFrom, resp.DateTo)
+	}
+	if len(resp.Books) != 1 {
+		t.Fatalf("len(books) = %d, want 1", len(resp.Books))
+	}
+	book := resp.Books[0]
+	if book.Url != "https://www.amazon.co.jp/dp/4862760856?tag=bookranking-22" {
+		t.Errorf("url = %q, want アフィリエイトリンク", book.Url)
+	}
+	if book.Rank != 1 || book.Isbn != "9784862760852" || book.BookSiteMappingId != "map-1" || book.Price != 1980 {
+		t.Errorf("book = %+v", book)
+	}
+}
+
+func TestGetRankingErrors(t *testing.T) {
+	testCases := []struct {
+		name     string
+		req      *rankingv1.GetRankingRequest
+		findErr  error
+		wantCode codes.Code
+	}{
+		{
+			name:     "異常系：カテゴリIDがない",
+			req:      &rankingv1.GetRankingRequest{},
+			wantCode: codes.InvalidArgument,
+		},
+		{
+			name:     "異常系：不正な日付",
+			req:      &rankingv1.GetRankingRequest{CategoryId: "001", Date: "2024/03/15"},
+			wantCode: codes.InvalidArgument,
+		},
+		{
+			name:     "異常系：取得数が上限を超える",
+			req:      &rankingv1.GetRankingRequest{CategoryId: "001", Limit: 101},
+			wantCode: codes.InvalidArgument,
+		},
+		{
+			name:     "異常系：不正な期間",
+			req:      &rankingv1.GetRankingRequest{CategoryId: "001", PeriodType: 9},
+			wantCode: codes.InvalidArgument,
+		},
+		{
+			name:     "異常系：データベースエラー",
+			req:      &rankingv1.GetRankingRequest{CategoryId: "001"},
+			findErr:  errors.New("接続エラー"),
+			wantCode: codes.Internal,
+		},
+		{
+			name:     "異常系：タイムアウト",
+			req:      &rankingv1.GetRankingRequest{CategoryId: "001"},
+			findErr:  context.DeadlineExceeded,
+			wantCode: codes.DeadlineExceeded,
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			service := NewRankingService(&fakeRankings{err: tc.findErr}, fakeCategories{})
+			client := rankingv1.NewRankingServiceClient(newTestClient(t, service))
+
+			_, err := client.GetRanking(context.Background(), tc.req)
+			if got := status.Code(err); got != tc.wantCode {
+				t.Errorf("code = %s, want %s (%v)", got, tc.wantCode, err)
+			}
+		})
+	}
+}
+
+func TestListCategories(t *testing.T) {
+	client := rankingv1.NewRankingServiceClient(newTestClient(t, NewRankingService(&fakeRankings{}, fakeCategories{})))
+
+	resp, err := client.ListCategories(context.Background(), &rankingv1.ListCategoriesRequest{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(resp.Categories) != 2 {
+		t.Fatalf("len(categories) = %d, want 2", len(resp.Categories))
+	}
+	if got := resp.Categories[0].ParentId; got != "" {
+		t.Errorf("最上位のカテゴリの parent_id = %q, want 空", got)
+	}
+	if got := resp.Categories[1].ParentId; got != "001" {
+		t.Errorf("parent_id = %q, want 001", got)
+	}
+}
+
+func TestServerHealthAndReflection(t *testing.T) {
+	conn := newTestClient(t, NewRankingService(&fakeRankings{}, fakeCategories{}))
+	ctx := context.Background()
+
+	health := healthpb.NewHealthClient(conn)
+	for _, service := range []string{"", "bookranking.v1.RankingService"} {
+		resp, err := health.Check(ctx, &healthpb.HealthCheckRequest{Service: service})
+		if err != nil {
+			t.Fatalf("Check(%q) error = %v", service, err)
+		}
+		if resp.Status != healthpb.HealthCheckResponse_SERVING {
+			t.Errorf("Check(%q) = %s, want SERVING", service, resp.Status)
+		}
+	}
+
+	stream, err := reflectionpb.NewServerReflectionClient(conn).ServerReflectionInfo(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := stream.Send(&reflectionpb.ServerReflectionRequest{
+		MessageRequest: &reflectionpb.ServerReflectionRequest_ListServices{},
+	}); err != nil {
+		t.Fatal(err)
+	}
+	resp, err := stream.Recv()
+	if err != nil {
+		t.Fatal(err)
+	}
+	var services []string
+	for _, s := range resp.GetListServicesResponse().GetService() {
+		services = append(services, s.Name)
+	}
+	sort.Strings(services)
+	want := []string{"bookranking.v1.RankingService", "grpc.health.v1.Health", "grpc.reflection.v1.ServerReflection", "grpc.reflection.v1alpha.ServerReflection"}
+	if len(services) != len(want) {
+		t.Fatalf("services = %v, want %v", services, want)
+	}
+	for i := range want {
+		if services[i] != want[i] {
+			t.Errorf("services = %v, want %v", services, want)
+			break
+		}
+	}
+}
+
+func TestServerShutdownHealth(t *testing.T) {
+	srv, conn := newTestServer(t, NewRankingService(&fakeRankings{}, fakeCategories{}))
+
+	srv.SetShuttingDown()
+	resp, err := healthpb.NewHealthClient(conn).Check(context.Background(), &healthpb.HealthCheckRequest{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if resp.Status != healthpb.HealthCheckResponse_NOT_SERVING {
+		t.Errorf("status = %s, want NOT_SERVING", resp.Status)
+	}
+}
@@ -0,0 +1,182 @@
+// Package rpc は社内サービス向けにランキングを gRPC で提供する。
+// REST API のハンドラーと同じリポジトリを使い、同じ内容を返す。
+package rpc
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+
+	"github.com/h-hiwatashi/super-business-book-ranking-backend/affiliate"
+	"github.com/h-hiwatashi/super-business-book-ranking-backend/api/rpc/rankingv1"
+	"github.com/h-hiwatashi/super-business-book-ranking-backend/breaker"
+	"github.com/h-hiwatashi/super-business-book-ranking-backend/logging"
+	"github.com/h-hiwatashi/super-business-book-ranking-backend/repository"
+)
+
+const (
+	// デフォルト取得数
+	defaultLimit = 10
+	// 取得数の上限
+	maxLimit = 100
+)
+
+// RankingFinder はランキングを取得するリポジトリである。
+type RankingFinder interface {
+	Find(ctx context.Context, q repository.RankingQuery) (*repository.Ranking, error)
+}
+
+// CategoryLister はカテゴリ一覧を取得するリポジトリである。
+type CategoryLister interface {
+	List(ctx context.Context) ([]repository.Category, error)
+}
+
+// periodTypes は期間の列挙値と repository の期間の種類の対応である。
+var periodTypes = map[rankingv1.PeriodType]string{
+	rankingv1.PeriodType_PERIOD_TYPE_UNSPECIFIED: "daily",
+	rankingv1.PeriodType_PERIOD_TYPE_DAILY:       "daily",
+	rankingv1.PeriodType_PERIOD_TYPE_WEEKLY:      "weekly",
+	rankingv1.PeriodType_PERIOD_TYPE_MONTHLY:     "monthly",
+	rankingv1.PeriodType_PERIOD_TYPE_YEARLY:      "yearly",
+}
+
+type RankingService struct {
+	rankingv1.UnimplementedRankingServiceServer
+
+	Rankings   RankingFinder
+	Categories CategoryLister
+	// Links が nil の場合は商品URLをそのまま返す。
+	Links affiliate.URLLinker
+}
+
+func NewRankingService(rankings RankingFinder, categories CategoryLister) *RankingService {
+	return &RankingService{
+		Rankings:   rankings,
+		Categories: categories,
+	}
+}
+
+// GetRanking はカテゴリのランキングを返す。
+func (s *RankingService) GetRanking(ctx context.Context, req *rankingv1.GetRankingRequest) (*rankingv1.Ranking, error) {
+	query, err := rankingQuery(req)
+	if err != nil {
+		return nil, status.Error(codes.InvalidArgument, err.Error())
+	}
+
+	ranking, err := s.Rankings.Find(ctx, query)
+	if err != nil {
+		return nil, statusError(ctx, err, "データベースクエリエラー", "categoryId", query.CategoryID)
+	}
+
+	books := make([]*rankingv1.RankedBook, len(ranking.Books))
+	for i, book := range ranking.Books {
+		url := affiliate.LinkURL(s.Links, book.Site, book.URL)
+		books[i] = &rankingv1.RankedBook{
+			Id:                book.ID,
+			Rank:              int32(book.Rank),
+			Title:             book.Title,
+			Author:            book.Author,
+			Publisher:         book.Publisher,
+			Isbn:              book.ISBN,
+			PublicationDate:   book.PublicationDate,
+			ImageUrl:          book.ImageURL,
+			Price:             book.Price,
+			Url:               url,
+			Site:              book.Site,
+			BookSiteMappingId: book.BookSiteMappingID,
+		}
+	}
+	return &rankingv1.Ranking{
+		CategoryId:   ranking.CategoryID,
+		CategoryName: ranking.CategoryName,
+		PeriodType:   ranking.PeriodType,
+		DateFrom:     ranking.DateFrom,
+		DateTo:       ranking.DateTo,
+		Books:        books,
+	}, nil
+}
+
+// ListCategories はすべてのカテゴリを返す。
+func (s *RankingService) ListCategories(ctx context.Context, req *rankingv1.ListCategoriesRequest) (*rankingv1.ListCategoriesResponse, error) {
+	categories, err := s.Categories.List(ctx)
+	if err != nil {
+		return nil, statusError(ctx, err, "データベースクエリエラー")
+	}
+
+	resp := &rankingv1.ListCategoriesResponse{
+		Categories: make([]*rankingv1.Category, len(categories)),
+	}
+	for i, category := range categories {
+		resp.Categories[i] = &rankingv1.Category{Id: category.ID, Name: category.Name}
+		if category.ParentID != nil {
+			resp.Categories[i].ParentId = *category.ParentID
+		}
+	}
+	return resp, nil
+}
+
+// rankingQuery はリクエストからランキングの取得条件を組み立てる。
+// 省略時の値と検証は REST API の /api/v1/rankings/{categoryId} に合わせる。
+func rankingQuery(req *rankingv1.GetRankingRequest) (repository.RankingQuery, error) {
+	query := repository.RankingQuery{
+		CategoryID: req.GetCategoryId(),
+		Date:       req.GetDate(),
+		Page:       1,
+		Limit:      defaultLimit,
+	}
+	if query.CategoryID == "" {
+		return query, errors.New("category_id を指定してください")
+	}
+
+	periodType, ok := periodTypes[req.GetPeriodType()]
+	if !ok {
+		return query, errors.New("period_type が不正です")
+	}
+	query.PeriodType = periodType
+
+	if query.Date != "" {
+		if _, err := time.Parse("2006-01-02", query.Date); err != nil {
+			return query, errors.New("date は YYYY-MM-DD 形式で指定してください")
+		}
+	}
+
+	switch page := req.GetPage(); {
+	case page < 0:
+		return query, errors.New("page は1以上の整数で指定してください")
+	case page > 0:
+		query.Page = int(page)
+	}
+	switch limit := req.GetLimit(); {
+	case limit < 0 || limit > maxLimit:
+		return query, errors.New("limit は1以上100以下で指定してください")
+	case limit > 0:
+		query.Limit = int(limit)
+	}
+
+	return query, nil
+}
+
+// statusError はエラーをログに記録し、対応する gRPC のステータスに変換する。
+// httperror.Write と同じく、タイムアウトとクライアントの切断、サーキットブレーカーは区別する。
+func statusError(ctx context.Context, err error, message string, attrs ...any) error {
+	logger := logging.FromContext(ctx)
+	attrs = append(attrs, "error", err)
+
+	switch {
+	case errors.Is(err, breaker.ErrOpen):
+		logger.Warn("外部サービスへの呼び出しを停止しています", attrs...)
+		return status.Error(codes.Unavailable, "外部サービスが一時的に利用できません")
+	case errors.Is(err, context.DeadlineExceeded):
+		logger.Warn("処理がタイムアウトしました", attrs...)
+		return status.Error(codes.DeadlineExceeded, "処理がタイムアウトしました")
+	case errors.Is(err, context.Canceled) && ctx.Err() != nil:
+		logger.Info("クライアントが切断しました", attrs...)
+		return status.Error(codes.Canceled, "クライアントが切断しました")
+	default:
+		logger.Error(message, attrs...)
+		return status.Error(codes.Internal, message)
+	}
+}
@@ -0,0 +1,189 @@
+// Package stream はランキングの更新を Server-Sent Events で配信する。
+package stream
+
+import (
+	"errors"
+	"sync"
+	"time"
+)
+
+// ErrTooManySubscribers は購読数が上限に達していることを表す。
+var ErrTooManySubscribers = errors.New("stream: 購読数が上限に達しています")
+
+// 購読者ごとに送信待ちにできるイベント数
+const subscriberBuffer = 16
+
+// Event はトピックに配信したイベントである。
+type Event struct {
+	// ID はブローカー内で単調に増加する。起動時刻から始めるため、再起動前のIDより大きくなる。
+	ID   uint64
+	Name string
+	Data []byte
+}
+
+// Broker はトピックごとにイベントを配信するプロセス内の pub/sub である。
+// 再接続した購読者が取りこぼしたイベントを受け取れるよう、トピックごとに直近のイベントを保持する。
+type Broker struct {
+	historySize    int
+	maxSubscribers int
+
+	mu          sync.Mutex
+	firstID     uint64
+	lastID      uint64
+	topics      map[string]*topic
+	subscribers int
+	closed      bool
+}
+
+type topic struct {
+	subscribers map[*Subscription]struct{}
+	history     []Event
+	// evicted は履歴から押し出した最新のイベントのIDである。
+	evicted uint64
+}
+
+// NewBroker はトピックごとに historySize 件のイベントを保持し、
+// 全体で maxSubscribers まで購読できる Broker を返す。
+func NewBroker(historySize, maxSubscribers int) *Broker {
+	first := uint64(time.Now().UnixMilli()) * 1000
+	return &Broker{
+		historySize:    historySize,
+		maxSubscribers: maxSubscribers,
+		firstID:        first,
+		lastID:         first,
+		topics:         make(map[string]*topic),
+	}
+}
+
+// Subscription はトピックの購読である。
+// 購読者の受信が追いつかない場合やブローカーが閉じられた場合は C が閉じられる。
+type Subscription struct {
+	C <-chan Event
+
+	c      chan Event
+	broker *Broker
+	topic  string
+}
+
+// Subscribe は name のトピックを購読する。
+// lastID が0でない場合は、それより後に配信したイベントを missed で返す。
+// 取りこぼしたイベントが履歴に残っていない場合は resumed が false になる。
+func (b *Broker) Subscribe(name string, lastID uint64) (sub *Subscription, missed []Event, resumed bool, err error) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	if b.closed {
+		return nil, nil, false, errors.New("stream: ブローカーは閉じられています")
+	}
+	if b.subscribers >= b.maxSubscribers {
+		return nil, nil, false, ErrTooManySubscribers
+	}
+
+	t := b.topic(name)
+	if lastID != 0 && lastID >= b.firstID && lastID <= b.lastID && lastID >= t.evicted {
+		resumed = true
+		for _, event := range t.history {
+			if event.ID > lastID {
+				missed = append(missed, event)
+			}
+		}
+	}
+
+	c := make(chan Event, subscriberBuffer)
+	sub = &Subscription{C: c, c: c, broker: b, topic: name}
+	t.subscribers[sub] = struct{}{}
+	b.subscribers++
+	return sub, missed, resumed, nil
+}
+
+// Close は購読をやめる。
+func (s *Subscription) Close() {
+	b := s.broker
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	b.remove(s)
+}
+
+// Publish は name のトピックにイベントを配信する。
+// 受信が追いつかない購読者は購読を終了させ、再接続して履歴から受け取らせる。
+func (b *Broker) Publish(name, eventName string, data []byte) Event {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	b.lastID++
+	event := Event{ID: b.lastID, Name: eventName, Data: data}
+
+	t := b.topic(name)
+	t.history = append(t.history, event)
+	if n := len(t.history) - b.historySize; n > 0 {
+		t.evicted = t.history[n-1].ID
+		t.history = append([]Event(nil), t.history[n:]...)
+	}
+
+	for sub := range t.subscribers {
+		select {
+		case sub.c <- event:
+		default:
+			b.remove(sub)
+		}
+	}
+	return event
+}
+
+// LastID は最後に配信したイベントのIDを返す。
+func (b *Broker) LastID() uint64 {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return b.lastID
+}
+
+// Subscribers は name のトピックの現在の購読者数を返す。
+func (b *Broker) Subscribers(name string) int {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	if t, ok := b.topics[name]; ok {
+		return len(t.subscribers)
+	}
+	return 0
+}
+
+// Watched は name のトピックが購読されたことがあるかを返す。
+// 購読者が再接続するまでの間のイベントも履歴に残すため、購読者がいなくなっても true を返す。
+func (b *Broker) Watched(name string) bool {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	_, ok := b.topics[name]
+	return ok
+}
+
+// Close はすべての購読を終了させ、以後の購読を受け付けない。
+// HTTP サーバーの停止がストリームの終了を待ち続けないよう、停止の前に呼ぶ。
+func (b *Broker) Close() {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	b.closed = true
+	for _, t := range b.topics {
+		for sub := range t.subscribers {
+			b.remove(sub)
+		}
+	}
+}
+
+func (b *Broker) topic(name string) *topic {
+	t, ok := b.topics[name]
+	if !ok {
+		t = &topic{subscribers: make(map[*Subscription]struct{})}
+		b.topics[name] = t
+	}
+	return t
+}
+
+// remove は購読を取り除いて C を閉じる。b.mu を保持して呼ぶ。
+func (b *Broker) remove(sub *Subscription) {
+	t := b.topics[sub.topic]
+	if _, ok := t.subscribers[sub]; !ok {
+		return
+	}
+	delete(t.subscribers, sub)
+	b.subscribers--
+	close(sub.c)
+}
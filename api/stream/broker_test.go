@@ -0,0 +1,124 @@
+package stream
+
+import (
+	"errors"
+	"testing"
+)
+
+func TestBrokerPublish(t *testing.T) {
+	b := NewBroker(10, 10)
+	sub, _, _, err := b.Subscribe("001:daily", 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	other, _, _, err := b.Subscribe("002:daily", 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	event := b.Publish("001:daily", EventUpdate, []byte(`{}`))
+	if got := <-sub.C; got.ID != event.ID || got.Name != EventUpdate {
+		t.Errorf("event = %+v, want %+v", got, event)
+	}
+	select {
+	case got := <-other.C:
+		t.Errorf("別のトピックにイベントが届きました: %+v", got)
+	default:
+	}
+
+	if next := b.Publish("001:daily", EventUpdate, nil); next.ID <= event.ID {
+		t.Errorf("ID = %d, want > %d", next.ID, event.ID)
+	}
+}
+
+func TestBrokerResume(t *testing.T) {
+	b := NewBroker(2, 10)
+	if b.Watched("001:daily") {
+		t.Fatal("Watched() = true before subscribe")
+	}
+	sub, _, _, _ := b.Subscribe("001:daily", 0)
+	sub.Close()
+	if !b.Watched("001:daily") {
+		t.Fatal("購読をやめた後も履歴を残すトピックとして扱う")
+	}
+
+	e1 := b.Publish("001:daily", EventUpdate, []byte("1"))
+	e2 := b.Publish("001:daily", EventUpdate, []byte("2"))
+	e3 := b.Publish("001:daily", EventUpdate, []byte("3"))
+
+	testCases := []struct {
+		name        string
+		lastID      uint64
+		wantResumed bool
+		wantMissed  []uint64
+	}{
+		{name: "正常系：履歴に残るイベントから再開", lastID: e1.ID, wantResumed: true, wantMissed: []uint64{e2.ID, e3.ID}},
+		{name: "正常系：取りこぼしなし", lastID: e3.ID, wantResumed: true},
+		{name: "異常系：履歴から押し出されたイベント", lastID: e1.ID - 1},
+		{name: "異常系：再起動前のID", lastID: 1},
+		{name: "異常系：未配信のID", lastID: e3.ID + 1},
+		{name: "正常系：指定なし", lastID: 0},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			sub, missed, resumed, err := b.Subscribe("001:daily", tc.lastID)
+			if err != nil {
+				t.Fatal(err)
+			}
+			defer sub.Close()
+
+			if resumed != tc.wantResumed {
+				t.Errorf("resumed = %v, want %v", resumed, tc.wantResumed)
+			}
+			var ids []uint64
+			for _, event := range missed {
+				ids = append(ids, event.ID)
+			}
+			if len(ids) != len(tc.wantMissed) {
+				t.Fatalf("missed = %v, want %v", ids, tc.wantMissed)
+			}
+			for i := range ids {
+				if ids[i] != tc.wantMissed[i] {
+					t.Errorf("missed = %v, want %v", ids, tc.wantMissed)
+				}
+			}
+		})
+	}
+}
+
+func TestBrokerSlowSubscriber(t *testing.T) {
+	b := NewBroker(100, 10)
+	sub, _, _, _ := b.Subscribe("001:daily", 0)
+
+	for i := 0; i < subscriberBuffer+1; i++ {
+		b.Publish("001:daily", EventUpdate, nil)
+	}
+	for i := 0; i < subscriberBuffer; i++ {
+		<-sub.C
+	}
+	if _, ok := <-sub.C; ok {
+		t.Error("受信が追いつかない購読が終了していません")
+	}
+	// 終了済みの購読を閉じても問題ない
+	sub.Close()
+}
+
+func TestBrokerLimitAndClose(t *testing.T) {
+	b := NewBroker(10, 1)
+	sub, _, _, err := b.Subscribe("001:daily", 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, _, _, err := b.Subscribe("002:daily", 0); !errors.Is(err, ErrTooManySubscribers) {
+		t.Errorf("err = %v, want %v", err, ErrTooManySubscribers)
+	}
+
+	b.Close()
+	if _, ok := <-sub.C; ok {
+		t.Error("Close() 後も購読が続いています")
+	}
+	if _, _, _, err := b.Subscribe("001:daily", 0); err == nil {
+		t.Error("Close() 後に購読できました")
+	}
+}
@@ -0,0 +1,82 @@
+package stream
+
+import "github.com/h-hiwatashi/super-business-book-ranking-backend/repository"
+
+// Update は update イベントの本文で、前回配信したランキングからの変化である。
+type Update struct {
+	CategoryID string `json:"categoryId"`
+	PeriodType string `json:"periodType"`
+	DateFrom   string `json:"dateFrom"`
+	DateTo     string `json:"dateTo"`
+	// Added は新たにランキングに入った書籍である。
+	Added []repository.RankedBook `json:"added"`
+	// Removed はランキングから外れた書籍である。Rank は0になる。
+	Removed []Change `json:"removed"`
+	// Moved は順位が変わった書籍である。
+	Moved []Change `json:"moved"`
+}
+
+// Change は書籍の順位の変化である。
+type Change struct {
+	ID           string `json:"id"`
+	Title        string `json:"title"`
+	Rank         int    `json:"rank"`
+	PreviousRank int    `json:"previousRank"`
+}
+
+// Empty は書籍の出入りも順位の変化もないかを返す。
+func (u *Update) Empty() bool {
+	return len(u.Added) == 0 && len(u.Removed) == 0 && len(u.Moved) == 0
+}
+
+// diff は previous から current への変化を返す。
+// 同じ書籍が複数のECサイトで載っている場合は、上位の方の順位で比べる。
+func diff(previous, current *repository.Ranking) *Update {
+	update := &Update{
+		CategoryID: current.CategoryID,
+		PeriodType: current.PeriodType,
+		DateFrom:   current.DateFrom,
+		DateTo:     current.DateTo,
+		Added:      []repository.RankedBook{},
+		Removed:    []Change{},
+		Moved:      []Change{},
+	}
+
+	before := uniqueBooks(previous.Books)
+	after := uniqueBooks(current.Books)
+	previousRanks := make(map[string]int, len(before))
+	for _, book := range before {
+		previousRanks[book.ID] = book.Rank
+	}
+	currentIDs := make(map[string]bool, len(after))
+
+	for _, book := range after {
+		currentIDs[book.ID] = true
+		previousRank, ok := previousRanks[book.ID]
+		switch {
+		case !ok:
+			update.Added = append(update.Added, book)
+		case previousRank != book.Rank:
+			update.Moved = append(update.Moved, Change{ID: book.ID, Title: book.Title, Rank: book.Rank, PreviousRank: previousRank})
+		}
+	}
+	for _, book := range before {
+		if !currentIDs[book.ID] {
+			update.Removed = append(update.Removed, Change{ID: book.ID, Title: book.Title, PreviousRank: book.Rank})
+		}
+	}
+	return update
+}
+
+// uniqueBooks は書籍IDごとに最初（最上位）の1件を残す。
+func uniqueBooks(books []repository.RankedBook) []repository.RankedBook {
+	seen := make(map[string]bool, len(books))
+	unique := make([]repository.RankedBook, 0, len(books))
+	for _, book := range books {
+		if !seen[book.ID] {
+			seen[book.ID] = true
+			unique = append(unique, book)
+		}
+	}
+	return unique
+}
@@ -0,0 +1,32 @@
+package stream
+
+import (
+	"testing"
+
+	"github.com/h-hiwatashi/super-business-book-ranking-backend/repository"
+)
+
+func TestDiff(t *testing.T) {
+	previous := &repository.Ranking{Books: []repository.RankedBook{book(1, "a"), book(2, "b"), book(3, "c"), book(4, "a")}}
+	current := &repository.Ranking{Books: []repository.RankedBook{book(1, "b"), book(2, "a"), book(3, "d"), book(4, "b")}}
+
+	update := diff(previous, current)
+	if len(update.Added) != 1 || update.Added[0].ID != "d" {
+		t.Errorf("added = %+v, want d", update.Added)
+	}
+	wantRemoved := []Change{{ID: "c", Title: "書籍c", PreviousRank: 3}}
+	if len(update.Removed) != 1 || update.Removed[0] != wantRemoved[0] {
+		t.Errorf("removed = %+v, want %+v", update.Removed, wantRemoved)
+	}
+	wantMoved := []Change{
+		{ID: "b", Title: "書籍b", Rank: 1, PreviousRank: 2},
+		{ID: "a", Title: "書籍a", Rank: 2, PreviousRank: 1},
+	}
+	if len(update.Moved) != 2 || update.Moved[0] != wantMoved[0] || update.Moved[1] != wantMoved[1] {
+		t.Errorf("moved = %+v, want %+v", update.Moved, wantMoved)
+	}
+
+	if !diff(current, current).Empty() {
+		t.Error("同じランキングの差分が空ではありません")
+	}
+}
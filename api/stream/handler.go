@@ -0,0 +1,294 @@
+package stream
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/gorilla/mux"
+
+	"github.com/h-hiwatashi/super-business-book-ranking-backend/affiliate"
+	"github.com/h-hiwatashi/super-business-book-ranking-backend/api/httperror"
+	"github.com/h-hiwatashi/super-business-book-ranking-backend/logging"
+	"github.com/h-hiwatashi/super-business-book-ranking-backend/repository"
+)
+
+const (
+	// 差分を比べる上位の書籍数
+	compareLimit = 100
+	// 切断後に再接続するまでの待ち時間としてクライアントに伝える値
+	retryDelay = 5 * time.Second
+)
+
+// イベント名
+const (
+	// EventSnapshot は接続時または履歴から再開できない場合に送る、現在のランキング全体である。
+	EventSnapshot = "snapshot"
+	// EventUpdate は新しいスナップショットを取り込んだときに送る、前回からの差分である。
+	EventUpdate = "update"
+)
+
+var validPeriods = map[string]bool{"daily": true, "weekly": true, "monthly": true, "yearly": true}
+
+// RankingFinder はランキングを取得するリポジトリである。
+type RankingFinder interface {
+	Find(ctx context.Context, q repository.RankingQuery) (*repository.Ranking, error)
+}
+
+// CategoryLister はカテゴリ一覧を取得するリポジトリである。
+type CategoryLister interface {
+	List(ctx context.Context) ([]repository.Category, error)
+}
+
+type StreamHandler struct {
+	Rankings RankingFinder
+	// Categories は存在しないカテゴリのトピックを作らないよう、購読の前に確かめるために使う。
+	Categories CategoryLister
+	Broker     *Broker
+	// Links が nil の場合は商品URLをそのまま返す。
+	Links affiliate.URLLinker
+	// Heartbeat はプロキシに切断されないよう、イベントがなくてもコメントを送る間隔である。
+	Heartbeat time.Duration
+
+	// mu はスナップショットの通知を直列にし、トピックごとの前回のランキングを守る。
+	// 前回のランキングは購読者のいるトピックだけが持ち、最後の購読者が切断すると捨てる。
+	mu   sync.Mutex
+	last map[string]*repository.Ranking
+}
+
+func NewStreamHandler(rankings RankingFinder, categories CategoryLister, broker *Broker, heartbeat time.Duration) *StreamHandler {
+	return &StreamHandler{
+		Rankings:   rankings,
+		Categories: categories,
+		Broker:     broker,
+		Heartbeat:  heartbeat,
+		last:       make(map[string]*repository.Ranking),
+	}
+}
+
+// ランキング更新のストリームハンドラー
+// Last-Event-ID（EventSource の再接続時に送られる）があれば取りこぼしたイベントから再開し、
+// 再開できない場合は現在のランキングを snapshot イベントで送り直す。
+func (h *StreamHandler) RankingStreamHandler(w http.ResponseWriter, r *http.Request) {
+	categoryID := mux.Vars(r)["categoryId"]
+	periodType := r.URL.Query().Get("period")
+	if periodType == "" {
+		periodType = "daily" // デフォルト値
+	}
+	if !validPeriods[periodType] {
+		http.Error(w, "period は daily、weekly、monthly、yearly のいずれかを指定してください", http.StatusBadRequest)
+		return
+	}
+	exists, err := h.categoryExists(r.Context(), categoryID)
+	if err != nil {
+		httperror.Write(w, r, err, "データベースクエリエラー", "categoryId", categoryID)
+		return
+	}
+	if !exists {
+		http.Error(w, "カテゴリが見つかりません", http.StatusNotFound)
+		return
+	}
+	lastID := lastEventID(r)
+	name := topicName(categoryID, periodType)
+
+	sub, missed, resumed, err := h.Broker.Subscribe(name, lastID)
+	if err != nil {
+		w.Header().Set("Retry-After", strconv.Itoa(int(retryDelay.Seconds())))
+		http.Error(w, "ストリームの接続数が上限に達しています", http.StatusServiceUnavailable)
+		return
+	}
+	defer h.unsubscribe(name, sub)
+
+	// 購読後に配信され、スナップショットに反映済みのイベントは送らない
+	var snapshot *Event
+	skipUntil := uint64(0)
+	if !resumed {
+		skipUntil = h.Broker.LastID()
+		ranking, err := h.Rankings.Find(r.Context(), repository.RankingQuery{
+			CategoryID: categoryID,
+			PeriodType: periodType,
+			Page:       1,
+			Limit:      compareLimit,
+		})
+		if err != nil {
+			httperror.Write(w, r, err, "データベースクエリエラー", "categoryId", categoryID)
+			return
+		}
+		h.seed(name, ranking)
+		data, err := json.Marshal(affiliate.LinkRanking(h.Links, ranking))
+		if err != nil {
+			httperror.Write(w, r, err, "ランキングの変換エラー", "categoryId", categoryID)
+			return
+		}
+		snapshot = &Event{ID: skipUntil, Name: EventSnapshot, Data: data}
+	}
+
+	rc := http.NewResponseController(w)
+	// サーバーの WriteTimeout で接続が切られないよう、書き込み期限を解除する
+	if err := rc.SetWriteDeadline(time.Time{}); err != nil && !errors.Is(err, http.ErrNotSupported) {
+		httperror.Write(w, r, err, "ストリームの開始エラー")
+		return
+	}
+	w.Header().Set("Content-Type", "text/event-stream")
+	w.Header().Set("Cache-Control", "no-store")
+	// nginx がレスポンスをバッファリングしてイベントが遅れないようにする
+	w.Header().Set("X-Accel-Buffering", "no")
+	w.WriteHeader(http.StatusOK)
+
+	fmt.Fprintf(w, "retry: %d\n\n", retryDelay.Milliseconds())
+	if snapshot != nil {
+		writeEvent(w, *snapshot)
+	}
+	for _, event := range missed {
+		writeEvent(w, event)
+	}
+	if err := flush(rc); err != nil {
+		return
+	}
+
+	heartbeat := time.NewTicker(h.Heartbeat)
+	defer heartbeat.Stop()
+	for {
+		select {
+		case <-r.Context().Done():
+			return
+		case event, ok := <-sub.C:
+			if !ok {
+				// 受信が追いつかないか、サーバーが停止する。クライアントは Last-Event-ID で再接続する
+				return
+			}
+			if event.ID <= skipUntil {
+				continue
+			}
+			writeEvent(w, event)
+		case <-heartbeat.C:
+			io.WriteString(w, ": heartbeat\n\n")
+		}
+		if err := flush(rc); err != nil {
+			return
+		}
+	}
+}
+
+// SnapshotSaved は購読されたことのあるカテゴリ・期間のスナップショットが保存されると、
+// 前回配信したランキングとの差分を update イベントとして配信する。
+// 購読者がいなくなり前回のランキングを捨てていた場合は、ランキング全体を snapshot イベントとして配信する。
+// キャッシュを破棄した後に最新のランキングを読むよう、キャッシュの Listener の後に登録する。
+func (h *StreamHandler) SnapshotSaved(ctx context.Context, snapshot repository.Snapshot) {
+	name := topicName(snapshot.CategoryID, snapshot.PeriodType)
+	if !h.Broker.Watched(name) {
+		return
+	}
+
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	current, err := h.Rankings.Find(ctx, repository.RankingQuery{
+		CategoryID: snapshot.CategoryID,
+		PeriodType: snapshot.PeriodType,
+		Page:       1,
+		Limit:      compareLimit,
+	})
+	if err != nil {
+		logging.FromContext(ctx).Error("ストリームのランキング取得エラー",
+			"categoryId", snapshot.CategoryID, "periodType", snapshot.PeriodType, "error", err)
+		return
+	}
+	previous, ok := h.last[name]
+	h.last[name] = current
+	if !ok {
+		// 差分の基準がないため、再接続した購読者が履歴から受け取れるようランキング全体を配信する
+		data, err := json.Marshal(affiliate.LinkRanking(h.Links, current))
+		if err != nil {
+			logging.FromContext(ctx).Error("ランキングの変換エラー", "categoryId", snapshot.CategoryID, "error", err)
+			return
+		}
+		h.Broker.Publish(name, EventSnapshot, data)
+		return
+	}
+
+	update := diff(previous, current)
+	if update.Empty() && previous.DateTo == current.DateTo {
+		return
+	}
+	for i, book := range update.Added {
+		update.Added[i].URL = affiliate.LinkURL(h.Links, book.Site, book.URL)
+	}
+	data, err := json.Marshal(update)
+	if err != nil {
+		logging.FromContext(ctx).Error("ランキングの差分の変換エラー", "categoryId", snapshot.CategoryID, "error", err)
+		return
+	}
+	h.Broker.Publish(name, EventUpdate, data)
+}
+
+// seed は差分の基準になる前回のランキングがない場合に ranking を設定する。
+func (h *StreamHandler) seed(name string, ranking *repository.Ranking) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	if _, ok := h.last[name]; !ok {
+		h.last[name] = ranking
+	}
+}
+
+// unsubscribe は購読をやめ、トピックの購読者がいなくなった場合は前回のランキングを捨てる。
+func (h *StreamHandler) unsubscribe(name string, sub *Subscription) {
+	sub.Close()
+
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	if h.Broker.Subscribers(name) == 0 {
+		delete(h.last, name)
+	}
+}
+
+// categoryExists は categoryID のカテゴリが存在するかを返す。
+func (h *StreamHandler) categoryExists(ctx context.Context, categoryID string) (bool, error) {
+	categories, err := h.Categories.List(ctx)
+	if err != nil {
+		return false, err
+	}
+	for _, category := range categories {
+		if category.ID == categoryID {
+			return true, nil
+		}
+	}
+	return false, nil
+}
+
+func topicName(categoryID, periodType string) string {
+	return categoryID + ":" + periodType
+}
+
+// lastEventID は Last-Event-ID ヘッダーを返す。
+// ヘッダーを送れないクライアントのため、lastEventId クエリパラメータも受け付ける。
+// 不正な値は指定がないものとして扱う。
+func lastEventID(r *http.Request) uint64 {
+	value := r.Header.Get("Last-Event-ID")
+	if value == "" {
+		value = r.URL.Query().Get("lastEventId")
+	}
+	id, err := strconv.ParseUint(value, 10, 64)
+	if err != nil {
+		return 0
+	}
+	return id
+}
+
+// writeEvent はイベントを text/event-stream の形式で書き込む。本文は改行を含まない JSON である。
+func writeEvent(w io.Writer, event Event) {
+	fmt.Fprintf(w, "id: %d\nevent: %s\ndata: %s\n\n", event.ID, event.Name, event.Data)
+}
+
+func flush(rc *http.ResponseController) error {
+	if err := rc.Flush(); err != nil && !errors.Is(err, http.ErrNotSupported) {
+		return err
+	}
+	return nil
+}
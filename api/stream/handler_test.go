@@ -0,0 +1,300 @@
+package stream
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/gorilla/mux"
+
+	"github.com/h-hiwatashi/super-business-book-ranking-backend/affiliate"
+	"github.com/h-hiwatashi/super-business-book-ranking-backend/repository"
+)
+
+// fakeRankings は差し替えられる現在のランキングを返す。
+type fakeRankings struct {
+	mu      sync.Mutex
+	ranking *repository.Ranking
+}
+
+func (f *fakeRankings) Find(ctx context.Context, q repository.RankingQuery) (*repository.Ranking, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	if q.CategoryID != "001" {
+		return &repository.Ranking{}, nil
+	}
+	return f.ranking, nil
+}
+
+func (f *fakeRankings) set(books ...repository.RankedBook) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.ranking = &repository.Ranking{CategoryID: "001", PeriodType: "daily", DateTo: "2024-03-15", Books: books}
+}
+
+// fakeCategories は固定のカテゴリ一覧を返す。
+type fakeCategories struct{}
+
+func (fakeCategories) List(ctx context.Context) ([]repository.Category, error) {
+	return []repository.Category{{ID: "001", Name: "ビジネス書"}, {ID: "002", Name: "自己啓発"}}, nil
+}
+
+func book(rank int, id string) repository.RankedBook {
+	return repository.RankedBook{Rank: rank, ID: id, Title: "書籍" + id, Site: affiliate.SiteAmazon, URL: "https://www.amazon.co.jp/dp/" + id}
+}
+
+// sseEvent は受信した1件のイベントである。
+type sseEvent struct {
+	id, name, data string
+}
+
+// sseReader は text/event-stream を1イベントずつ読む。コメントは読み飛ばす。
+type sseReader struct {
+	scanner  *bufio.Scanner
+	comments int
+}
+
+func (r *sseReader) next(t *testing.T) sseEvent {
+	t.Helper()
+	var event sseEvent
+	for r.scanner.Scan() {
+		line := r.scanner.Text()
+		switch {
+		case line == "":
+			if event.name != "" {
+				return event
+			}
+		case strings.HasPrefix(line, ":"):
+			r.comments++
+		case strings.HasPrefix(line, "id: "):
+			event.id = strings.TrimPrefix(line, "id: ")
+		case strings.HasPrefix(line, "event: "):
+			event.name = strings.TrimPrefix(line, "event: ")
+		case strings.HasPrefix(line, "data: "):
+			event.data = strings.TrimPrefix(line, "data: ")
+		}
+	}
+	t.Fatalf("ストリームが終了しました: %v", r.scanner.Err())
+	return event
+}
+
+func connect(t *testing.T, url, lastEventID string) *sseReader {
+	t.Helper()
+	req, err := http.NewRequest("GET", url, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if lastEventID != "" {
+		req.Header.Set("Last-Event-ID", lastEventID)
+	}
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { resp.Body.Close() })
+	if resp.StatusCode != http.StatusOK {
+		t.Fatalf("status = %d, want %d", resp.StatusCode, http.StatusOK)
+	}
+	if got := resp.Header.Get("Content-Type"); got != "text/event-stream" {
+		t.Errorf("Content-Type = %q, want text/event-stream", got)
+	}
+	return &sseReader{scanner: bufio.NewScanner(resp.Body)}
+}
+
+func newTestServer(t *testing.T, h *StreamHandler) *httptest.Server {
+	t.Helper()
+	router := mux.NewRouter()
+	router.HandleFunc("/api/rankings/{categoryId}/stream", h.RankingStreamHandler).Methods("GET")
+	srv := httptest.NewServer(router)
+	t.Cleanup(func() {
+		h.Broker.Close()
+		srv.Close()
+	})
+	return srv
+}
+
+func TestRankingStreamHandler(t *testing.T) {
+	rankings := &fakeRankings{}
+	rankings.set(book(1, "a"), book(2, "b"))
+	h := NewStreamHandler(rankings, fakeCategories{}, NewBroker(10, 10), 20*time.Millisecond)
+	h.Links = affiliate.NewLinker(map[string]string{affiliate.SiteAmazon: "bookranking-22"})
+	srv := newTestServer(t, h)
+
+	stream := connect(t, srv.URL+"/api/rankings/001/stream", "")
+
+	snapshot := stream.next(t)
+	if snapshot.name != EventSnapshot {
+		t.Fatalf("event = %q, want %q", snapshot.name, EventSnapshot)
+	}
+	var ranking repository.Ranking
+	if err := json.Unmarshal([]byte(snapshot.data), &ranking); err != nil {
+		t.Fatal(err)
+	}
+	if len(ranking.Books) != 2 || !strings.HasSuffix(ranking.Books[0].URL, "?tag=bookranking-22") {
+		t.Errorf("snapshot = %+v", ranking)
+	}
+
+	// 別のカテゴリ・期間の取り込みは配信しない
+	h.SnapshotSaved(context.Background(), repository.Snapshot{CategoryID: "001", PeriodType: "weekly"})
+
+	rankings.set(book(1, "c"), book(2, "a"))
+	h.SnapshotSaved(context.Background(), repository.Snapshot{CategoryID: "001", PeriodType: "daily"})
+
+	event := stream.next(t)
+	if event.name != EventUpdate {
+		t.Fatalf("event = %q, want %q", event.name, EventUpdate)
+	}
+	var update Update
+	if err := json.Unmarshal([]byte(event.data), &update); err != nil {
+		t.Fatal(err)
+	}
+	if len(update.Added) != 1 || update.Added[0].ID != "c" || !strings.HasSuffix(update.Added[0].URL, "?tag=bookranking-22") {
+		t.Errorf("added = %+v", update.Added)
+	}
+	if len(update.Removed) != 1 || update.Removed[0].ID != "b" {
+		t.Errorf("removed = %+v", update.Removed)
+	}
+	if len(update.Moved) != 1 || update.Moved[0] != (Change{ID: "a", Title: "書籍a", Rank: 2, PreviousRank: 1}) {
+		t.Errorf("moved = %+v", update.Moved)
+	}
+	if mustParse(t, event.id) <= mustParse(t, snapshot.id) {
+		t.Errorf("update の ID %s が snapshot の ID %s 以下です", event.id, snapshot.id)
+	}
+
+	// 変化がない取り込みは配信せず、ハートビートだけが届く
+	h.SnapshotSaved(context.Background(), repository.Snapshot{CategoryID: "001", PeriodType: "daily"})
+	rankings.set(book(1, "a"), book(2, "c"))
+	time.Sleep(50 * time.Millisecond)
+	h.SnapshotSaved(context.Background(), repository.Snapshot{CategoryID: "001", PeriodType: "daily"})
+	if next := stream.next(t); next.name != EventUpdate || !strings.Contains(next.data, `"moved":[{"id":"a"`) {
+		t.Errorf("event = %+v", next)
+	}
+	if stream.comments == 0 {
+		t.Error("ハートビートが届いていません")
+	}
+}
+
+func TestRankingStreamHandlerResume(t *testing.T) {
+	rankings := &fakeRankings{}
+	rankings.set(book(1, "a"))
+	h := NewStreamHandler(rankings, fakeCategories{}, NewBroker(10, 10), time.Minute)
+	srv := newTestServer(t, h)
+
+	first := connect(t, srv.URL+"/api/rankings/001/stream", "")
+	snapshot := first.next(t)
+
+	// 切断中の取り込みは、再接続時に Last-Event-ID より後のものだけを送る
+	rankings.set(book(1, "b"))
+	h.SnapshotSaved(context.Background(), repository.Snapshot{CategoryID: "001", PeriodType: "daily"})
+	missed := first.next(t)
+	rankings.set(book(1, "c"))
+	h.SnapshotSaved(context.Background(), repository.Snapshot{CategoryID: "001", PeriodType: "daily"})
+
+	resumed := connect(t, srv.URL+"/api/rankings/001/stream", missed.id)
+	if event := resumed.next(t); event.name != EventUpdate || !strings.Contains(event.data, `"added":[{"id":"c"`) {
+		t.Errorf("event = %+v, want c の update", event)
+	}
+
+	// 再開できない ID の場合は snapshot から送り直す
+	restarted := connect(t, srv.URL+"/api/rankings/001/stream", "1")
+	if event := restarted.next(t); event.name != EventSnapshot || mustParse(t, event.id) <= mustParse(t, snapshot.id) {
+		t.Errorf("event = %+v, want 最新の snapshot", event)
+	}
+}
+
+func TestRankingStreamHandlerErrors(t *testing.T) {
+	testCases := []struct {
+		name       string
+		path       string
+		subscribed int
+		wantStatus int
+	}{
+		{name: "異常系：不正な期間", path: "/api/rankings/001/stream?period=hourly", wantStatus: http.StatusBadRequest},
+		{name: "異常系：接続数の上限", path: "/api/rankings/001/stream", subscribed: 1, wantStatus: http.StatusServiceUnavailable},
+		{name: "異常系：存在しないカテゴリ", path: "/api/rankings/999/stream", wantStatus: http.StatusNotFound},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			rankings := &fakeRankings{}
+			rankings.set(book(1, "a"))
+			broker := NewBroker(10, 1)
+			for i := 0; i < tc.subscribed; i++ {
+				if _, _, _, err := broker.Subscribe("002:daily", 0); err != nil {
+					t.Fatal(err)
+				}
+			}
+			h := NewStreamHandler(rankings, fakeCategories{}, broker, time.Minute)
+
+			router := mux.NewRouter()
+			router.HandleFunc("/api/rankings/{categoryId}/stream", h.RankingStreamHandler)
+			rec := httptest.NewRecorder()
+			router.ServeHTTP(rec, httptest.NewRequest("GET", tc.path, nil))
+
+			if rec.Code != tc.wantStatus {
+				t.Errorf("status = %d, want %d", rec.Code, tc.wantStatus)
+			}
+			if broker.Watched("999:daily") {
+				t.Error("存在しないカテゴリのトピックが作られました")
+			}
+		})
+	}
+}
+
+func TestRankingStreamHandlerReleasesTopic(t *testing.T) {
+	rankings := &fakeRankings{}
+	rankings.set(book(1, "a"))
+	h := NewStreamHandler(rankings, fakeCategories{}, NewBroker(10, 10), time.Minute)
+	srv := newTestServer(t, h)
+
+	req, err := http.NewRequest("GET", srv.URL+"/api/rankings/001/stream", nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		t.Fatal(err)
+	}
+	stream := &sseReader{scanner: bufio.NewScanner(resp.Body)}
+	snapshot := stream.next(t)
+	resp.Body.Close()
+
+	// 最後の購読者が切断すると前回のランキングを捨てる
+	deadline := time.Now().Add(5 * time.Second)
+	for {
+		h.mu.Lock()
+		n := len(h.last)
+		h.mu.Unlock()
+		if n == 0 {
+			break
+		}
+		if time.Now().After(deadline) {
+			t.Fatalf("切断後も前回のランキングが %d 件残っています", n)
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+
+	// 差分を作れない取り込みは snapshot として配信し、再接続した購読者が履歴から受け取る
+	rankings.set(book(1, "b"))
+	h.SnapshotSaved(context.Background(), repository.Snapshot{CategoryID: "001", PeriodType: "daily"})
+	resumed := connect(t, srv.URL+"/api/rankings/001/stream", snapshot.id)
+	if event := resumed.next(t); event.name != EventSnapshot || !strings.Contains(event.data, `"id":"b"`) {
+		t.Errorf("event = %+v, want b の snapshot", event)
+	}
+}
+
+func mustParse(t *testing.T, id string) uint64 {
+	t.Helper()
+	n, err := strconv.ParseUint(id, 10, 64)
+	if err != nil {
+		t.Fatalf("不正なイベントID %q", id)
+	}
+	return n
+}
@@ -0,0 +1,194 @@
+// Package auth はAPIキーによる認証と、ロール・スコープによる認可を提供する。
+package auth
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/sha256"
+	"database/sql"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/h-hiwatashi/super-business-book-ranking-backend/cache"
+	"github.com/h-hiwatashi/super-business-book-ranking-backend/repository"
+)
+
+// ErrInvalidKey はAPIキーが存在しないか、失効していることを表す。
+var ErrInvalidKey = errors.New("APIキーが無効です")
+
+// Role はAPIキーの利用者の種類である。
+type Role string
+
+const (
+	// RolePublic は一般公開APIのみを利用する。
+	RolePublic Role = "public"
+	// RolePartner は提携先で、緩いレート制限などの優遇を受ける。
+	RolePartner Role = "partner"
+	// RoleAdmin は管理APIを利用できる。
+	RoleAdmin Role = "admin"
+)
+
+// ParseRole は文字列をロールに変換する。
+func ParseRole(s string) (Role, error) {
+	switch role := Role(s); role {
+	case RolePublic, RolePartner, RoleAdmin:
+		return role, nil
+	default:
+		return "", fmt.Errorf("不正なロールです: %q", s)
+	}
+}
+
+// スコープは操作ごとの権限である。管理APIはロールに加えてスコープでも制限する。
+const (
+	// ScopeIngestRun はランキング取り込みの実行を許可する。
+	ScopeIngestRun = "ingest:run"
+	// ScopeKeysManage はAPIキーの発行と失効を許可する。
+	ScopeKeysManage = "keys:manage"
+	// ScopeClicksRead はクリック集計の参照を許可する。
+	ScopeClicksRead = "clicks:read"
+	// ScopeWebhooksManage は Webhook の登録・無効化と配信ログの参照、再配信を許可する。
+	ScopeWebhooksManage = "webhooks:manage"
+	// ScopeMetricsRead は /metrics の参照を許可する。
+	ScopeMetricsRead = "metrics:read"
+)
+
+// KnownScopes は発行時に指定できるスコープの一覧である。
+var KnownScopes = []string{ScopeIngestRun, ScopeKeysManage, ScopeClicksRead, ScopeWebhooksManage, ScopeMetricsRead}
+
+// ValidateScopes は scopes がすべて既知のスコープかを検証する。
+func ValidateScopes(scopes []string) error {
+	for _, scope := range scopes {
+		if !slices.Contains(KnownScopes, scope) {
+			return fmt.Errorf("不正なスコープです: %q", scope)
+		}
+	}
+	return nil
+}
+
+// Principal は認証されたAPIキーの利用者である。
+type Principal struct {
+	KeyID  string
+	Name   string
+	Role   Role
+	Scopes []string
+}
+
+// HasScope は scope が許可されているかを返す。
+func (p *Principal) HasScope(scope string) bool {
+	return p != nil && slices.Contains(p.Scopes, scope)
+}
+
+type principalKey struct{}
+
+// WithPrincipal は利用者を格納したコンテキストを返す。
+func WithPrincipal(ctx context.Context, p *Principal) context.Context {
+	return context.WithValue(ctx, principalKey{}, p)
+}
+
+// FromContext はコンテキストに格納された利用者を返す。APIキーなしのリクエストでは nil を返す。
+func FromContext(ctx context.Context) *Principal {
+	p, _ := ctx.Value(principalKey{}).(*Principal)
+	return p
+}
+
+// キーの先頭に付ける識別子。漏洩時にシークレットスキャナーで検出しやすくする。
+const keyPrefix = "bkr_"
+
+// GenerateKey は新しいAPIキーを生成し、キー本体と、表示用の先頭部分、保存用のハッシュを返す。
+// キー本体は発行時に一度だけ利用者に渡し、保存しない。
+func GenerateKey() (key, prefix, hash string, err error) {
+	b := make([]byte, 32)
+	if _, err := rand.Read(b); err != nil {
+		return "", "", "", err
+	}
+	key = keyPrefix + hex.EncodeToString(b)
+	return key, key[:len(keyPrefix)+8], HashKey(key), nil
+}
+
+// HashKey はAPIキーの保存・照合用のハッシュを返す。
+// キーは十分な長さの乱数のため、ソルトやストレッチングは行わない。
+func HashKey(key string) string {
+	sum := sha256.Sum256([]byte(key))
+	return hex.EncodeToString(sum[:])
+}
+
+// KeyStore はハッシュからAPIキーを参照するストアである。
+type KeyStore interface {
+	FindByHash(ctx context.Context, hash string) (*repository.APIKey, error)
+}
+
+// 無効だったキーを覚えておく件数と期間
+const (
+	invalidKeyEntries = 10000
+	invalidKeyTTL     = 10 * time.Minute
+)
+
+// Authenticator はAPIキーを検証して利用者を返す。
+type Authenticator struct {
+	Keys KeyStore
+	// Invalid は無効だったキーのハッシュを覚え、同じキーで KeyStore を参照しないために使う。
+	// キーは乱数のため、存在しないキーや失効したキーが後から有効になることはない。nil の場合は覚えない。
+	Invalid cache.Store
+}
+
+func NewAuthenticator(keys KeyStore) *Authenticator {
+	return &Authenticator{Keys: keys, Invalid: cache.NewMemory(invalidKeyEntries)}
+}
+
+// Authenticate は key を検証する。存在しないか失効したキーの場合は ErrInvalidKey を返す。
+func (a *Authenticator) Authenticate(ctx context.Context, key string) (*Principal, error) {
+	if !strings.HasPrefix(key, keyPrefix) {
+		return nil, ErrInvalidKey
+	}
+
+	hash := HashKey(key)
+	if a.isKnownInvalid(ctx, hash) {
+		return nil, ErrInvalidKey
+	}
+	apiKey, err := a.Keys.FindByHash(ctx, hash)
+	if errors.Is(err, sql.ErrNoRows) {
+		a.rememberInvalid(ctx, hash)
+		return nil, ErrInvalidKey
+	}
+	if err != nil {
+		return nil, err
+	}
+	if apiKey.RevokedAt != nil {
+		a.rememberInvalid(ctx, hash)
+		return nil, ErrInvalidKey
+	}
+
+	role, err := ParseRole(apiKey.Role)
+	if err != nil {
+		return nil, err
+	}
+	return &Principal{
+		KeyID:  apiKey.ID,
+		Name:   apiKey.Name,
+		Role:   role,
+		Scopes: apiKey.Scopes,
+	}, nil
+}
+
+// isKnownInvalid は hash のキーが以前に無効だったかを返す。
+// キャッシュの障害時は KeyStore で検証できるよう、覚えていないものとする。
+func (a *Authenticator) isKnownInvalid(ctx context.Context, hash string) bool {
+	if a.Invalid == nil {
+		return false
+	}
+	_, ok, err := a.Invalid.Get(ctx, "invalid-key:"+hash)
+	return err == nil && ok
+}
+
+// rememberInvalid は hash のキーが無効だったことを覚える。
+func (a *Authenticator) rememberInvalid(ctx context.Context, hash string) {
+	if a.Invalid == nil {
+		return
+	}
+	// 覚えられなくても次回 KeyStore で検証するだけのため、エラーは無視する
+	_ = a.Invalid.Set(ctx, "invalid-key:"+hash, nil, invalidKeyTTL)
+}
@@ -0,0 +1,144 @@
+package auth
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/h-hiwatashi/super-business-book-ranking-backend/repository"
+)
+
+type fakeKeyStore map[string]*repository.APIKey
+
+func (s fakeKeyStore) FindByHash(ctx context.Context, hash string) (*repository.APIKey, error) {
+	key, ok := s[hash]
+	if !ok {
+		return nil, sql.ErrNoRows
+	}
+	return key, nil
+}
+
+// countingKeyStore は FindByHash の呼び出し回数を数える。
+type countingKeyStore struct {
+	fakeKeyStore
+	calls int
+}
+
+func (s *countingKeyStore) FindByHash(ctx context.Context, hash string) (*repository.APIKey, error) {
+	s.calls++
+	return s.fakeKeyStore.FindByHash(ctx, hash)
+}
+
+func TestGenerateKey(t *testing.T) {
+	key, prefix, hash, err := GenerateKey()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !strings.HasPrefix(key, keyPrefix) || len(key) != len(keyPrefix)+64 {
+		t.Errorf("key = %q, want %s followed by 64 hex characters", key, keyPrefix)
+	}
+	if !strings.HasPrefix(key, prefix) || len(prefix) != len(keyPrefix)+8 {
+		t.Errorf("prefix = %q, want first %d characters of key", prefix, len(keyPrefix)+8)
+	}
+	if hash != HashKey(key) {
+		t.Errorf("hash = %q, want %q", hash, HashKey(key))
+	}
+
+	other, _, _, err := GenerateKey()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if other == key {
+		t.Error("GenerateKey() returned the same key twice")
+	}
+}
+
+func TestAuthenticate(t *testing.T) {
+	revokedAt := time.Date(2024, 3, 15, 0, 0, 0, 0, time.UTC)
+	keys := fakeKeyStore{
+		HashKey("bkr_active"):  {ID: "key-1", Name: "運用", Role: "admin", Scopes: []string{ScopeIngestRun}},
+		HashKey("bkr_revoked"): {ID: "key-2", Name: "旧運用", Role: "admin", RevokedAt: &revokedAt},
+	}
+	authenticator := NewAuthenticator(keys)
+
+	testCases := []struct {
+		name      string
+		key       string
+		wantKeyID string
+		wantErr   error
+	}{
+		{
+			name:      "正常系：有効なキー",
+			key:       "bkr_active",
+			wantKeyID: "key-1",
+		},
+		{
+			name:    "異常系：失効したキー",
+			key:     "bkr_revoked",
+			wantErr: ErrInvalidKey,
+		},
+		{
+			name:    "異常系：存在しないキー",
+			key:     "bkr_unknown",
+			wantErr: ErrInvalidKey,
+		},
+		{
+			name:    "異常系：形式が異なるキー",
+			key:     "active",
+			wantErr: ErrInvalidKey,
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			principal, err := authenticator.Authenticate(context.Background(), tc.key)
+			if !errors.Is(err, tc.wantErr) {
+				t.Fatalf("Authenticate() error = %v, want %v", err, tc.wantErr)
+			}
+			if tc.wantErr != nil {
+				return
+			}
+			if principal.KeyID != tc.wantKeyID || principal.Role != RoleAdmin || !principal.HasScope(ScopeIngestRun) {
+				t.Errorf("unexpected principal: %+v", principal)
+			}
+		})
+	}
+}
+
+func TestAuthenticateCachesInvalidKeys(t *testing.T) {
+	revokedAt := time.Date(2024, 3, 15, 0, 0, 0, 0, time.UTC)
+	keys := &countingKeyStore{fakeKeyStore: fakeKeyStore{
+		HashKey("bkr_active"):  {ID: "key-1", Name: "運用", Role: "admin"},
+		HashKey("bkr_revoked"): {ID: "key-2", Name: "旧運用", Role: "admin", RevokedAt: &revokedAt},
+	}}
+	authenticator := NewAuthenticator(keys)
+	ctx := context.Background()
+
+	testCases := []struct {
+		name      string
+		key       string
+		wantErr   error
+		wantCalls int
+	}{
+		{name: "正常系：有効なキーは毎回検証する", key: "bkr_active", wantCalls: 3},
+		{name: "異常系：存在しないキーは一度だけ参照する", key: "bkr_unknown", wantErr: ErrInvalidKey, wantCalls: 1},
+		{name: "異常系：失効したキーは一度だけ参照する", key: "bkr_revoked", wantErr: ErrInvalidKey, wantCalls: 1},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			keys.calls = 0
+			for i := 0; i < 3; i++ {
+				if _, err := authenticator.Authenticate(ctx, tc.key); !errors.Is(err, tc.wantErr) {
+					t.Fatalf("Authenticate() error = %v, want %v", err, tc.wantErr)
+				}
+			}
+			if keys.calls != tc.wantCalls {
+				t.Errorf("FindByHash called %d times, want %d", keys.calls, tc.wantCalls)
+			}
+		})
+	}
+}
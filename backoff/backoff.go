@@ -0,0 +1,28 @@
+// Package backoff は失敗した呼び出しを再試行するまでの待ち時間を求める。
+package backoff
+
+import (
+	"math/rand"
+	"time"
+)
+
+// Delay は attempt 回目（0 始まり）の再試行までの待ち時間を返す。
+// base から倍々に増やして max で打ち切り、jitter でばらつかせる。
+func Delay(attempt int, base, max time.Duration, jitter func(time.Duration) time.Duration) time.Duration {
+	d := base
+	for i := 0; i < attempt && d < max; i++ {
+		d *= 2
+	}
+	if d > max {
+		d = max
+	}
+	return jitter(d)
+}
+
+// FullJitter は 0 以上 d 以下の乱数の期間を返す。
+func FullJitter(d time.Duration) time.Duration {
+	if d <= 0 {
+		return 0
+	}
+	return time.Duration(rand.Int63n(int64(d) + 1))
+}
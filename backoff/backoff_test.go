@@ -0,0 +1,32 @@
+package backoff
+
+import (
+	"testing"
+	"time"
+)
+
+func TestDelay(t *testing.T) {
+	noJitter := func(d time.Duration) time.Duration { return d }
+
+	testCases := []struct {
+		attempt int
+		want    time.Duration
+	}{
+		{attempt: 0, want: 500 * time.Millisecond},
+		{attempt: 1, want: time.Second},
+		{attempt: 3, want: 4 * time.Second},
+		{attempt: 10, want: 5 * time.Second},
+	}
+
+	for _, tc := range testCases {
+		if got := Delay(tc.attempt, 500*time.Millisecond, 5*time.Second, noJitter); got != tc.want {
+			t.Errorf("Delay(%d) = %v, want %v", tc.attempt, got, tc.want)
+		}
+	}
+
+	for i := 0; i < 100; i++ {
+		if got := Delay(2, time.Second, 10*time.Second, FullJitter); got < 0 || got > 4*time.Second {
+			t.Fatalf("Delay with jitter = %v, want between 0 and 4s", got)
+		}
+	}
+}
@@ -0,0 +1,194 @@
+// Package breaker は外部サービスの障害時に呼び出しを止めるサーキットブレーカーを提供する。
+package breaker
+
+import (
+	"errors"
+	"log/slog"
+	"sync"
+	"time"
+
+	"github.com/h-hiwatashi/super-business-book-ranking-backend/clock"
+	"github.com/h-hiwatashi/super-business-book-ranking-backend/metrics"
+)
+
+// ErrOpen はサーキットブレーカーが開いていて呼び出しを行わなかったことを表す。
+var ErrOpen = errors.New("サーキットブレーカーが開いています")
+
+// State はサーキットブレーカーの状態である。
+type State int
+
+const (
+	// Closed は通常どおり呼び出す状態である。
+	Closed State = iota
+	// HalfOpen は回復を確かめるため、限られた数だけ呼び出す状態である。
+	HalfOpen
+	// Open は呼び出さずに ErrOpen を返す状態である。
+	Open
+)
+
+func (s State) String() string {
+	switch s {
+	case Closed:
+		return "closed"
+	case HalfOpen:
+		return "half-open"
+	case Open:
+		return "open"
+	default:
+		return "unknown"
+	}
+}
+
+// Settings はサーキットブレーカーの設定である。
+type Settings struct {
+	// FailureThreshold は閉じた状態から開くまでの連続失敗回数である。
+	FailureThreshold int
+	// OpenTimeout は開いてから半開状態に移るまでの時間である。
+	OpenTimeout time.Duration
+	// HalfOpenMaxRequests は半開状態で許可する呼び出し数である。
+	// すべて成功すると閉じ、1つでも失敗すると再び開く。
+	HalfOpenMaxRequests int
+	// IsFailure は呼び出しのエラーを失敗として数えるかを返す。
+	// 失敗として数えないエラーは成功とも数えない。nil の場合はすべてのエラーを失敗とする。
+	IsFailure func(error) bool
+	// Clock は nil の場合に実時間を使う。
+	Clock clock.Clock
+}
+
+// Breaker はサーキットブレーカーである。
+type Breaker struct {
+	name     string
+	settings Settings
+
+	mu       sync.Mutex
+	state    State
+	failures int
+	openedAt time.Time
+	// 半開状態で許可した呼び出し数と、そのうち成功した数
+	halfOpenRequests  int
+	halfOpenSuccesses int
+	// generation は状態が変わるたびに増え、前の状態で始まった呼び出しの結果を無視するために使う。
+	generation uint64
+}
+
+// New は name で識別されるサーキットブレーカーを閉じた状態で返す。
+func New(name string, settings Settings) *Breaker {
+	if settings.FailureThreshold < 1 {
+		settings.FailureThreshold = 1
+	}
+	if settings.HalfOpenMaxRequests < 1 {
+		settings.HalfOpenMaxRequests = 1
+	}
+	if settings.IsFailure == nil {
+		settings.IsFailure = func(err error) bool { return err != nil }
+	}
+	if settings.Clock == nil {
+		settings.Clock = clock.Real{}
+	}
+	metrics.CircuitBreakerState.WithLabelValues(name).Set(float64(Closed))
+	return &Breaker{name: name, settings: settings}
+}
+
+// State は現在の状態を返す。
+func (b *Breaker) State() State {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	b.refresh(b.settings.Clock.Now())
+	return b.state
+}
+
+// Do はサーキットブレーカーが許可した場合に fn を呼び出し、その結果を記録する。
+// 許可しない場合は fn を呼び出さずに ErrOpen を返す。
+func (b *Breaker) Do(fn func() error) error {
+	generation, err := b.before()
+	if err != nil {
+		return err
+	}
+	err = fn()
+	b.after(generation, err)
+	return err
+}
+
+func (b *Breaker) before() (uint64, error) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	b.refresh(b.settings.Clock.Now())
+	switch b.state {
+	case Open:
+		return 0, ErrOpen
+	case HalfOpen:
+		if b.halfOpenRequests >= b.settings.HalfOpenMaxRequests {
+			return 0, ErrOpen
+		}
+		b.halfOpenRequests++
+	}
+	return b.generation, nil
+}
+
+func (b *Breaker) after(generation uint64, err error) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	if generation != b.generation {
+		return
+	}
+
+	switch {
+	case err == nil:
+		b.onSuccess()
+	case b.settings.IsFailure(err):
+		b.onFailure()
+	case b.state == HalfOpen:
+		// 判断できない結果だったため、別の呼び出しで回復を確かめられるよう枠を戻す
+		b.halfOpenRequests--
+	}
+}
+
+func (b *Breaker) onSuccess() {
+	switch b.state {
+	case Closed:
+		b.failures = 0
+	case HalfOpen:
+		b.halfOpenSuccesses++
+		if b.halfOpenSuccesses >= b.settings.HalfOpenMaxRequests {
+			b.setState(Closed)
+		}
+	}
+}
+
+func (b *Breaker) onFailure() {
+	switch b.state {
+	case Closed:
+		b.failures++
+		if b.failures >= b.settings.FailureThreshold {
+			b.setState(Open)
+		}
+	case HalfOpen:
+		b.setState(Open)
+	}
+}
+
+// refresh は開いてから OpenTimeout が経過していれば半開状態に移す。
+func (b *Breaker) refresh(now time.Time) {
+	if b.state == Open && !now.Before(b.openedAt.Add(b.settings.OpenTimeout)) {
+		b.setState(HalfOpen)
+	}
+}
+
+func (b *Breaker) setState(state State) {
+	if b.state == state {
+		return
+	}
+	slog.Warn("サーキットブレーカーの状態が変わりました", "breaker", b.name, "from", b.state.String(), "to", state.String())
+
+	b.state = state
+	b.generation++
+	b.failures = 0
+	b.halfOpenRequests = 0
+	b.halfOpenSuccesses = 0
+	if state == Open {
+		b.openedAt = b.settings.Clock.Now()
+	}
+	metrics.CircuitBreakerState.WithLabelValues(b.name).Set(float64(state))
+}
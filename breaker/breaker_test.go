@@ -0,0 +1,147 @@
+package breaker
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus/testutil"
+
+	"github.com/h-hiwatashi/super-business-book-ranking-backend/clock"
+	"github.com/h-hiwatashi/super-business-book-ranking-backend/metrics"
+)
+
+var errUnavailable = errors.New("unavailable")
+
+func fail() error    { return errUnavailable }
+func succeed() error { return nil }
+
+func newTestBreaker(name string) (*Breaker, *clock.Fake) {
+	clk := clock.NewFake(time.Date(2024, 3, 15, 0, 0, 0, 0, time.UTC))
+	b := New(name, Settings{
+		FailureThreshold:    3,
+		OpenTimeout:         30 * time.Second,
+		HalfOpenMaxRequests: 1,
+		IsFailure: func(err error) bool {
+			return !errors.Is(err, context.Canceled)
+		},
+		Clock: clk,
+	})
+	return b, clk
+}
+
+func TestBreakerOpensAfterConsecutiveFailures(t *testing.T) {
+	b, _ := newTestBreaker("test-open")
+
+	// 成功を挟むと連続失敗回数はリセットされる
+	b.Do(fail)
+	b.Do(fail)
+	b.Do(succeed)
+	b.Do(fail)
+	b.Do(fail)
+	if got := b.State(); got != Closed {
+		t.Fatalf("State() = %v, want %v", got, Closed)
+	}
+
+	b.Do(fail)
+	if got := b.State(); got != Open {
+		t.Fatalf("State() = %v, want %v", got, Open)
+	}
+
+	called := false
+	err := b.Do(func() error {
+		called = true
+		return nil
+	})
+	if !errors.Is(err, ErrOpen) {
+		t.Errorf("Do() error = %v, want %v", err, ErrOpen)
+	}
+	if called {
+		t.Error("Do() called fn while open")
+	}
+	if got := testutil.ToFloat64(metrics.CircuitBreakerState.WithLabelValues("test-open")); got != float64(Open) {
+		t.Errorf("circuit breaker state metric = %v, want %v", got, float64(Open))
+	}
+}
+
+func TestBreakerHalfOpen(t *testing.T) {
+	testCases := []struct {
+		name  string
+		probe func() error
+		want  State
+	}{
+		{name: "試行が成功すると閉じる", probe: succeed, want: Closed},
+		{name: "試行が失敗すると再び開く", probe: fail, want: Open},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			b, clk := newTestBreaker("test-half-open")
+			for i := 0; i < 3; i++ {
+				b.Do(fail)
+			}
+
+			clk.Advance(29 * time.Second)
+			if got := b.State(); got != Open {
+				t.Fatalf("State() = %v, want %v", got, Open)
+			}
+			clk.Advance(time.Second)
+			if got := b.State(); got != HalfOpen {
+				t.Fatalf("State() = %v, want %v", got, HalfOpen)
+			}
+
+			b.Do(tc.probe)
+			if got := b.State(); got != tc.want {
+				t.Errorf("State() = %v, want %v", got, tc.want)
+			}
+		})
+	}
+}
+
+func TestBreakerHalfOpenLimitsRequests(t *testing.T) {
+	b, clk := newTestBreaker("test-half-open-limit")
+	for i := 0; i < 3; i++ {
+		b.Do(fail)
+	}
+	clk.Advance(30 * time.Second)
+
+	// 試行中は他の呼び出しを通さない
+	err := b.Do(func() error {
+		if err := b.Do(succeed); !errors.Is(err, ErrOpen) {
+			t.Errorf("concurrent Do() error = %v, want %v", err, ErrOpen)
+		}
+		return nil
+	})
+	if err != nil {
+		t.Fatalf("Do() error = %v", err)
+	}
+	if got := b.State(); got != Closed {
+		t.Errorf("State() = %v, want %v", got, Closed)
+	}
+}
+
+func TestBreakerIgnoresNonFailures(t *testing.T) {
+	b, clk := newTestBreaker("test-ignore")
+	canceled := func() error { return context.Canceled }
+
+	for i := 0; i < 5; i++ {
+		b.Do(canceled)
+	}
+	if got := b.State(); got != Closed {
+		t.Fatalf("State() = %v, want %v", got, Closed)
+	}
+
+	// 半開状態でキャンセルされた場合は、次の呼び出しで回復を確かめる
+	for i := 0; i < 3; i++ {
+		b.Do(fail)
+	}
+	clk.Advance(30 * time.Second)
+	b.Do(canceled)
+	if err := b.Do(succeed); err != nil {
+		t.Fatalf("Do() error = %v", err)
+	}
+	if got := b.State(); got != Closed {
+		t.Errorf("State() = %v, want %v", got, Closed)
+	}
+}
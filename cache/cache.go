@@ -0,0 +1,27 @@
+// Package cache はレスポンスなどを一時的に保持するキャッシュストアを提供する。
+package cache
+
+import (
+	"context"
+	"time"
+)
+
+// Store はキャッシュの保存先である。
+// プロセス内の LRU キャッシュと Redis 互換のストアを差し替えられるようにする。
+type Store interface {
+	// Get はキーに対応する値を返す。存在しないか期限切れの場合は false を返す。
+	Get(ctx context.Context, key string) ([]byte, bool, error)
+	// Set は ttl の間有効な値を保存する。
+	Set(ctx context.Context, key string, value []byte, ttl time.Duration) error
+	// DeletePrefix は prefix で始まるキーをすべて削除する。
+	DeletePrefix(ctx context.Context, prefix string) error
+}
+
+// Nop は何も保存しない Store である。キャッシュを無効にする場合に使う。
+type Nop struct{}
+
+func (Nop) Get(ctx context.Context, key string) ([]byte, bool, error) { return nil, false, nil }
+
+func (Nop) Set(ctx context.Context, key string, value []byte, ttl time.Duration) error { return nil }
+
+func (Nop) DeletePrefix(ctx context.Context, prefix string) error { return nil }
@@ -0,0 +1,97 @@
+package cache
+
+import (
+	"container/list"
+	"context"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Memory はプロセス内で保持する LRU キャッシュである。
+// 保持件数が上限に達すると最も長く参照されていない値から破棄する。
+type Memory struct {
+	mu         sync.Mutex
+	maxEntries int
+	ll         *list.List
+	items      map[string]*list.Element
+
+	now func() time.Time
+}
+
+type memoryEntry struct {
+	key       string
+	value     []byte
+	expiresAt time.Time
+}
+
+func NewMemory(maxEntries int) *Memory {
+	return &Memory{
+		maxEntries: maxEntries,
+		ll:         list.New(),
+		items:      make(map[string]*list.Element),
+		now:        time.Now,
+	}
+}
+
+func (m *Memory) Get(ctx context.Context, key string) ([]byte, bool, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	elem, ok := m.items[key]
+	if !ok {
+		return nil, false, nil
+	}
+	entry := elem.Value.(*memoryEntry)
+	if !m.now().Before(entry.expiresAt) {
+		m.remove(elem)
+		return nil, false, nil
+	}
+
+	m.ll.MoveToFront(elem)
+	return entry.value, true, nil
+}
+
+func (m *Memory) Set(ctx context.Context, key string, value []byte, ttl time.Duration) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	expiresAt := m.now().Add(ttl)
+	if elem, ok := m.items[key]; ok {
+		entry := elem.Value.(*memoryEntry)
+		entry.value = value
+		entry.expiresAt = expiresAt
+		m.ll.MoveToFront(elem)
+		return nil
+	}
+
+	m.items[key] = m.ll.PushFront(&memoryEntry{key: key, value: value, expiresAt: expiresAt})
+	for m.maxEntries > 0 && m.ll.Len() > m.maxEntries {
+		m.remove(m.ll.Back())
+	}
+	return nil
+}
+
+func (m *Memory) DeletePrefix(ctx context.Context, prefix string) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	for key, elem := range m.items {
+		if strings.HasPrefix(key, prefix) {
+			m.remove(elem)
+		}
+	}
+	return nil
+}
+
+// Len は保持している件数を返す。期限切れで未破棄の値も含む。
+func (m *Memory) Len() int {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	return m.ll.Len()
+}
+
+func (m *Memory) remove(elem *list.Element) {
+	m.ll.Remove(elem)
+	delete(m.items, elem.Value.(*memoryEntry).key)
+}
@@ -0,0 +1,70 @@
+package cache
+
+import (
+	"context"
+	"testing"
+	"time"
+)
+
+func TestMemoryGetSet(t *testing.T) {
+	ctx := context.Background()
+	now := time.Date(2024, 3, 15, 0, 0, 0, 0, time.UTC)
+	m := NewMemory(10)
+	m.now = func() time.Time { return now }
+
+	if _, ok, _ := m.Get(ctx, "missing"); ok {
+		t.Error("Get() found a missing key")
+	}
+
+	m.Set(ctx, "key", []byte("value"), time.Minute)
+	if got, ok, _ := m.Get(ctx, "key"); !ok || string(got) != "value" {
+		t.Errorf("Get() = %q, %v, want value, true", got, ok)
+	}
+
+	// 期限切れの値は返さない
+	now = now.Add(time.Minute)
+	if _, ok, _ := m.Get(ctx, "key"); ok {
+		t.Error("Get() returned an expired value")
+	}
+	if m.Len() != 0 {
+		t.Errorf("Len() = %d, want 0 after expiry", m.Len())
+	}
+}
+
+func TestMemoryEviction(t *testing.T) {
+	ctx := context.Background()
+	m := NewMemory(2)
+
+	m.Set(ctx, "a", []byte("1"), time.Minute)
+	m.Set(ctx, "b", []byte("2"), time.Minute)
+	// a を参照して b を最も古い値にする
+	m.Get(ctx, "a")
+	m.Set(ctx, "c", []byte("3"), time.Minute)
+
+	if _, ok, _ := m.Get(ctx, "b"); ok {
+		t.Error("least recently used key b was not evicted")
+	}
+	for _, key := range []string{"a", "c"} {
+		if _, ok, _ := m.Get(ctx, key); !ok {
+			t.Errorf("key %s was evicted", key)
+		}
+	}
+}
+
+func TestMemoryDeletePrefix(t *testing.T) {
+	ctx := context.Background()
+	m := NewMemory(10)
+
+	m.Set(ctx, "rankings:001:daily:latest:1", []byte("x"), time.Minute)
+	m.Set(ctx, "rankings:001:daily:2024-03-15:1", []byte("x"), time.Minute)
+	m.Set(ctx, "rankings:001:weekly:latest:1", []byte("x"), time.Minute)
+
+	m.DeletePrefix(ctx, "rankings:001:daily:")
+
+	if m.Len() != 1 {
+		t.Errorf("Len() = %d, want 1", m.Len())
+	}
+	if _, ok, _ := m.Get(ctx, "rankings:001:weekly:latest:1"); !ok {
+		t.Error("key with a different prefix was deleted")
+	}
+}
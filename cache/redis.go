@@ -0,0 +1,76 @@
+package cache
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"time"
+
+	"github.com/redis/go-redis/v9"
+)
+
+// DeletePrefix で1回の SCAN で取得するキー数の目安
+const scanCount = 100
+
+// Redis は Redis 互換のサーバーに保存するストアである。
+// 複数のサーバープロセスでキャッシュと無効化を共有できる。
+type Redis struct {
+	client    redis.UniversalClient
+	keyPrefix string
+}
+
+// NewRedis は client を使うストアを返す。keyPrefix は他の用途のキーと衝突しないよう全キーに付与する。
+func NewRedis(client redis.UniversalClient, keyPrefix string) *Redis {
+	return &Redis{client: client, keyPrefix: keyPrefix}
+}
+
+func (r *Redis) Get(ctx context.Context, key string) ([]byte, bool, error) {
+	value, err := r.client.Get(ctx, r.keyPrefix+key).Bytes()
+	if errors.Is(err, redis.Nil) {
+		return nil, false, nil
+	}
+	if err != nil {
+		return nil, false, err
+	}
+	return value, true, nil
+}
+
+func (r *Redis) Set(ctx context.Context, key string, value []byte, ttl time.Duration) error {
+	return r.client.Set(ctx, r.keyPrefix+key, value, ttl).Err()
+}
+
+// DeletePrefix は SCAN で該当キーを列挙して削除する。
+// KEYS と異なりサーバーを長時間ブロックしない。
+func (r *Redis) DeletePrefix(ctx context.Context, prefix string) error {
+	pattern := escapeGlob(r.keyPrefix+prefix) + "*"
+
+	var cursor uint64
+	for {
+		keys, next, err := r.client.Scan(ctx, cursor, pattern, scanCount).Result()
+		if err != nil {
+			return err
+		}
+		if len(keys) > 0 {
+			if err := r.client.Unlink(ctx, keys...).Err(); err != nil {
+				return err
+			}
+		}
+		if next == 0 {
+			return nil
+		}
+		cursor = next
+	}
+}
+
+// escapeGlob は SCAN の MATCH パターンで特別な意味を持つ文字をエスケープする。
+func escapeGlob(s string) string {
+	var b strings.Builder
+	for _, c := range s {
+		switch c {
+		case '*', '?', '[', ']', '\\':
+			b.WriteRune('\\')
+		}
+		b.WriteRune(c)
+	}
+	return b.String()
+}
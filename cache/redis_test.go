@@ -0,0 +1,54 @@
+package cache
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/alicebob/miniredis/v2"
+	"github.com/redis/go-redis/v9"
+)
+
+func TestRedis(t *testing.T) {
+	ctx := context.Background()
+	server := miniredis.RunT(t)
+	client := redis.NewClient(&redis.Options{Addr: server.Addr()})
+	defer client.Close()
+
+	store := NewRedis(client, "book-ranking:")
+
+	if _, ok, err := store.Get(ctx, "missing"); ok || err != nil {
+		t.Errorf("Get() = %v, %v, want false, nil", ok, err)
+	}
+
+	if err := store.Set(ctx, "rankings:001:daily:latest:1", []byte("a"), time.Minute); err != nil {
+		t.Fatalf("Set() error = %v", err)
+	}
+	store.Set(ctx, "rankings:001:weekly:latest:1", []byte("b"), time.Minute)
+	store.Set(ctx, "rankings:00*:daily:latest:1", []byte("c"), time.Minute)
+
+	if got, ok, _ := store.Get(ctx, "rankings:001:daily:latest:1"); !ok || string(got) != "a" {
+		t.Errorf("Get() = %q, %v, want a, true", got, ok)
+	}
+	if !server.Exists("book-ranking:rankings:001:daily:latest:1") {
+		t.Error("key prefix was not applied")
+	}
+
+	if err := store.DeletePrefix(ctx, "rankings:001:daily:"); err != nil {
+		t.Fatalf("DeletePrefix() error = %v", err)
+	}
+	if _, ok, _ := store.Get(ctx, "rankings:001:daily:latest:1"); ok {
+		t.Error("DeletePrefix() did not delete matching key")
+	}
+	for _, key := range []string{"rankings:001:weekly:latest:1", "rankings:00*:daily:latest:1"} {
+		if _, ok, _ := store.Get(ctx, key); !ok {
+			t.Errorf("DeletePrefix() deleted unrelated key %s", key)
+		}
+	}
+
+	// TTL が切れた値は返さない
+	server.FastForward(2 * time.Minute)
+	if _, ok, _ := store.Get(ctx, "rankings:001:weekly:latest:1"); ok {
+		t.Error("Get() returned an expired value")
+	}
+}
@@ -0,0 +1,152 @@
+// Package clicklog は商品リンクのクリックをリクエストの処理と切り離して保存する。
+package clicklog
+
+import (
+	"context"
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/hex"
+	"log/slog"
+	"sync"
+	"time"
+
+	"github.com/h-hiwatashi/super-business-book-ranking-backend/metrics"
+	"github.com/h-hiwatashi/super-business-book-ranking-backend/repository"
+)
+
+// 1回の保存にかける時間の上限
+const writeTimeout = 10 * time.Second
+
+// Store はクリックをまとめて保存するストアである。
+type Store interface {
+	InsertClicks(ctx context.Context, clicks []repository.Click) error
+}
+
+// Writer はクリックをバッファに溜め、件数か時間の上限に達するごとにまとめて保存する。
+// リダイレクトを保存の完了まで待たせないよう、Record はブロックしない。
+type Writer struct {
+	store         Store
+	batchSize     int
+	flushInterval time.Duration
+
+	mu     sync.RWMutex
+	closed bool
+	clicks chan repository.Click
+	done   chan struct{}
+}
+
+// NewWriter は Writer を作り、保存を行うゴルーチンを起動する。
+// bufferSize は保存待ちにできるクリック数の上限である。
+func NewWriter(store Store, bufferSize, batchSize int, flushInterval time.Duration) *Writer {
+	w := &Writer{
+		store:         store,
+		batchSize:     batchSize,
+		flushInterval: flushInterval,
+		clicks:        make(chan repository.Click, bufferSize),
+		done:          make(chan struct{}),
+	}
+	go w.run()
+	return w
+}
+
+// Record はクリックを保存待ちに加える。
+// バッファが一杯の場合や Close 後はクリックを破棄し、false を返す。
+func (w *Writer) Record(click repository.Click) bool {
+	w.mu.RLock()
+	defer w.mu.RUnlock()
+	if w.closed {
+		return false
+	}
+
+	select {
+	case w.clicks <- click:
+		return true
+	default:
+		metrics.ClicksDropped.WithLabelValues("buffer_full").Inc()
+		return false
+	}
+}
+
+// Close は新しいクリックの受け付けを止め、保存待ちのクリックを保存し終えるまで待つ。
+// ctx が先に終了した場合は ctx のエラーを返す。
+func (w *Writer) Close(ctx context.Context) error {
+	w.mu.Lock()
+	if !w.closed {
+		w.closed = true
+		close(w.clicks)
+	}
+	w.mu.Unlock()
+
+	select {
+	case <-w.done:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+func (w *Writer) run() {
+	defer close(w.done)
+
+	ticker := time.NewTicker(w.flushInterval)
+	defer ticker.Stop()
+
+	batch := make([]repository.Click, 0, w.batchSize)
+	for {
+		select {
+		case click, ok := <-w.clicks:
+			if !ok {
+				w.flush(batch)
+				return
+			}
+			batch = append(batch, click)
+			if len(batch) >= w.batchSize {
+				w.flush(batch)
+				batch = batch[:0]
+			}
+		case <-ticker.C:
+			w.flush(batch)
+			batch = batch[:0]
+		}
+	}
+}
+
+func (w *Writer) flush(batch []repository.Click) {
+	if len(batch) == 0 {
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), writeTimeout)
+	defer cancel()
+	if err := w.store.InsertClicks(ctx, batch); err != nil {
+		metrics.ClicksDropped.WithLabelValues("write_error").Add(float64(len(batch)))
+		slog.Error("クリックの保存エラー", "clicks", len(batch), "error", err)
+	}
+}
+
+// IPHasher は送信元IPアドレスを鍵付きハッシュに変換する。
+// IPv4 アドレスは総当たりで元に戻せるため、鍵のない単純なハッシュは使わない。
+type IPHasher struct {
+	key []byte
+}
+
+// NewIPHasher は key を鍵とする IPHasher を返す。
+// key が空の場合は乱数の鍵を使うため、再起動をまたいで同じアドレスを突き合わせることはできない。
+func NewIPHasher(key string) (*IPHasher, error) {
+	if key != "" {
+		return &IPHasher{key: []byte(key)}, nil
+	}
+	b := make([]byte, 32)
+	if _, err := rand.Read(b); err != nil {
+		return nil, err
+	}
+	return &IPHasher{key: b}, nil
+}
+
+// Hash は ip の HMAC-SHA256 を16進数で返す。
+func (h *IPHasher) Hash(ip string) string {
+	mac := hmac.New(sha256.New, h.key)
+	mac.Write([]byte(ip))
+	return hex.EncodeToString(mac.Sum(nil))
+}
@@ -0,0 +1,192 @@
+package clicklog
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus/testutil"
+
+	"github.com/h-hiwatashi/super-business-book-ranking-backend/metrics"
+	"github.com/h-hiwatashi/super-business-book-ranking-backend/repository"
+)
+
+type fakeStore struct {
+	mu      sync.Mutex
+	batches [][]repository.Click
+	saved   chan int
+	block   chan struct{}
+	err     error
+}
+
+func newFakeStore() *fakeStore {
+	return &fakeStore{saved: make(chan int, 10)}
+}
+
+func (s *fakeStore) InsertClicks(ctx context.Context, clicks []repository.Click) error {
+	if s.block != nil {
+		<-s.block
+	}
+	s.mu.Lock()
+	s.batches = append(s.batches, append([]repository.Click(nil), clicks...))
+	s.mu.Unlock()
+	s.saved <- len(clicks)
+	return s.err
+}
+
+func (s *fakeStore) total() int {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	n := 0
+	for _, batch := range s.batches {
+		n += len(batch)
+	}
+	return n
+}
+
+func receiveBatch(t *testing.T, store *fakeStore) int {
+	t.Helper()
+	select {
+	case n := <-store.saved:
+		return n
+	case <-time.After(5 * time.Second):
+		t.Fatal("clicks were not saved")
+		return 0
+	}
+}
+
+func testClick(id string) repository.Click {
+	return repository.Click{BookSiteMappingID: id, ClickedAt: time.Date(2024, 3, 15, 0, 0, 0, 0, time.UTC)}
+}
+
+func TestWriterFlushesFullBatch(t *testing.T) {
+	store := newFakeStore()
+	w := NewWriter(store, 10, 2, time.Hour)
+	defer w.Close(context.Background())
+
+	w.Record(testClick("bsm-1"))
+	w.Record(testClick("bsm-2"))
+
+	if n := receiveBatch(t, store); n != 2 {
+		t.Errorf("saved batch of %d, want 2", n)
+	}
+}
+
+func TestWriterFlushesOnInterval(t *testing.T) {
+	store := newFakeStore()
+	w := NewWriter(store, 10, 100, 10*time.Millisecond)
+	defer w.Close(context.Background())
+
+	w.Record(testClick("bsm-1"))
+
+	if n := receiveBatch(t, store); n != 1 {
+		t.Errorf("saved batch of %d, want 1", n)
+	}
+}
+
+func TestWriterCloseFlushesPending(t *testing.T) {
+	store := newFakeStore()
+	w := NewWriter(store, 10, 100, time.Hour)
+
+	for _, id := range []string{"bsm-1", "bsm-2", "bsm-3"} {
+		if !w.Record(testClick(id)) {
+			t.Fatalf("Record(%s) = false, want true", id)
+		}
+	}
+	if err := w.Close(context.Background()); err != nil {
+		t.Fatalf("Close() error = %v", err)
+	}
+
+	if got := store.total(); got != 3 {
+		t.Errorf("saved %d clicks, want 3", got)
+	}
+	// 停止後のクリックは受け付けない
+	if w.Record(testClick("bsm-4")) {
+		t.Error("Record() after Close = true, want false")
+	}
+}
+
+func TestWriterDropsWhenBufferFull(t *testing.T) {
+	store := newFakeStore()
+	store.block = make(chan struct{})
+	w := NewWriter(store, 1, 1, time.Hour)
+	before := testutil.ToFloat64(metrics.ClicksDropped.WithLabelValues("buffer_full"))
+
+	// 1件目は保存中で止まり、2件目がバッファを埋める
+	w.Record(testClick("bsm-1"))
+	deadline := time.Now().Add(5 * time.Second)
+	for !w.Record(testClick("bsm-2")) {
+		if time.Now().After(deadline) {
+			t.Fatal("buffer did not drain")
+		}
+		time.Sleep(time.Millisecond)
+	}
+	if w.Record(testClick("bsm-3")) {
+		t.Error("Record() with full buffer = true, want false")
+	}
+	if got := testutil.ToFloat64(metrics.ClicksDropped.WithLabelValues("buffer_full")) - before; got < 1 {
+		t.Errorf("dropped clicks = %v, want at least 1", got)
+	}
+
+	close(store.block)
+	if err := w.Close(context.Background()); err != nil {
+		t.Fatalf("Close() error = %v", err)
+	}
+	if got := store.total(); got != 2 {
+		t.Errorf("saved %d clicks, want 2", got)
+	}
+}
+
+func TestWriterCountsWriteErrors(t *testing.T) {
+	store := newFakeStore()
+	store.err = errors.New("db down")
+	w := NewWriter(store, 10, 100, time.Hour)
+	before := testutil.ToFloat64(metrics.ClicksDropped.WithLabelValues("write_error"))
+
+	w.Record(testClick("bsm-1"))
+	w.Record(testClick("bsm-2"))
+	if err := w.Close(context.Background()); err != nil {
+		t.Fatalf("Close() error = %v", err)
+	}
+
+	if got := testutil.ToFloat64(metrics.ClicksDropped.WithLabelValues("write_error")) - before; got != 2 {
+		t.Errorf("dropped clicks = %v, want 2", got)
+	}
+}
+
+func TestWriterCloseTimeout(t *testing.T) {
+	store := newFakeStore()
+	store.block = make(chan struct{})
+	defer close(store.block)
+	w := NewWriter(store, 10, 100, time.Hour)
+	w.Record(testClick("bsm-1"))
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	if err := w.Close(ctx); !errors.Is(err, context.Canceled) {
+		t.Errorf("Close() error = %v, want %v", err, context.Canceled)
+	}
+}
+
+func TestIPHasher(t *testing.T) {
+	h, err := NewIPHasher("secret")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if h.Hash("192.0.2.1") != h.Hash("192.0.2.1") {
+		t.Error("same address produced different hashes")
+	}
+	if h.Hash("192.0.2.1") == h.Hash("192.0.2.2") {
+		t.Error("different addresses produced the same hash")
+	}
+
+	other, err := NewIPHasher("")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if other.Hash("192.0.2.1") == h.Hash("192.0.2.1") {
+		t.Error("different keys produced the same hash")
+	}
+}
@@ -0,0 +1,87 @@
+// Package clock は時刻の取得と待機を抽象化し、テストで時間を制御できるようにする。
+package clock
+
+import (
+	"sync"
+	"time"
+)
+
+// Clock は現在時刻と待機を提供する。
+type Clock interface {
+	Now() time.Time
+	// After は d 経過後に現在時刻を送信するチャネルを返す。
+	After(d time.Duration) <-chan time.Time
+}
+
+// Real は実時間の Clock である。
+type Real struct{}
+
+func (Real) Now() time.Time { return time.Now() }
+
+func (Real) After(d time.Duration) <-chan time.Time { return time.After(d) }
+
+// Fake はテスト用に手動で進める Clock である。
+type Fake struct {
+	mu      sync.Mutex
+	cond    *sync.Cond
+	now     time.Time
+	waiters []fakeWaiter
+}
+
+type fakeWaiter struct {
+	until time.Time
+	ch    chan time.Time
+}
+
+func NewFake(now time.Time) *Fake {
+	f := &Fake{now: now}
+	f.cond = sync.NewCond(&f.mu)
+	return f
+}
+
+func (f *Fake) Now() time.Time {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	return f.now
+}
+
+func (f *Fake) After(d time.Duration) <-chan time.Time {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	ch := make(chan time.Time, 1)
+	if d <= 0 {
+		ch <- f.now
+		return ch
+	}
+	f.waiters = append(f.waiters, fakeWaiter{until: f.now.Add(d), ch: ch})
+	f.cond.Broadcast()
+	return ch
+}
+
+// Advance は時刻を d 進め、期限に達した After のチャネルに送信する。
+func (f *Fake) Advance(d time.Duration) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	f.now = f.now.Add(d)
+	pending := f.waiters[:0]
+	for _, w := range f.waiters {
+		if !f.now.Before(w.until) {
+			w.ch <- f.now
+			continue
+		}
+		pending = append(pending, w)
+	}
+	f.waiters = pending
+}
+
+// BlockUntil は After で待機しているものが n 個以上になるまで待つ。
+// 別のゴルーチンが待機に入ってから Advance するために使う。
+func (f *Fake) BlockUntil(n int) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	for len(f.waiters) < n {
+		f.cond.Wait()
+	}
+}
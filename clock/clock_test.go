@@ -0,0 +1,32 @@
+package clock
+
+import (
+	"testing"
+	"time"
+)
+
+func TestFake(t *testing.T) {
+	start := time.Date(2024, 3, 15, 0, 0, 0, 0, time.UTC)
+	f := NewFake(start)
+
+	done := make(chan time.Time)
+	go func() {
+		done <- <-f.After(time.Second)
+	}()
+
+	f.BlockUntil(1)
+	f.Advance(500 * time.Millisecond)
+	select {
+	case <-done:
+		t.Fatal("After fired before the deadline")
+	default:
+	}
+
+	f.Advance(500 * time.Millisecond)
+	if got := <-done; !got.Equal(start.Add(time.Second)) {
+		t.Errorf("After sent %v, want %v", got, start.Add(time.Second))
+	}
+	if got := f.Now(); !got.Equal(start.Add(time.Second)) {
+		t.Errorf("Now() = %v, want %v", got, start.Add(time.Second))
+	}
+}
@@ -0,0 +1,89 @@
+// apikey は最初の管理者キーなど、管理APIを使わずにAPIキーを発行するコマンドである。
+// データベースの接続先はサーバーと同じく設定ファイルと環境変数から読み込む。
+//
+//	go run ./cmd/apikey -name 運用 -role admin -scopes ingest:run,keys:manage
+package main
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"flag"
+	"fmt"
+	"os"
+	"strings"
+
+	_ "github.com/go-sql-driver/mysql"
+
+	"github.com/h-hiwatashi/super-business-book-ranking-backend/auth"
+	"github.com/h-hiwatashi/super-business-book-ranking-backend/config"
+	"github.com/h-hiwatashi/super-business-book-ranking-backend/repository"
+)
+
+func main() {
+	fs := flag.NewFlagSet("apikey", flag.ContinueOnError)
+	name := fs.String("name", "", "APIキーの名前（必須）")
+	role := fs.String("role", string(auth.RoleAdmin), "ロール（public, partner, admin）")
+	scopes := fs.String("scopes", strings.Join(auth.KnownScopes, ","), "スコープ（カンマ区切り）")
+	if err := fs.Parse(os.Args[1:]); err != nil {
+		if errors.Is(err, flag.ErrHelp) {
+			return
+		}
+		os.Exit(2)
+	}
+
+	if err := run(*name, *role, *scopes); err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		os.Exit(1)
+	}
+}
+
+func run(name, roleName, scopeList string) error {
+	name = strings.TrimSpace(name)
+	if name == "" {
+		return errors.New("-name は必須です")
+	}
+	role, err := auth.ParseRole(roleName)
+	if err != nil {
+		return err
+	}
+	scopes := []string{}
+	for _, scope := range strings.Split(scopeList, ",") {
+		if scope = strings.TrimSpace(scope); scope != "" {
+			scopes = append(scopes, scope)
+		}
+	}
+	if err := auth.ValidateScopes(scopes); err != nil {
+		return err
+	}
+
+	loaded, err := config.Load(nil)
+	if err != nil {
+		return fmt.Errorf("設定読み込みエラー: %w", err)
+	}
+	db, err := sql.Open("mysql", loaded.Config.Database.DSN())
+	if err != nil {
+		return fmt.Errorf("データベース接続エラー: %w", err)
+	}
+	defer db.Close()
+
+	key, prefix, hash, err := auth.GenerateKey()
+	if err != nil {
+		return fmt.Errorf("APIキーの生成エラー: %w", err)
+	}
+	apiKey := repository.APIKey{
+		Name:    name,
+		Prefix:  prefix,
+		KeyHash: hash,
+		Role:    string(role),
+		Scopes:  scopes,
+	}
+	if err := repository.NewAPIKeyRepository(db).Create(context.Background(), &apiKey); err != nil {
+		return fmt.Errorf("APIキーの保存エラー: %w", err)
+	}
+
+	// キー本体は再表示できないため、標準出力にはキーだけを出してリダイレクトで保存できるようにする
+	fmt.Fprintf(os.Stderr, "APIキーを発行しました（id: %s, role: %s, scopes: %s）\n", apiKey.ID, apiKey.Role, strings.Join(scopes, ","))
+	fmt.Println(key)
+	return nil
+}
@@ -0,0 +1,649 @@
+// Package config はアプリケーションの設定を読み込み、検証する。
+//
+// 設定は デフォルト値 < 設定ファイル（YAML または TOML）< 環境変数 < コマンドラインフラグ
+// の順に上書きされる。秘密情報は環境変数名に _FILE を付けた変数でファイルから読み込める。
+package config
+
+import (
+	"errors"
+	"fmt"
+	"net/netip"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/h-hiwatashi/super-business-book-ranking-backend/affiliate"
+)
+
+// Config はアプリケーション全体の設定である。
+type Config struct {
+	Server    ServerConfig    `yaml:"server" toml:"server"`
+	Database  DatabaseConfig  `yaml:"database" toml:"database"`
+	Log       LogConfig       `yaml:"log" toml:"log"`
+	Ingest    IngestConfig    `yaml:"ingest" toml:"ingest"`
+	Health    HealthConfig    `yaml:"health" toml:"health"`
+	Cache     CacheConfig     `yaml:"cache" toml:"cache"`
+	Redis     RedisConfig     `yaml:"redis" toml:"redis"`
+	Rakuten   RakutenConfig   `yaml:"rakuten" toml:"rakuten"`
+	RateLimit RateLimitConfig `yaml:"rate_limit" toml:"rate_limit"`
+	CORS      CORSConfig      `yaml:"cors" toml:"cors"`
+	Affiliate AffiliateConfig `yaml:"affiliate" toml:"affiliate"`
+	Clicks    ClicksConfig    `yaml:"clicks" toml:"clicks"`
+	Feeds     FeedsConfig     `yaml:"feeds" toml:"feeds"`
+	GraphQL   GraphQLConfig   `yaml:"graphql" toml:"graphql"`
+	GRPC      GRPCConfig      `yaml:"grpc" toml:"grpc"`
+	Stream    StreamConfig    `yaml:"stream" toml:"stream"`
+	Webhook   WebhookConfig   `yaml:"webhook" toml:"webhook"`
+	OpenAPI   OpenAPIConfig   `yaml:"openapi" toml:"openapi"`
+	Versions  VersionsConfig  `yaml:"versions" toml:"versions"`
+}
+
+// ServerConfig は HTTP サーバーの設定である。
+type ServerConfig struct {
+	Port                  string        `yaml:"port" toml:"port"`
+	ReadTimeout           time.Duration `yaml:"read_timeout" toml:"read_timeout"`
+	ReadHeaderTimeout     time.Duration `yaml:"read_header_timeout" toml:"read_header_timeout"`
+	WriteTimeout          time.Duration `yaml:"write_timeout" toml:"write_timeout"`
+	IdleTimeout           time.Duration `yaml:"idle_timeout" toml:"idle_timeout"`
+	MaxHeaderBytes        int           `yaml:"max_header_bytes" toml:"max_header_bytes"`
+	ShutdownDrainPeriod   time.Duration `yaml:"shutdown_drain_period" toml:"shutdown_drain_period"`
+	ShutdownTimeout       time.Duration `yaml:"shutdown_timeout" toml:"shutdown_timeout"`
+	APIRequestTimeout     time.Duration `yaml:"api_request_timeout" toml:"api_request_timeout"`
+	RakutenRequestTimeout time.Duration `yaml:"rakuten_request_timeout" toml:"rakuten_request_timeout"`
+	// TrustedProxies は X-Forwarded-For を信頼するプロキシのアドレス（CIDR 表記も可）である。
+	TrustedProxies []string `yaml:"trusted_proxies" toml:"trusted_proxies"`
+}
+
+// DatabaseConfig は MySQL への接続設定である。
+type DatabaseConfig struct {
+	Host     string `yaml:"host" toml:"host"`
+	Port     string `yaml:"port" toml:"port"`
+	User     string `yaml:"user" toml:"user"`
+	Password string `yaml:"password" toml:"password"`
+	Name     string `yaml:"name" toml:"name"`
+	Params   string `yaml:"params" toml:"params"`
+}
+
+// LogConfig はログ出力の設定である。
+type LogConfig struct {
+	Level string `yaml:"level" toml:"level"`
+}
+
+// IngestConfig はランキング取り込みジョブの設定である。
+type IngestConfig struct {
+	Enabled  bool          `yaml:"enabled" toml:"enabled"`
+	Interval time.Duration `yaml:"interval" toml:"interval"`
+	Periods  []string      `yaml:"periods" toml:"periods"`
+	// Concurrency は1つの取得元から同時にランキングを取得するワーカー数である。
+	Concurrency int `yaml:"concurrency" toml:"concurrency"`
+}
+
+// HealthConfig はヘルスチェックの設定である。
+type HealthConfig struct {
+	Timeout         time.Duration `yaml:"timeout" toml:"timeout"`
+	MaxIngestionAge time.Duration `yaml:"max_ingestion_age" toml:"max_ingestion_age"`
+}
+
+// CacheConfig はレスポンスキャッシュの設定である。
+type CacheConfig struct {
+	// Backend は memory, redis, none のいずれかである。
+	Backend    string        `yaml:"backend" toml:"backend"`
+	TTL        time.Duration `yaml:"ttl" toml:"ttl"`
+	MaxEntries int           `yaml:"max_entries" toml:"max_entries"`
+}
+
+// RedisConfig は Redis 互換ストアへの接続設定である。
+type RedisConfig struct {
+	Addr      string `yaml:"addr" toml:"addr"`
+	Password  string `yaml:"password" toml:"password"`
+	DB        int    `yaml:"db" toml:"db"`
+	KeyPrefix string `yaml:"key_prefix" toml:"key_prefix"`
+}
+
+// RakutenConfig は楽天ウェブサービスの設定である。
+type RakutenConfig struct {
+	// ApplicationID が空の場合は楽天APIを呼び出さず、モックデータを返す。
+	ApplicationID string `yaml:"application_id" toml:"application_id"`
+	BaseURL       string `yaml:"base_url" toml:"base_url"`
+	// Timeout は楽天APIへの1回のリクエストの期限で、Server.RakutenRequestTimeout より短くする。
+	Timeout time.Duration `yaml:"timeout" toml:"timeout"`
+	// RateLimit は1秒あたりのリクエスト数の上限で、アプリIDごとの制限に合わせる。
+	RateLimit      float64       `yaml:"rate_limit" toml:"rate_limit"`
+	Burst          int           `yaml:"burst" toml:"burst"`
+	MaxRetries     int           `yaml:"max_retries" toml:"max_retries"`
+	RetryBaseDelay time.Duration `yaml:"retry_base_delay" toml:"retry_base_delay"`
+	RetryMaxDelay  time.Duration `yaml:"retry_max_delay" toml:"retry_max_delay"`
+	Breaker        BreakerConfig `yaml:"breaker" toml:"breaker"`
+}
+
+// BreakerConfig はサーキットブレーカーの設定である。
+type BreakerConfig struct {
+	// FailureThreshold は呼び出しを止めるまでの連続失敗回数である。
+	FailureThreshold int `yaml:"failure_threshold" toml:"failure_threshold"`
+	// OpenTimeout は呼び出しを止めてから回復を確かめるまでの時間である。
+	OpenTimeout time.Duration `yaml:"open_timeout" toml:"open_timeout"`
+	// HalfOpenRequests は回復を確かめるために許可する呼び出し数である。
+	HalfOpenRequests int `yaml:"half_open_requests" toml:"half_open_requests"`
+}
+
+// RateLimitConfig は公開APIのレート制限の設定である。
+type RateLimitConfig struct {
+	Enabled bool          `yaml:"enabled" toml:"enabled"`
+	Window  time.Duration `yaml:"window" toml:"window"`
+	// Public はランキング・書籍・カテゴリの参照APIの上限である。
+	Public RateLimitGroup `yaml:"public" toml:"public"`
+	// Search は楽天APIを呼び出す検索系APIの上限である。
+	Search RateLimitGroup `yaml:"search" toml:"search"`
+	// Admin は管理APIの上限である。
+	Admin RateLimitGroup `yaml:"admin" toml:"admin"`
+	// AuthPerIP はAPIキーを付けたリクエストのIPアドレスごとの上限で、キーの検証より前に数える。
+	AuthPerIP int `yaml:"auth_per_ip" toml:"auth_per_ip"`
+}
+
+// RateLimitGroup はルートグループごとの Window あたりのリクエスト数の上限である。
+type RateLimitGroup struct {
+	PerIP  int `yaml:"per_ip" toml:"per_ip"`
+	PerKey int `yaml:"per_key" toml:"per_key"`
+}
+
+// CORSConfig は別オリジンのフロントエンドからの呼び出しを許可する設定である。
+type CORSConfig struct {
+	// AllowedOrigins が空の場合は CORS のヘッダーを付けない。
+	// "*" はすべてのオリジン、"https://*.example.com" はサブドメインすべてを許可する。
+	AllowedOrigins   []string      `yaml:"allowed_origins" toml:"allowed_origins"`
+	AllowedMethods   []string      `yaml:"allowed_methods" toml:"allowed_methods"`
+	AllowedHeaders   []string      `yaml:"allowed_headers" toml:"allowed_headers"`
+	ExposedHeaders   []string      `yaml:"exposed_headers" toml:"exposed_headers"`
+	AllowCredentials bool          `yaml:"allow_credentials" toml:"allow_credentials"`
+	MaxAge           time.Duration `yaml:"max_age" toml:"max_age"`
+}
+
+// AffiliateConfig はアフィリエイトリンクの設定である。
+// IDを指定したサイトは sites.affiliate_id より優先する。
+type AffiliateConfig struct {
+	Enabled bool `yaml:"enabled" toml:"enabled"`
+	// RakutenID は楽天アフィリエイトのアフィリエイトIDである。
+	RakutenID string `yaml:"rakuten_id" toml:"rakuten_id"`
+	// AmazonTag はAmazonアソシエイトのトラッキングIDである。
+	AmazonTag string `yaml:"amazon_tag" toml:"amazon_tag"`
+	// YahooID はYahoo!ショッピングに使うバリューコマースの "sid:pid" である。
+	YahooID string `yaml:"yahoo_id" toml:"yahoo_id"`
+}
+
+// ClicksConfig は商品リンクのクリック記録の設定である。
+type ClicksConfig struct {
+	// BufferSize は保存待ちにできるクリック数の上限で、超えたクリックは破棄する。
+	BufferSize    int           `yaml:"buffer_size" toml:"buffer_size"`
+	BatchSize     int           `yaml:"batch_size" toml:"batch_size"`
+	FlushInterval time.Duration `yaml:"flush_interval" toml:"flush_interval"`
+	// IPHashKey は送信元IPアドレスのハッシュの鍵である。空の場合は起動ごとに乱数の鍵を使う。
+	IPHashKey string `yaml:"ip_hash_key" toml:"ip_hash_key"`
+}
+
+// FeedsConfig はランキングの Atom・RSS フィードの設定である。
+type FeedsConfig struct {
+	// BaseURL はフィードのIDと自身へのリンクに使う公開URLである。空の場合はリクエストのホストから組み立てる。
+	BaseURL string `yaml:"base_url" toml:"base_url"`
+	// Size はフィードに載せる上位の書籍数である。
+	Size int `yaml:"size" toml:"size"`
+	// MoverThreshold は急上昇フィードに載せる順位の上昇幅の下限である。
+	MoverThreshold int `yaml:"mover_threshold" toml:"mover_threshold"`
+}
+
+// GraphQLConfig は /graphql エンドポイントの設定である。
+type GraphQLConfig struct {
+	Enabled bool `yaml:"enabled" toml:"enabled"`
+	// MaxDepth はクエリのフィールドの入れ子の深さの上限である。
+	MaxDepth int `yaml:"max_depth" toml:"max_depth"`
+	// MaxComplexity はクエリの複雑さ（フィールド数をリストの要素数の見積もりで掛け合わせた値）の上限である。
+	MaxComplexity int `yaml:"max_complexity" toml:"max_complexity"`
+	// Introspection はスキーマのイントロスペクションを許可するかである。
+	Introspection bool `yaml:"introspection" toml:"introspection"`
+}
+
+// GRPCConfig は社内サービス向けの gRPC サーバーの設定である。
+type GRPCConfig struct {
+	Enabled bool `yaml:"enabled" toml:"enabled"`
+	// Port は HTTP サーバーとは別に待ち受けるポート番号である。
+	Port string `yaml:"port" toml:"port"`
+}
+
+// StreamConfig はランキング更新の Server-Sent Events の設定である。
+type StreamConfig struct {
+	// HeartbeatInterval はイベントがない間もプロキシに切断されないよう、コメントを送る間隔である。
+	HeartbeatInterval time.Duration `yaml:"heartbeat_interval" toml:"heartbeat_interval"`
+	// HistorySize は再接続したクライアントに送り直せるよう、カテゴリ・期間ごとに保持するイベント数である。
+	HistorySize int `yaml:"history_size" toml:"history_size"`
+	// MaxSubscribers は同時に接続できるストリームの数の上限である。
+	MaxSubscribers int `yaml:"max_subscribers" toml:"max_subscribers"`
+}
+
+// WebhookConfig はランキングの変化を通知する Webhook の設定である。
+type WebhookConfig struct {
+	// Enabled は取り込み時の通知の登録と、配信キューからの送信を行うかである。
+	Enabled bool `yaml:"enabled" toml:"enabled"`
+	// PollInterval は配信キューを確認する間隔である。
+	PollInterval time.Duration `yaml:"poll_interval" toml:"poll_interval"`
+	// BatchSize は1回に取り出して並行に送信する通知の数である。
+	BatchSize int `yaml:"batch_size" toml:"batch_size"`
+	// MaxAttempts は配信をあきらめて dead にするまでの送信回数の上限である。
+	MaxAttempts    int           `yaml:"max_attempts" toml:"max_attempts"`
+	RetryBaseDelay time.Duration `yaml:"retry_base_delay" toml:"retry_base_delay"`
+	RetryMaxDelay  time.Duration `yaml:"retry_max_delay" toml:"retry_max_delay"`
+	// Timeout は1回の送信にかける時間の上限である。
+	Timeout time.Duration `yaml:"timeout" toml:"timeout"`
+}
+
+// OpenAPIConfig は API 仕様（openapi/openapi.yaml）に関する設定である。
+type OpenAPIConfig struct {
+	// ValidateRequests はリクエストを API 仕様と照合し、合わないものを 400 で拒否するかである。
+	ValidateRequests bool `yaml:"validate_requests" toml:"validate_requests"`
+	// Docs は /openapi.yaml・/openapi.json と Swagger UI（/docs）を公開するかである。
+	Docs bool `yaml:"docs" toml:"docs"`
+	// BaseURL は公開する API 仕様の servers に記載するURLである。空の場合はリクエストのホストから組み立てる。
+	BaseURL string `yaml:"base_url" toml:"base_url"`
+}
+
+// 日付の設定値の書式
+const dateLayout = "2006-01-02"
+
+// VersionsConfig は API のバージョンの設定である。
+// バージョンのないルート（/api/...）は /api/v1/... の別名として残し、非推奨であることをヘッダーで知らせる。
+type VersionsConfig struct {
+	// LegacyDeprecatedAt はバージョンのないルートを非推奨にした日（YYYY-MM-DD）である。
+	LegacyDeprecatedAt string `yaml:"legacy_deprecated_at" toml:"legacy_deprecated_at"`
+	// LegacySunsetAt はバージョンのないルートの提供を終える予定の日（YYYY-MM-DD）である。空の場合は未定とする。
+	LegacySunsetAt string `yaml:"legacy_sunset_at" toml:"legacy_sunset_at"`
+}
+
+// Default はデフォルト値を設定した Config を返す。
+// データベースのパスワードは誤って既定値で接続しないよう、デフォルト値を持たない。
+func Default() Config {
+	return Config{
+		Server: ServerConfig{
+			Port:                  "8080",
+			ReadTimeout:           10 * time.Second,
+			ReadHeaderTimeout:     5 * time.Second,
+			WriteTimeout:          30 * time.Second,
+			IdleTimeout:           120 * time.Second,
+			MaxHeaderBytes:        1 << 20,
+			ShutdownDrainPeriod:   5 * time.Second,
+			ShutdownTimeout:       30 * time.Second,
+			APIRequestTimeout:     5 * time.Second,
+			RakutenRequestTimeout: 15 * time.Second,
+		},
+		Database: DatabaseConfig{
+			Host:   "localhost",
+			Port:   "3306",
+			User:   "root",
+			Name:   "book_ranking",
+			Params: "parseTime=true&loc=Asia%2FTokyo",
+		},
+		Log: LogConfig{
+			Level: "info",
+		},
+		Ingest: IngestConfig{
+			Enabled:  true,
+			Interval: time.Hour,
+			Periods:  []string{"daily", "weekly", "monthly"},
+			// 楽天APIの制限はクライアント内で共有するため、ワーカーを増やしても送信間隔は変わらない
+			Concurrency: 2,
+		},
+		Health: HealthConfig{
+			Timeout:         2 * time.Second,
+			MaxIngestionAge: 3 * time.Hour,
+		},
+		Cache: CacheConfig{
+			Backend:    "memory",
+			TTL:        10 * time.Minute,
+			MaxEntries: 1000,
+		},
+		Redis: RedisConfig{
+			Addr:      "localhost:6379",
+			KeyPrefix: "book-ranking:",
+		},
+		Rakuten: RakutenConfig{
+			BaseURL:        "https://app.rakuten.co.jp/services/api/BooksBook/Search/20170404",
+			Timeout:        10 * time.Second,
+			RateLimit:      1,
+			Burst:          1,
+			MaxRetries:     3,
+			RetryBaseDelay: 500 * time.Millisecond,
+			RetryMaxDelay:  10 * time.Second,
+			Breaker: BreakerConfig{
+				FailureThreshold: 5,
+				OpenTimeout:      30 * time.Second,
+				HalfOpenRequests: 1,
+			},
+		},
+		RateLimit: RateLimitConfig{
+			Enabled: true,
+			Window:  time.Minute,
+			Public:  RateLimitGroup{PerIP: 120, PerKey: 600},
+			Search:  RateLimitGroup{PerIP: 30, PerKey: 120},
+			Admin:   RateLimitGroup{PerIP: 30, PerKey: 60},
+			// ルートグループごとの上限の合計より多くし、有効なキーの利用者を妨げないようにする
+			AuthPerIP: 300,
+		},
+		CORS: CORSConfig{
+			AllowedMethods: []string{"GET", "HEAD", "POST", "DELETE"},
+			// 条件付きリクエスト、APIキー、EventSource の再接続のヘッダーはプリフライトで許可が必要になる
+			AllowedHeaders: []string{"Content-Type", "X-API-Key", "If-None-Match", "If-Modified-Since", "Last-Event-ID"},
+			ExposedHeaders: []string{"ETag", "Retry-After", "X-Request-ID", "X-RateLimit-Limit", "X-RateLimit-Remaining", "X-RateLimit-Reset", "Deprecation", "Sunset", "Link"},
+			MaxAge:         10 * time.Minute,
+		},
+		Affiliate: AffiliateConfig{
+			Enabled: true,
+		},
+		Clicks: ClicksConfig{
+			BufferSize:    4096,
+			BatchSize:     200,
+			FlushInterval: 5 * time.Second,
+		},
+		Feeds: FeedsConfig{
+			Size:           10,
+			MoverThreshold: 5,
+		},
+		GraphQL: GraphQLConfig{
+			Enabled:       true,
+			MaxDepth:      8,
+			MaxComplexity: 5000,
+			Introspection: true,
+		},
+		GRPC: GRPCConfig{
+			Port: "9090",
+		},
+		Stream: StreamConfig{
+			HeartbeatInterval: 15 * time.Second,
+			HistorySize:       50,
+			MaxSubscribers:    1000,
+		},
+		Webhook: WebhookConfig{
+			PollInterval: 10 * time.Second,
+			BatchSize:    20,
+			// 待ち時間の上限に達するまでおよそ半日かけて再試行する
+			MaxAttempts:    10,
+			RetryBaseDelay: 30 * time.Second,
+			RetryMaxDelay:  2 * time.Hour,
+			Timeout:        10 * time.Second,
+		},
+		OpenAPI: OpenAPIConfig{
+			Docs: true,
+		},
+		Versions: VersionsConfig{
+			// /api/v1 と /api/v2 を導入した日
+			LegacyDeprecatedAt: "2026-10-18",
+		},
+	}
+}
+
+// DSN は MySQL ドライバーに渡すデータソース名を返す。
+func (c DatabaseConfig) DSN() string {
+	return fmt.Sprintf("%s:%s@tcp(%s:%s)/%s?%s",
+		c.User, c.Password, c.Host, c.Port, c.Name, c.Params)
+}
+
+// LegacyDeprecation はバージョンのないルートを非推奨にした日時と、提供を終える予定の日時を返す。
+// 提供終了日が未定の場合はゼロ値を返す。日付は Validate で検証済みとする。
+func (c VersionsConfig) LegacyDeprecation() (deprecatedAt, sunsetAt time.Time) {
+	deprecatedAt, _ = time.Parse(dateLayout, c.LegacyDeprecatedAt)
+	if c.LegacySunsetAt != "" {
+		sunsetAt, _ = time.Parse(dateLayout, c.LegacySunsetAt)
+	}
+	return deprecatedAt, sunsetAt
+}
+
+// Validate は必須項目と値の範囲を検証し、問題をまとめて返す。
+func (c Config) Validate() error {
+	var errs []error
+	required := func(name, value string) {
+		if value == "" {
+			errs = append(errs, fmt.Errorf("%s は必須です", name))
+		}
+	}
+	positive := func(name string, value time.Duration) {
+		if value <= 0 {
+			errs = append(errs, fmt.Errorf("%s は正の期間を指定してください: %s", name, value))
+		}
+	}
+
+	validPort(&errs, "PORT", c.Server.Port)
+	positive("HTTP_READ_TIMEOUT", c.Server.ReadTimeout)
+	positive("HTTP_READ_HEADER_TIMEOUT", c.Server.ReadHeaderTimeout)
+	positive("HTTP_WRITE_TIMEOUT", c.Server.WriteTimeout)
+	positive("HTTP_IDLE_TIMEOUT", c.Server.IdleTimeout)
+	if c.Server.MaxHeaderBytes <= 0 {
+		errs = append(errs, fmt.Errorf("HTTP_MAX_HEADER_BYTES は正の値を指定してください: %d", c.Server.MaxHeaderBytes))
+	}
+	if c.Server.ShutdownDrainPeriod < 0 {
+		errs = append(errs, fmt.Errorf("SHUTDOWN_DRAIN_PERIOD は0以上を指定してください: %s", c.Server.ShutdownDrainPeriod))
+	}
+	positive("SHUTDOWN_TIMEOUT", c.Server.ShutdownTimeout)
+	positive("API_REQUEST_TIMEOUT", c.Server.APIRequestTimeout)
+	positive("RAKUTEN_REQUEST_TIMEOUT", c.Server.RakutenRequestTimeout)
+	for _, proxy := range c.Server.TrustedProxies {
+		if _, err := netip.ParsePrefix(proxy); err == nil {
+			continue
+		}
+		if _, err := netip.ParseAddr(proxy); err != nil {
+			errs = append(errs, fmt.Errorf("TRUSTED_PROXIES に不正なアドレスがあります: %q", proxy))
+		}
+	}
+
+	required("DB_HOST", c.Database.Host)
+	validPort(&errs, "DB_PORT", c.Database.Port)
+	required("DB_USER", c.Database.User)
+	required("DB_PASSWORD", c.Database.Password)
+	required("DB_NAME", c.Database.Name)
+
+	switch c.Log.Level {
+	case "debug", "info", "warn", "error":
+	default:
+		errs = append(errs, fmt.Errorf("LOG_LEVEL は debug, info, warn, error のいずれかです: %q", c.Log.Level))
+	}
+
+	if c.Ingest.Enabled {
+		positive("INGEST_INTERVAL", c.Ingest.Interval)
+		if len(c.Ingest.Periods) == 0 {
+			errs = append(errs, errors.New("INGEST_PERIODS は1つ以上指定してください"))
+		}
+		if c.Ingest.Concurrency <= 0 {
+			errs = append(errs, fmt.Errorf("INGEST_CONCURRENCY は正の値を指定してください: %d", c.Ingest.Concurrency))
+		}
+		for _, period := range c.Ingest.Periods {
+			switch period {
+			case "daily", "weekly", "monthly", "yearly":
+			default:
+				errs = append(errs, fmt.Errorf("INGEST_PERIODS に不正な期間があります: %q", period))
+			}
+		}
+	}
+
+	positive("HEALTH_TIMEOUT", c.Health.Timeout)
+	positive("INGEST_MAX_AGE", c.Health.MaxIngestionAge)
+
+	switch c.Cache.Backend {
+	case "none":
+	case "memory":
+		positive("CACHE_TTL", c.Cache.TTL)
+		if c.Cache.MaxEntries <= 0 {
+			errs = append(errs, fmt.Errorf("CACHE_MAX_ENTRIES は正の値を指定してください: %d", c.Cache.MaxEntries))
+		}
+	case "redis":
+		positive("CACHE_TTL", c.Cache.TTL)
+		required("REDIS_ADDR", c.Redis.Addr)
+	default:
+		errs = append(errs, fmt.Errorf("CACHE_BACKEND は memory, redis, none のいずれかです: %q", c.Cache.Backend))
+	}
+
+	if c.Rakuten.ApplicationID != "" {
+		required("RAKUTEN_BASE_URL", c.Rakuten.BaseURL)
+		positive("RAKUTEN_TIMEOUT", c.Rakuten.Timeout)
+		// 楽天APIの期限切れ後に前回のランキングを返せるよう、ルートの期限に余裕を残す
+		if c.Server.RakutenRequestTimeout <= c.Rakuten.Timeout {
+			errs = append(errs, fmt.Errorf("RAKUTEN_REQUEST_TIMEOUT は RAKUTEN_TIMEOUT（%s）より長くしてください: %s", c.Rakuten.Timeout, c.Server.RakutenRequestTimeout))
+		}
+		if c.Rakuten.RateLimit <= 0 {
+			errs = append(errs, fmt.Errorf("RAKUTEN_RATE_LIMIT は正の値を指定してください: %g", c.Rakuten.RateLimit))
+		}
+		if c.Rakuten.Burst <= 0 {
+			errs = append(errs, fmt.Errorf("RAKUTEN_BURST は正の値を指定してください: %d", c.Rakuten.Burst))
+		}
+		if c.Rakuten.MaxRetries < 0 {
+			errs = append(errs, fmt.Errorf("RAKUTEN_MAX_RETRIES は0以上を指定してください: %d", c.Rakuten.MaxRetries))
+		}
+		positive("RAKUTEN_RETRY_BASE_DELAY", c.Rakuten.RetryBaseDelay)
+		if c.Rakuten.RetryMaxDelay < c.Rakuten.RetryBaseDelay {
+			errs = append(errs, fmt.Errorf("RAKUTEN_RETRY_MAX_DELAY は RAKUTEN_RETRY_BASE_DELAY 以上を指定してください: %s", c.Rakuten.RetryMaxDelay))
+		}
+		if c.Rakuten.Breaker.FailureThreshold <= 0 {
+			errs = append(errs, fmt.Errorf("RAKUTEN_BREAKER_FAILURE_THRESHOLD は正の値を指定してください: %d", c.Rakuten.Breaker.FailureThreshold))
+		}
+		positive("RAKUTEN_BREAKER_OPEN_TIMEOUT", c.Rakuten.Breaker.OpenTimeout)
+		if c.Rakuten.Breaker.HalfOpenRequests <= 0 {
+			errs = append(errs, fmt.Errorf("RAKUTEN_BREAKER_HALF_OPEN_REQUESTS は正の値を指定してください: %d", c.Rakuten.Breaker.HalfOpenRequests))
+		}
+	}
+
+	if c.RateLimit.Enabled {
+		positive("RATE_LIMIT_WINDOW", c.RateLimit.Window)
+		for _, g := range []struct {
+			name  string
+			group RateLimitGroup
+		}{
+			{"PUBLIC", c.RateLimit.Public},
+			{"SEARCH", c.RateLimit.Search},
+			{"ADMIN", c.RateLimit.Admin},
+		} {
+			if g.group.PerIP <= 0 {
+				errs = append(errs, fmt.Errorf("RATE_LIMIT_%s_PER_IP は正の値を指定してください: %d", g.name, g.group.PerIP))
+			}
+			if g.group.PerKey < 0 {
+				errs = append(errs, fmt.Errorf("RATE_LIMIT_%s_PER_KEY は0以上を指定してください: %d", g.name, g.group.PerKey))
+			}
+		}
+		if c.RateLimit.AuthPerIP <= 0 {
+			errs = append(errs, fmt.Errorf("RATE_LIMIT_AUTH_PER_IP は正の値を指定してください: %d", c.RateLimit.AuthPerIP))
+		}
+	}
+
+	for _, origin := range c.CORS.AllowedOrigins {
+		if err := validOrigin(origin); err != nil {
+			errs = append(errs, fmt.Errorf("CORS_ALLOWED_ORIGINS に不正なオリジンがあります: %w", err))
+		}
+		// すべてのオリジンに資格情報付きの呼び出しを許可すると、任意のサイトから利用者として操作できてしまう
+		if origin == "*" && c.CORS.AllowCredentials {
+			errs = append(errs, errors.New("CORS_ALLOW_CREDENTIALS を有効にする場合、CORS_ALLOWED_ORIGINS に * は指定できません"))
+		}
+	}
+	if len(c.CORS.AllowedOrigins) > 0 && len(c.CORS.AllowedMethods) == 0 {
+		errs = append(errs, errors.New("CORS_ALLOWED_METHODS は1つ以上指定してください"))
+	}
+	if c.CORS.MaxAge < 0 {
+		errs = append(errs, fmt.Errorf("CORS_MAX_AGE は0以上を指定してください: %s", c.CORS.MaxAge))
+	}
+
+	if c.Clicks.BufferSize <= 0 {
+		errs = append(errs, fmt.Errorf("CLICK_BUFFER_SIZE は正の値を指定してください: %d", c.Clicks.BufferSize))
+	}
+	if c.Clicks.BatchSize <= 0 {
+		errs = append(errs, fmt.Errorf("CLICK_BATCH_SIZE は正の値を指定してください: %d", c.Clicks.BatchSize))
+	}
+	positive("CLICK_FLUSH_INTERVAL", c.Clicks.FlushInterval)
+
+	if c.Feeds.BaseURL != "" {
+		if u, err := url.Parse(c.Feeds.BaseURL); err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+			errs = append(errs, fmt.Errorf("FEED_BASE_URL は http または https のURLを指定してください: %q", c.Feeds.BaseURL))
+		}
+	}
+	if c.Feeds.Size <= 0 {
+		errs = append(errs, fmt.Errorf("FEED_SIZE は正の値を指定してください: %d", c.Feeds.Size))
+	}
+	if c.Feeds.MoverThreshold <= 0 {
+		errs = append(errs, fmt.Errorf("FEED_MOVER_THRESHOLD は正の値を指定してください: %d", c.Feeds.MoverThreshold))
+	}
+
+	if c.GraphQL.MaxDepth <= 0 {
+		errs = append(errs, fmt.Errorf("GRAPHQL_MAX_DEPTH は正の値を指定してください: %d", c.GraphQL.MaxDepth))
+	}
+	if c.GraphQL.MaxComplexity <= 0 {
+		errs = append(errs, fmt.Errorf("GRAPHQL_MAX_COMPLEXITY は正の値を指定してください: %d", c.GraphQL.MaxComplexity))
+	}
+
+	if c.GRPC.Enabled {
+		validPort(&errs, "GRPC_PORT", c.GRPC.Port)
+		if c.GRPC.Port == c.Server.Port {
+			errs = append(errs, fmt.Errorf("GRPC_PORT は PORT と別のポート番号を指定してください: %q", c.GRPC.Port))
+		}
+	}
+
+	positive("STREAM_HEARTBEAT_INTERVAL", c.Stream.HeartbeatInterval)
+	if c.Stream.HistorySize <= 0 {
+		errs = append(errs, fmt.Errorf("STREAM_HISTORY_SIZE は正の値を指定してください: %d", c.Stream.HistorySize))
+	}
+	if c.Stream.MaxSubscribers <= 0 {
+		errs = append(errs, fmt.Errorf("STREAM_MAX_SUBSCRIBERS は正の値を指定してください: %d", c.Stream.MaxSubscribers))
+	}
+
+	positive("WEBHOOK_POLL_INTERVAL", c.Webhook.PollInterval)
+	if c.Webhook.BatchSize <= 0 {
+		errs = append(errs, fmt.Errorf("WEBHOOK_BATCH_SIZE は正の値を指定してください: %d", c.Webhook.BatchSize))
+	}
+	if c.Webhook.MaxAttempts <= 0 {
+		errs = append(errs, fmt.Errorf("WEBHOOK_MAX_ATTEMPTS は正の値を指定してください: %d", c.Webhook.MaxAttempts))
+	}
+	positive("WEBHOOK_RETRY_BASE_DELAY", c.Webhook.RetryBaseDelay)
+	positive("WEBHOOK_RETRY_MAX_DELAY", c.Webhook.RetryMaxDelay)
+	if c.Webhook.RetryMaxDelay < c.Webhook.RetryBaseDelay {
+		errs = append(errs, fmt.Errorf("WEBHOOK_RETRY_MAX_DELAY は WEBHOOK_RETRY_BASE_DELAY 以上を指定してください: %s", c.Webhook.RetryMaxDelay))
+	}
+	positive("WEBHOOK_TIMEOUT", c.Webhook.Timeout)
+	if c.OpenAPI.BaseURL != "" {
+		if u, err := url.Parse(c.OpenAPI.BaseURL); err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+			errs = append(errs, fmt.Errorf("OPENAPI_BASE_URL は http または https のURLを指定してください: %q", c.OpenAPI.BaseURL))
+		}
+	}
+
+	deprecatedAt, err := time.Parse(dateLayout, c.Versions.LegacyDeprecatedAt)
+	if err != nil {
+		errs = append(errs, fmt.Errorf("API_LEGACY_DEPRECATED_AT は YYYY-MM-DD 形式で指定してください: %q", c.Versions.LegacyDeprecatedAt))
+	}
+	if c.Versions.LegacySunsetAt != "" {
+		sunsetAt, err := time.Parse(dateLayout, c.Versions.LegacySunsetAt)
+		if err != nil {
+			errs = append(errs, fmt.Errorf("API_LEGACY_SUNSET_AT は YYYY-MM-DD 形式で指定してください: %q", c.Versions.LegacySunsetAt))
+		} else if !deprecatedAt.IsZero() && !sunsetAt.After(deprecatedAt) {
+			errs = append(errs, fmt.Errorf("API_LEGACY_SUNSET_AT は API_LEGACY_DEPRECATED_AT より後の日を指定してください: %q", c.Versions.LegacySunsetAt))
+		}
+	}
+
+	if c.Affiliate.YahooID != "" {
+		if _, _, err := affiliate.ParseValueCommerceID(c.Affiliate.YahooID); err != nil {
+			errs = append(errs, fmt.Errorf("AFFILIATE_YAHOO_ID: %w", err))
+		}
+	}
+
+	return errors.Join(errs...)
+}
+
+// validOrigin は origin が "*" か、パスを含まない "scheme://host[:port]" の形式かを検証する。
+// ホストの先頭に限り "*." でサブドメインを表せる。
+func validOrigin(origin string) error {
+	if origin == "*" {
+		return nil
+	}
+	u, err := url.Parse(strings.Replace(origin, "://*.", "://wildcard.", 1))
+	if err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" ||
+		u.Path != "" || u.RawQuery != "" || u.Fragment != "" || u.User != nil || strings.Contains(u.Host, "*") {
+		return fmt.Errorf("%q", origin)
+	}
+	return nil
+}
+
+func validPort(errs *[]error, name, value string) {
+	port, err := strconv.Atoi(value)
+	if err != nil || port < 1 || port > 65535 {
+		*errs = append(*errs, fmt.Errorf("%s は 1〜65535 のポート番号を指定してください: %q", name, value))
+	}
+}
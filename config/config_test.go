@@ -0,0 +1,284 @@
+package config
+
+import (
+	"errors"
+	"os"
+	"strings"
+	"testing"
+	"time"
+)
+
+// fakeEnv はテスト用の環境変数である。
+type fakeEnv map[string]string
+
+func (e fakeEnv) lookup(key string) (string, bool) {
+	v, ok := e[key]
+	return v, ok
+}
+
+// fakeFiles はテスト用のファイルシステムである。
+type fakeFiles map[string]string
+
+func (f fakeFiles) read(path string) ([]byte, error) {
+	if content, ok := f[path]; ok {
+		return []byte(content), nil
+	}
+	return nil, os.ErrNotExist
+}
+
+func TestLoadPrecedence(t *testing.T) {
+	files := fakeFiles{
+		"/etc/app.yaml": `
+server:
+  port: "9000"
+  api_request_timeout: 7s
+database:
+  host: db.internal
+  name: from_file
+ingest:
+  interval: 30m
+`,
+	}
+	env := fakeEnv{
+		"CONFIG_FILE": "/etc/app.yaml",
+		"DB_PASSWORD": "secret",
+		"DB_NAME":     "from_env",
+		"PORT":        "9100",
+	}
+
+	loaded, err := load([]string{"-port", "9200"}, env.lookup, files.read)
+	if err != nil {
+		t.Fatalf("load() error = %v", err)
+	}
+	cfg := loaded.Config
+
+	testCases := []struct {
+		name string
+		got  interface{}
+		want interface{}
+	}{
+		{"フラグは環境変数より優先される", cfg.Server.Port, "9200"},
+		{"環境変数は設定ファイルより優先される", cfg.Database.Name, "from_env"},
+		{"設定ファイルはデフォルト値より優先される", cfg.Database.Host, "db.internal"},
+		{"設定ファイルの期間", cfg.Server.APIRequestTimeout, 7 * time.Second},
+		{"設定ファイルの取り込み間隔", cfg.Ingest.Interval, 30 * time.Minute},
+		{"未指定の項目はデフォルト値", cfg.Database.User, "root"},
+	}
+	for _, tc := range testCases {
+		if tc.got != tc.want {
+			t.Errorf("%s: got %v, want %v", tc.name, tc.got, tc.want)
+		}
+	}
+
+	sources := map[string]string{}
+	for _, s := range loaded.Effective() {
+		sources[s.Key] = s.Source
+	}
+	wantSources := map[string]string{
+		"PORT":        SourceFlag,
+		"DB_NAME":     SourceEnv,
+		"DB_HOST":     SourceFile,
+		"DB_USER":     SourceDefault,
+		"DB_PASSWORD": SourceEnv,
+	}
+	for key, want := range wantSources {
+		if sources[key] != want {
+			t.Errorf("source of %s = %q, want %q", key, sources[key], want)
+		}
+	}
+}
+
+func TestLoadTOML(t *testing.T) {
+	files := fakeFiles{
+		"app.toml": `
+[database]
+host = "toml-db"
+password = "toml-secret"
+
+[ingest]
+periods = ["daily"]
+`,
+	}
+
+	loaded, err := load([]string{"-config", "app.toml"}, fakeEnv{}.lookup, files.read)
+	if err != nil {
+		t.Fatalf("load() error = %v", err)
+	}
+	if loaded.Config.Database.Host != "toml-db" {
+		t.Errorf("Database.Host = %q, want toml-db", loaded.Config.Database.Host)
+	}
+	if got := loaded.Config.Ingest.Periods; len(got) != 1 || got[0] != "daily" {
+		t.Errorf("Ingest.Periods = %v, want [daily]", got)
+	}
+}
+
+func TestLoadSecretFile(t *testing.T) {
+	files := fakeFiles{"/run/secrets/db_password": "from-secret-file\n"}
+
+	t.Run("_FILE からの読み込み", func(t *testing.T) {
+		env := fakeEnv{"DB_PASSWORD_FILE": "/run/secrets/db_password"}
+		loaded, err := load(nil, env.lookup, files.read)
+		if err != nil {
+			t.Fatalf("load() error = %v", err)
+		}
+		if loaded.Config.Database.Password != "from-secret-file" {
+			t.Errorf("Database.Password = %q, want from-secret-file", loaded.Config.Database.Password)
+		}
+	})
+
+	t.Run("値と _FILE の同時指定はエラー", func(t *testing.T) {
+		env := fakeEnv{"DB_PASSWORD": "x", "DB_PASSWORD_FILE": "/run/secrets/db_password"}
+		if _, err := load(nil, env.lookup, files.read); err == nil {
+			t.Error("load() error = nil, want error")
+		}
+	})
+
+	t.Run("旧名の DB_PASS も参照する", func(t *testing.T) {
+		env := fakeEnv{"DB_PASS": "legacy"}
+		loaded, err := load(nil, env.lookup, files.read)
+		if err != nil {
+			t.Fatalf("load() error = %v", err)
+		}
+		if loaded.Config.Database.Password != "legacy" {
+			t.Errorf("Database.Password = %q, want legacy", loaded.Config.Database.Password)
+		}
+	})
+}
+
+func TestLoadValidation(t *testing.T) {
+	testCases := []struct {
+		name    string
+		env     fakeEnv
+		wantErr string
+	}{
+		{
+			name:    "パスワード未設定",
+			env:     fakeEnv{},
+			wantErr: "DB_PASSWORD",
+		},
+		{
+			name:    "不正な期間",
+			env:     fakeEnv{"DB_PASSWORD": "x", "INGEST_INTERVAL": "soon"},
+			wantErr: "INGEST_INTERVAL",
+		},
+		{
+			name:    "不正なポート番号",
+			env:     fakeEnv{"DB_PASSWORD": "x", "PORT": "99999"},
+			wantErr: "PORT",
+		},
+		{
+			name:    "不正な取り込み期間",
+			env:     fakeEnv{"DB_PASSWORD": "x", "INGEST_PERIODS": "daily,hourly"},
+			wantErr: "INGEST_PERIODS",
+		},
+		{
+			name:    "パスを含むCORSオリジン",
+			env:     fakeEnv{"DB_PASSWORD": "x", "CORS_ALLOWED_ORIGINS": "https://app.example.com/path"},
+			wantErr: "CORS_ALLOWED_ORIGINS",
+		},
+		{
+			name:    "ホストの途中にワイルドカードがあるCORSオリジン",
+			env:     fakeEnv{"DB_PASSWORD": "x", "CORS_ALLOWED_ORIGINS": "https://app.*.example.com"},
+			wantErr: "CORS_ALLOWED_ORIGINS",
+		},
+		{
+			name:    "すべてのオリジンに資格情報を許可",
+			env:     fakeEnv{"DB_PASSWORD": "x", "CORS_ALLOWED_ORIGINS": "*", "CORS_ALLOW_CREDENTIALS": "true"},
+			wantErr: "CORS_ALLOW_CREDENTIALS",
+		},
+		{
+			name:    "スキームのないフィードの公開URL",
+			env:     fakeEnv{"DB_PASSWORD": "x", "FEED_BASE_URL": "ranking.example.com"},
+			wantErr: "FEED_BASE_URL",
+		},
+		{
+			name:    "API 仕様の公開URLのスキームが http・https 以外",
+			env:     fakeEnv{"DB_PASSWORD": "x", "OPENAPI_BASE_URL": "ftp://ranking.example.com"},
+			wantErr: "OPENAPI_BASE_URL",
+		},
+		{
+			name:    "非推奨にした日の形式が不正",
+			env:     fakeEnv{"DB_PASSWORD": "x", "API_LEGACY_DEPRECATED_AT": "2026/10/18"},
+			wantErr: "API_LEGACY_DEPRECATED_AT",
+		},
+		{
+			name:    "提供終了日が非推奨にした日より前",
+			env:     fakeEnv{"DB_PASSWORD": "x", "API_LEGACY_DEPRECATED_AT": "2026-10-18", "API_LEGACY_SUNSET_AT": "2026-10-01"},
+			wantErr: "API_LEGACY_SUNSET_AT",
+		},
+		{
+			name:    "GraphQL の複雑さの上限が0",
+			env:     fakeEnv{"DB_PASSWORD": "x", "GRAPHQL_MAX_COMPLEXITY": "0"},
+			wantErr: "GRAPHQL_MAX_COMPLEXITY",
+		},
+		{
+			name:    "gRPC と HTTP のポートが同じ",
+			env:     fakeEnv{"DB_PASSWORD": "x", "GRPC_ENABLED": "true", "GRPC_PORT": "8080"},
+			wantErr: "GRPC_PORT",
+		},
+		{
+			name:    "Webhook の再試行の待ち時間の上限が初期値より短い",
+			env:     fakeEnv{"DB_PASSWORD": "x", "WEBHOOK_RETRY_BASE_DELAY": "1m", "WEBHOOK_RETRY_MAX_DELAY": "30s"},
+			wantErr: "WEBHOOK_RETRY_MAX_DELAY",
+		},
+		{
+			name:    "APIキーの検証回数の上限が0",
+			env:     fakeEnv{"DB_PASSWORD": "x", "RATE_LIMIT_AUTH_PER_IP": "0"},
+			wantErr: "RATE_LIMIT_AUTH_PER_IP",
+		},
+		{
+			name:    "楽天ランキングAPIの期限が楽天APIのリクエストの期限以下",
+			env:     fakeEnv{"DB_PASSWORD": "x", "RAKUTEN_APPLICATION_ID": "app", "RAKUTEN_REQUEST_TIMEOUT": "10s", "RAKUTEN_TIMEOUT": "10s"},
+			wantErr: "RAKUTEN_REQUEST_TIMEOUT",
+		},
+		{
+			name:    "不正なバリューコマースのID",
+			env:     fakeEnv{"DB_PASSWORD": "x", "AFFILIATE_YAHOO_ID": "3123456"},
+			wantErr: "AFFILIATE_YAHOO_ID",
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			_, err := load(nil, tc.env.lookup, fakeFiles{}.read)
+			if err == nil {
+				t.Fatal("load() error = nil, want error")
+			}
+			if !strings.Contains(err.Error(), tc.wantErr) {
+				t.Errorf("error %q does not mention %s", err, tc.wantErr)
+			}
+		})
+	}
+}
+
+func TestEffectiveRedactsSecrets(t *testing.T) {
+	env := fakeEnv{"DB_PASSWORD": "super-secret"}
+	loaded, err := load(nil, env.lookup, fakeFiles{}.read)
+	if err != nil {
+		t.Fatalf("load() error = %v", err)
+	}
+
+	for _, s := range loaded.Effective() {
+		if strings.Contains(s.Value, "super-secret") {
+			t.Errorf("%s exposes secret value", s.Key)
+		}
+		if s.Key == "DB_PASSWORD" && s.Value != redacted {
+			t.Errorf("DB_PASSWORD = %q, want %q", s.Value, redacted)
+		}
+	}
+}
+
+func TestLoadUnknownFileExtension(t *testing.T) {
+	files := fakeFiles{"app.ini": "port=1"}
+	_, err := load([]string{"-config", "app.ini"}, fakeEnv{}.lookup, files.read)
+	if err == nil || errors.Is(err, os.ErrNotExist) {
+		t.Errorf("load() error = %v, want unsupported extension error", err)
+	}
+}
+
+func TestLoadExampleFile(t *testing.T) {
+	env := fakeEnv{"DB_PASSWORD": "x"}
+	if _, err := load([]string{"-config", "../config.example.yaml"}, env.lookup, os.ReadFile); err != nil {
+		t.Errorf("config.example.yaml could not be loaded: %v", err)
+	}
+}
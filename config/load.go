@@ -0,0 +1,320 @@
+package config
+
+import (
+	"errors"
+	"flag"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"github.com/BurntSushi/toml"
+	"gopkg.in/yaml.v3"
+)
+
+// 設定値の取得元
+const (
+	SourceDefault = "default"
+	SourceFile    = "file"
+	SourceEnv     = "env"
+	SourceFlag    = "flag"
+)
+
+// 秘密情報を出力する際の置換文字列
+const redacted = "[REDACTED]"
+
+// field は1つの設定項目と、それを上書きする環境変数・フラグの対応である。
+type field struct {
+	key     string
+	flag    string
+	usage   string
+	secret  bool
+	aliases []string
+	value   flag.Value
+}
+
+// fields は c の各フィールドに結び付いた設定項目の一覧を返す。
+func (c *Config) fields() []field {
+	return []field{
+		{key: "PORT", flag: "port", usage: "HTTPサーバーのポート番号", value: (*stringValue)(&c.Server.Port)},
+		{key: "HTTP_READ_TIMEOUT", flag: "http-read-timeout", usage: "リクエスト全体の読み込み期限", value: (*durationValue)(&c.Server.ReadTimeout)},
+		{key: "HTTP_READ_HEADER_TIMEOUT", flag: "http-read-header-timeout", usage: "リクエストヘッダーの読み込み期限", value: (*durationValue)(&c.Server.ReadHeaderTimeout)},
+		{key: "HTTP_WRITE_TIMEOUT", flag: "http-write-timeout", usage: "レスポンスの書き込み期限", value: (*durationValue)(&c.Server.WriteTimeout)},
+		{key: "HTTP_IDLE_TIMEOUT", flag: "http-idle-timeout", usage: "Keep-Alive 接続の待機期限", value: (*durationValue)(&c.Server.IdleTimeout)},
+		{key: "HTTP_MAX_HEADER_BYTES", flag: "http-max-header-bytes", usage: "リクエストヘッダーの最大バイト数", value: (*intValue)(&c.Server.MaxHeaderBytes)},
+		{key: "SHUTDOWN_DRAIN_PERIOD", flag: "shutdown-drain-period", usage: "シャットダウン開始から新規受付を止めるまでの猶予", value: (*durationValue)(&c.Server.ShutdownDrainPeriod)},
+		{key: "SHUTDOWN_TIMEOUT", flag: "shutdown-timeout", usage: "処理中のリクエストの完了を待つ期限", value: (*durationValue)(&c.Server.ShutdownTimeout)},
+		{key: "API_REQUEST_TIMEOUT", flag: "api-request-timeout", usage: "データベースを参照するAPIの処理期限", value: (*durationValue)(&c.Server.APIRequestTimeout)},
+		{key: "RAKUTEN_REQUEST_TIMEOUT", flag: "rakuten-request-timeout", usage: "楽天ランキングAPIの処理期限", value: (*durationValue)(&c.Server.RakutenRequestTimeout)},
+		{key: "TRUSTED_PROXIES", flag: "trusted-proxies", usage: "X-Forwarded-For を信頼するプロキシのアドレス（カンマ区切り、CIDR 可）", value: (*stringListValue)(&c.Server.TrustedProxies)},
+
+		{key: "DB_HOST", flag: "db-host", usage: "データベースのホスト名", value: (*stringValue)(&c.Database.Host)},
+		{key: "DB_PORT", flag: "db-port", usage: "データベースのポート番号", value: (*stringValue)(&c.Database.Port)},
+		{key: "DB_USER", flag: "db-user", usage: "データベースのユーザー名", value: (*stringValue)(&c.Database.User)},
+		{key: "DB_PASSWORD", flag: "db-password", usage: "データベースのパスワード", secret: true, aliases: []string{"DB_PASS"}, value: (*stringValue)(&c.Database.Password)},
+		{key: "DB_NAME", flag: "db-name", usage: "データベース名", value: (*stringValue)(&c.Database.Name)},
+		{key: "DB_PARAMS", flag: "db-params", usage: "データソース名に付加するパラメータ", value: (*stringValue)(&c.Database.Params)},
+
+		{key: "LOG_LEVEL", flag: "log-level", usage: "ログレベル（debug, info, warn, error）", value: (*stringValue)(&c.Log.Level)},
+
+		{key: "INGEST_ENABLED", flag: "ingest-enabled", usage: "ランキング取り込みジョブを動かすか", value: (*boolValue)(&c.Ingest.Enabled)},
+		{key: "INGEST_INTERVAL", flag: "ingest-interval", usage: "ランキング取り込みの間隔", value: (*durationValue)(&c.Ingest.Interval)},
+		{key: "INGEST_PERIODS", flag: "ingest-periods", usage: "取り込む期間（カンマ区切り）", value: (*stringListValue)(&c.Ingest.Periods)},
+		{key: "INGEST_CONCURRENCY", flag: "ingest-concurrency", usage: "取得元ごとの取り込みワーカー数", value: (*intValue)(&c.Ingest.Concurrency)},
+
+		{key: "HEALTH_TIMEOUT", flag: "health-timeout", usage: "レディネスチェックの期限", value: (*durationValue)(&c.Health.Timeout)},
+		{key: "INGEST_MAX_AGE", flag: "ingest-max-age", usage: "取り込みが古いと判定するまでの経過時間", value: (*durationValue)(&c.Health.MaxIngestionAge)},
+
+		{key: "CACHE_BACKEND", flag: "cache-backend", usage: "ランキングのキャッシュ先（memory, redis, none）", value: (*stringValue)(&c.Cache.Backend)},
+		{key: "CACHE_TTL", flag: "cache-ttl", usage: "キャッシュの有効期間", value: (*durationValue)(&c.Cache.TTL)},
+		{key: "CACHE_MAX_ENTRIES", flag: "cache-max-entries", usage: "プロセス内キャッシュの最大件数", value: (*intValue)(&c.Cache.MaxEntries)},
+
+		{key: "REDIS_ADDR", flag: "redis-addr", usage: "Redis 互換サーバーのアドレス", value: (*stringValue)(&c.Redis.Addr)},
+		{key: "REDIS_PASSWORD", flag: "redis-password", usage: "Redis 互換サーバーのパスワード", secret: true, value: (*stringValue)(&c.Redis.Password)},
+		{key: "REDIS_DB", flag: "redis-db", usage: "Redis のデータベース番号", value: (*intValue)(&c.Redis.DB)},
+		{key: "REDIS_KEY_PREFIX", flag: "redis-key-prefix", usage: "Redis に保存するキーの接頭辞", value: (*stringValue)(&c.Redis.KeyPrefix)},
+
+		{key: "RAKUTEN_APPLICATION_ID", flag: "rakuten-application-id", usage: "楽天ウェブサービスのアプリID（未指定の場合はモックデータ）", secret: true, value: (*stringValue)(&c.Rakuten.ApplicationID)},
+		{key: "RAKUTEN_BASE_URL", flag: "rakuten-base-url", usage: "楽天ブックス書籍検索APIのURL", value: (*stringValue)(&c.Rakuten.BaseURL)},
+		{key: "RAKUTEN_TIMEOUT", flag: "rakuten-timeout", usage: "楽天APIへの1回のリクエストの期限（RAKUTEN_REQUEST_TIMEOUT より短くする）", value: (*durationValue)(&c.Rakuten.Timeout)},
+		{key: "RAKUTEN_RATE_LIMIT", flag: "rakuten-rate-limit", usage: "楽天APIへの1秒あたりのリクエスト数の上限", value: (*floatValue)(&c.Rakuten.RateLimit)},
+		{key: "RAKUTEN_BURST", flag: "rakuten-burst", usage: "楽天APIへ連続して送れるリクエスト数", value: (*intValue)(&c.Rakuten.Burst)},
+		{key: "RAKUTEN_MAX_RETRIES", flag: "rakuten-max-retries", usage: "楽天APIの 429・5xx 応答を再試行する回数", value: (*intValue)(&c.Rakuten.MaxRetries)},
+		{key: "RAKUTEN_RETRY_BASE_DELAY", flag: "rakuten-retry-base-delay", usage: "楽天APIの再試行の初回待ち時間", value: (*durationValue)(&c.Rakuten.RetryBaseDelay)},
+		{key: "RAKUTEN_RETRY_MAX_DELAY", flag: "rakuten-retry-max-delay", usage: "楽天APIの再試行の最大待ち時間", value: (*durationValue)(&c.Rakuten.RetryMaxDelay)},
+		{key: "RAKUTEN_BREAKER_FAILURE_THRESHOLD", flag: "rakuten-breaker-failure-threshold", usage: "楽天APIの呼び出しを止めるまでの連続失敗回数", value: (*intValue)(&c.Rakuten.Breaker.FailureThreshold)},
+		{key: "RAKUTEN_BREAKER_OPEN_TIMEOUT", flag: "rakuten-breaker-open-timeout", usage: "楽天APIの呼び出しを止めてから回復を確かめるまでの時間", value: (*durationValue)(&c.Rakuten.Breaker.OpenTimeout)},
+		{key: "RAKUTEN_BREAKER_HALF_OPEN_REQUESTS", flag: "rakuten-breaker-half-open-requests", usage: "楽天APIの回復を確かめるために許可する呼び出し数", value: (*intValue)(&c.Rakuten.Breaker.HalfOpenRequests)},
+
+		{key: "RATE_LIMIT_ENABLED", flag: "rate-limit-enabled", usage: "公開APIのレート制限を行うか", value: (*boolValue)(&c.RateLimit.Enabled)},
+		{key: "RATE_LIMIT_WINDOW", flag: "rate-limit-window", usage: "レート制限でリクエスト数を数える期間", value: (*durationValue)(&c.RateLimit.Window)},
+		{key: "RATE_LIMIT_PUBLIC_PER_IP", flag: "rate-limit-public-per-ip", usage: "参照APIのIPアドレスごとの上限", value: (*intValue)(&c.RateLimit.Public.PerIP)},
+		{key: "RATE_LIMIT_PUBLIC_PER_KEY", flag: "rate-limit-public-per-key", usage: "参照APIのAPIキーごとの上限（0 で制限なし）", value: (*intValue)(&c.RateLimit.Public.PerKey)},
+		{key: "RATE_LIMIT_SEARCH_PER_IP", flag: "rate-limit-search-per-ip", usage: "検索APIのIPアドレスごとの上限", value: (*intValue)(&c.RateLimit.Search.PerIP)},
+		{key: "RATE_LIMIT_SEARCH_PER_KEY", flag: "rate-limit-search-per-key", usage: "検索APIのAPIキーごとの上限（0 で制限なし）", value: (*intValue)(&c.RateLimit.Search.PerKey)},
+		{key: "RATE_LIMIT_ADMIN_PER_IP", flag: "rate-limit-admin-per-ip", usage: "管理APIのIPアドレスごとの上限", value: (*intValue)(&c.RateLimit.Admin.PerIP)},
+		{key: "RATE_LIMIT_ADMIN_PER_KEY", flag: "rate-limit-admin-per-key", usage: "管理APIのAPIキーごとの上限（0 で制限なし）", value: (*intValue)(&c.RateLimit.Admin.PerKey)},
+		{key: "RATE_LIMIT_AUTH_PER_IP", flag: "rate-limit-auth-per-ip", usage: "APIキーを付けたリクエストのIPアドレスごとの上限（キーの検証前に数える）", value: (*intValue)(&c.RateLimit.AuthPerIP)},
+
+		{key: "CORS_ALLOWED_ORIGINS", flag: "cors-allowed-origins", usage: "CORS で許可するオリジン（カンマ区切り、未指定の場合は CORS 無効）", value: (*stringListValue)(&c.CORS.AllowedOrigins)},
+		{key: "CORS_ALLOWED_METHODS", flag: "cors-allowed-methods", usage: "CORS で許可するメソッド（カンマ区切り）", value: (*stringListValue)(&c.CORS.AllowedMethods)},
+		{key: "CORS_ALLOWED_HEADERS", flag: "cors-allowed-headers", usage: "CORS で許可するリクエストヘッダー（カンマ区切り）", value: (*stringListValue)(&c.CORS.AllowedHeaders)},
+		{key: "CORS_EXPOSED_HEADERS", flag: "cors-exposed-headers", usage: "ブラウザーに公開するレスポンスヘッダー（カンマ区切り）", value: (*stringListValue)(&c.CORS.ExposedHeaders)},
+		{key: "CORS_ALLOW_CREDENTIALS", flag: "cors-allow-credentials", usage: "資格情報付きのリクエストを許可するか", value: (*boolValue)(&c.CORS.AllowCredentials)},
+		{key: "CORS_MAX_AGE", flag: "cors-max-age", usage: "プリフライトの結果をキャッシュさせる期間", value: (*durationValue)(&c.CORS.MaxAge)},
+
+		{key: "AFFILIATE_ENABLED", flag: "affiliate-enabled", usage: "商品URLをアフィリエイトリンクに変換するか", value: (*boolValue)(&c.Affiliate.Enabled)},
+		{key: "AFFILIATE_RAKUTEN_ID", flag: "affiliate-rakuten-id", usage: "楽天アフィリエイトのアフィリエイトID（sites.affiliate_id より優先）", value: (*stringValue)(&c.Affiliate.RakutenID)},
+		{key: "AFFILIATE_AMAZON_TAG", flag: "affiliate-amazon-tag", usage: "AmazonアソシエイトのトラッキングID（sites.affiliate_id より優先）", value: (*stringValue)(&c.Affiliate.AmazonTag)},
+		{key: "AFFILIATE_YAHOO_ID", flag: "affiliate-yahoo-id", usage: "Yahoo!ショッピングに使うバリューコマースの sid:pid（sites.affiliate_id より優先）", value: (*stringValue)(&c.Affiliate.YahooID)},
+
+		{key: "CLICK_BUFFER_SIZE", flag: "click-buffer-size", usage: "保存待ちにできるクリック数の上限", value: (*intValue)(&c.Clicks.BufferSize)},
+		{key: "CLICK_BATCH_SIZE", flag: "click-batch-size", usage: "まとめて保存するクリック数", value: (*intValue)(&c.Clicks.BatchSize)},
+		{key: "CLICK_FLUSH_INTERVAL", flag: "click-flush-interval", usage: "保存待ちのクリックを保存する間隔", value: (*durationValue)(&c.Clicks.FlushInterval)},
+		{key: "CLICK_IP_HASH_KEY", flag: "click-ip-hash-key", usage: "送信元IPアドレスのハッシュの鍵（未指定の場合は起動ごとに乱数）", secret: true, value: (*stringValue)(&c.Clicks.IPHashKey)},
+
+		{key: "FEED_BASE_URL", flag: "feed-base-url", usage: "フィードのIDとリンクに使う公開URL（未指定の場合はリクエストのホスト）", value: (*stringValue)(&c.Feeds.BaseURL)},
+		{key: "FEED_SIZE", flag: "feed-size", usage: "フィードに載せる上位の書籍数", value: (*intValue)(&c.Feeds.Size)},
+		{key: "FEED_MOVER_THRESHOLD", flag: "feed-mover-threshold", usage: "急上昇フィードに載せる順位の上昇幅の下限", value: (*intValue)(&c.Feeds.MoverThreshold)},
+
+		{key: "GRAPHQL_ENABLED", flag: "graphql-enabled", usage: "/graphql エンドポイントを有効にするか", value: (*boolValue)(&c.GraphQL.Enabled)},
+		{key: "GRAPHQL_MAX_DEPTH", flag: "graphql-max-depth", usage: "GraphQL クエリの入れ子の深さの上限", value: (*intValue)(&c.GraphQL.MaxDepth)},
+		{key: "GRAPHQL_MAX_COMPLEXITY", flag: "graphql-max-complexity", usage: "GraphQL クエリの複雑さの上限", value: (*intValue)(&c.GraphQL.MaxComplexity)},
+		{key: "GRAPHQL_INTROSPECTION", flag: "graphql-introspection", usage: "GraphQL スキーマのイントロスペクションを許可するか", value: (*boolValue)(&c.GraphQL.Introspection)},
+
+		{key: "GRPC_ENABLED", flag: "grpc-enabled", usage: "社内サービス向けの gRPC サーバーを起動するか", value: (*boolValue)(&c.GRPC.Enabled)},
+		{key: "GRPC_PORT", flag: "grpc-port", usage: "gRPC サーバーのポート番号", value: (*stringValue)(&c.GRPC.Port)},
+
+		{key: "STREAM_HEARTBEAT_INTERVAL", flag: "stream-heartbeat-interval", usage: "ランキング更新のストリームでハートビートを送る間隔", value: (*durationValue)(&c.Stream.HeartbeatInterval)},
+		{key: "STREAM_HISTORY_SIZE", flag: "stream-history-size", usage: "再接続時に送り直せるよう、カテゴリ・期間ごとに保持するイベント数", value: (*intValue)(&c.Stream.HistorySize)},
+		{key: "STREAM_MAX_SUBSCRIBERS", flag: "stream-max-subscribers", usage: "ランキング更新のストリームの同時接続数の上限", value: (*intValue)(&c.Stream.MaxSubscribers)},
+
+		{key: "WEBHOOK_ENABLED", flag: "webhook-enabled", usage: "ランキングの変化を Webhook で通知するか", value: (*boolValue)(&c.Webhook.Enabled)},
+		{key: "WEBHOOK_POLL_INTERVAL", flag: "webhook-poll-interval", usage: "Webhook の配信キューを確認する間隔", value: (*durationValue)(&c.Webhook.PollInterval)},
+		{key: "WEBHOOK_BATCH_SIZE", flag: "webhook-batch-size", usage: "1回に並行して送信する Webhook の通知数", value: (*intValue)(&c.Webhook.BatchSize)},
+		{key: "WEBHOOK_MAX_ATTEMPTS", flag: "webhook-max-attempts", usage: "Webhook の配信をあきらめるまでの送信回数", value: (*intValue)(&c.Webhook.MaxAttempts)},
+		{key: "WEBHOOK_RETRY_BASE_DELAY", flag: "webhook-retry-base-delay", usage: "Webhook の再試行までの待ち時間の初期値", value: (*durationValue)(&c.Webhook.RetryBaseDelay)},
+		{key: "WEBHOOK_RETRY_MAX_DELAY", flag: "webhook-retry-max-delay", usage: "Webhook の再試行までの待ち時間の上限", value: (*durationValue)(&c.Webhook.RetryMaxDelay)},
+		{key: "WEBHOOK_TIMEOUT", flag: "webhook-timeout", usage: "Webhook の1回の送信のタイムアウト", value: (*durationValue)(&c.Webhook.Timeout)},
+
+		{key: "OPENAPI_VALIDATE_REQUESTS", flag: "openapi-validate-requests", usage: "リクエストを API 仕様と照合し、合わないものを拒否するか", value: (*boolValue)(&c.OpenAPI.ValidateRequests)},
+		{key: "OPENAPI_DOCS_ENABLED", flag: "openapi-docs-enabled", usage: "API 仕様と Swagger UI（/docs）を公開するか", value: (*boolValue)(&c.OpenAPI.Docs)},
+		{key: "OPENAPI_BASE_URL", flag: "openapi-base-url", usage: "公開する API 仕様の servers に記載するURL（未指定の場合はリクエストのホスト）", value: (*stringValue)(&c.OpenAPI.BaseURL)},
+
+		{key: "API_LEGACY_DEPRECATED_AT", flag: "api-legacy-deprecated-at", usage: "バージョンのないルート（/api/...）を非推奨にした日（YYYY-MM-DD）", value: (*stringValue)(&c.Versions.LegacyDeprecatedAt)},
+		{key: "API_LEGACY_SUNSET_AT", flag: "api-legacy-sunset-at", usage: "バージョンのないルートの提供を終える予定の日（YYYY-MM-DD、未指定の場合は未定）", value: (*stringValue)(&c.Versions.LegacySunsetAt)},
+	}
+}
+
+// Setting は有効な設定値とその取得元である。
+type Setting struct {
+	Key    string `json:"key"`
+	Value  string `json:"value"`
+	Source string `json:"source"`
+}
+
+// Loaded は読み込んだ設定と、各項目の取得元である。
+type Loaded struct {
+	Config  Config
+	sources map[string]string
+}
+
+// Load はデフォルト値、設定ファイル、環境変数、コマンドラインフラグの順に設定を読み込み、検証する。
+// 設定ファイルは -config フラグまたは CONFIG_FILE 環境変数で指定する。
+func Load(args []string) (*Loaded, error) {
+	return load(args, os.LookupEnv, os.ReadFile)
+}
+
+func load(args []string, lookupEnv func(string) (string, bool), readFile func(string) ([]byte, error)) (*Loaded, error) {
+	cfg := Default()
+	fields := cfg.fields()
+	sources := make(map[string]string, len(fields))
+	for _, f := range fields {
+		sources[f.key] = SourceDefault
+	}
+
+	// フラグ
+	fs := flag.NewFlagSet(filepath.Base(os.Args[0]), flag.ContinueOnError)
+	configPath := fs.String("config", "", "設定ファイルのパス（.yaml, .yml, .toml）。環境変数 CONFIG_FILE でも指定できる")
+	flagValues := make(map[string]*string, len(fields))
+	for _, f := range fields {
+		usage := fmt.Sprintf("%s（環境変数 %s）", f.usage, f.key)
+		def := f.value.String()
+		if f.secret {
+			def = ""
+		}
+		flagValues[f.flag] = fs.String(f.flag, def, usage)
+	}
+	if err := fs.Parse(args); err != nil {
+		return nil, err
+	}
+
+	// 設定ファイル
+	path := *configPath
+	if path == "" {
+		path, _ = lookupEnv("CONFIG_FILE")
+	}
+	if path != "" {
+		before := make(map[string]string, len(fields))
+		for _, f := range fields {
+			before[f.key] = f.value.String()
+		}
+		if err := loadFile(path, &cfg, readFile); err != nil {
+			return nil, err
+		}
+		for _, f := range fields {
+			if f.value.String() != before[f.key] {
+				sources[f.key] = SourceFile
+			}
+		}
+	}
+
+	// 環境変数
+	var errs []error
+	for _, f := range fields {
+		value, ok, err := lookup(f, lookupEnv, readFile)
+		if err != nil {
+			errs = append(errs, err)
+			continue
+		}
+		if !ok {
+			continue
+		}
+		if err := f.value.Set(value); err != nil {
+			errs = append(errs, fmt.Errorf("環境変数 %s の形式が不正です: %w", f.key, err))
+			continue
+		}
+		sources[f.key] = SourceEnv
+	}
+
+	// フラグ（明示的に指定されたものだけ）
+	byFlag := make(map[string]field, len(fields))
+	for _, f := range fields {
+		byFlag[f.flag] = f
+	}
+	fs.Visit(func(fl *flag.Flag) {
+		f, ok := byFlag[fl.Name]
+		if !ok {
+			return
+		}
+		if err := f.value.Set(*flagValues[fl.Name]); err != nil {
+			errs = append(errs, fmt.Errorf("フラグ -%s の形式が不正です: %w", fl.Name, err))
+			return
+		}
+		sources[f.key] = SourceFlag
+	})
+
+	if err := errors.Join(errs...); err != nil {
+		return nil, err
+	}
+	if err := cfg.Validate(); err != nil {
+		return nil, fmt.Errorf("設定が不正です: %w", err)
+	}
+
+	return &Loaded{Config: cfg, sources: sources}, nil
+}
+
+// lookup は環境変数を取得する。KEY_FILE が設定されている場合はそのファイルの内容を値とし、
+// KEY が未設定の場合は旧名の環境変数も参照する。
+func lookup(f field, lookupEnv func(string) (string, bool), readFile func(string) ([]byte, error)) (string, bool, error) {
+	value, ok := lookupEnv(f.key)
+	if path, fileOK := lookupEnv(f.key + "_FILE"); fileOK {
+		if ok {
+			return "", false, fmt.Errorf("環境変数 %s と %s_FILE は同時に指定できません", f.key, f.key)
+		}
+		b, err := readFile(path)
+		if err != nil {
+			return "", false, fmt.Errorf("環境変数 %s_FILE のファイルを読み込めません: %w", f.key, err)
+		}
+		return strings.TrimRight(string(b), "\r\n"), true, nil
+	}
+	if ok {
+		return value, true, nil
+	}
+	for _, alias := range f.aliases {
+		if value, ok := lookupEnv(alias); ok {
+			return value, true, nil
+		}
+	}
+	return "", false, nil
+}
+
+// loadFile は拡張子に応じて YAML または TOML の設定ファイルを cfg に読み込む。
+// ファイルに記載のない項目は cfg の値のまま残る。
+func loadFile(path string, cfg *Config, readFile func(string) ([]byte, error)) error {
+	b, err := readFile(path)
+	if err != nil {
+		return fmt.Errorf("設定ファイルを読み込めません: %w", err)
+	}
+
+	switch ext := strings.ToLower(filepath.Ext(path)); ext {
+	case ".yaml", ".yml":
+		err = yaml.Unmarshal(b, cfg)
+	case ".toml":
+		_, err = toml.Decode(string(b), cfg)
+	default:
+		return fmt.Errorf("設定ファイルの拡張子 %q には対応していません", ext)
+	}
+	if err != nil {
+		return fmt.Errorf("設定ファイル %s の形式が不正です: %w", path, err)
+	}
+	return nil
+}
+
+// Effective は有効な設定値を取得元とともにキー順で返す。秘密情報は伏せ字にする。
+func (l *Loaded) Effective() []Setting {
+	cfg := l.Config
+	fields := cfg.fields()
+
+	settings := make([]Setting, 0, len(fields))
+	for _, f := range fields {
+		value := f.value.String()
+		if f.secret && value != "" {
+			value = redacted
+		}
+		settings = append(settings, Setting{Key: f.key, Value: value, Source: l.sources[f.key]})
+	}
+	sort.Slice(settings, func(i, j int) bool { return settings[i].Key < settings[j].Key })
+	return settings
+}
@@ -65,4 +65,104 @@ CREATE TABLE rankings (
   FOREIGN KEY (category_id) REFERENCES categories(id),
   INDEX idx_rank_period (rank, period_type, date_from),
   INDEX idx_category_period (category_id, period_type, date_from)
-);
\ No newline at end of file
+);
+
+-- 取得元ごとに最後に取得できたランキング（取得元の障害時に返す）
+CREATE TABLE source_responses (
+  source VARCHAR(50) NOT NULL,
+  category_id VARCHAR(100) NOT NULL,
+  period_type VARCHAR(20) NOT NULL,
+  body JSON NOT NULL,
+  fetched_at TIMESTAMP NOT NULL,
+  PRIMARY KEY (source, category_id, period_type)
+);
+
+-- APIキー（キー本体は保存せず SHA-256 ハッシュで照合する）
+CREATE TABLE api_keys (
+  id VARCHAR(36) PRIMARY KEY,
+  name VARCHAR(100) NOT NULL,
+  prefix VARCHAR(16) NOT NULL,
+  key_hash CHAR(64) NOT NULL,
+  role ENUM('public', 'partner', 'admin') NOT NULL,
+  scopes VARCHAR(255) NOT NULL DEFAULT '',
+  created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+  revoked_at TIMESTAMP NULL,
+  UNIQUE KEY uq_api_keys_hash (key_hash)
+);
+
+-- 商品リンクのクリック（/go/{bookSiteMappingId} 経由）
+CREATE TABLE clicks (
+  id BIGINT AUTO_INCREMENT PRIMARY KEY,
+  book_site_mapping_id VARCHAR(36) NOT NULL,
+  clicked_at TIMESTAMP NOT NULL,
+  referrer VARCHAR(512) NOT NULL DEFAULT '',
+  category_id VARCHAR(36),
+  period_type ENUM('daily', 'weekly', 'monthly', 'yearly'),
+  `rank` INT,
+  -- 送信元IPアドレスの HMAC-SHA256。アドレスそのものは保存しない
+  ip_hash CHAR(64) NOT NULL,
+  FOREIGN KEY (book_site_mapping_id) REFERENCES book_site_mappings(id),
+  INDEX idx_clicks_clicked_at (clicked_at),
+  INDEX idx_clicks_mapping (book_site_mapping_id, clicked_at)
+);
+
+-- ランキングの変化を外部サービスへ通知する Webhook の登録
+CREATE TABLE webhook_subscriptions (
+  id VARCHAR(36) PRIMARY KEY,
+  name VARCHAR(100) NOT NULL,
+  url VARCHAR(2048) NOT NULL,
+  -- 通知の署名（HMAC-SHA256）の鍵。送信のたびに使うため、ハッシュにせず保存する
+  secret VARCHAR(128) NOT NULL,
+  -- NULL の場合はすべてのカテゴリを通知する
+  category_id VARCHAR(36),
+  period_type ENUM('daily', 'weekly', 'monthly', 'yearly') NOT NULL DEFAULT 'daily',
+  -- 通知するイベントの種類（カンマ区切り）
+  events VARCHAR(255) NOT NULL,
+  top_n INT NOT NULL,
+  created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+  disabled_at TIMESTAMP NULL,
+  FOREIGN KEY (category_id) REFERENCES categories(id)
+);
+
+-- Webhook の配信キューと配信ログ。配信に成功するか、再試行の上限に達して dead になるまで pending のまま残る
+CREATE TABLE webhook_deliveries (
+  id BIGINT AUTO_INCREMENT PRIMARY KEY,
+  subscription_id VARCHAR(36) NOT NULL,
+  event_type VARCHAR(32) NOT NULL,
+  -- 同じ変化を二重に通知しないためのキー（種類・カテゴリ・期間・日付・書籍）
+  event_key VARCHAR(191) NOT NULL,
+  payload JSON NOT NULL,
+  status ENUM('pending', 'succeeded', 'dead') NOT NULL DEFAULT 'pending',
+  attempts INT NOT NULL DEFAULT 0,
+  next_attempt_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP,
+  last_status_code INT,
+  last_error VARCHAR(512) NOT NULL DEFAULT '',
+  created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+  delivered_at TIMESTAMP NULL,
+  FOREIGN KEY (subscription_id) REFERENCES webhook_subscriptions(id),
+  UNIQUE KEY uq_webhook_deliveries_event (subscription_id, event_key),
+  INDEX idx_webhook_deliveries_due (status, next_attempt_at),
+  INDEX idx_webhook_deliveries_created (created_at)
+);
+
+-- 初期データ: ECサイト
+INSERT INTO sites (id, name, base_url, affiliate_id) VALUES
+  ('site-rakuten', 'rakuten', 'https://books.rakuten.co.jp', NULL),
+  ('site-amazon', 'amazon', 'https://www.amazon.co.jp', NULL),
+  ('site-yahoo', 'yahoo', 'https://shopping.yahoo.co.jp', NULL);
+
+-- 初期データ: カテゴリ
+INSERT INTO categories (id, name, parent_id) VALUES
+  ('001', 'ビジネス書', NULL),
+  ('002', '自己啓発', '001'),
+  ('003', 'マーケティング', '001'),
+  ('004', '経済・金融', '001'),
+  ('005', 'IT・テクノロジー', '001');
+
+-- 初期データ: 楽天のカテゴリマッピング（取り込み対象）
+INSERT INTO site_category_mappings (id, category_id, site_id, site_specific_category_id) VALUES
+  ('scm-rakuten-001', '001', 'site-rakuten', '001'),
+  ('scm-rakuten-002', '002', 'site-rakuten', '002'),
+  ('scm-rakuten-003', '003', 'site-rakuten', '003'),
+  ('scm-rakuten-004', '004', 'site-rakuten', '004'),
+  ('scm-rakuten-005', '005', 'site-rakuten', '005');
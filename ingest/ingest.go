@@ -0,0 +1,248 @@
+// Package ingest は外部ECサイトからランキングを取得してデータベースへ取り込むジョブを提供する。
+package ingest
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"sync"
+	"time"
+
+	"github.com/h-hiwatashi/super-business-book-ranking-backend/logging"
+	"github.com/h-hiwatashi/super-business-book-ranking-backend/metrics"
+	"github.com/h-hiwatashi/super-business-book-ranking-backend/repository"
+)
+
+// ランキングの日付を決めるタイムゾーン
+var jst = time.FixedZone("Asia/Tokyo", 9*60*60)
+
+// Source はランキングの取得元である。
+type Source interface {
+	// Name は sites.name と一致する取得元の名前を返す。
+	Name() string
+	// FetchRanking はサイト固有のカテゴリIDと期間でランキングを取得する。
+	FetchRanking(ctx context.Context, siteCategoryID, periodType string) ([]repository.SnapshotEntry, error)
+}
+
+// 1件のスナップショット保存にかける時間の上限
+const saveTimeout = 30 * time.Second
+
+// SiteStore は取得元に対応するサイト情報を参照するストアである。
+type SiteStore interface {
+	FindByName(ctx context.Context, name string) (*repository.Site, error)
+	ListCategoryMappings(ctx context.Context, siteID string) ([]repository.CategoryMapping, error)
+}
+
+// SnapshotStore は取り込んだランキングを保存するストアである。
+type SnapshotStore interface {
+	SaveSnapshot(ctx context.Context, snapshot repository.Snapshot) error
+}
+
+// Listener はスナップショットの保存後に通知を受け取る。
+// キャッシュの無効化など、新しいランキングに応じた処理を行う。
+type Listener interface {
+	SnapshotSaved(ctx context.Context, snapshot repository.Snapshot)
+}
+
+// Job は登録された取得元から定期的にランキングを取り込むジョブである。
+type Job struct {
+	Sources   []Source
+	Sites     SiteStore
+	Rankings  SnapshotStore
+	Periods   []string
+	Interval  time.Duration
+	Listeners []Listener
+	// Concurrency は1つの取得元から同時にランキングを取得するワーカー数である。
+	// 取得元ごとの送信間隔の制限は Source 側で行う。
+	Concurrency int
+
+	now     func() time.Time
+	trigger chan struct{}
+}
+
+func NewJob(sites SiteStore, rankings SnapshotStore, sources []Source, periods []string, interval time.Duration) *Job {
+	return &Job{
+		Sources:  sources,
+		Sites:    sites,
+		Rankings: rankings,
+		Periods:  periods,
+		Interval: interval,
+		// 取得元への負荷を抑えるため、既定では1件ずつ取得する
+		Concurrency: 1,
+		now:         time.Now,
+		trigger:     make(chan struct{}, 1),
+	}
+}
+
+// Trigger は次の定期実行を待たずに取り込みを行うよう Run に要求する。
+// 要求は待たずに戻り、取り込み中や要求済みの場合の要求は1回分にまとめる。
+func (j *Job) Trigger() {
+	select {
+	case j.trigger <- struct{}{}:
+	default:
+	}
+}
+
+// Run は直ちに1回取り込みを行い、以降は Interval ごと、または Trigger が呼ばれるたびに ctx が終了するまで繰り返す。
+func (j *Job) Run(ctx context.Context) {
+	logger := logging.FromContext(ctx)
+
+	ticker := time.NewTicker(j.Interval)
+	defer ticker.Stop()
+
+	for {
+		if err := j.RunOnce(ctx); err != nil {
+			logger.Error("ランキング取り込みエラー", "error", err)
+		}
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		case <-j.trigger:
+		}
+	}
+}
+
+// RunOnce はすべての取得元からランキングを1回取り込む。
+// 一部の取得元やカテゴリで失敗しても残りの取り込みは継続し、発生したエラーをまとめて返す。
+func (j *Job) RunOnce(ctx context.Context) error {
+	var errs []error
+	for _, source := range j.Sources {
+		if err := j.ingestSource(ctx, source); err != nil {
+			errs = append(errs, fmt.Errorf("%s: %w", source.Name(), err))
+		}
+	}
+	return errors.Join(errs...)
+}
+
+func (j *Job) ingestSource(ctx context.Context, source Source) error {
+	name := source.Name()
+	logger := logging.FromContext(ctx).With("source", name)
+
+	site, err := j.Sites.FindByName(ctx, name)
+	if err != nil {
+		metrics.IngestionErrors.WithLabelValues(name).Inc()
+		return fmt.Errorf("サイト情報取得エラー: %w", err)
+	}
+
+	mappings, err := j.Sites.ListCategoryMappings(ctx, site.ID)
+	if err != nil {
+		metrics.IngestionErrors.WithLabelValues(name).Inc()
+		return fmt.Errorf("カテゴリマッピング取得エラー: %w", err)
+	}
+
+	// カテゴリと期間の組み合わせを Concurrency 個のワーカーで取り込む
+	type task struct {
+		mapping    repository.CategoryMapping
+		periodType string
+	}
+	tasks := make(chan task)
+
+	var (
+		mu   sync.Mutex
+		errs []error
+		wg   sync.WaitGroup
+	)
+	for i := 0; i < max(j.Concurrency, 1); i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for t := range tasks {
+				if err := j.ingestRanking(ctx, logger, source, site, t.mapping, t.periodType); err != nil {
+					mu.Lock()
+					errs = append(errs, err)
+					mu.Unlock()
+				}
+			}
+		}()
+	}
+
+feed:
+	for _, mapping := range mappings {
+		for _, periodType := range j.Periods {
+			select {
+			case tasks <- task{mapping: mapping, periodType: periodType}:
+			case <-ctx.Done():
+				break feed
+			}
+		}
+	}
+	close(tasks)
+	wg.Wait()
+
+	if ctx.Err() != nil {
+		return errors.Join(append(errs, ctx.Err())...)
+	}
+	if len(errs) > 0 {
+		return errors.Join(errs...)
+	}
+	metrics.IngestionLastSuccess.WithLabelValues(name).SetToCurrentTime()
+	return nil
+}
+
+// ingestRanking は1つのカテゴリと期間のランキングを取得して保存する。
+func (j *Job) ingestRanking(ctx context.Context, logger *slog.Logger, source Source, site *repository.Site, mapping repository.CategoryMapping, periodType string) error {
+	name := source.Name()
+
+	entries, err := source.FetchRanking(ctx, mapping.SiteSpecificCategoryID, periodType)
+	if err != nil {
+		metrics.IngestionErrors.WithLabelValues(name).Inc()
+		return fmt.Errorf("ランキング取得エラー (category: %s, period: %s): %w", mapping.CategoryID, periodType, err)
+	}
+	metrics.IngestionItemsFetched.WithLabelValues(name).Add(float64(len(entries)))
+
+	dateFrom, dateTo := periodRange(periodType, j.now())
+	snapshot := repository.Snapshot{
+		SiteID:     site.ID,
+		CategoryID: mapping.CategoryID,
+		PeriodType: periodType,
+		DateFrom:   dateFrom,
+		DateTo:     dateTo,
+		Entries:    entries,
+	}
+	if err := j.save(ctx, snapshot); err != nil {
+		metrics.IngestionErrors.WithLabelValues(name).Inc()
+		return fmt.Errorf("ランキング保存エラー (category: %s, period: %s): %w", mapping.CategoryID, periodType, err)
+	}
+
+	j.notify(ctx, snapshot)
+
+	logger.Info("ランキング取り込み完了", "categoryId", mapping.CategoryID, "period", periodType, "items", len(entries))
+	return nil
+}
+
+// save はスナップショットを保存する。
+// 停止要求で保存が途中で止まらないよう、ctx のキャンセルは引き継がずに期限だけを設ける。
+func (j *Job) save(ctx context.Context, snapshot repository.Snapshot) error {
+	ctx, cancel := context.WithTimeout(context.WithoutCancel(ctx), saveTimeout)
+	defer cancel()
+	return j.Rankings.SaveSnapshot(ctx, snapshot)
+}
+
+// notify は保存済みのスナップショットをリスナーに通知する。
+// 保存は完了しているため、停止要求があってもキャッシュの無効化などは必ず行う。
+func (j *Job) notify(ctx context.Context, snapshot repository.Snapshot) {
+	ctx = context.WithoutCancel(ctx)
+	for _, listener := range j.Listeners {
+		listener.SnapshotSaved(ctx, snapshot)
+	}
+}
+
+// periodRange は取り込み時刻を基準にランキング期間の開始日と終了日を返す。
+func periodRange(periodType string, now time.Time) (time.Time, time.Time) {
+	y, m, d := now.In(jst).Date()
+	to := time.Date(y, m, d, 0, 0, 0, 0, jst)
+
+	switch periodType {
+	case "weekly":
+		return to.AddDate(0, 0, -6), to
+	case "monthly":
+		return to.AddDate(0, -1, 1), to
+	case "yearly":
+		return to.AddDate(-1, 0, 1), to
+	default:
+		return to, to
+	}
+}
@@ -0,0 +1,225 @@
+package ingest
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus/testutil"
+
+	"github.com/h-hiwatashi/super-business-book-ranking-backend/metrics"
+	"github.com/h-hiwatashi/super-business-book-ranking-backend/repository"
+)
+
+type fakeSource struct {
+	name    string
+	entries []repository.SnapshotEntry
+	failFor map[string]bool
+}
+
+func (s *fakeSource) Name() string { return s.name }
+
+func (s *fakeSource) FetchRanking(ctx context.Context, siteCategoryID, periodType string) ([]repository.SnapshotEntry, error) {
+	if s.failFor[siteCategoryID] {
+		return nil, errors.New("fetch failed")
+	}
+	return s.entries, nil
+}
+
+type fakeSiteStore struct {
+	mappings []repository.CategoryMapping
+}
+
+func (s *fakeSiteStore) FindByName(ctx context.Context, name string) (*repository.Site, error) {
+	return &repository.Site{ID: "site-" + name, Name: name}, nil
+}
+
+func (s *fakeSiteStore) ListCategoryMappings(ctx context.Context, siteID string) ([]repository.CategoryMapping, error) {
+	return s.mappings, nil
+}
+
+type fakeSnapshotStore struct {
+	mu    sync.Mutex
+	saved []repository.Snapshot
+}
+
+func (s *fakeSnapshotStore) SaveSnapshot(ctx context.Context, snapshot repository.Snapshot) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.saved = append(s.saved, snapshot)
+	return nil
+}
+
+func TestRunOnce(t *testing.T) {
+	source := &fakeSource{
+		name: "test-success",
+		entries: []repository.SnapshotEntry{
+			{Rank: 1, ISBN: "9784123456789", SiteSpecificID: "9784123456789"},
+			{Rank: 2, ISBN: "9784123456790", SiteSpecificID: "9784123456790"},
+		},
+	}
+	sites := &fakeSiteStore{mappings: []repository.CategoryMapping{
+		{CategoryID: "cat-1", SiteSpecificCategoryID: "001"},
+		{CategoryID: "cat-2", SiteSpecificCategoryID: "002"},
+	}}
+	store := &fakeSnapshotStore{}
+
+	job := NewJob(sites, store, []Source{source}, []string{"daily", "weekly"}, time.Hour)
+	job.now = func() time.Time { return time.Date(2024, 3, 15, 1, 0, 0, 0, time.UTC) }
+
+	if err := job.RunOnce(context.Background()); err != nil {
+		t.Fatalf("RunOnce() error = %v", err)
+	}
+
+	if len(store.saved) != 4 {
+		t.Fatalf("saved %d snapshots, want 4", len(store.saved))
+	}
+	first := store.saved[0]
+	if first.SiteID != "site-test-success" || first.CategoryID != "cat-1" || first.PeriodType != "daily" {
+		t.Errorf("unexpected snapshot: %+v", first)
+	}
+	if got := first.DateTo.Format("2006-01-02"); got != "2024-03-15" {
+		t.Errorf("DateTo = %s, want 2024-03-15", got)
+	}
+
+	if got := testutil.ToFloat64(metrics.IngestionItemsFetched.WithLabelValues("test-success")); got != 8 {
+		t.Errorf("items fetched = %v, want 8", got)
+	}
+	if got := testutil.ToFloat64(metrics.IngestionLastSuccess.WithLabelValues("test-success")); got == 0 {
+		t.Error("last success timestamp was not recorded")
+	}
+}
+
+func TestRunOnceConcurrent(t *testing.T) {
+	source := &fakeSource{
+		name:    "test-concurrent",
+		entries: []repository.SnapshotEntry{{Rank: 1, ISBN: "9784123456789"}},
+	}
+	sites := &fakeSiteStore{mappings: []repository.CategoryMapping{
+		{CategoryID: "cat-1", SiteSpecificCategoryID: "001"},
+		{CategoryID: "cat-2", SiteSpecificCategoryID: "002"},
+		{CategoryID: "cat-3", SiteSpecificCategoryID: "003"},
+	}}
+	store := &fakeSnapshotStore{}
+
+	job := NewJob(sites, store, []Source{source}, []string{"daily", "weekly", "monthly"}, time.Hour)
+	job.Concurrency = 4
+
+	if err := job.RunOnce(context.Background()); err != nil {
+		t.Fatalf("RunOnce() error = %v", err)
+	}
+
+	if len(store.saved) != 9 {
+		t.Errorf("saved %d snapshots, want 9", len(store.saved))
+	}
+	if got := testutil.ToFloat64(metrics.IngestionItemsFetched.WithLabelValues("test-concurrent")); got != 9 {
+		t.Errorf("items fetched = %v, want 9", got)
+	}
+}
+
+type notifyListener chan repository.Snapshot
+
+func (l notifyListener) SnapshotSaved(ctx context.Context, snapshot repository.Snapshot) {
+	l <- snapshot
+}
+
+func TestRunTrigger(t *testing.T) {
+	source := &fakeSource{
+		name:    "test-trigger",
+		entries: []repository.SnapshotEntry{{Rank: 1, ISBN: "9784123456789"}},
+	}
+	sites := &fakeSiteStore{mappings: []repository.CategoryMapping{
+		{CategoryID: "cat-1", SiteSpecificCategoryID: "001"},
+	}}
+	saved := make(notifyListener, 1)
+
+	job := NewJob(sites, &fakeSnapshotStore{}, []Source{source}, []string{"daily"}, time.Hour)
+	job.Listeners = append(job.Listeners, saved)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		job.Run(ctx)
+	}()
+	defer func() {
+		cancel()
+		<-done
+	}()
+
+	// 起動直後の取り込み
+	receiveSnapshot(t, saved)
+
+	// Interval を待たずに取り込まれる
+	job.Trigger()
+	receiveSnapshot(t, saved)
+}
+
+func receiveSnapshot(t *testing.T, saved notifyListener) {
+	t.Helper()
+	select {
+	case <-saved:
+	case <-time.After(5 * time.Second):
+		t.Fatal("snapshot was not saved")
+	}
+}
+
+func TestRunOncePartialFailure(t *testing.T) {
+	source := &fakeSource{
+		name:    "test-failure",
+		entries: []repository.SnapshotEntry{{Rank: 1, ISBN: "9784123456789"}},
+		failFor: map[string]bool{"002": true},
+	}
+	sites := &fakeSiteStore{mappings: []repository.CategoryMapping{
+		{CategoryID: "cat-1", SiteSpecificCategoryID: "001"},
+		{CategoryID: "cat-2", SiteSpecificCategoryID: "002"},
+	}}
+	store := &fakeSnapshotStore{}
+
+	job := NewJob(sites, store, []Source{source}, []string{"daily"}, time.Hour)
+
+	if err := job.RunOnce(context.Background()); err == nil {
+		t.Fatal("RunOnce() error = nil, want error")
+	}
+
+	// 失敗したカテゴリ以外は保存される
+	if len(store.saved) != 1 {
+		t.Errorf("saved %d snapshots, want 1", len(store.saved))
+	}
+	if got := testutil.ToFloat64(metrics.IngestionErrors.WithLabelValues("test-failure")); got != 1 {
+		t.Errorf("ingestion errors = %v, want 1", got)
+	}
+	if got := testutil.ToFloat64(metrics.IngestionLastSuccess.WithLabelValues("test-failure")); got != 0 {
+		t.Errorf("last success timestamp = %v, want 0", got)
+	}
+}
+
+func TestPeriodRange(t *testing.T) {
+	// UTC 2024-03-14 16:00 は JST 2024-03-15 01:00
+	now := time.Date(2024, 3, 14, 16, 0, 0, 0, time.UTC)
+
+	testCases := []struct {
+		periodType string
+		wantFrom   string
+		wantTo     string
+	}{
+		{periodType: "daily", wantFrom: "2024-03-15", wantTo: "2024-03-15"},
+		{periodType: "weekly", wantFrom: "2024-03-09", wantTo: "2024-03-15"},
+		{periodType: "monthly", wantFrom: "2024-02-16", wantTo: "2024-03-15"},
+		{periodType: "yearly", wantFrom: "2023-03-16", wantTo: "2024-03-15"},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.periodType, func(t *testing.T) {
+			from, to := periodRange(tc.periodType, now)
+			if got := from.Format("2006-01-02"); got != tc.wantFrom {
+				t.Errorf("from = %s, want %s", got, tc.wantFrom)
+			}
+			if got := to.Format("2006-01-02"); got != tc.wantTo {
+				t.Errorf("to = %s, want %s", got, tc.wantTo)
+			}
+		})
+	}
+}
@@ -0,0 +1,60 @@
+package ingest
+
+import (
+	"context"
+	"strings"
+	"time"
+
+	"github.com/h-hiwatashi/super-business-book-ranking-backend/api/rakuten"
+	"github.com/h-hiwatashi/super-business-book-ranking-backend/repository"
+)
+
+// RakutenSource は楽天ブックスのランキングを取得する Source である。
+type RakutenSource struct {
+	Client *rakuten.RakutenClient
+}
+
+func NewRakutenSource(client *rakuten.RakutenClient) *RakutenSource {
+	return &RakutenSource{Client: client}
+}
+
+func (s *RakutenSource) Name() string {
+	return "rakuten"
+}
+
+func (s *RakutenSource) FetchRanking(ctx context.Context, siteCategoryID, periodType string) ([]repository.SnapshotEntry, error) {
+	ranking, err := s.Client.GetBookRanking(ctx, siteCategoryID, periodType)
+	if err != nil {
+		return nil, err
+	}
+
+	entries := make([]repository.SnapshotEntry, 0, len(ranking.Items))
+	for _, item := range ranking.Items {
+		book := item.Item
+		entries = append(entries, repository.SnapshotEntry{
+			Rank:            book.Rank,
+			SiteSpecificID:  book.ISBN,
+			Title:           book.Title,
+			Author:          book.Author,
+			Publisher:       book.PublisherName,
+			ISBN:            book.ISBN,
+			PublicationDate: normalizeSalesDate(book.SalesDate),
+			ImageURL:        book.LargeImageURL,
+			Price:           float64(book.ItemPrice),
+			URL:             book.ItemURL,
+		})
+	}
+	return entries, nil
+}
+
+// normalizeSalesDate は楽天の発売日を YYYY-MM-DD 形式に変換する。
+// 「2024年03月頃」のように日付が確定していない場合は空文字を返す。
+func normalizeSalesDate(salesDate string) string {
+	salesDate = strings.TrimSpace(salesDate)
+	for _, layout := range []string{"2006-01-02", "2006年01月02日"} {
+		if t, err := time.Parse(layout, salesDate); err == nil {
+			return t.Format("2006-01-02")
+		}
+	}
+	return ""
+}
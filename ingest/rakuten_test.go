@@ -0,0 +1,44 @@
+package ingest
+
+import (
+	"context"
+	"testing"
+
+	"github.com/h-hiwatashi/super-business-book-ranking-backend/api/rakuten"
+)
+
+func TestRakutenSourceFetchRanking(t *testing.T) {
+	source := NewRakutenSource(rakuten.NewRakutenClient())
+
+	entries, err := source.FetchRanking(context.Background(), "001", "daily")
+	if err != nil {
+		t.Fatalf("FetchRanking() error = %v", err)
+	}
+	if len(entries) == 0 {
+		t.Fatal("FetchRanking() returned no entries")
+	}
+
+	for i, entry := range entries {
+		if entry.Rank != i+1 {
+			t.Errorf("entry %d has rank %d, want %d", i, entry.Rank, i+1)
+		}
+		if entry.SiteSpecificID == "" || entry.SiteSpecificID != entry.ISBN {
+			t.Errorf("entry %d has site specific ID %q, want ISBN %q", i, entry.SiteSpecificID, entry.ISBN)
+		}
+	}
+}
+
+func TestNormalizeSalesDate(t *testing.T) {
+	testCases := map[string]string{
+		"2024-03-15":  "2024-03-15",
+		"2024年03月15日": "2024-03-15",
+		"2024年03月頃":   "",
+		"":            "",
+	}
+
+	for input, want := range testCases {
+		if got := normalizeSalesDate(input); got != want {
+			t.Errorf("normalizeSalesDate(%q) = %q, want %q", input, got, want)
+		}
+	}
+}
@@ -0,0 +1,47 @@
+// Package logging はアプリケーション全体で利用する構造化ロガーを提供する。
+package logging
+
+import (
+	"context"
+	"io"
+	"log/slog"
+	"strings"
+)
+
+type contextKey struct{}
+
+// New は JSON 形式で出力する slog.Logger を生成する。
+// level には debug, info, warn, error のいずれかを指定し、それ以外は info として扱う。
+func New(w io.Writer, level string) *slog.Logger {
+	return slog.New(slog.NewJSONHandler(w, &slog.HandlerOptions{
+		Level: ParseLevel(level),
+	}))
+}
+
+// ParseLevel はログレベル文字列を slog.Level に変換する。
+func ParseLevel(level string) slog.Level {
+	switch strings.ToLower(level) {
+	case "debug":
+		return slog.LevelDebug
+	case "warn", "warning":
+		return slog.LevelWarn
+	case "error":
+		return slog.LevelError
+	default:
+		return slog.LevelInfo
+	}
+}
+
+// WithLogger はロガーを格納したコンテキストを返す。
+func WithLogger(ctx context.Context, logger *slog.Logger) context.Context {
+	return context.WithValue(ctx, contextKey{}, logger)
+}
+
+// FromContext はコンテキストに格納されたロガーを返す。
+// ロガーが格納されていない場合は slog.Default() を返す。
+func FromContext(ctx context.Context) *slog.Logger {
+	if logger, ok := ctx.Value(contextKey{}).(*slog.Logger); ok {
+		return logger
+	}
+	return slog.Default()
+}
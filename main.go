@@ -1,284 +1,465 @@
 package main
 
 import (
+	"context"
 	"database/sql"
-	"encoding/json"
+	"errors"
+	"flag"
 	"fmt"
-	"log"
+	"log/slog"
+	"net"
 	"net/http"
 	"os"
+	"os/signal"
+	"sync"
+	"syscall"
 	"time"
 
 	_ "github.com/go-sql-driver/mysql"
-	"github.com/gorilla/mux"
+	"github.com/redis/go-redis/v9"
 	
+	"github.com/h-hiwatashi/super-business-book-ranking-backend/affiliate"
+	"github.com/h-hiwatashi/super-business-book-ranking-backend/api/admin"
+	"github.com/h-hiwatashi/super-business-book-ranking-backend/api/click"
+	"github.com/h-hiwatashi/super-business-book-ranking-backend/api/docs"
+	"github.com/h-hiwatashi/super-business-book-ranking-backend/api/feed"
+	"github.com/h-hiwatashi/super-business-book-ranking-backend/api/graphql"
+	"github.com/h-hiwatashi/super-business-book-ranking-backend/api/health"
 	"github.com/h-hiwatashi/super-business-book-ranking-backend/api/rakuten"
+	"github.com/h-hiwatashi/super-business-book-ranking-backend/api/ranking"
+	"github.com/h-hiwatashi/super-business-book-ranking-backend/api/rpc"
+	"github.com/h-hiwatashi/super-business-book-ranking-backend/api/stream"
+	"github.com/h-hiwatashi/super-business-book-ranking-backend/auth"
+	"github.com/h-hiwatashi/super-business-book-ranking-backend/breaker"
+	"github.com/h-hiwatashi/super-business-book-ranking-backend/cache"
+	"github.com/h-hiwatashi/super-business-book-ranking-backend/clicklog"
+	"github.com/h-hiwatashi/super-business-book-ranking-backend/config"
+	"github.com/h-hiwatashi/super-business-book-ranking-backend/ingest"
+	"github.com/h-hiwatashi/super-business-book-ranking-backend/logging"
+	"github.com/h-hiwatashi/super-business-book-ranking-backend/metrics"
+	"github.com/h-hiwatashi/super-business-book-ranking-backend/middleware"
+	"github.com/h-hiwatashi/super-business-book-ranking-backend/openapi"
+	"github.com/h-hiwatashi/super-business-book-ranking-backend/ratelimit"
+	"github.com/h-hiwatashi/super-business-book-ranking-backend/repository"
+	"github.com/h-hiwatashi/super-business-book-ranking-backend/webhook"
 )
 
-// 環境変数の設定とデフォルト値
-var (
-	port     = getEnv("PORT", "8080")
-	dbHost   = getEnv("DB_HOST", "localhost")
-	dbPort   = getEnv("DB_PORT", "3306")
-	dbUser   = getEnv("DB_USER", "root")
-	dbPass   = getEnv("DB_PASS", "password")
-	dbName   = getEnv("DB_NAME", "book_ranking")
-	dbParams = getEnv("DB_PARAMS", "parseTime=true&loc=Asia%2FTokyo")
-)
-
-// ヘルスチェックレスポンス
-type HealthResponse struct {
-	Status  string `json:"status"`
-	Version string `json:"version"`
-	Time    string `json:"time"`
-}
+func main() {
+	loaded, err := config.Load(os.Args[1:])
+	if err != nil {
+		if errors.Is(err, flag.ErrHelp) {
+			return
+		}
+		slog.Error("設定読み込みエラー", "error", err)
+		os.Exit(2)
+	}
+	cfg := loaded.Config
 
-// ランキング書籍情報
-type RankedBook struct {
-	ID             string    `json:"id"`
-	Rank           int       `json:"rank"`
-	Title          string    `json:"title"`
-	Author         string    `json:"author"`
-	Publisher      string    `json:"publisher"`
-	ISBN           string    `json:"isbn"`
-	PublicationDate string   `json:"publicationDate"`
-	ImageURL       string    `json:"imageUrl"`
-	Price          float64   `json:"price"`
-	URL            string    `json:"url"`
-}
+	// 構造化ロガーの設定
+	slog.SetDefault(logging.New(os.Stdout, cfg.Log.Level))
+	slog.Info("有効な設定", "config", loaded.Effective())
 
-// ランキングリスト
-type RankingResponse struct {
-	CategoryID   string       `json:"categoryId"`
-	CategoryName string       `json:"categoryName"`
-	PeriodType   string       `json:"periodType"`
-	DateFrom     string       `json:"dateFrom"`
-	DateTo       string       `json:"dateTo"`
-	Books        []RankedBook `json:"books"`
+	if err := run(cfg); err != nil {
+		slog.Error("サーバーエラー", "error", err)
+		os.Exit(1)
+	}
+	slog.Info("サーバーを停止しました")
 }
 
-// データベース接続用のグローバル変数
-var db *sql.DB
-
-func main() {
+// run はサーバーを起動し、SIGINT または SIGTERM を受け取るまで処理を続ける。
+// シグナル受信後はレディネスを落として振り分け対象から外れるのを待ち、
+// 処理中のリクエストと取り込みジョブの完了を待ってからデータベース接続を閉じる。
+func run(cfg config.Config) error {
+	ctx, stop := signal.NotifyContext(context.Background(), syscall.SIGINT, syscall.SIGTERM)
+	defer stop()
 
 	// データベース接続
-	dataSourceName := fmt.Sprintf("%s:%s@tcp(%s:%s)/%s?%s", 
-		dbUser, dbPass, dbHost, dbPort, dbName, dbParams)
-	
-	var err error
-	db, err = sql.Open("mysql", dataSourceName)
+	db, err := sql.Open("mysql", cfg.Database.DSN())
 	if err != nil {
-		log.Fatalf("データベース接続エラー: %v", err)
+		return fmt.Errorf("データベース接続エラー: %w", err)
 	}
+	// 処理中のリクエストと取り込みジョブが終わった後に閉じる
 	defer db.Close()
 
 	// 接続テスト
-	err = db.Ping()
+	err = db.PingContext(ctx)
 	if err != nil {
-		log.Fatalf("データベースPingエラー: %v", err)
+		return fmt.Errorf("データベースPingエラー: %w", err)
 	}
-	log.Println("データベース接続成功")
+	slog.Info("データベース接続成功")
 
-	// ルーターの設定
-	r := mux.NewRouter()
+	if err := metrics.RegisterDBStats(db, cfg.Database.Name); err != nil {
+		return fmt.Errorf("メトリクス登録エラー: %w", err)
+	}
+
+	// リポジトリ
+	rankingRepo := repository.NewRankingRepository(db)
+	bookRepo := repository.NewBookRepository(db)
+	categoryRepo := repository.NewCategoryRepository(db)
+	siteRepo := repository.NewSiteRepository(db)
+	sourceResponseRepo := repository.NewSourceResponseRepository(db)
+	apiKeyRepo := repository.NewAPIKeyRepository(db)
+	clickRepo := repository.NewClickRepository(db)
+	webhookRepo := repository.NewWebhookRepository(db)
 	
 	rakutenHandler := rakuten.NewRakutenHandler()
-	
-	// APIエンドポイント
-	r.HandleFunc("/health", healthCheckHandler).Methods("GET")
-	r.HandleFunc("/api/rankings/{categoryId}", getRankingsHandler).Methods("GET")
-	r.HandleFunc("/api/books/{bookId}", getBookDetailsHandler).Methods("GET")
-	r.HandleFunc("/api/categories", getCategoriesHandler).Methods("GET")
-	
-	r.HandleFunc("/api/rakuten/rankings/{categoryId}", rakutenHandler.GetRakutenBookRankingHandler).Methods("GET")
+	if cfg.Rakuten.ApplicationID != "" {
+		// ハンドラーと取り込みジョブで同じクライアントを使い、アプリIDごとの制限とブレーカーの状態を共有する
+		client := rakuten.NewRakutenAPIClient(rakuten.ClientConfig{
+			ApplicationID:  cfg.Rakuten.ApplicationID,
+			BaseURL:        cfg.Rakuten.BaseURL,
+			Timeout:        cfg.Rakuten.Timeout,
+			RateLimit:      cfg.Rakuten.RateLimit,
+			Burst:          cfg.Rakuten.Burst,
+			MaxRetries:     cfg.Rakuten.MaxRetries,
+			RetryBaseDelay: cfg.Rakuten.RetryBaseDelay,
+			RetryMaxDelay:  cfg.Rakuten.RetryMaxDelay,
+		})
+		client.Breaker = breaker.New("rakuten", breaker.Settings{
+			FailureThreshold:    cfg.Rakuten.Breaker.FailureThreshold,
+			OpenTimeout:         cfg.Rakuten.Breaker.OpenTimeout,
+			HalfOpenMaxRequests: cfg.Rakuten.Breaker.HalfOpenRequests,
+			IsFailure:           rakuten.IsSourceFailure,
+		})
+		client.Responses = sourceResponseRepo
+		rakutenHandler.Client = client
+	}
+	// ランキングのキャッシュ
+	cacheStore, closeCache := newCacheStore(cfg)
+	defer closeCache()
+	cachedRankings := ranking.NewCachedRankingFinder(rankingRepo, cacheStore, cfg.Cache.TTL)
 
-	// サーバー起動
-	log.Printf("サーバーを起動しています。ポート: %s\n", port)
-	log.Fatal(http.ListenAndServe(":"+port, r))
-}
+	rankingHandler := ranking.NewRankingHandler(cachedRankings, bookRepo, categoryRepo)
+	// v2 のランキングの急上昇はフィードの急上昇と同じ基準にする
+	rankingHandler.Snapshots = rankingRepo
+	rankingHandler.Exports = rankingRepo
+	rankingHandler.MoverThreshold = cfg.Feeds.MoverThreshold
 
-// 環境変数を取得（デフォルト値付き）
-func getEnv(key, fallback string) string {
-	if value, exists := os.LookupEnv(key); exists {
-		return value
+	// クリックの記録
+	// 処理中のリクエストのクリックを保存し終えてからデータベース接続を閉じる
+	ipHasher, err := clicklog.NewIPHasher(cfg.Clicks.IPHashKey)
+	if err != nil {
+		return fmt.Errorf("IPアドレスのハッシュ鍵の生成エラー: %w", err)
 	}
-	return fallback
-}
+	clickWriter := clicklog.NewWriter(clickRepo, cfg.Clicks.BufferSize, cfg.Clicks.BatchSize, cfg.Clicks.FlushInterval)
+	defer func() {
+		closeCtx, cancel := context.WithTimeout(context.Background(), cfg.Server.ShutdownTimeout)
+		defer cancel()
+		if err := clickWriter.Close(closeCtx); err != nil {
+			slog.Error("保存待ちのクリックを保存できませんでした", "error", err)
+		}
+	}()
+	clickHandler := click.NewClickHandler(clickRepo, clickWriter, ipHasher)
 
-// ヘルスチェック用ハンドラー
-func healthCheckHandler(w http.ResponseWriter, r *http.Request) {
-	response := HealthResponse{
-		Status:  "ok",
-		Version: "1.0.0",
-		Time:    time.Now().Format(time.RFC3339),
+	// ランキングのフィード
+	feedHandler := feed.NewFeedHandler(cachedRankings, rankingRepo, cfg.Feeds.Size, cfg.Feeds.MoverThreshold)
+	feedHandler.BaseURL = cfg.Feeds.BaseURL
+
+	// ランキング更新のストリーム
+	streamBroker := stream.NewBroker(cfg.Stream.HistorySize, cfg.Stream.MaxSubscribers)
+	streamHandler := stream.NewStreamHandler(cachedRankings, categoryRepo, streamBroker, cfg.Stream.HeartbeatInterval)
+
+	// ランキングの変化の Webhook
+	// 保存した直後のスナップショットと比べるため、キャッシュを通さずに読む
+	var webhookNotifier *webhook.Notifier
+	var webhookDispatcher *webhook.Dispatcher
+	if cfg.Webhook.Enabled {
+		webhookNotifier = webhook.NewNotifier(rankingRepo, webhookRepo)
+		webhookDispatcher = webhook.NewDispatcher(webhookRepo, webhook.Settings{
+			PollInterval:   cfg.Webhook.PollInterval,
+			BatchSize:      cfg.Webhook.BatchSize,
+			MaxAttempts:    cfg.Webhook.MaxAttempts,
+			RetryBaseDelay: cfg.Webhook.RetryBaseDelay,
+			RetryMaxDelay:  cfg.Webhook.RetryMaxDelay,
+			Timeout:        cfg.Webhook.Timeout,
+		})
 	}
-	
-	w.Header().Set("Content-Type", "application/json")
-	json.NewEncoder(w).Encode(response)
-}
 
-// ランキング取得ハンドラー
-func getRankingsHandler(w http.ResponseWriter, r *http.Request) {
-	vars := mux.Vars(r)
-	categoryID := vars["categoryId"]
-	
-	// クエリパラメータ
-	periodType := r.URL.Query().Get("period")
-	if periodType == "" {
-		periodType = "daily" // デフォルト値
+	// 社内サービス向けの gRPC
+	rankingService := rpc.NewRankingService(cachedRankings, categoryRepo)
+
+	// GraphQL
+	var graphqlHandler *graphql.GraphQLHandler
+	if cfg.GraphQL.Enabled {
+		graphqlHandler, err = graphql.NewGraphQLHandler(categoryRepo, bookRepo, cachedRankings, rankingRepo, graphql.Limits{
+			MaxDepth:      cfg.GraphQL.MaxDepth,
+			MaxComplexity: cfg.GraphQL.MaxComplexity,
+			Introspection: cfg.GraphQL.Introspection,
+		})
+		if err != nil {
+			return err
+		}
 	}
-	
-	limit := 10 // デフォルト取得数
-	
-	// ランキングデータ取得のSQLクエリ
-	query := `
-		SELECT 
-			r.rank, b.id, b.title, b.author, b.publisher, 
-			b.isbn, b.publication_date, b.image_url, 
-			bsm.price, bsm.url, c.id, c.name, 
-			r.period_type, r.date_from, r.date_to
-		FROM rankings r
-		JOIN book_site_mappings bsm ON r.book_site_mapping_id = bsm.id
-		JOIN books b ON bsm.book_id = b.id
-		JOIN categories c ON r.category_id = c.id
-		WHERE r.category_id = ? AND r.period_type = ?
-		ORDER BY r.rank
-		LIMIT ?
-	`
-	
-	rows, err := db.Query(query, categoryID, periodType, limit)
-	if err != nil {
-		http.Error(w, "データベースクエリエラー", http.StatusInternalServerError)
-		log.Printf("クエリエラー: %v", err)
-		return
+
+	// アフィリエイトリンク
+	if cfg.Affiliate.Enabled {
+		linker, err := newAffiliateLinker(ctx, cfg.Affiliate, siteRepo)
+		if err != nil {
+			return fmt.Errorf("アフィリエイトIDの読み込みエラー: %w", err)
+		}
+		rankingHandler.Links = linker
+		rakutenHandler.Links = linker
+		clickHandler.Affiliate = linker
+		feedHandler.Links = linker
+		if graphqlHandler != nil {
+			graphqlHandler.Links = linker
+		}
+		rankingService.Links = linker
+		streamHandler.Links = linker
+		if webhookNotifier != nil {
+			webhookNotifier.Links = linker
+		}
 	}
-	defer rows.Close()
-	
-	var books []RankedBook
-	var response RankingResponse
 	
-	for rows.Next() {
-		var book RankedBook
-		var categoryID, categoryName, periodType, dateFrom, dateTo string
-		var publicationDate sql.NullString
-		
-		err := rows.Scan(
-			&book.Rank, &book.ID, &book.Title, &book.Author, &book.Publisher,
-			&book.ISBN, &publicationDate, &book.ImageURL,
-			&book.Price, &book.URL, &categoryID, &categoryName,
-			&periodType, &dateFrom, &dateTo,
-		)
-		
+	// ランキングの取得元
+	sources := []ingest.Source{
+		ingest.NewRakutenSource(rakutenHandler.Client),
+	}
+	sourceNames := make([]string, 0, len(sources))
+	for _, source := range sources {
+		sourceNames = append(sourceNames, source.Name())
+	}
+
+	healthHandler := health.NewHealthHandler(db, rankingRepo, sourceNames, cfg.Health.Timeout, cfg.Health.MaxIngestionAge)
+
+	// ランキング取り込みジョブ
+	var job *ingest.Job
+	var ingestTrigger admin.IngestTrigger
+	if cfg.Ingest.Enabled {
+		job = ingest.NewJob(siteRepo, rankingRepo, sources, cfg.Ingest.Periods, cfg.Ingest.Interval)
+		// ストリームはキャッシュを破棄した後の最新のランキングを読むため、キャッシュの後に通知する
+		job.Listeners = append(job.Listeners, cachedRankings, streamHandler)
+		if webhookNotifier != nil {
+			job.Listeners = append(job.Listeners, webhookNotifier)
+		}
+		job.Concurrency = cfg.Ingest.Concurrency
+		ingestTrigger = job
+	}
+	adminHandler := admin.NewAdminHandler(ingestTrigger, apiKeyRepo, clickRepo, webhookRepo)
+
+	// ルートグループごとのレート制限
+	rateLimitStore := ratelimit.NewMemory()
+	rateLimit := func(group string, limits config.RateLimitGroup) func(http.Handler) http.Handler {
+		if !cfg.RateLimit.Enabled {
+			return func(next http.Handler) http.Handler { return next }
+		}
+		return middleware.RateLimit(rateLimitStore, middleware.RateLimitPolicy{
+			Group:  group,
+			Window: cfg.RateLimit.Window,
+			PerIP:  limits.PerIP,
+			PerKey: limits.PerKey,
+		})
+	}
+
+	authLimit := func(next http.Handler) http.Handler { return next }
+	if cfg.RateLimit.Enabled {
+		authLimit = middleware.LimitAuthAttempts(rateLimitStore, middleware.RateLimitPolicy{
+			Group:  "auth",
+			Window: cfg.RateLimit.Window,
+			PerIP:  cfg.RateLimit.AuthPerIP,
+		})
+	}
+
+	var docsHandler *docs.DocsHandler
+	if cfg.OpenAPI.Docs {
+		docsHandler, err = docs.NewDocsHandler(openapi.YAML)
 		if err != nil {
-			http.Error(w, "データスキャンエラー", http.StatusInternalServerError)
-			log.Printf("スキャンエラー: %v", err)
-			return
+			return err
 		}
-		
-		if publicationDate.Valid {
-			book.PublicationDate = publicationDate.String
+		docsHandler.BaseURL = cfg.OpenAPI.BaseURL
+	}
+
+	deprecatedAt, sunsetAt := cfg.Versions.LegacyDeprecation()
+	legacyDeprecation := middleware.DeprecationPolicy{DeprecatedAt: deprecatedAt, SunsetAt: sunsetAt}
+
+	// ルーターの設定
+	r := newRouter(auth.NewAuthenticator(apiKeyRepo), handlers{
+		health:  healthHandler,
+		ranking: rankingHandler,
+		stream:  streamHandler,
+		rakuten: rakutenHandler,
+		feed:    feedHandler,
+		graphql: graphqlHandler,
+		click:   clickHandler,
+		admin:   adminHandler,
+		docs:    docsHandler,
+	}, routeLimits{
+		auth:           authLimit,
+		public:         rateLimit("public", cfg.RateLimit.Public),
+		search:         rateLimit("search", cfg.RateLimit.Search),
+		admin:          rateLimit("admin", cfg.RateLimit.Admin),
+		apiTimeout:     middleware.Timeout(cfg.Server.APIRequestTimeout),
+		rakutenTimeout: middleware.Timeout(cfg.Server.RakutenRequestTimeout),
+	}, legacyDeprecation)
+	if cfg.OpenAPI.ValidateRequests {
+		spec, err := openapi.Load()
+		if err != nil {
+			return err
 		}
-		
-		books = append(books, book)
-		
-		// 最初の行からカテゴリ情報などを設定
-		if response.CategoryID == "" {
-			response.CategoryID = categoryID
-			response.CategoryName = categoryName
-			response.PeriodType = periodType
-			response.DateFrom = dateFrom
-			response.DateTo = dateTo
+		validateRequests, err := middleware.ValidateRequests(spec)
+		if err != nil {
+			return err
 		}
+		// 認証の後に照合し、無効なAPIキーのリクエストには仕様との照合より先に 401 を返す
+		r.Use(validateRequests)
 	}
-	
-	response.Books = books
-	
-	w.Header().Set("Content-Type", "application/json")
-	json.NewEncoder(w).Encode(response)
-}
 
-// 書籍詳細取得ハンドラー
-func getBookDetailsHandler(w http.ResponseWriter, r *http.Request) {
-	vars := mux.Vars(r)
-	bookID := vars["bookId"]
-	
-	query := `
-		SELECT 
-			b.id, b.title, b.author, b.publisher, 
-			b.isbn, b.publication_date, b.image_url
-		FROM books b
-		WHERE b.id = ?
-	`
-	
-	var book RankedBook
-	var publicationDate sql.NullString
-	
-	err := db.QueryRow(query, bookID).Scan(
-		&book.ID, &book.Title, &book.Author, &book.Publisher,
-		&book.ISBN, &publicationDate, &book.ImageURL,
-	)
-	
+	// サーバーの停止と順序を制御できるよう、取り込みジョブはシグナルとは別のコンテキストで動かす
+	ingestCtx, stopIngest := context.WithCancel(context.Background())
+	defer stopIngest()
+	var jobs sync.WaitGroup
+	if job != nil {
+		jobs.Add(1)
+		go func() {
+			defer jobs.Done()
+			job.Run(ingestCtx)
+		}()
+	}
+	// 配信キューは取り込みと同時に止め、停止で中断した送信は他のインスタンスか再起動後に送り直す
+	if webhookDispatcher != nil {
+		jobs.Add(1)
+		go func() {
+			defer jobs.Done()
+			webhookDispatcher.Run(ingestCtx)
+		}()
+	}
+
+	trustedProxies, err := middleware.ParseTrustedProxies(cfg.Server.TrustedProxies)
 	if err != nil {
-		if err == sql.ErrNoRows {
-			http.Error(w, "書籍が見つかりません", http.StatusNotFound)
-		} else {
-			http.Error(w, "データベースクエリエラー", http.StatusInternalServerError)
-			log.Printf("クエリエラー: %v", err)
+		return err
+	}
+
+	cors := middleware.CORS(middleware.CORSPolicy{
+		AllowedOrigins:   cfg.CORS.AllowedOrigins,
+		AllowedMethods:   cfg.CORS.AllowedMethods,
+		AllowedHeaders:   cfg.CORS.AllowedHeaders,
+		ExposedHeaders:   cfg.CORS.ExposedHeaders,
+		AllowCredentials: cfg.CORS.AllowCredentials,
+		MaxAge:           cfg.CORS.MaxAge,
+	})
+
+	// リクエストIDとアクセスログはルーティング前に適用し、404 なども記録する
+	// プリフライトは GET のみのルートでも 405 にならないよう、ルーティング前に応答する
+	handler := middleware.RequestID(middleware.RealIP(trustedProxies)(middleware.AccessLog(cors(r))))
+
+	srv := &http.Server{
+		Addr:              ":" + cfg.Server.Port,
+		Handler:           handler,
+		ReadTimeout:       cfg.Server.ReadTimeout,
+		ReadHeaderTimeout: cfg.Server.ReadHeaderTimeout,
+		WriteTimeout:      cfg.Server.WriteTimeout,
+		IdleTimeout:       cfg.Server.IdleTimeout,
+		MaxHeaderBytes:    cfg.Server.MaxHeaderBytes,
+		ErrorLog:          slog.NewLogLogger(slog.Default().Handler(), slog.LevelError),
+	}
+
+	// gRPC サーバー起動
+	var grpcServer *rpc.Server
+	if cfg.GRPC.Enabled {
+		lis, err := net.Listen("tcp", ":"+cfg.GRPC.Port)
+		if err != nil {
+			stopIngest()
+			jobs.Wait()
+			return fmt.Errorf("gRPC サーバーの起動エラー: %w", err)
 		}
-		return
+		grpcServer = rpc.NewServer(rankingService, cfg.Server.APIRequestTimeout)
+		go func() {
+			slog.Info("gRPC サーバーを起動しています", "port", cfg.GRPC.Port)
+			if err := grpcServer.Serve(lis); err != nil {
+				slog.Error("gRPC サーバーエラー", "error", err)
+			}
+		}()
 	}
-	
-	if publicationDate.Valid {
-		book.PublicationDate = publicationDate.String
+
+	// サーバー起動
+	serverErr := make(chan error, 1)
+	go func() {
+		slog.Info("サーバーを起動しています", "port", cfg.Server.Port)
+		if err := srv.ListenAndServe(); err != nil && !errors.Is(err, http.ErrServerClosed) {
+			serverErr <- err
+		}
+		close(serverErr)
+	}()
+
+	select {
+	case err := <-serverErr:
+		if grpcServer != nil {
+			grpcServer.Shutdown(context.Background())
+		}
+		stopIngest()
+		jobs.Wait()
+		return err
+	case <-ctx.Done():
 	}
-	
-	w.Header().Set("Content-Type", "application/json")
-	json.NewEncoder(w).Encode(book)
+	// 2回目のシグナルでは即座に終了できるようにする
+	stop()
+
+	slog.Info("シャットダウンを開始します")
+	healthHandler.SetShuttingDown()
+	if grpcServer != nil {
+		grpcServer.SetShuttingDown()
+	}
+	stopIngest()
+
+	// ロードバランサーがレディネスの変化を検知するまで新規リクエストを受け付け続ける
+	time.Sleep(cfg.Server.ShutdownDrainPeriod)
+
+	// 接続中のストリームを終了させないと、処理中のリクエストとして停止を待ち続ける
+	streamBroker.Close()
+
+	shutdownCtx, cancel := context.WithTimeout(context.Background(), cfg.Server.ShutdownTimeout)
+	defer cancel()
+	if err := srv.Shutdown(shutdownCtx); err != nil {
+		slog.Error("処理中のリクエストを待機中にタイムアウトしました", "error", err)
+	}
+	if grpcServer != nil {
+		if err := grpcServer.Shutdown(shutdownCtx); err != nil {
+			slog.Error("処理中の gRPC 呼び出しを待機中にタイムアウトしました", "error", err)
+		}
+	}
+
+	jobs.Wait()
+	slog.Info("取り込みジョブを停止しました")
+	return nil
 }
 
-// カテゴリ一覧取得ハンドラー
-func getCategoriesHandler(w http.ResponseWriter, r *http.Request) {
-	query := `
-		SELECT id, name, parent_id
-		FROM categories
-		ORDER BY name
-	`
-	
-	rows, err := db.Query(query)
+// newAffiliateLinker は sites.affiliate_id と設定からサイトごとのアフィリエイトIDを決め、Linker を作る。
+// sites.affiliate_id は起動時にのみ読み込む。
+func newAffiliateLinker(ctx context.Context, cfg config.AffiliateConfig, sites *repository.SiteRepository) (*affiliate.Linker, error) {
+	list, err := sites.List(ctx)
 	if err != nil {
-		http.Error(w, "データベースクエリエラー", http.StatusInternalServerError)
-		log.Printf("クエリエラー: %v", err)
-		return
+		return nil, err
 	}
-	defer rows.Close()
-	
-	type Category struct {
-		ID       string  `json:"id"`
-		Name     string  `json:"name"`
-		ParentID *string `json:"parentId"`
+	ids := make(map[string]string, len(list))
+	for _, site := range list {
+		ids[site.Name] = site.AffiliateID
 	}
-	
-	var categories []Category
-	
-	for rows.Next() {
-		var category Category
-		var parentID sql.NullString
-		
-		err := rows.Scan(&category.ID, &category.Name, &parentID)
-		if err != nil {
-			http.Error(w, "データスキャンエラー", http.StatusInternalServerError)
-			log.Printf("スキャンエラー: %v", err)
-			return
-		}
-		
-		if parentID.Valid {
-			category.ParentID = &parentID.String
+
+	for site, id := range map[string]string{
+		affiliate.SiteRakuten: cfg.RakutenID,
+		affiliate.SiteAmazon:  cfg.AmazonTag,
+		affiliate.SiteYahoo:   cfg.YahooID,
+	} {
+		if id != "" {
+			ids[site] = id
 		}
-		
-		categories = append(categories, category)
 	}
-	
-	w.Header().Set("Content-Type", "application/json")
-	json.NewEncoder(w).Encode(categories)
+	return affiliate.NewLinker(ids), nil
+}
+
+// newCacheStore は設定に応じたキャッシュストアと、その終了処理を返す。
+func newCacheStore(cfg config.Config) (cache.Store, func()) {
+	switch cfg.Cache.Backend {
+	case "redis":
+		client := redis.NewClient(&redis.Options{
+			Addr:     cfg.Redis.Addr,
+			Password: cfg.Redis.Password,
+			DB:       cfg.Redis.DB,
+		})
+		return cache.NewRedis(client, cfg.Redis.KeyPrefix), func() { client.Close() }
+	case "memory":
+		return cache.NewMemory(cfg.Cache.MaxEntries), func() {}
+	default:
+		return cache.Nop{}, func() {}
+	}
 }
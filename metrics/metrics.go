@@ -0,0 +1,137 @@
+// Package metrics は Prometheus 形式で公開するアプリケーションメトリクスを定義する。
+package metrics
+
+import (
+	"database/sql"
+	"net/http"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/collectors"
+	"github.com/prometheus/client_golang/prometheus/promhttp"
+)
+
+const namespace = "book_ranking"
+
+// Registry はアプリケーションのメトリクスを登録するレジストリである。
+var Registry = prometheus.NewRegistry()
+
+var (
+	// HTTPRequestDuration はルートテンプレート別の HTTP リクエスト処理時間である。
+	HTTPRequestDuration = prometheus.NewHistogramVec(prometheus.HistogramOpts{
+		Namespace: namespace,
+		Name:      "http_request_duration_seconds",
+		Help:      "HTTPリクエストの処理時間（秒）。",
+		Buckets:   prometheus.DefBuckets,
+	}, []string{"method", "route", "status"})
+
+	// DBQueryDuration はリポジトリのメソッド別のクエリ実行時間である。
+	DBQueryDuration = prometheus.NewHistogramVec(prometheus.HistogramOpts{
+		Namespace: namespace,
+		Name:      "db_query_duration_seconds",
+		Help:      "リポジトリメソッドごとのデータベースクエリ実行時間（秒）。",
+		Buckets:   []float64{.001, .0025, .005, .01, .025, .05, .1, .25, .5, 1, 2.5},
+	}, []string{"repository", "method"})
+
+	// IngestionItemsFetched は取得元ごとに取り込んだランキング項目数である。
+	IngestionItemsFetched = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "ingestion_items_fetched_total",
+		Help:      "取得元ごとに取り込んだランキング項目の総数。",
+	}, []string{"source"})
+
+	// IngestionErrors は取得元ごとの取り込みエラー数である。
+	IngestionErrors = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "ingestion_errors_total",
+		Help:      "取得元ごとの取り込みエラーの総数。",
+	}, []string{"source"})
+
+	// IngestionLastSuccess は取得元ごとの最終取り込み成功時刻（UNIX 秒）である。
+	IngestionLastSuccess = prometheus.NewGaugeVec(prometheus.GaugeOpts{
+		Namespace: namespace,
+		Name:      "ingestion_last_success_timestamp_seconds",
+		Help:      "取得元ごとの最終取り込み成功時刻（UNIX 秒）。",
+	}, []string{"source"})
+
+	// CacheHits はキャッシュ名ごとのヒット数である。
+	CacheHits = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "cache_hits_total",
+		Help:      "キャッシュのヒット数。",
+	}, []string{"cache"})
+
+	// CacheMisses はキャッシュ名ごとのミス数である。
+	CacheMisses = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "cache_misses_total",
+		Help:      "キャッシュのミス数。",
+	}, []string{"cache"})
+
+	// RateLimitedRequests はルートグループごとにレート制限で拒否したリクエスト数である。
+	RateLimitedRequests = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "rate_limited_requests_total",
+		Help:      "レート制限で拒否したリクエストの総数。",
+	}, []string{"group"})
+
+	// CircuitBreakerState はサーキットブレーカーの状態（0: closed, 1: half-open, 2: open）である。
+	CircuitBreakerState = prometheus.NewGaugeVec(prometheus.GaugeOpts{
+		Namespace: namespace,
+		Name:      "circuit_breaker_state",
+		Help:      "サーキットブレーカーの状態（0: closed, 1: half-open, 2: open）。",
+	}, []string{"breaker"})
+
+	// ClicksDropped は保存できずに破棄したクリック数である。
+	// reason は buffer_full（書き込み待ちが上限に達した）か write_error（保存に失敗した）である。
+	ClicksDropped = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "clicks_dropped_total",
+		Help:      "保存できずに破棄したクリックの総数。",
+	}, []string{"reason"})
+
+	// WebhookDeliveries は Webhook の通知の送信結果ごとの数である。
+	// result は succeeded（成功）、retried（失敗して再試行を予定）、dead（再試行の上限に達した）である。
+	WebhookDeliveries = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "webhook_deliveries_total",
+		Help:      "Webhook の通知の送信結果ごとの総数。",
+	}, []string{"result"})
+)
+
+func init() {
+	Registry.MustRegister(
+		collectors.NewGoCollector(),
+		collectors.NewProcessCollector(collectors.ProcessCollectorOpts{}),
+		HTTPRequestDuration,
+		DBQueryDuration,
+		IngestionItemsFetched,
+		IngestionErrors,
+		IngestionLastSuccess,
+		CacheHits,
+		CacheMisses,
+		RateLimitedRequests,
+		CircuitBreakerState,
+		ClicksDropped,
+		WebhookDeliveries,
+	)
+}
+
+// RegisterDBStats は sql.DB のコネクションプール統計をゲージとして登録する。
+func RegisterDBStats(db *sql.DB, dbName string) error {
+	return Registry.Register(collectors.NewDBStatsCollector(db, dbName))
+}
+
+// ObserveDBQuery はクエリ実行時間を記録する関数を返す。
+// メソッドの先頭で defer metrics.ObserveDBQuery("ranking", "FindLatest")() のように使う。
+func ObserveDBQuery(repository, method string) func() {
+	start := time.Now()
+	return func() {
+		DBQueryDuration.WithLabelValues(repository, method).Observe(time.Since(start).Seconds())
+	}
+}
+
+// Handler は Prometheus テキスト形式でメトリクスを出力するハンドラーを返す。
+func Handler() http.Handler {
+	return promhttp.HandlerFor(Registry, promhttp.HandlerOpts{})
+}
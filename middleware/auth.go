@@ -0,0 +1,88 @@
+package middleware
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"slices"
+
+	"github.com/h-hiwatashi/super-business-book-ranking-backend/api/httperror"
+	"github.com/h-hiwatashi/super-business-book-ranking-backend/auth"
+	"github.com/h-hiwatashi/super-business-book-ranking-backend/logging"
+)
+
+// APIKeyHeader はAPIキーを受け渡すヘッダー名である。
+const APIKeyHeader = "X-API-Key"
+
+// Authenticator はAPIキーを検証して利用者を返す。
+type Authenticator interface {
+	Authenticate(ctx context.Context, key string) (*auth.Principal, error)
+}
+
+// Authenticate は X-API-Key ヘッダーのAPIキーを検証し、利用者をコンテキストに格納するミドルウェアを返す。
+// キーのないリクエストは匿名で通し、無効なキーのリクエストには 401 を返す。
+// アクセス制限はこのミドルウェアでは行わず、RequireRole と RequireScope で行う。
+func Authenticate(a Authenticator) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			key := r.Header.Get(APIKeyHeader)
+			if key == "" {
+				next.ServeHTTP(w, r)
+				return
+			}
+
+			principal, err := a.Authenticate(r.Context(), key)
+			if errors.Is(err, auth.ErrInvalidKey) {
+				http.Error(w, "APIキーが無効です", http.StatusUnauthorized)
+				return
+			}
+			if err != nil {
+				httperror.Write(w, r, err, "認証エラー")
+				return
+			}
+
+			ctx := auth.WithPrincipal(r.Context(), principal)
+			logger := logging.FromContext(ctx).With("apiKeyId", principal.KeyID)
+			ctx = logging.WithLogger(ctx, logger)
+			next.ServeHTTP(w, r.WithContext(ctx))
+		})
+	}
+}
+
+// RequireRole は roles のいずれかのロールを持つ利用者だけを通すミドルウェアを返す。
+// APIキーのないリクエストには 401、ロールが異なる場合は 403 を返す。
+func RequireRole(roles ...auth.Role) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			principal := auth.FromContext(r.Context())
+			if principal == nil {
+				http.Error(w, "APIキーが必要です", http.StatusUnauthorized)
+				return
+			}
+			if !slices.Contains(roles, principal.Role) {
+				http.Error(w, "この操作を行う権限がありません", http.StatusForbidden)
+				return
+			}
+			next.ServeHTTP(w, r)
+		})
+	}
+}
+
+// RequireScope は scope を許可されたAPIキーの利用者だけを通すミドルウェアを返す。
+// APIキーのないリクエストには 401、スコープがない場合は 403 を返す。
+func RequireScope(scope string) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			principal := auth.FromContext(r.Context())
+			if principal == nil {
+				http.Error(w, "APIキーが必要です", http.StatusUnauthorized)
+				return
+			}
+			if !principal.HasScope(scope) {
+				http.Error(w, "この操作を行う権限がありません", http.StatusForbidden)
+				return
+			}
+			next.ServeHTTP(w, r)
+		})
+	}
+}
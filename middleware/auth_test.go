@@ -0,0 +1,137 @@
+package middleware
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/h-hiwatashi/super-business-book-ranking-backend/auth"
+)
+
+type fakeAuthenticator map[string]*auth.Principal
+
+func (a fakeAuthenticator) Authenticate(ctx context.Context, key string) (*auth.Principal, error) {
+	if key == "broken" {
+		return nil, errors.New("db down")
+	}
+	p, ok := a[key]
+	if !ok {
+		return nil, auth.ErrInvalidKey
+	}
+	return p, nil
+}
+
+func TestAuthenticate(t *testing.T) {
+	authenticator := fakeAuthenticator{
+		"bkr_admin": {KeyID: "key-admin", Role: auth.RoleAdmin, Scopes: []string{auth.ScopeIngestRun}},
+	}
+
+	testCases := []struct {
+		name           string
+		apiKey         string
+		wantStatusCode int
+		wantKeyID      string
+	}{
+		{
+			name:           "正常系：APIキーなしは匿名で通す",
+			wantStatusCode: http.StatusOK,
+		},
+		{
+			name:           "正常系：有効なキー",
+			apiKey:         "bkr_admin",
+			wantStatusCode: http.StatusOK,
+			wantKeyID:      "key-admin",
+		},
+		{
+			name:           "異常系：無効なキー",
+			apiKey:         "bkr_unknown",
+			wantStatusCode: http.StatusUnauthorized,
+		},
+		{
+			name:           "異常系：検証中のエラー",
+			apiKey:         "broken",
+			wantStatusCode: http.StatusInternalServerError,
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			var gotKeyID string
+			handler := Authenticate(authenticator)(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				if p := auth.FromContext(r.Context()); p != nil {
+					gotKeyID = p.KeyID
+				}
+			}))
+
+			req := httptest.NewRequest("GET", "/", nil)
+			if tc.apiKey != "" {
+				req.Header.Set(APIKeyHeader, tc.apiKey)
+			}
+			rr := httptest.NewRecorder()
+			handler.ServeHTTP(rr, req)
+
+			if rr.Code != tc.wantStatusCode {
+				t.Errorf("status = %v, want %v", rr.Code, tc.wantStatusCode)
+			}
+			if gotKeyID != tc.wantKeyID {
+				t.Errorf("principal key id = %q, want %q", gotKeyID, tc.wantKeyID)
+			}
+		})
+	}
+}
+
+func TestRequireRoleAndScope(t *testing.T) {
+	admin := &auth.Principal{KeyID: "key-admin", Role: auth.RoleAdmin, Scopes: []string{auth.ScopeIngestRun}}
+	partner := &auth.Principal{KeyID: "key-partner", Role: auth.RolePartner, Scopes: []string{auth.ScopeKeysManage}}
+
+	testCases := []struct {
+		name           string
+		principal      *auth.Principal
+		scope          string
+		wantStatusCode int
+	}{
+		{
+			name:           "正常系：ロールとスコープを満たす",
+			principal:      admin,
+			scope:          auth.ScopeIngestRun,
+			wantStatusCode: http.StatusOK,
+		},
+		{
+			name:           "異常系：APIキーなし",
+			scope:          auth.ScopeIngestRun,
+			wantStatusCode: http.StatusUnauthorized,
+		},
+		{
+			name:           "異常系：スコープを持つが管理者でない",
+			principal:      partner,
+			scope:          auth.ScopeKeysManage,
+			wantStatusCode: http.StatusForbidden,
+		},
+		{
+			name:           "異常系：管理者だがスコープがない",
+			principal:      admin,
+			scope:          auth.ScopeKeysManage,
+			wantStatusCode: http.StatusForbidden,
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			ok := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {})
+			handler := RequireRole(auth.RoleAdmin)(RequireScope(tc.scope)(ok))
+
+			req := httptest.NewRequest("POST", "/admin/ingest", nil)
+			if tc.principal != nil {
+				req = req.WithContext(auth.WithPrincipal(req.Context(), tc.principal))
+			}
+			rr := httptest.NewRecorder()
+			handler.ServeHTTP(rr, req)
+
+			if rr.Code != tc.wantStatusCode {
+				t.Errorf("status = %v, want %v", rr.Code, tc.wantStatusCode)
+			}
+		})
+	}
+}
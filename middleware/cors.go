@@ -0,0 +1,137 @@
+package middleware
+
+import (
+	"net/http"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// CORSPolicy は別オリジンのブラウザーからの呼び出しを許可する条件である。
+type CORSPolicy struct {
+	// AllowedOrigins は許可するオリジンである。"*" はすべてのオリジン、
+	// "https://*.example.com" は example.com のサブドメインすべてを表す。
+	AllowedOrigins []string
+	AllowedMethods []string
+	AllowedHeaders []string
+	// ExposedHeaders はブラウザーのスクリプトに読み取りを許可するレスポンスヘッダーである。
+	ExposedHeaders   []string
+	AllowCredentials bool
+	// MaxAge はブラウザーがプリフライトの結果をキャッシュする期間である。0 の場合は指定しない。
+	MaxAge time.Duration
+}
+
+// CORS は CORS のヘッダーを付け、プリフライトリクエストに応答するミドルウェアを返す。
+// プリフライトはルーティング前に応答するため、ルーターより外側に適用する。
+// ルートに OPTIONS メソッドを登録していなくても 405 にならない。
+// AllowedOrigins が空の場合は何もしない。
+func CORS(policy CORSPolicy) func(http.Handler) http.Handler {
+	if len(policy.AllowedOrigins) == 0 {
+		return func(next http.Handler) http.Handler { return next }
+	}
+
+	origins := make([]string, len(policy.AllowedOrigins))
+	for i, origin := range policy.AllowedOrigins {
+		origins[i] = strings.ToLower(origin)
+	}
+	headers := make([]string, len(policy.AllowedHeaders))
+	for i, header := range policy.AllowedHeaders {
+		headers[i] = strings.ToLower(header)
+	}
+	allowMethods := strings.Join(policy.AllowedMethods, ", ")
+	allowHeaders := strings.Join(policy.AllowedHeaders, ", ")
+	exposeHeaders := strings.Join(policy.ExposedHeaders, ", ")
+	maxAge := strconv.Itoa(int(policy.MaxAge.Seconds()))
+
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			origin := r.Header.Get("Origin")
+			if origin == "" {
+				next.ServeHTTP(w, r)
+				return
+			}
+
+			// 許可の有無がオリジンによって変わるため、共有キャッシュにオリジンごとに保存させる
+			w.Header().Add("Vary", "Origin")
+			allowOrigin, ok := matchOrigin(origins, origin, policy.AllowCredentials)
+
+			if r.Method == http.MethodOptions && r.Header.Get("Access-Control-Request-Method") != "" {
+				w.Header().Add("Vary", "Access-Control-Request-Method")
+				w.Header().Add("Vary", "Access-Control-Request-Headers")
+				// 許可しない場合は CORS のヘッダーを付けずに応答し、ブラウザーに拒否させる
+				if ok && slices.Contains(policy.AllowedMethods, r.Header.Get("Access-Control-Request-Method")) &&
+					headersAllowed(headers, r.Header.Values("Access-Control-Request-Headers")) {
+					w.Header().Set("Access-Control-Allow-Origin", allowOrigin)
+					w.Header().Set("Access-Control-Allow-Methods", allowMethods)
+					if allowHeaders != "" {
+						w.Header().Set("Access-Control-Allow-Headers", allowHeaders)
+					}
+					if policy.AllowCredentials {
+						w.Header().Set("Access-Control-Allow-Credentials", "true")
+					}
+					if policy.MaxAge > 0 {
+						w.Header().Set("Access-Control-Max-Age", maxAge)
+					}
+				}
+				w.WriteHeader(http.StatusNoContent)
+				return
+			}
+
+			if ok {
+				w.Header().Set("Access-Control-Allow-Origin", allowOrigin)
+				if exposeHeaders != "" {
+					w.Header().Set("Access-Control-Expose-Headers", exposeHeaders)
+				}
+				if policy.AllowCredentials {
+					w.Header().Set("Access-Control-Allow-Credentials", "true")
+				}
+			}
+			next.ServeHTTP(w, r)
+		})
+	}
+}
+
+// matchOrigin は origin が許可されているかと、Access-Control-Allow-Origin に返す値を返す。
+// 資格情報を伴うリクエストには "*" を返せないため、その場合はオリジンをそのまま返す。
+func matchOrigin(patterns []string, origin string, credentials bool) (string, bool) {
+	lower := strings.ToLower(origin)
+	scheme, host, _ := strings.Cut(lower, "://")
+
+	for _, pattern := range patterns {
+		if pattern == "*" {
+			if credentials {
+				return origin, true
+			}
+			return "*", true
+		}
+		if pattern == lower {
+			return origin, true
+		}
+
+		// "https://*.example.com" は "https://a.example.com" などに一致し、"https://example.com" には一致しない
+		patternScheme, patternHost, ok := strings.Cut(pattern, "://")
+		if !ok || !strings.HasPrefix(patternHost, "*.") || patternScheme != scheme {
+			continue
+		}
+		suffix := patternHost[1:]
+		if len(host) > len(suffix) && strings.HasSuffix(host, suffix) {
+			return origin, true
+		}
+	}
+	return "", false
+}
+
+// headersAllowed は Access-Control-Request-Headers に列挙されたヘッダーがすべて許可されているかを返す。
+// allowed は小文字にしたヘッダー名である。
+func headersAllowed(allowed []string, requested []string) bool {
+	for _, value := range requested {
+		for _, header := range strings.Split(value, ",") {
+			header = strings.ToLower(strings.TrimSpace(header))
+			if header != "" && !slices.Contains(allowed, header) {
+				return false
+			}
+		}
+	}
+	return true
+}
@@ -0,0 +1,228 @@
+package middleware
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/gorilla/mux"
+)
+
+func newCORSTestHandler(policy CORSPolicy) http.Handler {
+	r := mux.NewRouter()
+	r.HandleFunc("/api/categories", func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte("[]"))
+	}).Methods("GET")
+	return CORS(policy)(r)
+}
+
+var testCORSPolicy = CORSPolicy{
+	AllowedOrigins: []string{"https://app.example.com", "https://*.preview.example.com", "http://localhost:3000"},
+	AllowedMethods: []string{"GET", "POST"},
+	AllowedHeaders: []string{"X-API-Key", "If-None-Match"},
+	ExposedHeaders: []string{"ETag", "X-Request-ID"},
+	MaxAge:         10 * time.Minute,
+}
+
+func TestCORS(t *testing.T) {
+	testCases := []struct {
+		name            string
+		origin          string
+		wantAllowOrigin string
+	}{
+		{
+			name:            "正常系：一致するオリジン",
+			origin:          "https://app.example.com",
+			wantAllowOrigin: "https://app.example.com",
+		},
+		{
+			name:            "正常系：サブドメインのワイルドカード",
+			origin:          "https://pr-12.preview.example.com",
+			wantAllowOrigin: "https://pr-12.preview.example.com",
+		},
+		{
+			name:            "正常系：ポート番号付きのオリジン",
+			origin:          "http://localhost:3000",
+			wantAllowOrigin: "http://localhost:3000",
+		},
+		{
+			name:   "異常系：ワイルドカードは親ドメインに一致しない",
+			origin: "https://preview.example.com",
+		},
+		{
+			name:   "異常系：スキームが異なる",
+			origin: "http://app.example.com",
+		},
+		{
+			name:   "異常系：末尾が一致するだけの別ドメイン",
+			origin: "https://evilpreview.example.com",
+		},
+		{
+			name:   "異常系：ポート番号が異なる",
+			origin: "http://localhost:8080",
+		},
+	}
+
+	handler := newCORSTestHandler(testCORSPolicy)
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			req := httptest.NewRequest("GET", "/api/categories", nil)
+			req.Header.Set("Origin", tc.origin)
+			rr := httptest.NewRecorder()
+			handler.ServeHTTP(rr, req)
+
+			// 許可しないオリジンでもリクエスト自体は処理し、ブラウザーに拒否させる
+			if rr.Code != http.StatusOK {
+				t.Errorf("status = %v, want %v", rr.Code, http.StatusOK)
+			}
+			if got := rr.Header().Get("Access-Control-Allow-Origin"); got != tc.wantAllowOrigin {
+				t.Errorf("Access-Control-Allow-Origin = %q, want %q", got, tc.wantAllowOrigin)
+			}
+			if got := rr.Header().Get("Vary"); got != "Origin" {
+				t.Errorf("Vary = %q, want Origin", got)
+			}
+			wantExpose := ""
+			if tc.wantAllowOrigin != "" {
+				wantExpose = "ETag, X-Request-ID"
+			}
+			if got := rr.Header().Get("Access-Control-Expose-Headers"); got != wantExpose {
+				t.Errorf("Access-Control-Expose-Headers = %q, want %q", got, wantExpose)
+			}
+		})
+	}
+}
+
+func TestCORSPreflight(t *testing.T) {
+	testCases := []struct {
+		name           string
+		origin         string
+		method         string
+		headers        string
+		wantAllowed    bool
+		wantStatusCode int
+	}{
+		{
+			name:           "正常系：GET のみのルートへのプリフライト",
+			origin:         "https://app.example.com",
+			method:         "GET",
+			headers:        "x-api-key, if-none-match",
+			wantAllowed:    true,
+			wantStatusCode: http.StatusNoContent,
+		},
+		{
+			name:           "異常系：許可しないメソッド",
+			origin:         "https://app.example.com",
+			method:         "PUT",
+			wantStatusCode: http.StatusNoContent,
+		},
+		{
+			name:           "異常系：許可しないヘッダー",
+			origin:         "https://app.example.com",
+			method:         "GET",
+			headers:        "X-API-Key, Authorization",
+			wantStatusCode: http.StatusNoContent,
+		},
+		{
+			name:           "異常系：許可しないオリジン",
+			origin:         "https://evil.example.net",
+			method:         "GET",
+			wantStatusCode: http.StatusNoContent,
+		},
+	}
+
+	handler := newCORSTestHandler(testCORSPolicy)
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			req := httptest.NewRequest("OPTIONS", "/api/categories", nil)
+			req.Header.Set("Origin", tc.origin)
+			req.Header.Set("Access-Control-Request-Method", tc.method)
+			if tc.headers != "" {
+				req.Header.Set("Access-Control-Request-Headers", tc.headers)
+			}
+			rr := httptest.NewRecorder()
+			handler.ServeHTTP(rr, req)
+
+			if rr.Code != tc.wantStatusCode {
+				t.Errorf("status = %v, want %v", rr.Code, tc.wantStatusCode)
+			}
+			gotOrigin := rr.Header().Get("Access-Control-Allow-Origin")
+			if allowed := gotOrigin != ""; allowed != tc.wantAllowed {
+				t.Fatalf("Access-Control-Allow-Origin = %q, want allowed %v", gotOrigin, tc.wantAllowed)
+			}
+			if !tc.wantAllowed {
+				return
+			}
+			if got := rr.Header().Get("Access-Control-Allow-Methods"); got != "GET, POST" {
+				t.Errorf("Access-Control-Allow-Methods = %q, want %q", got, "GET, POST")
+			}
+			if got := rr.Header().Get("Access-Control-Allow-Headers"); got != "X-API-Key, If-None-Match" {
+				t.Errorf("Access-Control-Allow-Headers = %q, want %q", got, "X-API-Key, If-None-Match")
+			}
+			if got := rr.Header().Get("Access-Control-Max-Age"); got != "600" {
+				t.Errorf("Access-Control-Max-Age = %q, want 600", got)
+			}
+		})
+	}
+}
+
+func TestCORSWildcardOrigin(t *testing.T) {
+	testCases := []struct {
+		name            string
+		credentials     bool
+		wantAllowOrigin string
+	}{
+		{
+			name:            "正常系：資格情報なしは * を返す",
+			wantAllowOrigin: "*",
+		},
+		{
+			name:            "正常系：資格情報ありはオリジンを返す",
+			credentials:     true,
+			wantAllowOrigin: "https://app.example.com",
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			handler := newCORSTestHandler(CORSPolicy{
+				AllowedOrigins:   []string{"*"},
+				AllowedMethods:   []string{"GET"},
+				AllowCredentials: tc.credentials,
+			})
+
+			req := httptest.NewRequest("GET", "/api/categories", nil)
+			req.Header.Set("Origin", "https://app.example.com")
+			rr := httptest.NewRecorder()
+			handler.ServeHTTP(rr, req)
+
+			if got := rr.Header().Get("Access-Control-Allow-Origin"); got != tc.wantAllowOrigin {
+				t.Errorf("Access-Control-Allow-Origin = %q, want %q", got, tc.wantAllowOrigin)
+			}
+			wantCredentials := ""
+			if tc.credentials {
+				wantCredentials = "true"
+			}
+			if got := rr.Header().Get("Access-Control-Allow-Credentials"); got != wantCredentials {
+				t.Errorf("Access-Control-Allow-Credentials = %q, want %q", got, wantCredentials)
+			}
+		})
+	}
+}
+
+func TestCORSDisabled(t *testing.T) {
+	handler := newCORSTestHandler(CORSPolicy{})
+
+	req := httptest.NewRequest("OPTIONS", "/api/categories", nil)
+	req.Header.Set("Origin", "https://app.example.com")
+	req.Header.Set("Access-Control-Request-Method", "GET")
+	rr := httptest.NewRecorder()
+	handler.ServeHTTP(rr, req)
+
+	if rr.Code != http.StatusMethodNotAllowed {
+		t.Errorf("status = %v, want %v", rr.Code, http.StatusMethodNotAllowed)
+	}
+	if got := rr.Header().Get("Access-Control-Allow-Origin"); got != "" {
+		t.Errorf("Access-Control-Allow-Origin = %q, want empty", got)
+	}
+}
@@ -0,0 +1,41 @@
+package middleware
+
+import (
+	"fmt"
+	"net/http"
+	"strconv"
+	"time"
+)
+
+// DeprecationPolicy は非推奨のルートに付けるヘッダーの内容である。
+type DeprecationPolicy struct {
+	// DeprecatedAt は非推奨になった日時で、Deprecation ヘッダー（RFC 9745）に記載する。
+	DeprecatedAt time.Time
+	// SunsetAt は提供を終える予定の日時で、Sunset ヘッダー（RFC 8594）に記載する。ゼロ値の場合は付けない。
+	SunsetAt time.Time
+	// Successor はリクエストのパスから移行先のパスを返す。nil の場合は Link ヘッダーを付けない。
+	Successor func(path string) string
+}
+
+// Deprecated は非推奨のルートであることを Deprecation・Sunset・Link ヘッダーで知らせるミドルウェアを返す。
+// レスポンスの内容は変えないため、既存のクライアントはそのまま使い続けられる。
+func Deprecated(policy DeprecationPolicy) func(http.Handler) http.Handler {
+	deprecation := "@" + strconv.FormatInt(policy.DeprecatedAt.Unix(), 10)
+	var sunset string
+	if !policy.SunsetAt.IsZero() {
+		sunset = policy.SunsetAt.UTC().Format(http.TimeFormat)
+	}
+
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			w.Header().Set("Deprecation", deprecation)
+			if sunset != "" {
+				w.Header().Set("Sunset", sunset)
+			}
+			if policy.Successor != nil {
+				w.Header().Add("Link", fmt.Sprintf(`<%s>; rel="successor-version"`, policy.Successor(r.URL.Path)))
+			}
+			next.ServeHTTP(w, r)
+		})
+	}
+}
@@ -0,0 +1,65 @@
+package middleware
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestDeprecated(t *testing.T) {
+	deprecatedAt := time.Date(2026, 10, 1, 0, 0, 0, 0, time.UTC)
+	successor := func(path string) string { return strings.Replace(path, "/api/", "/api/v1/", 1) }
+
+	testCases := []struct {
+		name            string
+		policy          DeprecationPolicy
+		wantDeprecation string
+		wantSunset      string
+		wantLink        string
+	}{
+		{
+			name:            "正常系：提供終了日と移行先あり",
+			policy:          DeprecationPolicy{DeprecatedAt: deprecatedAt, SunsetAt: time.Date(2027, 4, 1, 0, 0, 0, 0, time.UTC), Successor: successor},
+			wantDeprecation: "@1790812800",
+			wantSunset:      "Thu, 01 Apr 2027 00:00:00 GMT",
+			wantLink:        `</api/v1/categories>; rel="successor-version"`,
+		},
+		{
+			name:            "正常系：提供終了日が未定",
+			policy:          DeprecationPolicy{DeprecatedAt: deprecatedAt, Successor: successor},
+			wantDeprecation: "@1790812800",
+			wantLink:        `</api/v1/categories>; rel="successor-version"`,
+		},
+		{
+			name:            "正常系：移行先なし",
+			policy:          DeprecationPolicy{DeprecatedAt: deprecatedAt},
+			wantDeprecation: "@1790812800",
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			called := false
+			handler := Deprecated(tc.policy)(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				called = true
+			}))
+			rr := httptest.NewRecorder()
+			handler.ServeHTTP(rr, httptest.NewRequest("GET", "/api/categories", nil))
+
+			if !called {
+				t.Fatal("ハンドラーが呼ばれていません")
+			}
+			if got := rr.Header().Get("Deprecation"); got != tc.wantDeprecation {
+				t.Errorf("Deprecation = %q, want %q", got, tc.wantDeprecation)
+			}
+			if got := rr.Header().Get("Sunset"); got != tc.wantSunset {
+				t.Errorf("Sunset = %q, want %q", got, tc.wantSunset)
+			}
+			if got := rr.Header().Get("Link"); got != tc.wantLink {
+				t.Errorf("Link = %q, want %q", got, tc.wantLink)
+			}
+		})
+	}
+}
@@ -0,0 +1,67 @@
+package middleware
+
+import (
+	"net/http"
+	"time"
+
+	"github.com/h-hiwatashi/super-business-book-ranking-backend/logging"
+)
+
+// responseRecorder はステータスコードと書き込みバイト数を記録する ResponseWriter である。
+type responseRecorder struct {
+	http.ResponseWriter
+	status int
+	bytes  int
+}
+
+func (rec *responseRecorder) WriteHeader(status int) {
+	if rec.status == 0 {
+		rec.status = status
+	}
+	rec.ResponseWriter.WriteHeader(status)
+}
+
+func (rec *responseRecorder) Write(b []byte) (int, error) {
+	if rec.status == 0 {
+		rec.status = http.StatusOK
+	}
+	n, err := rec.ResponseWriter.Write(b)
+	rec.bytes += n
+	return n, err
+}
+
+// Unwrap は http.ResponseController から元の ResponseWriter を参照できるようにする。
+func (rec *responseRecorder) Unwrap() http.ResponseWriter {
+	return rec.ResponseWriter
+}
+
+// Status は記録されたステータスコードを返す。何も書き込まれていない場合は 200 を返す。
+func (rec *responseRecorder) Status() int {
+	if rec.status == 0 {
+		return http.StatusOK
+	}
+	return rec.status
+}
+
+// AccessLog はリクエストごとにメソッド、パス、ステータス、処理時間、
+// レスポンスサイズを構造化ログとして出力するミドルウェアである。
+// リクエストIDを含めるため RequestID の内側で適用する。
+func AccessLog(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		start := time.Now()
+		rec := &responseRecorder{ResponseWriter: w}
+
+		next.ServeHTTP(rec, r)
+
+		logging.FromContext(r.Context()).Info("HTTPリクエスト",
+			"method", r.Method,
+			"path", r.URL.Path,
+			"status", rec.Status(),
+			"latencyMs", float64(time.Since(start).Microseconds())/1000,
+			"bytes", rec.bytes,
+			"remoteAddr", r.RemoteAddr,
+			"clientIp", ClientIP(r),
+			"userAgent", r.UserAgent(),
+		)
+	})
+}
@@ -0,0 +1,52 @@
+package middleware
+
+import (
+	"bytes"
+	"encoding/json"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/h-hiwatashi/super-business-book-ranking-backend/logging"
+)
+
+func TestAccessLog(t *testing.T) {
+	var buf bytes.Buffer
+	logger := logging.New(&buf, "info")
+
+	handler := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusNotFound)
+		w.Write([]byte("not found"))
+	})
+
+	req := httptest.NewRequest("GET", "/api/books/unknown", nil)
+	req = req.WithContext(logging.WithLogger(req.Context(), logger))
+	req.Header.Set(RequestIDHeader, "test-request-id")
+	rr := httptest.NewRecorder()
+
+	// RequestID は外側のロガーを引き継いでリクエストIDを付与する
+	RequestID(AccessLog(handler)).ServeHTTP(rr, req)
+
+	var entry map[string]interface{}
+	if err := json.Unmarshal(buf.Bytes(), &entry); err != nil {
+		t.Fatalf("access log is not valid JSON: %v: %s", err, buf.String())
+	}
+
+	want := map[string]interface{}{
+		slog.MessageKey: "HTTPリクエスト",
+		"method":        "GET",
+		"path":          "/api/books/unknown",
+		"status":        float64(http.StatusNotFound),
+		"bytes":         float64(len("not found")),
+		"requestId":     "test-request-id",
+	}
+	for key, value := range want {
+		if entry[key] != value {
+			t.Errorf("log entry %q = %v, want %v", key, entry[key], value)
+		}
+	}
+	if _, ok := entry["latencyMs"]; !ok {
+		t.Error("log entry does not contain latencyMs")
+	}
+}
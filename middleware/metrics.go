@@ -0,0 +1,33 @@
+package middleware
+
+import (
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/gorilla/mux"
+
+	"github.com/h-hiwatashi/super-business-book-ranking-backend/metrics"
+)
+
+// Metrics はルートテンプレート別にリクエスト処理時間を記録するミドルウェアである。
+// ラベルの種類が増えすぎないよう、実際のパスではなく mux のルートテンプレートを使うため、
+// mux.Router.Use で適用する。
+func Metrics(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		start := time.Now()
+		rec := &responseRecorder{ResponseWriter: w}
+
+		next.ServeHTTP(rec, r)
+
+		route := "unknown"
+		if current := mux.CurrentRoute(r); current != nil {
+			if tmpl, err := current.GetPathTemplate(); err == nil {
+				route = tmpl
+			}
+		}
+		metrics.HTTPRequestDuration.
+			WithLabelValues(r.Method, route, strconv.Itoa(rec.Status())).
+			Observe(time.Since(start).Seconds())
+	})
+}
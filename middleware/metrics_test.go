@@ -0,0 +1,38 @@
+package middleware
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/gorilla/mux"
+	dto "github.com/prometheus/client_model/go"
+
+	"github.com/h-hiwatashi/super-business-book-ranking-backend/metrics"
+)
+
+func TestMetrics(t *testing.T) {
+	router := mux.NewRouter()
+	router.Use(Metrics)
+	router.HandleFunc("/api/books/{bookId}", func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusTeapot)
+	}).Methods("GET")
+
+	for _, id := range []string{"a", "b", "c"} {
+		req := httptest.NewRequest("GET", "/api/books/"+id, nil)
+		router.ServeHTTP(httptest.NewRecorder(), req)
+	}
+
+	// 実際のパスではなくルートテンプレートで集計される
+	observer, err := metrics.HTTPRequestDuration.GetMetricWithLabelValues("GET", "/api/books/{bookId}", "418")
+	if err != nil {
+		t.Fatal(err)
+	}
+	var m dto.Metric
+	if err := observer.(interface{ Write(*dto.Metric) error }).Write(&m); err != nil {
+		t.Fatal(err)
+	}
+	if got := m.GetHistogram().GetSampleCount(); got != 3 {
+		t.Errorf("sample count = %d, want 3", got)
+	}
+}
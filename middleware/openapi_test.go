@@ -0,0 +1,109 @@
+package middleware
+
+import (
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/h-hiwatashi/super-business-book-ranking-backend/openapi"
+)
+
+func TestValidateRequests(t *testing.T) {
+	spec, err := openapi.Load()
+	if err != nil {
+		t.Fatal(err)
+	}
+	validate, err := ValidateRequests(spec)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	testCases := []struct {
+		name           string
+		method         string
+		target         string
+		body           string
+		wantStatusCode int
+		// wantBody はハンドラーに届いた本文である。照合で読んだ本文を戻していることを確かめる。
+		wantBody string
+	}{
+		{
+			name:           "正常系：仕様に合うクエリ",
+			method:         "GET",
+			target:         "/api/rankings/001?period=weekly&page=2",
+			wantStatusCode: http.StatusOK,
+		},
+		{
+			name:           "正常系：仕様にないルートは照合しない",
+			method:         "GET",
+			target:         "/unknown?period=hourly",
+			wantStatusCode: http.StatusOK,
+		},
+		{
+			name:           "正常系：仕様に合う本文",
+			method:         "POST",
+			target:         "/admin/webhooks",
+			body:           `{"name":"提携先","url":"https://partner.example.com/hooks","events":["new_entry"]}`,
+			wantStatusCode: http.StatusOK,
+			wantBody:       `{"name":"提携先","url":"https://partner.example.com/hooks","events":["new_entry"]}`,
+		},
+		{
+			name:           "異常系：列挙にない期間",
+			method:         "GET",
+			target:         "/api/rankings/001?period=hourly",
+			wantStatusCode: http.StatusBadRequest,
+		},
+		{
+			name:           "異常系：最小値未満のページ",
+			method:         "GET",
+			target:         "/api/rankings/001?page=0",
+			wantStatusCode: http.StatusBadRequest,
+		},
+		{
+			name:           "異常系：列挙にないフィードの形式",
+			method:         "GET",
+			target:         "/feeds/rankings/001/new.rss?period=hourly",
+			wantStatusCode: http.StatusBadRequest,
+		},
+		{
+			name:           "異常系：必須項目のない本文",
+			method:         "POST",
+			target:         "/admin/webhooks",
+			body:           `{"name":"提携先","url":"https://partner.example.com/hooks"}`,
+			wantStatusCode: http.StatusBadRequest,
+		},
+		{
+			name:           "異常系：上限を超える値",
+			method:         "POST",
+			target:         "/admin/webhooks",
+			body:           `{"name":"提携先","url":"https://partner.example.com/hooks","events":["new_entry"],"topN":101}`,
+			wantStatusCode: http.StatusBadRequest,
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			var gotBody string
+			handler := validate(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				body, _ := io.ReadAll(r.Body)
+				gotBody = string(body)
+			}))
+
+			req := httptest.NewRequest(tc.method, tc.target, strings.NewReader(tc.body))
+			if tc.body != "" {
+				req.Header.Set("Content-Type", "application/json")
+			}
+			rr := httptest.NewRecorder()
+			handler.ServeHTTP(rr, req)
+
+			if rr.Code != tc.wantStatusCode {
+				t.Fatalf("status = %v, want %v: %s", rr.Code, tc.wantStatusCode, rr.Body.String())
+			}
+			if gotBody != tc.wantBody {
+				t.Errorf("body = %q, want %q", gotBody, tc.wantBody)
+			}
+		})
+	}
+}
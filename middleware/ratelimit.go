@@ -0,0 +1,105 @@
+package middleware
+
+import (
+	"math"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/h-hiwatashi/super-business-book-ranking-backend/auth"
+	"github.com/h-hiwatashi/super-business-book-ranking-backend/logging"
+	"github.com/h-hiwatashi/super-business-book-ranking-backend/metrics"
+	"github.com/h-hiwatashi/super-business-book-ranking-backend/ratelimit"
+)
+
+// RateLimitPolicy はルートグループごとのリクエスト数の上限である。
+type RateLimitPolicy struct {
+	// Group はルートグループの名前で、グループごとに別々に数える。
+	Group  string
+	Window time.Duration
+	// PerIP はリクエスト元のIPアドレスごとの上限である。
+	PerIP int
+	// PerKey はAPIキーごとの上限である。0 の場合はAPIキーごとには制限しない。
+	PerKey int
+}
+
+// RateLimit はリクエスト元のIPアドレスごと、およびAPIキーごとにリクエスト数を制限するミドルウェアを返す。
+// APIキーは Authenticate で検証済みのものだけを数える。
+// APIキーを付けたリクエストは両方の上限が適用されるため、キーを付け替えてもIPアドレスの上限は逃れられない。
+// 拒否したリクエストはどちらの上限でも数えないため、上限に達したAPIキーがIPアドレスの上限を使い切ることはない。
+//
+// 残りのリクエスト数を X-RateLimit-Limit, X-RateLimit-Remaining, X-RateLimit-Reset（秒）で返し、
+// 上限を超えた場合は Retry-After を付けて 429 を返す。
+// ストアの障害時はAPI全体を止めないよう、制限せずにリクエストを通す。
+func RateLimit(store ratelimit.Store, policy RateLimitPolicy) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			prefix := "ratelimit:" + policy.Group + ":"
+			limits := []ratelimit.Limit{{Key: prefix + "ip:" + ClientIP(r), Limit: policy.PerIP}}
+			if principal := auth.FromContext(r.Context()); principal != nil && policy.PerKey > 0 {
+				limits = append(limits, ratelimit.Limit{Key: prefix + "key:" + principal.KeyID, Limit: policy.PerKey})
+			}
+
+			results, err := store.Allow(r.Context(), limits, policy.Window)
+			if err != nil {
+				logging.FromContext(r.Context()).Warn("レート制限の判定に失敗しました", "group", policy.Group, "error", err)
+				next.ServeHTTP(w, r)
+				return
+			}
+			result := results[0]
+			for _, res := range results[1:] {
+				if moreRestrictive(res, result) {
+					result = res
+				}
+			}
+
+			h := w.Header()
+			h.Set("X-RateLimit-Limit", strconv.Itoa(result.Limit))
+			h.Set("X-RateLimit-Remaining", strconv.Itoa(result.Remaining))
+			h.Set("X-RateLimit-Reset", strconv.Itoa(ceilSeconds(result.Reset)))
+
+			if !result.Allowed {
+				metrics.RateLimitedRequests.WithLabelValues(policy.Group).Inc()
+				h.Set("Retry-After", strconv.Itoa(max(ceilSeconds(result.RetryAfter), 1)))
+				http.Error(w, "リクエスト数の上限を超えました", http.StatusTooManyRequests)
+				return
+			}
+			next.ServeHTTP(w, r)
+		})
+	}
+}
+
+// LimitAuthAttempts は X-API-Key を付けたリクエストをIPアドレスごとに数え、
+// 上限を超えた場合はキーを検証せずに 429 を返すミドルウェアを返す。
+// Authenticate より前に置き、無効なキーの総当たりと、それによる KeyStore への問い合わせを抑える。
+// policy の PerKey は使わない。
+func LimitAuthAttempts(store ratelimit.Store, policy RateLimitPolicy) func(http.Handler) http.Handler {
+	policy.PerKey = 0
+	limit := RateLimit(store, policy)
+	return func(next http.Handler) http.Handler {
+		limited := limit(next)
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			if r.Header.Get(APIKeyHeader) == "" {
+				next.ServeHTTP(w, r)
+				return
+			}
+			limited.ServeHTTP(w, r)
+		})
+	}
+}
+
+// moreRestrictive は a が b より厳しい判定結果かを返す。
+// 拒否された結果を優先し、その中では待ち時間の長いもの、許可された中では残りの少ないものを選ぶ。
+func moreRestrictive(a, b ratelimit.Result) bool {
+	if a.Allowed != b.Allowed {
+		return !a.Allowed
+	}
+	if !a.Allowed {
+		return a.RetryAfter > b.RetryAfter
+	}
+	return a.Remaining < b.Remaining
+}
+
+func ceilSeconds(d time.Duration) int {
+	return int(math.Ceil(d.Seconds()))
+}
@@ -0,0 +1,125 @@
+package middleware
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/h-hiwatashi/super-business-book-ranking-backend/auth"
+	"github.com/h-hiwatashi/super-business-book-ranking-backend/ratelimit"
+)
+
+type failingStore struct{}
+
+func (failingStore) Allow(ctx context.Context, limits []ratelimit.Limit, window time.Duration) ([]ratelimit.Result, error) {
+	return nil, errors.New("store unavailable")
+}
+
+func TestRateLimit(t *testing.T) {
+	store := ratelimit.NewMemory()
+	policy := RateLimitPolicy{Group: "test", Window: time.Minute, PerIP: 2, PerKey: 3}
+	handler := RateLimit(store, policy)(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {}))
+
+	send := func(remoteAddr, apiKey string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest("GET", "/api/categories", nil)
+		req.RemoteAddr = remoteAddr
+		if apiKey != "" {
+			req = req.WithContext(auth.WithPrincipal(req.Context(), &auth.Principal{KeyID: apiKey, Role: auth.RolePartner}))
+		}
+		rr := httptest.NewRecorder()
+		handler.ServeHTTP(rr, req)
+		return rr
+	}
+
+	testCases := []struct {
+		name           string
+		remoteAddr     string
+		apiKey         string
+		wantStatusCode int
+		wantRemaining  string
+	}{
+		{name: "1件目", remoteAddr: "192.0.2.1:1000", wantStatusCode: http.StatusOK, wantRemaining: "1"},
+		{name: "2件目", remoteAddr: "192.0.2.1:1001", wantStatusCode: http.StatusOK, wantRemaining: "0"},
+		{name: "IPアドレスの上限を超えると 429", remoteAddr: "192.0.2.1:1002", wantStatusCode: http.StatusTooManyRequests, wantRemaining: "0"},
+		{name: "APIキーを付けてもIPアドレスの上限は逃れられない", remoteAddr: "192.0.2.1:1003", apiKey: "key-a", wantStatusCode: http.StatusTooManyRequests, wantRemaining: "0"},
+		{name: "別のIPアドレスは数えない", remoteAddr: "192.0.2.2:1000", apiKey: "key-a", wantStatusCode: http.StatusOK, wantRemaining: "1"},
+		{name: "残りの少ない方を返す", remoteAddr: "192.0.2.3:1000", apiKey: "key-a", wantStatusCode: http.StatusOK, wantRemaining: "1"},
+		{name: "APIキーの残りが 0", remoteAddr: "192.0.2.4:1000", apiKey: "key-a", wantStatusCode: http.StatusOK, wantRemaining: "0"},
+		{name: "APIキーの上限を超えると 429", remoteAddr: "192.0.2.5:1000", apiKey: "key-a", wantStatusCode: http.StatusTooManyRequests, wantRemaining: "0"},
+		{name: "APIキーの上限で拒否したリクエストはIPアドレスの上限で数えない", remoteAddr: "192.0.2.5:1001", apiKey: "key-b", wantStatusCode: http.StatusOK, wantRemaining: "1"},
+		{name: "別のAPIキーは数えない", remoteAddr: "192.0.2.6:1000", apiKey: "key-b", wantStatusCode: http.StatusOK, wantRemaining: "1"},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			rr := send(tc.remoteAddr, tc.apiKey)
+
+			if rr.Code != tc.wantStatusCode {
+				t.Errorf("status = %d, want %d", rr.Code, tc.wantStatusCode)
+			}
+			if got := rr.Header().Get("X-RateLimit-Remaining"); got != tc.wantRemaining {
+				t.Errorf("X-RateLimit-Remaining = %q, want %q", got, tc.wantRemaining)
+			}
+			if rr.Header().Get("X-RateLimit-Limit") == "" || rr.Header().Get("X-RateLimit-Reset") == "" {
+				t.Error("X-RateLimit-Limit or X-RateLimit-Reset is missing")
+			}
+			if tc.wantStatusCode == http.StatusTooManyRequests && rr.Header().Get("Retry-After") == "" {
+				t.Error("Retry-After is missing")
+			}
+		})
+	}
+}
+
+func TestRateLimitStoreError(t *testing.T) {
+	called := false
+	handler := RateLimit(failingStore{}, RateLimitPolicy{Group: "test", Window: time.Minute, PerIP: 1})(
+		http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) { called = true }))
+
+	rr := httptest.NewRecorder()
+	handler.ServeHTTP(rr, httptest.NewRequest("GET", "/api/categories", nil))
+
+	if !called || rr.Code != http.StatusOK {
+		t.Errorf("request was not passed through on store error: called = %v, status = %d", called, rr.Code)
+	}
+}
+
+func TestLimitAuthAttempts(t *testing.T) {
+	authenticated := 0
+	handler := LimitAuthAttempts(ratelimit.NewMemory(), RateLimitPolicy{Group: "auth", Window: time.Minute, PerIP: 2})(
+		http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			if r.Header.Get(APIKeyHeader) != "" {
+				authenticated++
+			}
+		}))
+
+	send := func(apiKey string) int {
+		req := httptest.NewRequest("GET", "/api/categories", nil)
+		req.RemoteAddr = "192.0.2.1:1000"
+		if apiKey != "" {
+			req.Header.Set(APIKeyHeader, apiKey)
+		}
+		rr := httptest.NewRecorder()
+		handler.ServeHTTP(rr, req)
+		return rr.Code
+	}
+
+	for i, key := range []string{"bkr_guess1", "bkr_guess2"} {
+		if status := send(key); status != http.StatusOK {
+			t.Fatalf("request %d: status = %d, want %d", i+1, status, http.StatusOK)
+		}
+	}
+	// 上限を超えるとキーを検証しない
+	if status := send("bkr_guess3"); status != http.StatusTooManyRequests {
+		t.Errorf("status = %d, want %d", status, http.StatusTooManyRequests)
+	}
+	if authenticated != 2 {
+		t.Errorf("authenticated %d times, want 2", authenticated)
+	}
+	// APIキーのないリクエストは数えない
+	if status := send(""); status != http.StatusOK {
+		t.Errorf("status without API key = %d, want %d", status, http.StatusOK)
+	}
+}
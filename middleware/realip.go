@@ -0,0 +1,108 @@
+package middleware
+
+import (
+	"context"
+	"fmt"
+	"net"
+	"net/http"
+	"net/netip"
+	"strings"
+)
+
+type clientIPKey struct{}
+
+// RealIP はリクエスト元のIPアドレスを決めてコンテキストに格納するミドルウェアを返す。
+// 直接の接続元が trusted に含まれるプロキシの場合に限り X-Forwarded-For を参照し、
+// 右から順にたどって最初に現れた信頼できないアドレスをリクエスト元とする。
+// クライアントが X-Forwarded-For を偽装してもレート制限を逃れられないよう、
+// 信頼できないアドレスから届いたヘッダーは参照しない。
+func RealIP(trusted []netip.Prefix) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			ip := remoteIP(r)
+			if ip.IsValid() && contains(trusted, ip) {
+				ip = forwardedIP(r.Header.Values("X-Forwarded-For"), trusted, ip)
+			}
+			if ip.IsValid() {
+				r = r.WithContext(context.WithValue(r.Context(), clientIPKey{}, ip.String()))
+			}
+			next.ServeHTTP(w, r)
+		})
+	}
+}
+
+// ClientIP はリクエスト元のIPアドレスを返す。
+// RealIP を適用していない場合は接続元のアドレスを返す。
+func ClientIP(r *http.Request) string {
+	if ip, ok := r.Context().Value(clientIPKey{}).(string); ok {
+		return ip
+	}
+	if ip := remoteIP(r); ip.IsValid() {
+		return ip.String()
+	}
+	return r.RemoteAddr
+}
+
+// ParseTrustedProxies は CIDR 表記または単一のIPアドレスの一覧を解析する。
+func ParseTrustedProxies(values []string) ([]netip.Prefix, error) {
+	prefixes := make([]netip.Prefix, 0, len(values))
+	for _, v := range values {
+		if strings.Contains(v, "/") {
+			prefix, err := netip.ParsePrefix(v)
+			if err != nil {
+				return nil, fmt.Errorf("信頼するプロキシの指定が不正です: %w", err)
+			}
+			prefixes = append(prefixes, prefix.Masked())
+			continue
+		}
+		addr, err := netip.ParseAddr(v)
+		if err != nil {
+			return nil, fmt.Errorf("信頼するプロキシの指定が不正です: %w", err)
+		}
+		addr = addr.Unmap()
+		prefixes = append(prefixes, netip.PrefixFrom(addr, addr.BitLen()))
+	}
+	return prefixes, nil
+}
+
+func remoteIP(r *http.Request) netip.Addr {
+	host, _, err := net.SplitHostPort(r.RemoteAddr)
+	if err != nil {
+		host = r.RemoteAddr
+	}
+	addr, err := netip.ParseAddr(host)
+	if err != nil {
+		return netip.Addr{}
+	}
+	return addr.Unmap()
+}
+
+func forwardedIP(headers []string, trusted []netip.Prefix, remote netip.Addr) netip.Addr {
+	var hops []string
+	for _, h := range headers {
+		hops = append(hops, strings.Split(h, ",")...)
+	}
+
+	ip := remote
+	for i := len(hops) - 1; i >= 0; i-- {
+		addr, err := netip.ParseAddr(strings.TrimSpace(hops[i]))
+		if err != nil {
+			// 解析できない値より左は信頼しない
+			return ip
+		}
+		ip = addr.Unmap()
+		if !contains(trusted, ip) {
+			return ip
+		}
+	}
+	return ip
+}
+
+func contains(prefixes []netip.Prefix, ip netip.Addr) bool {
+	for _, p := range prefixes {
+		if p.Contains(ip) {
+			return true
+		}
+	}
+	return false
+}
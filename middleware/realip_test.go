@@ -0,0 +1,70 @@
+package middleware
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestRealIP(t *testing.T) {
+	trusted, err := ParseTrustedProxies([]string{"10.0.0.0/8", "192.0.2.1"})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	testCases := []struct {
+		name         string
+		remoteAddr   string
+		forwardedFor string
+		wantClientIP string
+	}{
+		{
+			name:         "プロキシを経由しない接続",
+			remoteAddr:   "198.51.100.7:52000",
+			wantClientIP: "198.51.100.7",
+		},
+		{
+			name:         "信頼できない接続元の X-Forwarded-For は無視する",
+			remoteAddr:   "198.51.100.7:52000",
+			forwardedFor: "203.0.113.9",
+			wantClientIP: "198.51.100.7",
+		},
+		{
+			name:         "信頼するプロキシ経由ではヘッダーの値を使う",
+			remoteAddr:   "10.0.0.5:52000",
+			forwardedFor: "203.0.113.9",
+			wantClientIP: "203.0.113.9",
+		},
+		{
+			name:         "多段のプロキシは右から信頼できるものを飛ばす",
+			remoteAddr:   "10.0.0.5:52000",
+			forwardedFor: "1.1.1.1, 203.0.113.9, 192.0.2.1",
+			wantClientIP: "203.0.113.9",
+		},
+		{
+			name:         "ヘッダーがなければ接続元を使う",
+			remoteAddr:   "10.0.0.5:52000",
+			wantClientIP: "10.0.0.5",
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			var got string
+			handler := RealIP(trusted)(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				got = ClientIP(r)
+			}))
+
+			req := httptest.NewRequest("GET", "/api/categories", nil)
+			req.RemoteAddr = tc.remoteAddr
+			if tc.forwardedFor != "" {
+				req.Header.Set("X-Forwarded-For", tc.forwardedFor)
+			}
+			handler.ServeHTTP(httptest.NewRecorder(), req)
+
+			if got != tc.wantClientIP {
+				t.Errorf("ClientIP() = %q, want %q", got, tc.wantClientIP)
+			}
+		})
+	}
+}
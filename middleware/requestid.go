@@ -0,0 +1,70 @@
+// Package middleware は gorilla/mux のルーターに適用する HTTP ミドルウェアを提供する。
+package middleware
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"net/http"
+
+	"github.com/h-hiwatashi/super-business-book-ranking-backend/logging"
+)
+
+// RequestIDHeader はリクエストIDを受け渡すヘッダー名である。
+const RequestIDHeader = "X-Request-ID"
+
+// 受け入れるリクエストIDの最大長。
+const maxRequestIDLength = 128
+
+type requestIDKey struct{}
+
+// RequestID はリクエストIDを付与するミドルウェアである。
+// クライアントから妥当な X-Request-ID が渡された場合はそれを引き継ぎ、
+// そうでない場合は新たに生成する。リクエストIDはレスポンスヘッダーに設定され、
+// リクエストIDを属性に持つロガーとともにコンテキストへ格納される。
+func RequestID(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		id := r.Header.Get(RequestIDHeader)
+		if !validRequestID(id) {
+			id = newRequestID()
+		}
+		w.Header().Set(RequestIDHeader, id)
+
+		ctx := context.WithValue(r.Context(), requestIDKey{}, id)
+		logger := logging.FromContext(ctx).With("requestId", id)
+		ctx = logging.WithLogger(ctx, logger)
+
+		next.ServeHTTP(w, r.WithContext(ctx))
+	})
+}
+
+// RequestIDFromContext はコンテキストに格納されたリクエストIDを返す。
+func RequestIDFromContext(ctx context.Context) string {
+	id, _ := ctx.Value(requestIDKey{}).(string)
+	return id
+}
+
+// validRequestID はヘッダーインジェクションやログ汚染を防ぐため、
+// 英数字と一部の記号のみで構成された適切な長さのIDだけを受け入れる。
+func validRequestID(id string) bool {
+	if id == "" || len(id) > maxRequestIDLength {
+		return false
+	}
+	for _, c := range id {
+		switch {
+		case c >= 'a' && c <= 'z', c >= 'A' && c <= 'Z', c >= '0' && c <= '9':
+		case c == '-', c == '_', c == '.', c == ':':
+		default:
+			return false
+		}
+	}
+	return true
+}
+
+func newRequestID() string {
+	b := make([]byte, 16)
+	if _, err := rand.Read(b); err != nil {
+		return "unknown"
+	}
+	return hex.EncodeToString(b)
+}
@@ -0,0 +1,61 @@
+package middleware
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestRequestID(t *testing.T) {
+	testCases := []struct {
+		name     string
+		incoming string
+		wantSame bool
+	}{
+		{
+			name:     "ヘッダーなし（新規生成される）",
+			incoming: "",
+			wantSame: false,
+		},
+		{
+			name:     "妥当なリクエストIDは引き継がれる",
+			incoming: "abc-123_DEF.456:789",
+			wantSame: true,
+		},
+		{
+			name:     "不正な文字を含むリクエストIDは置き換えられる",
+			incoming: "abc\r\nX-Injected: 1",
+			wantSame: false,
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			var ctxID string
+			handler := RequestID(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				ctxID = RequestIDFromContext(r.Context())
+			}))
+
+			req := httptest.NewRequest("GET", "/health", nil)
+			if tc.incoming != "" {
+				req.Header.Set(RequestIDHeader, tc.incoming)
+			}
+			rr := httptest.NewRecorder()
+			handler.ServeHTTP(rr, req)
+
+			got := rr.Header().Get(RequestIDHeader)
+			if got == "" {
+				t.Fatal("response does not contain X-Request-ID")
+			}
+			if got != ctxID {
+				t.Errorf("context request ID = %q, header = %q", ctxID, got)
+			}
+			if tc.wantSame && got != tc.incoming {
+				t.Errorf("request ID = %q, want %q", got, tc.incoming)
+			}
+			if !tc.wantSame && got == tc.incoming {
+				t.Errorf("request ID %q was not regenerated", got)
+			}
+		})
+	}
+}
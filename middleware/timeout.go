@@ -0,0 +1,20 @@
+package middleware
+
+import (
+	"context"
+	"net/http"
+	"time"
+)
+
+// Timeout はリクエストのコンテキストに期限を設定するミドルウェアを返す。
+// 期限を過ぎるとデータベースや外部APIの呼び出しが中断され、ハンドラーは 504 を返す。
+// ルートごとに異なる期限を設定できるよう、個々のハンドラーに適用する。
+func Timeout(d time.Duration) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			ctx, cancel := context.WithTimeout(r.Context(), d)
+			defer cancel()
+			next.ServeHTTP(w, r.WithContext(ctx))
+		})
+	}
+}
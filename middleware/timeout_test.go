@@ -0,0 +1,27 @@
+package middleware
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+)
+
+func TestTimeout(t *testing.T) {
+	var deadline time.Time
+	var ok bool
+	handler := Timeout(time.Second)(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		deadline, ok = r.Context().Deadline()
+	}))
+
+	start := time.Now()
+	handler.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest("GET", "/api/categories", nil))
+	end := time.Now()
+
+	if !ok {
+		t.Fatal("request context has no deadline")
+	}
+	if deadline.Before(start.Add(time.Second)) || deadline.After(end.Add(time.Second)) {
+		t.Errorf("deadline %v is not 1s after the request", deadline)
+	}
+}
@@ -0,0 +1,28 @@
+// Package openapi は API の仕様（openapi.yaml）をバイナリに埋め込んで提供する。
+package openapi
+
+import (
+	"context"
+	_ "embed"
+	"fmt"
+
+	"github.com/getkin/kin-openapi/openapi3"
+)
+
+// YAML は API の仕様である。
+//
+//go:embed openapi.yaml
+var YAML []byte
+
+// Load は API の仕様を読み込み、仕様として正しいことを検証して返す。
+// 呼び出し側で書き換えられるよう、呼び出しごとに新しく読み込む。
+func Load() (*openapi3.T, error) {
+	doc, err := openapi3.NewLoader().LoadFromData(YAML)
+	if err != nil {
+		return nil, fmt.Errorf("API 仕様の読み込みエラー: %w", err)
+	}
+	if err := doc.Validate(context.Background()); err != nil {
+		return nil, fmt.Errorf("API 仕様の検証エラー: %w", err)
+	}
+	return doc, nil
+}
@@ -0,0 +1,137 @@
+package ratelimit
+
+import (
+	"context"
+	"math"
+	"sync"
+	"time"
+
+	"github.com/h-hiwatashi/super-business-book-ranking-backend/clock"
+)
+
+// Memory はプロセス内でリクエスト数を数える Store である。
+//
+// 固定ウィンドウの件数を2つ持ち、直前のウィンドウの件数を経過時間に応じて按分して
+// 直近 window 内の件数を見積もる（スライディングウィンドウカウンター）。
+type Memory struct {
+	mu        sync.Mutex
+	counters  map[string]*counter
+	lastSweep time.Time
+	clock     clock.Clock
+}
+
+type counter struct {
+	window   time.Duration
+	start    time.Time // 現在のウィンドウの開始時刻
+	current  int
+	previous int
+}
+
+func NewMemory() *Memory {
+	return newMemory(clock.Real{})
+}
+
+func newMemory(clk clock.Clock) *Memory {
+	return &Memory{
+		counters:  make(map[string]*counter),
+		lastSweep: clk.Now(),
+		clock:     clk,
+	}
+}
+
+func (m *Memory) Allow(ctx context.Context, limits []Limit, window time.Duration) ([]Result, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	now := m.clock.Now()
+	m.sweep(now, window)
+
+	results := make([]Result, len(limits))
+	counters := make([]*counter, len(limits))
+	estimates := make([]float64, len(limits))
+	admitted := true
+	for i, l := range limits {
+		if l.Limit <= 0 {
+			results[i] = Result{Limit: l.Limit, RetryAfter: window, Reset: window}
+			admitted = false
+			continue
+		}
+
+		c, ok := m.counters[l.Key]
+		if !ok {
+			c = &counter{window: window, start: now.Truncate(window)}
+			m.counters[l.Key] = c
+		}
+		c.advance(now)
+
+		elapsed := now.Sub(c.start)
+		weight := 1 - float64(elapsed)/float64(window)
+		estimate := float64(c.previous)*weight + float64(c.current)
+
+		results[i] = Result{Limit: l.Limit, Reset: window - elapsed}
+		if estimate+1 > float64(l.Limit) {
+			results[i].RetryAfter = c.retryAfter(now, l.Limit)
+			admitted = false
+			continue
+		}
+		results[i].Allowed = true
+		counters[i], estimates[i] = c, estimate
+	}
+
+	for i, c := range counters {
+		if c == nil {
+			continue
+		}
+		remaining := float64(limits[i].Limit) - estimates[i]
+		if admitted {
+			c.current++
+			remaining--
+		}
+		results[i].Remaining = max(int(math.Floor(remaining)), 0)
+	}
+	return results, nil
+}
+
+// advance は now が属するウィンドウまでカウンターを進める。
+func (c *counter) advance(now time.Time) {
+	start := now.Truncate(c.window)
+	switch {
+	case start.Equal(c.start):
+	case start.Sub(c.start) == c.window:
+		c.previous, c.current = c.current, 0
+		c.start = start
+	default:
+		c.previous, c.current = 0, 0
+		c.start = start
+	}
+}
+
+// retryAfter は見積もり件数が limit-1 以下に下がり、次のリクエストが許可されるまでの時間を返す。
+func (c *counter) retryAfter(now time.Time, limit int) time.Duration {
+	elapsed := now.Sub(c.start)
+	allowed := float64(limit - 1)
+
+	if float64(c.current) <= allowed {
+		// 現在のウィンドウ内で、直前のウィンドウの按分が十分に減るのを待つ
+		// previous*(1-t/window) + current <= allowed を満たす t を求める
+		t := float64(c.window) * (1 - (allowed-float64(c.current))/float64(c.previous))
+		return max(time.Duration(math.Round(t))-elapsed, 0)
+	}
+
+	// 次のウィンドウに入り、現在の件数の按分が十分に減るのを待つ
+	t := float64(c.window) * (1 - allowed/float64(c.current))
+	return c.window - elapsed + time.Duration(math.Round(t))
+}
+
+// sweep は2ウィンドウ以上更新されていないカウンターを削除する。
+func (m *Memory) sweep(now time.Time, window time.Duration) {
+	if now.Sub(m.lastSweep) < window {
+		return
+	}
+	m.lastSweep = now
+	for key, c := range m.counters {
+		if now.Sub(c.start) >= 2*c.window {
+			delete(m.counters, key)
+		}
+	}
+}
@@ -0,0 +1,113 @@
+package ratelimit
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/h-hiwatashi/super-business-book-ranking-backend/clock"
+)
+
+// allowOne は1つのキーだけを1分間のウィンドウで判定する。
+func allowOne(m *Memory, key string, limit int) (Result, error) {
+	results, err := m.Allow(context.Background(), []Limit{{Key: key, Limit: limit}}, time.Minute)
+	if err != nil {
+		return Result{}, err
+	}
+	return results[0], nil
+}
+
+func TestMemoryAllow(t *testing.T) {
+	clk := clock.NewFake(time.Date(2024, 3, 15, 0, 0, 0, 0, time.UTC))
+	m := newMemory(clk)
+
+	for i := 0; i < 3; i++ {
+		result, err := allowOne(m, "ip:192.0.2.1", 3)
+		if err != nil {
+			t.Fatalf("Allow() error = %v", err)
+		}
+		if !result.Allowed || result.Remaining != 2-i {
+			t.Errorf("request %d: allowed = %v, remaining = %d want true, %d", i+1, result.Allowed, result.Remaining, 2-i)
+		}
+	}
+
+	result, _ := allowOne(m, "ip:192.0.2.1", 3)
+	if result.Allowed {
+		t.Fatal("Allow() allowed a request over the limit")
+	}
+	// 3件とも現在のウィンドウにあるため、次のウィンドウで按分が 2件以下になる 20秒後まで待つ
+	if want := time.Minute + 20*time.Second; result.RetryAfter != want {
+		t.Errorf("RetryAfter = %v, want %v", result.RetryAfter, want)
+	}
+
+	// 他のキーには影響しない
+	if result, _ := allowOne(m, "ip:192.0.2.2", 3); !result.Allowed {
+		t.Error("Allow() blocked a different key")
+	}
+}
+
+func TestMemorySlidingWindow(t *testing.T) {
+	clk := clock.NewFake(time.Date(2024, 3, 15, 0, 0, 0, 0, time.UTC))
+	m := newMemory(clk)
+
+	for i := 0; i < 4; i++ {
+		allowOne(m, "key", 4)
+	}
+
+	// ウィンドウが切り替わった直後は直前のウィンドウの件数がほぼそのまま数えられる
+	clk.Advance(time.Minute + 10*time.Second)
+	result, _ := allowOne(m, "key", 4)
+	if result.Allowed {
+		t.Fatal("Allow() allowed a request right after the window boundary")
+	}
+	// 4*(1-t/60s) <= 3 となる t=15s まで待つ
+	if want := 5 * time.Second; result.RetryAfter != want {
+		t.Errorf("RetryAfter = %v, want %v", result.RetryAfter, want)
+	}
+
+	clk.Advance(result.RetryAfter)
+	result, _ = allowOne(m, "key", 4)
+	if !result.Allowed {
+		t.Fatal("Allow() blocked a request after the previous window decayed")
+	}
+
+	// 2ウィンドウ以上経つと以前の件数は数えない
+	clk.Advance(2 * time.Minute)
+	for i := 0; i < 4; i++ {
+		if result, _ := allowOne(m, "key", 4); !result.Allowed {
+			t.Fatalf("request %d was blocked after the window expired", i+1)
+		}
+	}
+}
+
+func TestMemoryAllowMultipleKeys(t *testing.T) {
+	clk := clock.NewFake(time.Date(2024, 3, 15, 0, 0, 0, 0, time.UTC))
+	m := newMemory(clk)
+	ctx := context.Background()
+	limits := []Limit{{Key: "ip:192.0.2.1", Limit: 3}, {Key: "key:a", Limit: 1}}
+
+	results, err := m.Allow(ctx, limits, time.Minute)
+	if err != nil {
+		t.Fatalf("Allow() error = %v", err)
+	}
+	if !results[0].Allowed || !results[1].Allowed {
+		t.Fatalf("Allow() = %+v, want both allowed", results)
+	}
+	if results[0].Remaining != 2 || results[1].Remaining != 0 {
+		t.Errorf("remaining = %d, %d, want 2, 0", results[0].Remaining, results[1].Remaining)
+	}
+
+	// APIキーの上限で拒否したリクエストはIPアドレスの上限で数えない
+	for i := 0; i < 3; i++ {
+		results, _ = m.Allow(ctx, limits, time.Minute)
+		if !results[0].Allowed || results[1].Allowed {
+			t.Fatalf("request %d: Allow() = %+v, want the key to be rejected", i+2, results)
+		}
+		if results[0].Remaining != 2 {
+			t.Errorf("request %d: IP remaining = %d, want 2", i+2, results[0].Remaining)
+		}
+	}
+	if result, _ := allowOne(m, "ip:192.0.2.1", 3); !result.Allowed || result.Remaining != 1 {
+		t.Errorf("allowOne() = %+v, want allowed with 1 remaining", result)
+	}
+}
@@ -0,0 +1,35 @@
+// Package ratelimit はクライアントごとのリクエスト数をスライディングウィンドウで制限する。
+package ratelimit
+
+import (
+	"context"
+	"time"
+)
+
+// Store はキーごとのリクエスト数を数えるストアである。
+// 複数のサーバーで制限を共有する場合は、共有ストアを使う実装に差し替える。
+type Store interface {
+	// Allow は limits のすべてのキーで直近 window 内の件数が上限以下に収まる場合に、
+	// それぞれのキーにリクエストを1件数えて許可する。結果は limits と同じ順に返す。
+	// いずれかのキーで許可しなかったリクエストは、どのキーにも数えない。
+	Allow(ctx context.Context, limits []Limit, window time.Duration) ([]Result, error)
+}
+
+// Limit はキーごとのリクエスト数の上限である。
+type Limit struct {
+	Key   string
+	Limit int
+}
+
+// Result は Allow のキーごとの判定結果である。
+type Result struct {
+	// Allowed はこのキーの上限に収まっているかを表す。
+	Allowed bool
+	Limit   int
+	// Remaining は続けて許可されるリクエスト数である。
+	Remaining int
+	// RetryAfter は許可されなかった場合に、次のリクエストが許可されるまでの時間である。
+	RetryAfter time.Duration
+	// Reset は現在のウィンドウが終わるまでの時間である。
+	Reset time.Duration
+}
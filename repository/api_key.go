@@ -0,0 +1,122 @@
+package repository
+
+import (
+	"context"
+	"database/sql"
+	"strings"
+
+	"github.com/h-hiwatashi/super-business-book-ranking-backend/metrics"
+)
+
+// APIKeyRepository は発行したAPIキーを扱うリポジトリである。
+type APIKeyRepository struct {
+	db *sql.DB
+}
+
+func NewAPIKeyRepository(db *sql.DB) *APIKeyRepository {
+	return &APIKeyRepository{db: db}
+}
+
+// Create はAPIキーを保存する。ID と作成日時は設定したうえで key に書き戻す。
+func (r *APIKeyRepository) Create(ctx context.Context, key *APIKey) error {
+	defer metrics.ObserveDBQuery("api_key", "Create")()
+
+	id, err := newID()
+	if err != nil {
+		return err
+	}
+
+	_, err = r.db.ExecContext(ctx, `
+		INSERT INTO api_keys (id, name, prefix, key_hash, role, scopes)
+		VALUES (?, ?, ?, ?, ?, ?)
+	`, id, key.Name, key.Prefix, key.KeyHash, key.Role, strings.Join(key.Scopes, ","))
+	if err != nil {
+		return err
+	}
+
+	key.ID = id
+	return r.db.QueryRowContext(ctx, `SELECT created_at FROM api_keys WHERE id = ?`, id).Scan(&key.CreatedAt)
+}
+
+// FindByHash はハッシュが一致するAPIキーを取得する。存在しない場合は sql.ErrNoRows を返す。
+// 失効したキーも返すため、呼び出し側で RevokedAt を確認する。
+func (r *APIKeyRepository) FindByHash(ctx context.Context, hash string) (*APIKey, error) {
+	defer metrics.ObserveDBQuery("api_key", "FindByHash")()
+
+	row := r.db.QueryRowContext(ctx, `
+		SELECT id, name, prefix, key_hash, role, scopes, created_at, revoked_at
+		FROM api_keys
+		WHERE key_hash = ?
+	`, hash)
+	return scanAPIKey(row)
+}
+
+// List はAPIキーを作成日時の新しい順にすべて取得する。
+func (r *APIKeyRepository) List(ctx context.Context) ([]APIKey, error) {
+	defer metrics.ObserveDBQuery("api_key", "List")()
+
+	rows, err := r.db.QueryContext(ctx, `
+		SELECT id, name, prefix, key_hash, role, scopes, created_at, revoked_at
+		FROM api_keys
+		ORDER BY created_at DESC, id
+	`)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	keys := []APIKey{}
+	for rows.Next() {
+		key, err := scanAPIKey(rows)
+		if err != nil {
+			return nil, err
+		}
+		keys = append(keys, *key)
+	}
+	return keys, rows.Err()
+}
+
+// Revoke はAPIキーを失効させる。存在しないか失効済みの場合は sql.ErrNoRows を返す。
+func (r *APIKeyRepository) Revoke(ctx context.Context, id string) error {
+	defer metrics.ObserveDBQuery("api_key", "Revoke")()
+
+	result, err := r.db.ExecContext(ctx, `
+		UPDATE api_keys SET revoked_at = CURRENT_TIMESTAMP
+		WHERE id = ? AND revoked_at IS NULL
+	`, id)
+	if err != nil {
+		return err
+	}
+	n, err := result.RowsAffected()
+	if err != nil {
+		return err
+	}
+	if n == 0 {
+		return sql.ErrNoRows
+	}
+	return nil
+}
+
+type scanner interface {
+	Scan(dest ...any) error
+}
+
+func scanAPIKey(s scanner) (*APIKey, error) {
+	var key APIKey
+	var scopes string
+	var revokedAt sql.NullTime
+
+	err := s.Scan(&key.ID, &key.Name, &key.Prefix, &key.KeyHash, &key.Role, &scopes, &key.CreatedAt, &revokedAt)
+	if err != nil {
+		return nil, err
+	}
+
+	key.Scopes = []string{}
+	if scopes != "" {
+		key.Scopes = strings.Split(scopes, ",")
+	}
+	if revokedAt.Valid {
+		key.RevokedAt = &revokedAt.Time
+	}
+	return &key, nil
+}
@@ -0,0 +1,139 @@
+package repository
+
+import (
+	"context"
+	"database/sql"
+	"strings"
+
+	"github.com/h-hiwatashi/super-business-book-ranking-backend/metrics"
+)
+
+// BookRepository は書籍情報を扱うリポジトリである。
+type BookRepository struct {
+	db *sql.DB
+}
+
+func NewBookRepository(db *sql.DB) *BookRepository {
+	return &BookRepository{db: db}
+}
+
+// FindByID は書籍IDで書籍を取得する。存在しない場合は sql.ErrNoRows を返す。
+func (r *BookRepository) FindByID(ctx context.Context, bookID string) (*Book, error) {
+	defer metrics.ObserveDBQuery("book", "FindByID")()
+	return r.find(ctx, bookID, nil)
+}
+
+// FindFields は FindByID と同じく書籍を取得するが、fields の項目（JSON の名前）の列だけを読み込み、ほかの項目はゼロ値とする。
+// 書籍IDと更新時刻は常に読み込む。fields が nil の場合はすべての項目を読み込む。
+func (r *BookRepository) FindFields(ctx context.Context, bookID string, fields []string) (*Book, error) {
+	defer metrics.ObserveDBQuery("book", "FindFields")()
+	return r.find(ctx, bookID, fields)
+}
+
+// find は書籍IDで書籍の fields の項目を読み込む。列の選び方は bookSelect を参照。
+func (r *BookRepository) find(ctx context.Context, bookID string, fields []string) (*Book, error) {
+	bookList, _ := bookSelect(fields)
+	query := `
+		SELECT 
+			b.id, ` + bookList + `, b.updated_at
+		FROM books b
+		WHERE b.id = ?
+	`
+
+	var book Book
+	var publicationDate sql.NullString
+
+	err := r.db.QueryRowContext(ctx, query, bookID).Scan(
+		&book.ID, &book.Title, &book.Author, &book.Publisher,
+		&book.ISBN, &publicationDate, &book.ImageURL, &book.UpdatedAt,
+	)
+	if err != nil {
+		return nil, err
+	}
+
+	if publicationDate.Valid {
+		book.PublicationDate = publicationDate.String
+	}
+
+	return &book, nil
+}
+
+// FindOffers は複数の書籍のECサイトごとの販売情報をまとめて取得し、書籍IDごとにサイト名順で返す。
+func (r *BookRepository) FindOffers(ctx context.Context, bookIDs []string) (map[string][]Offer, error) {
+	defer metrics.ObserveDBQuery("book", "FindOffers")()
+
+	offers := make(map[string][]Offer)
+	if len(bookIDs) == 0 {
+		return offers, nil
+	}
+
+	args := make([]interface{}, len(bookIDs))
+	for i, id := range bookIDs {
+		args[i] = id
+	}
+
+	rows, err := r.db.QueryContext(ctx, `
+		SELECT bsm.id, bsm.book_id, s.name, bsm.price, bsm.url
+		FROM book_site_mappings bsm
+		JOIN sites s ON bsm.site_id = s.id
+		WHERE bsm.book_id IN (`+placeholders(len(bookIDs))+`)
+		ORDER BY bsm.book_id, s.name
+	`, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var offer Offer
+		var price sql.NullFloat64
+		if err := rows.Scan(&offer.ID, &offer.BookID, &offer.Site, &price, &offer.URL); err != nil {
+			return nil, err
+		}
+		offer.Price = price.Float64
+		offers[offer.BookID] = append(offers[offer.BookID], offer)
+	}
+
+	return offers, rows.Err()
+}
+
+// FindCategories は書籍がランキングに載ったことのあるカテゴリを名前順に返す。
+func (r *BookRepository) FindCategories(ctx context.Context, bookID string) ([]Category, error) {
+	defer metrics.ObserveDBQuery("book", "FindCategories")()
+
+	rows, err := r.db.QueryContext(ctx, `
+		SELECT c.id, c.name, c.parent_id
+		FROM categories c
+		WHERE c.id IN (
+			SELECT r.category_id
+			FROM rankings r
+			JOIN book_site_mappings bsm ON r.book_site_mapping_id = bsm.id
+			WHERE bsm.book_id = ?
+		)
+		ORDER BY c.name
+	`, bookID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	categories := []Category{}
+	for rows.Next() {
+		var category Category
+		var parentID sql.NullString
+		if err := rows.Scan(&category.ID, &category.Name, &parentID); err != nil {
+			return nil, err
+		}
+		if parentID.Valid {
+			category.ParentID = &parentID.String
+		}
+		categories = append(categories, category)
+	}
+
+	return categories, rows.Err()
+}
+
+// placeholders は IN 句に使う n 個のプレースホルダーを返す。
+func placeholders(n int) string {
+	return strings.TrimSuffix(strings.Repeat("?, ", n), ", ")
+}
@@ -0,0 +1,53 @@
+package repository
+
+import (
+	"context"
+	"database/sql"
+
+	"github.com/h-hiwatashi/super-business-book-ranking-backend/metrics"
+)
+
+// CategoryRepository はカテゴリ情報を扱うリポジトリである。
+type CategoryRepository struct {
+	db *sql.DB
+}
+
+func NewCategoryRepository(db *sql.DB) *CategoryRepository {
+	return &CategoryRepository{db: db}
+}
+
+// List はカテゴリを名前順にすべて取得する。
+func (r *CategoryRepository) List(ctx context.Context) ([]Category, error) {
+	defer metrics.ObserveDBQuery("category", "List")()
+
+	query := `
+		SELECT id, name, parent_id
+		FROM categories
+		ORDER BY name
+	`
+
+	rows, err := r.db.QueryContext(ctx, query)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var categories []Category
+
+	for rows.Next() {
+		var category Category
+		var parentID sql.NullString
+
+		if err := rows.Scan(&category.ID, &category.Name, &parentID); err != nil {
+			return nil, err
+		}
+
+		if parentID.Valid {
+			category.ParentID = &parentID.String
+		}
+
+		categories = append(categories, category)
+	}
+
+	return categories, rows.Err()
+}
@@ -0,0 +1,118 @@
+package repository
+
+import (
+	"context"
+	"database/sql"
+	"strings"
+	"time"
+
+	"github.com/h-hiwatashi/super-business-book-ranking-backend/metrics"
+)
+
+// ClickRepository は商品リンクのクリックを扱うリポジトリである。
+type ClickRepository struct {
+	db *sql.DB
+}
+
+func NewClickRepository(db *sql.DB) *ClickRepository {
+	return &ClickRepository{db: db}
+}
+
+// FindLink は書籍のサイト別情報IDで商品ページへのリンクを取得する。存在しない場合は sql.ErrNoRows を返す。
+func (r *ClickRepository) FindLink(ctx context.Context, bookSiteMappingID string) (*SiteLink, error) {
+	defer metrics.ObserveDBQuery("click", "FindLink")()
+
+	var link SiteLink
+	err := r.db.QueryRowContext(ctx, `
+		SELECT bsm.id, bsm.book_id, s.name, bsm.url
+		FROM book_site_mappings bsm
+		JOIN sites s ON bsm.site_id = s.id
+		WHERE bsm.id = ?
+	`, bookSiteMappingID).Scan(&link.BookSiteMappingID, &link.BookID, &link.Site, &link.URL)
+	if err != nil {
+		return nil, err
+	}
+	return &link, nil
+}
+
+// InsertClicks はクリックをまとめて保存する。
+func (r *ClickRepository) InsertClicks(ctx context.Context, clicks []Click) error {
+	defer metrics.ObserveDBQuery("click", "InsertClicks")()
+
+	if len(clicks) == 0 {
+		return nil
+	}
+
+	placeholders := make([]string, len(clicks))
+	args := make([]interface{}, 0, len(clicks)*7)
+	for i, c := range clicks {
+		placeholders[i] = "(?, ?, ?, ?, ?, ?, ?)"
+		args = append(args, c.BookSiteMappingID, c.ClickedAt, c.Referrer,
+			nullString(c.CategoryID), nullString(c.PeriodType), nullInt(c.Rank), c.IPHash)
+	}
+
+	// rank は MySQL 8.0 の予約語のため引用符で囲む
+	_, err := r.db.ExecContext(ctx, `
+		INSERT INTO clicks (book_site_mapping_id, clicked_at, referrer, category_id, period_type, `+"`rank`"+`, ip_hash)
+		VALUES `+strings.Join(placeholders, ", "), args...)
+	return err
+}
+
+// Report は期間内のクリック数を日・書籍・サイトごとに集計する。
+// 日付の古い順、同じ日の中ではクリック数の多い順に返す。
+func (r *ClickRepository) Report(ctx context.Context, q ClickReportQuery) ([]ClickStat, error) {
+	defer metrics.ObserveDBQuery("click", "Report")()
+
+	from, err := time.Parse(dateLayout, q.From)
+	if err != nil {
+		return nil, err
+	}
+	to, err := time.Parse(dateLayout, q.To)
+	if err != nil {
+		return nil, err
+	}
+
+	conditions := []string{"c.clicked_at >= ?", "c.clicked_at < ?"}
+	args := []interface{}{from.Format(dateLayout), to.AddDate(0, 0, 1).Format(dateLayout)}
+	if q.Site != "" {
+		conditions = append(conditions, "s.name = ?")
+		args = append(args, q.Site)
+	}
+	if q.BookID != "" {
+		conditions = append(conditions, "b.id = ?")
+		args = append(args, q.BookID)
+	}
+
+	rows, err := r.db.QueryContext(ctx, `
+		SELECT DATE_FORMAT(c.clicked_at, '%Y-%m-%d') AS day, b.id, b.title, s.name, COUNT(*) AS clicks
+		FROM clicks c
+		JOIN book_site_mappings bsm ON c.book_site_mapping_id = bsm.id
+		JOIN books b ON bsm.book_id = b.id
+		JOIN sites s ON bsm.site_id = s.id
+		WHERE `+strings.Join(conditions, " AND ")+`
+		GROUP BY day, b.id, b.title, s.name
+		ORDER BY day, clicks DESC, b.id, s.name
+	`, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	stats := []ClickStat{}
+	for rows.Next() {
+		var stat ClickStat
+		if err := rows.Scan(&stat.Date, &stat.BookID, &stat.Title, &stat.Site, &stat.Clicks); err != nil {
+			return nil, err
+		}
+		stats = append(stats, stat)
+	}
+	return stats, rows.Err()
+}
+
+func nullString(s string) sql.NullString {
+	return sql.NullString{String: s, Valid: s != ""}
+}
+
+func nullInt(n int) sql.NullInt64 {
+	return sql.NullInt64{Int64: int64(n), Valid: n != 0}
+}
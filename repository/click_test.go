@@ -0,0 +1,34 @@
+package repository
+
+import (
+	"context"
+	"database/sql"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestInsertClicks(t *testing.T) {
+	connector := &recordingConnector{}
+	repo := NewClickRepository(sql.OpenDB(connector))
+
+	clicks := []Click{
+		{BookSiteMappingID: "bsm-1", ClickedAt: time.Now(), CategoryID: "001", PeriodType: "daily", Rank: 3, IPHash: "hash"},
+		{BookSiteMappingID: "bsm-2", ClickedAt: time.Now(), IPHash: "hash"},
+	}
+	if err := repo.InsertClicks(context.Background(), clicks); err != nil {
+		t.Fatal(err)
+	}
+	if len(connector.queries) != 1 {
+		t.Fatalf("queries = %v, want 1 query", connector.queries)
+	}
+
+	query := connector.queries[0]
+	// rank は MySQL 8.0 の予約語のため、引用符で囲まないと構文エラーになる
+	if !strings.Contains(query, "period_type, `rank`, ip_hash)") {
+		t.Errorf("rank column is not quoted: %s", query)
+	}
+	if got := strings.Count(query, "(?, ?, ?, ?, ?, ?, ?)"); got != len(clicks) {
+		t.Errorf("rows = %d, want %d: %s", got, len(clicks), query)
+	}
+}
@@ -0,0 +1,64 @@
+package repository
+
+import (
+	"context"
+	"database/sql/driver"
+	"errors"
+	"io"
+)
+
+// recordingConnector は実行された SQL を記録し、問い合わせには rows を返すだけのデータベースに接続する。
+type recordingConnector struct {
+	queries []string
+	// columns・rows は QueryContext が返す列名と行である。
+	columns []string
+	rows    [][]driver.Value
+}
+
+func (c *recordingConnector) Connect(ctx context.Context) (driver.Conn, error) {
+	return recordingConn{c}, nil
+}
+
+func (c *recordingConnector) Driver() driver.Driver { return nil }
+
+type recordingConn struct {
+	c *recordingConnector
+}
+
+func (conn recordingConn) ExecContext(ctx context.Context, query string, args []driver.NamedValue) (driver.Result, error) {
+	conn.c.queries = append(conn.c.queries, query)
+	return driver.RowsAffected(1), nil
+}
+
+func (conn recordingConn) QueryContext(ctx context.Context, query string, args []driver.NamedValue) (driver.Rows, error) {
+	conn.c.queries = append(conn.c.queries, query)
+	return &recordedRows{columns: conn.c.columns, rows: conn.c.rows}, nil
+}
+
+func (recordingConn) Prepare(query string) (driver.Stmt, error) {
+	return nil, errors.New("prepare is not supported")
+}
+
+func (recordingConn) Close() error { return nil }
+
+func (recordingConn) Begin() (driver.Tx, error) {
+	return nil, errors.New("transactions are not supported")
+}
+
+type recordedRows struct {
+	columns []string
+	rows    [][]driver.Value
+}
+
+func (r *recordedRows) Columns() []string { return r.columns }
+
+func (r *recordedRows) Close() error { return nil }
+
+func (r *recordedRows) Next(dest []driver.Value) error {
+	if len(r.rows) == 0 {
+		return io.EOF
+	}
+	copy(dest, r.rows[0])
+	r.rows = r.rows[1:]
+	return nil
+}
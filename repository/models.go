@@ -0,0 +1,253 @@
+// Package repository はデータベースへのアクセスを担うリポジトリを提供する。
+package repository
+
+import (
+	"encoding/json"
+	"time"
+)
+
+// ランキング書籍情報
+type RankedBook struct {
+	ID              string  `json:"id"`
+	Rank            int     `json:"rank"`
+	Title           string  `json:"title"`
+	Author          string  `json:"author"`
+	Publisher       string  `json:"publisher"`
+	ISBN            string  `json:"isbn"`
+	PublicationDate string  `json:"publicationDate"`
+	ImageURL        string  `json:"imageUrl"`
+	Price           float64 `json:"price"`
+	URL             string  `json:"url"`
+	// Site は販売するECサイトの名前（sites.name）である。
+	Site string `json:"site"`
+	// BookSiteMappingID はクリックを計測するリンク（/go/{bookSiteMappingId}）に使う。
+	BookSiteMappingID string `json:"bookSiteMappingId"`
+}
+
+// ランキングリスト
+type Ranking struct {
+	CategoryID   string       `json:"categoryId"`
+	CategoryName string       `json:"categoryName"`
+	PeriodType   string       `json:"periodType"`
+	DateFrom     string       `json:"dateFrom"`
+	DateTo       string       `json:"dateTo"`
+	Books        []RankedBook `json:"books"`
+	// LastModified はスナップショットの登録時刻（rankings.created_at の最大値）である。
+	LastModified time.Time `json:"-"`
+}
+
+// RankingQuery はランキングの取得条件である。
+type RankingQuery struct {
+	CategoryID string
+	PeriodType string
+	// Date は取得するスナップショットの終了日（YYYY-MM-DD）である。空の場合は最新を取得する。
+	Date string
+	// Page は1始まりのページ番号である。
+	Page  int
+	Limit int
+	// Columns は取得する項目である。nil の場合はすべての項目を取得する。
+	Columns *RankingColumns
+}
+
+// RankingColumns はランキングの取得で読み込む項目である。
+// 読み込まない項目はゼロ値とし、その項目のためだけのテーブルは結合しない。
+type RankingColumns struct {
+	// BookFields は読み込む書籍の項目（JSON の名前）である。書籍IDは常に読み込む。
+	// 空の場合は books を結合しない。
+	BookFields []string
+	// Offers は販売情報（サイト名・価格・URL）を読み込むかである。false の場合は sites を結合しない。
+	Offers bool
+}
+
+// RankPoint は1回のスナップショットでの書籍の順位である。
+type RankPoint struct {
+	// DateTo はスナップショットの終了日（YYYY-MM-DD）である。
+	DateTo string `json:"dateTo"`
+	Rank   int    `json:"rank"`
+}
+
+// BookRankPoint はカテゴリ・期間ごとの書籍の順位の履歴の1件である。
+type BookRankPoint struct {
+	CategoryID string `json:"categoryId"`
+	PeriodType string `json:"periodType"`
+	// DateTo はスナップショットの終了日（YYYY-MM-DD）である。
+	DateTo string `json:"dateTo"`
+	Rank   int    `json:"rank"`
+}
+
+// 書籍詳細
+type Book struct {
+	ID              string `json:"id"`
+	Title           string `json:"title"`
+	Author          string `json:"author"`
+	Publisher       string `json:"publisher"`
+	ISBN            string `json:"isbn"`
+	PublicationDate string `json:"publicationDate"`
+	ImageURL        string `json:"imageUrl"`
+	// UpdatedAt は書籍情報の更新時刻である。
+	UpdatedAt time.Time `json:"-"`
+}
+
+// Offer は書籍のECサイトごとの販売情報である。
+type Offer struct {
+	// ID は書籍のサイト別情報ID（book_site_mappings.id）である。
+	ID     string  `json:"id"`
+	BookID string  `json:"-"`
+	Site   string  `json:"site"`
+	Price  float64 `json:"price"`
+	URL    string  `json:"url"`
+}
+
+// カテゴリ
+type Category struct {
+	ID       string  `json:"id"`
+	Name     string  `json:"name"`
+	ParentID *string `json:"parentId"`
+}
+
+// ECサイト
+type Site struct {
+	ID          string
+	Name        string
+	BaseURL     string
+	AffiliateID string
+}
+
+// サイト別カテゴリマッピング
+type CategoryMapping struct {
+	CategoryID             string
+	SiteID                 string
+	SiteSpecificCategoryID string
+}
+
+// Snapshot は取り込み時に保存する1回分のランキングである。
+type Snapshot struct {
+	SiteID     string
+	CategoryID string
+	PeriodType string
+	DateFrom   time.Time
+	DateTo     time.Time
+	Entries    []SnapshotEntry
+}
+
+// SnapshotEntry はスナップショット内の1書籍分の情報である。
+type SnapshotEntry struct {
+	Rank            int
+	SiteSpecificID  string
+	Title           string
+	Author          string
+	Publisher       string
+	ISBN            string
+	PublicationDate string
+	ImageURL        string
+	Price           float64
+	URL             string
+}
+
+// SourceResponse は取得元から最後に取得できたランキングのレスポンスである。
+// 取得元の障害時に古いランキングを返すために保存する。
+type SourceResponse struct {
+	Source     string
+	CategoryID string
+	PeriodType string
+	Body       []byte
+	FetchedAt  time.Time
+}
+
+// APIKey は発行したAPIキーである。キー本体は保存せず、ハッシュで照合する。
+type APIKey struct {
+	ID        string     `json:"id"`
+	Name      string     `json:"name"`
+	Prefix    string     `json:"prefix"`
+	KeyHash   string     `json:"-"`
+	Role      string     `json:"role"`
+	Scopes    []string   `json:"scopes"`
+	CreatedAt time.Time  `json:"createdAt"`
+	RevokedAt *time.Time `json:"revokedAt,omitempty"`
+}
+
+// SiteLink はECサイトの商品ページへのリンクである。
+type SiteLink struct {
+	BookSiteMappingID string
+	BookID            string
+	Site              string
+	URL               string
+}
+
+// Click は商品リンクのクリックである。
+type Click struct {
+	BookSiteMappingID string
+	ClickedAt         time.Time
+	Referrer          string
+	// CategoryID・PeriodType・Rank はクリック元のランキングで、不明な場合はゼロ値とする。
+	CategoryID string
+	PeriodType string
+	Rank       int
+	// IPHash は送信元IPアドレスのハッシュである。
+	IPHash string
+}
+
+// ClickReportQuery はクリック集計の取得条件である。
+type ClickReportQuery struct {
+	// From と To は集計する期間の初日と最終日（YYYY-MM-DD）である。
+	From string
+	To   string
+	// Site と BookID が空でない場合はそのサイト・書籍に絞り込む。
+	Site   string
+	BookID string
+}
+
+// ClickStat は書籍・サイト・日ごとのクリック数である。
+type ClickStat struct {
+	Date   string `json:"date"`
+	BookID string `json:"bookId"`
+	Title  string `json:"title"`
+	Site   string `json:"site"`
+	Clicks int    `json:"clicks"`
+}
+
+// WebhookSubscription はランキングの変化を通知する Webhook の登録である。
+type WebhookSubscription struct {
+	ID   string `json:"id"`
+	Name string `json:"name"`
+	URL  string `json:"url"`
+	// Secret は通知の署名の鍵である。登録時のレスポンスでのみ返す。
+	Secret string `json:"-"`
+	// CategoryID が空の場合はすべてのカテゴリを通知する。
+	CategoryID string   `json:"categoryId,omitempty"`
+	PeriodType string   `json:"periodType"`
+	Events     []string `json:"events"`
+	// TopN は通知の対象にする上位の順位である。
+	TopN       int        `json:"topN"`
+	CreatedAt  time.Time  `json:"createdAt"`
+	DisabledAt *time.Time `json:"disabledAt,omitempty"`
+}
+
+// WebhookDelivery は Webhook の1件の通知と、その配信状況である。
+type WebhookDelivery struct {
+	ID             int64  `json:"id"`
+	SubscriptionID string `json:"subscriptionId"`
+	EventType      string `json:"eventType"`
+	// EventKey は同じ変化を二重に通知しないためのキーである。
+	EventKey string          `json:"-"`
+	Payload  json.RawMessage `json:"payload"`
+	// Status は pending・succeeded・dead のいずれかである。
+	Status         string     `json:"status"`
+	Attempts       int        `json:"attempts"`
+	NextAttemptAt  time.Time  `json:"nextAttemptAt"`
+	LastStatusCode int        `json:"lastStatusCode,omitempty"`
+	LastError      string     `json:"lastError,omitempty"`
+	CreatedAt      time.Time  `json:"createdAt"`
+	DeliveredAt    *time.Time `json:"deliveredAt,omitempty"`
+	// URL と Secret は配信時に通知先の登録から読み込む。
+	URL    string `json:"-"`
+	Secret string `json:"-"`
+}
+
+// WebhookDeliveryQuery は配信ログの取得条件である。
+type WebhookDeliveryQuery struct {
+	// SubscriptionID と Status が空でない場合はその登録・状態に絞り込む。
+	SubscriptionID string
+	Status         string
+	Limit          int
+}
@@ -0,0 +1,494 @@
+package repository
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/h-hiwatashi/super-business-book-ranking-backend/metrics"
+)
+
+// 日付カラムの書式
+const dateLayout = "2006-01-02"
+
+// RankingRepository はランキング情報を扱うリポジトリである。
+type RankingRepository struct {
+	db *sql.DB
+}
+
+func NewRankingRepository(db *sql.DB) *RankingRepository {
+	return &RankingRepository{db: db}
+}
+
+// Find は指定カテゴリ・期間のスナップショットから1ページ分のランキングを取得する。
+// 日付を指定しない場合は最新のスナップショットを対象とする。
+// 該当するランキングがない場合は書籍が空の Ranking を返す。
+func (r *RankingRepository) Find(ctx context.Context, q RankingQuery) (*Ranking, error) {
+	defer metrics.ObserveDBQuery("ranking", "Find")()
+
+	args := []interface{}{q.CategoryID, q.PeriodType}
+	dateCondition := "r.date_to = ?"
+	if q.Date == "" {
+		dateCondition = `r.date_to = (
+				SELECT MAX(date_to) FROM rankings
+				WHERE category_id = ? AND period_type = ?
+			)`
+		args = append(args, q.CategoryID, q.PeriodType)
+	} else {
+		args = append(args, q.Date)
+	}
+	args = append(args, q.Limit, (q.Page-1)*q.Limit)
+	bookList, offerList, joins := rankingSelect(q.Columns)
+
+	// ランキングデータ取得のSQLクエリ
+	query := `
+		SELECT 
+			r.rank, bsm.book_id, ` + bookList + `, 
+			bsm.id, ` + offerList + `, c.id, c.name, 
+			r.period_type, r.date_from, r.date_to, r.created_at
+		FROM rankings r
+		JOIN book_site_mappings bsm ON r.book_site_mapping_id = bsm.id` + joins + `
+		JOIN categories c ON r.category_id = c.id
+		WHERE r.category_id = ? AND r.period_type = ?
+			AND ` + dateCondition + `
+		ORDER BY r.rank
+		LIMIT ? OFFSET ?
+	`
+
+	rows, err := r.db.QueryContext(ctx, query, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	rankings, err := scanRankings(rows)
+	if err != nil {
+		return nil, err
+	}
+	if ranking, ok := rankings[q.CategoryID]; ok {
+		return ranking, nil
+	}
+	return &Ranking{}, nil
+}
+
+// bookColumns は書籍の項目（JSON の名前）と、それを読み込む列である。
+// empty は項目を読み込まない場合に代わりに選ぶ値で、scanRankings が同じ形で読めるようにする。
+var bookColumns = []struct {
+	field, column, empty string
+}{
+	{field: "title", column: "b.title", empty: "''"},
+	{field: "author", column: "b.author", empty: "''"},
+	{field: "publisher", column: "b.publisher", empty: "''"},
+	{field: "isbn", column: "b.isbn", empty: "''"},
+	{field: "publicationDate", column: "b.publication_date", empty: "NULL"},
+	{field: "imageUrl", column: "b.image_url", empty: "''"},
+}
+
+// bookSelect は fields の項目を読み込む書籍の列を bookColumns の順に返す。fields が nil の場合はすべての項目を読み込む。
+// 読み込む列が1つもない場合は false を返す。
+func bookSelect(fields []string) (string, bool) {
+	books := make([]string, len(bookColumns))
+	selected := false
+	for i, c := range bookColumns {
+		if fields == nil || slices.Contains(fields, c.field) {
+			books[i] = c.column
+			selected = true
+		} else {
+			books[i] = c.empty
+		}
+	}
+	return strings.Join(books, ", "), selected
+}
+
+// rankingSelect は columns に応じて Find が選ぶ書籍と販売情報の列、および結合するテーブルを返す。
+func rankingSelect(columns *RankingColumns) (bookList, offerList, joins string) {
+	var fields []string
+	if columns != nil {
+		// BookFields が nil でも書籍の項目は読み込まない
+		fields = append([]string{}, columns.BookFields...)
+	}
+	bookList, joinBooks := bookSelect(fields)
+	if joinBooks {
+		joins += "\n\t\tJOIN books b ON bsm.book_id = b.id"
+	}
+
+	offerList = "0, '', ''"
+	if columns == nil || columns.Offers {
+		offerList = "bsm.price, bsm.url, s.name"
+		joins += "\n\t\tJOIN sites s ON bsm.site_id = s.id"
+	}
+	return bookList, offerList, joins
+}
+
+// FindLatest は複数のカテゴリの最新のスナップショットから上位 limit 位までをまとめて取得し、カテゴリIDごとに返す。
+// 同じ順位に複数のサイトの書籍がある場合はすべて返すため、limit 件を超えることがある。
+// ランキングのないカテゴリは結果に含めない。
+func (r *RankingRepository) FindLatest(ctx context.Context, categoryIDs []string, periodType string, limit int) (map[string]*Ranking, error) {
+	defer metrics.ObserveDBQuery("ranking", "FindLatest")()
+
+	if len(categoryIDs) == 0 {
+		return map[string]*Ranking{}, nil
+	}
+
+	args := []interface{}{periodType}
+	for _, id := range categoryIDs {
+		args = append(args, id)
+	}
+	args = append(args, periodType, limit)
+
+	rows, err := r.db.QueryContext(ctx, `
+		SELECT 
+			r.rank, b.id, b.title, b.author, b.publisher, 
+			b.isbn, b.publication_date, b.image_url, 
+			bsm.id, bsm.price, bsm.url, s.name, c.id, c.name, 
+			r.period_type, r.date_from, r.date_to, r.created_at
+		FROM rankings r
+		JOIN (
+			SELECT category_id, MAX(date_to) AS date_to FROM rankings
+			WHERE period_type = ? AND category_id IN (`+placeholders(len(categoryIDs))+`)
+			GROUP BY category_id
+		) latest ON r.category_id = latest.category_id AND r.date_to = latest.date_to
+		JOIN book_site_mappings bsm ON r.book_site_mapping_id = bsm.id
+		JOIN sites s ON bsm.site_id = s.id
+		JOIN books b ON bsm.book_id = b.id
+		JOIN categories c ON r.category_id = c.id
+		WHERE r.period_type = ? AND r.rank <= ?
+		ORDER BY r.category_id, r.rank
+	`, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	return scanRankings(rows)
+}
+
+// FindAll はすべてのカテゴリの終了日 date（空の場合はカテゴリごとの最新）のスナップショットから
+// 上位 limit 位までをまとめて取得し、カテゴリIDごとに返す。
+// 同じ順位に複数のサイトの書籍がある場合はすべて返すため、limit 件を超えることがある。
+// ランキングのないカテゴリは結果に含めない。
+func (r *RankingRepository) FindAll(ctx context.Context, periodType, date string, limit int) (map[string]*Ranking, error) {
+	defer metrics.ObserveDBQuery("ranking", "FindAll")()
+
+	rows, err := r.db.QueryContext(ctx, `
+		SELECT 
+			r.rank, b.id, b.title, b.author, b.publisher, 
+			b.isbn, b.publication_date, b.image_url, 
+			bsm.id, bsm.price, bsm.url, s.name, c.id, c.name, 
+			r.period_type, r.date_from, r.date_to, r.created_at
+		FROM rankings r
+		JOIN (
+			SELECT category_id, MAX(date_to) AS date_to FROM rankings
+			WHERE period_type = ? AND (? = '' OR date_to = ?)
+			GROUP BY category_id
+		) snapshot ON r.category_id = snapshot.category_id AND r.date_to = snapshot.date_to
+		JOIN book_site_mappings bsm ON r.book_site_mapping_id = bsm.id
+		JOIN sites s ON bsm.site_id = s.id
+		JOIN books b ON bsm.book_id = b.id
+		JOIN categories c ON r.category_id = c.id
+		WHERE r.period_type = ? AND r.rank <= ?
+		ORDER BY r.category_id, r.rank
+	`, periodType, date, date, periodType, limit)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	return scanRankings(rows)
+}
+
+// scanRankings は Find、FindLatest、FindAll の行を読み込み、カテゴリIDごとのランキングにまとめる。
+func scanRankings(rows *sql.Rows) (map[string]*Ranking, error) {
+	rankings := make(map[string]*Ranking)
+
+	for rows.Next() {
+		var book RankedBook
+		var categoryID, categoryName, periodType string
+		var publicationDate sql.NullString
+		var dateFrom, dateTo, createdAt time.Time
+
+		err := rows.Scan(
+			&book.Rank, &book.ID, &book.Title, &book.Author, &book.Publisher,
+			&book.ISBN, &publicationDate, &book.ImageURL,
+			&book.BookSiteMappingID, &book.Price, &book.URL, &book.Site, &categoryID, &categoryName,
+			&periodType, &dateFrom, &dateTo, &createdAt,
+		)
+		if err != nil {
+			return nil, err
+		}
+
+		if publicationDate.Valid {
+			book.PublicationDate = publicationDate.String
+		}
+
+		// 最初の行からカテゴリ情報などを設定
+		ranking, ok := rankings[categoryID]
+		if !ok {
+			ranking = &Ranking{
+				CategoryID:   categoryID,
+				CategoryName: categoryName,
+				PeriodType:   periodType,
+				DateFrom:     dateFrom.Format(dateLayout),
+				DateTo:       dateTo.Format(dateLayout),
+			}
+			rankings[categoryID] = ranking
+		}
+
+		ranking.Books = append(ranking.Books, book)
+		if createdAt.After(ranking.LastModified) {
+			ranking.LastModified = createdAt
+		}
+	}
+
+	return rankings, rows.Err()
+}
+
+// PreviousDate は指定カテゴリ・期間の最新の1つ前のスナップショットの終了日（YYYY-MM-DD）を返す。
+// スナップショットが1つ以下の場合は sql.ErrNoRows を返す。
+func (r *RankingRepository) PreviousDate(ctx context.Context, categoryID, periodType string) (string, error) {
+	defer metrics.ObserveDBQuery("ranking", "PreviousDate")()
+
+	var date sql.NullString
+	err := r.db.QueryRowContext(ctx, `
+		SELECT DATE_FORMAT(MAX(date_to), '%Y-%m-%d')
+		FROM rankings
+		WHERE category_id = ? AND period_type = ?
+			AND date_to < (
+				SELECT MAX(date_to) FROM rankings
+				WHERE category_id = ? AND period_type = ?
+			)
+	`, categoryID, periodType, categoryID, periodType).Scan(&date)
+	if err != nil {
+		return "", err
+	}
+	if !date.Valid {
+		return "", sql.ErrNoRows
+	}
+	return date.String, nil
+}
+
+// FindRankHistory は指定カテゴリ・期間の終了日 date（空の場合は最新）までの直近 limit 回のスナップショットでの書籍ごとの順位を、
+// 書籍IDごとに新しい順で返す。同じスナップショットに複数のサイトの書籍がある場合は最も高い順位を返す。
+func (r *RankingRepository) FindRankHistory(ctx context.Context, categoryID, periodType, date string, bookIDs []string, limit int) (map[string][]RankPoint, error) {
+	defer metrics.ObserveDBQuery("ranking", "FindRankHistory")()
+
+	history := make(map[string][]RankPoint)
+	if len(bookIDs) == 0 {
+		return history, nil
+	}
+
+	args := []interface{}{categoryID, periodType, date, date, limit, categoryID, periodType}
+	for _, id := range bookIDs {
+		args = append(args, id)
+	}
+
+	rows, err := r.db.QueryContext(ctx, `
+		SELECT bsm.book_id, DATE_FORMAT(r.date_to, '%Y-%m-%d'), MIN(r.rank)
+		FROM rankings r
+		JOIN (
+			SELECT DISTINCT date_to FROM rankings
+			WHERE category_id = ? AND period_type = ?
+				AND (? = '' OR date_to <= ?)
+			ORDER BY date_to DESC
+			LIMIT ?
+		) recent ON r.date_to = recent.date_to
+		JOIN book_site_mappings bsm ON r.book_site_mapping_id = bsm.id
+		WHERE r.category_id = ? AND r.period_type = ?
+			AND bsm.book_id IN (`+placeholders(len(bookIDs))+`)
+		GROUP BY bsm.book_id, r.date_to
+		ORDER BY bsm.book_id, r.date_to DESC
+	`, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var bookID string
+		var point RankPoint
+		if err := rows.Scan(&bookID, &point.DateTo, &point.Rank); err != nil {
+			return nil, err
+		}
+		history[bookID] = append(history[bookID], point)
+	}
+
+	return history, rows.Err()
+}
+
+// FindBookHistory は書籍のすべてのカテゴリ・期間での順位を、新しいスナップショットから limit 件返す。
+// 同じスナップショットに複数のサイトの書籍がある場合は最も高い順位を返す。
+func (r *RankingRepository) FindBookHistory(ctx context.Context, bookID string, limit int) ([]BookRankPoint, error) {
+	defer metrics.ObserveDBQuery("ranking", "FindBookHistory")()
+
+	rows, err := r.db.QueryContext(ctx, `
+		SELECT r.category_id, r.period_type, DATE_FORMAT(r.date_to, '%Y-%m-%d'), MIN(r.rank)
+		FROM rankings r
+		JOIN book_site_mappings bsm ON r.book_site_mapping_id = bsm.id
+		WHERE bsm.book_id = ?
+		GROUP BY r.category_id, r.period_type, r.date_to
+		ORDER BY r.date_to DESC, r.category_id, r.period_type
+		LIMIT ?
+	`, bookID, limit)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	history := []BookRankPoint{}
+	for rows.Next() {
+		var point BookRankPoint
+		if err := rows.Scan(&point.CategoryID, &point.PeriodType, &point.DateTo, &point.Rank); err != nil {
+			return nil, err
+		}
+		history = append(history, point)
+	}
+
+	return history, rows.Err()
+}
+
+// SaveSnapshot は取り込んだランキングを1トランザクションで保存する。
+// 書籍とサイト別情報は ISBN とサイト固有IDで突き合わせて登録または更新し、
+// 同じサイト・カテゴリ・期間・日付の既存ランキングは置き換える。
+func (r *RankingRepository) SaveSnapshot(ctx context.Context, snapshot Snapshot) (err error) {
+	defer metrics.ObserveDBQuery("ranking", "SaveSnapshot")()
+
+	tx, err := r.db.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer func() {
+		if err != nil {
+			tx.Rollback()
+		}
+	}()
+
+	dateFrom := snapshot.DateFrom.Format(dateLayout)
+	dateTo := snapshot.DateTo.Format(dateLayout)
+
+	_, err = tx.ExecContext(ctx, `
+		DELETE FROM rankings
+		WHERE category_id = ? AND period_type = ? AND date_from = ? AND date_to = ?
+			AND book_site_mapping_id IN (
+				SELECT id FROM book_site_mappings WHERE site_id = ?
+			)
+	`, snapshot.CategoryID, snapshot.PeriodType, dateFrom, dateTo, snapshot.SiteID)
+	if err != nil {
+		return fmt.Errorf("既存ランキング削除エラー: %w", err)
+	}
+
+	for _, entry := range snapshot.Entries {
+		bookID, err := upsertBook(ctx, tx, entry)
+		if err != nil {
+			return fmt.Errorf("書籍登録エラー (ISBN: %s): %w", entry.ISBN, err)
+		}
+
+		mappingID, err := upsertBookSiteMapping(ctx, tx, bookID, snapshot.SiteID, entry)
+		if err != nil {
+			return fmt.Errorf("サイト別書籍情報登録エラー (ISBN: %s): %w", entry.ISBN, err)
+		}
+
+		rankingID, err := newID()
+		if err != nil {
+			return err
+		}
+		_, err = tx.ExecContext(ctx, `
+			INSERT INTO rankings (id, book_site_mapping_id, category_id, `+"`rank`"+`, period_type, date_from, date_to)
+			VALUES (?, ?, ?, ?, ?, ?, ?)
+		`, rankingID, mappingID, snapshot.CategoryID, entry.Rank, snapshot.PeriodType, dateFrom, dateTo)
+		if err != nil {
+			return fmt.Errorf("ランキング登録エラー: %w", err)
+		}
+	}
+
+	return tx.Commit()
+}
+
+// upsertBook は ISBN が一致する書籍を更新し、存在しなければ登録して書籍IDを返す。
+func upsertBook(ctx context.Context, tx *sql.Tx, entry SnapshotEntry) (string, error) {
+	var publicationDate interface{}
+	if entry.PublicationDate != "" {
+		publicationDate = entry.PublicationDate
+	}
+
+	var bookID string
+	err := tx.QueryRowContext(ctx, `SELECT id FROM books WHERE isbn = ? LIMIT 1`, entry.ISBN).Scan(&bookID)
+	switch {
+	case err == sql.ErrNoRows:
+		if bookID, err = newID(); err != nil {
+			return "", err
+		}
+		_, err = tx.ExecContext(ctx, `
+			INSERT INTO books (id, title, author, publisher, isbn, publication_date, image_url)
+			VALUES (?, ?, ?, ?, ?, ?, ?)
+		`, bookID, entry.Title, entry.Author, entry.Publisher, entry.ISBN, publicationDate, entry.ImageURL)
+		return bookID, err
+	case err != nil:
+		return "", err
+	}
+
+	_, err = tx.ExecContext(ctx, `
+		UPDATE books
+		SET title = ?, author = ?, publisher = ?, publication_date = ?, image_url = ?
+		WHERE id = ?
+	`, entry.Title, entry.Author, entry.Publisher, publicationDate, entry.ImageURL, bookID)
+	return bookID, err
+}
+
+// upsertBookSiteMapping はサイト固有IDが一致するサイト別書籍情報を更新し、
+// 存在しなければ登録してIDを返す。
+func upsertBookSiteMapping(ctx context.Context, tx *sql.Tx, bookID, siteID string, entry SnapshotEntry) (string, error) {
+	var mappingID string
+	err := tx.QueryRowContext(ctx, `
+		SELECT id FROM book_site_mappings WHERE site_id = ? AND site_specific_id = ? LIMIT 1
+	`, siteID, entry.SiteSpecificID).Scan(&mappingID)
+	switch {
+	case err == sql.ErrNoRows:
+		if mappingID, err = newID(); err != nil {
+			return "", err
+		}
+		_, err = tx.ExecContext(ctx, `
+			INSERT INTO book_site_mappings (id, book_id, site_id, site_specific_id, price, url)
+			VALUES (?, ?, ?, ?, ?, ?)
+		`, mappingID, bookID, siteID, entry.SiteSpecificID, entry.Price, entry.URL)
+		return mappingID, err
+	case err != nil:
+		return "", err
+	}
+
+	_, err = tx.ExecContext(ctx, `
+		UPDATE book_site_mappings SET book_id = ?, price = ?, url = ? WHERE id = ?
+	`, bookID, entry.Price, entry.URL, mappingID)
+	return mappingID, err
+}
+
+// LastIngestedAt はサイト名ごとに最後にランキングを取り込んだ時刻を返す。
+func (r *RankingRepository) LastIngestedAt(ctx context.Context) (map[string]time.Time, error) {
+	defer metrics.ObserveDBQuery("ranking", "LastIngestedAt")()
+
+	rows, err := r.db.QueryContext(ctx, `
+		SELECT s.name, MAX(r.created_at)
+		FROM rankings r
+		JOIN book_site_mappings bsm ON r.book_site_mapping_id = bsm.id
+		JOIN sites s ON bsm.site_id = s.id
+		GROUP BY s.name
+	`)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	result := make(map[string]time.Time)
+	for rows.Next() {
+		var name string
+		var lastIngestedAt time.Time
+		if err := rows.Scan(&name, &lastIngestedAt); err != nil {
+			return nil, err
+		}
+		result[name] = lastIngestedAt
+	}
+
+	return result, rows.Err()
+}
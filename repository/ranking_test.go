@@ -0,0 +1,35 @@
+package repository
+
+import (
+	"context"
+	"database/sql"
+	"database/sql/driver"
+	"testing"
+	"time"
+)
+
+func TestFindFormatsDates(t *testing.T) {
+	// parseTime=true の接続では DATE 列も time.Time として返る
+	tokyo := time.FixedZone("Asia/Tokyo", 9*60*60)
+	connector := &recordingConnector{
+		columns: make([]string, 18),
+		rows: [][]driver.Value{{
+			int64(1), "book-1", "イシューからはじめよ", "安宅和人", "英治出版", "9784862760852", nil, "",
+			"bsm-1", 1980.0, "https://books.rakuten.co.jp/rb/1/", "rakuten", "001", "ビジネス書",
+			"daily", time.Date(2024, 3, 14, 0, 0, 0, 0, tokyo), time.Date(2024, 3, 15, 0, 0, 0, 0, tokyo),
+			time.Date(2024, 3, 15, 6, 0, 0, 0, tokyo),
+		}},
+	}
+	repo := NewRankingRepository(sql.OpenDB(connector))
+
+	ranking, err := repo.Find(context.Background(), RankingQuery{CategoryID: "001", PeriodType: "daily", Page: 1, Limit: 10})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if ranking.DateFrom != "2024-03-14" || ranking.DateTo != "2024-03-15" {
+		t.Errorf("dateFrom = %q, dateTo = %q, want 2024-03-14 and 2024-03-15", ranking.DateFrom, ranking.DateTo)
+	}
+	if len(ranking.Books) != 1 || ranking.Books[0].ID != "book-1" {
+		t.Errorf("books = %+v", ranking.Books)
+	}
+}
@@ -0,0 +1,85 @@
+package repository
+
+import (
+	"context"
+	"database/sql"
+
+	"github.com/h-hiwatashi/super-business-book-ranking-backend/metrics"
+)
+
+// SiteRepository はECサイト情報とサイト別カテゴリマッピングを扱うリポジトリである。
+type SiteRepository struct {
+	db *sql.DB
+}
+
+func NewSiteRepository(db *sql.DB) *SiteRepository {
+	return &SiteRepository{db: db}
+}
+
+// FindByName はサイト名でECサイトを取得する。存在しない場合は sql.ErrNoRows を返す。
+func (r *SiteRepository) FindByName(ctx context.Context, name string) (*Site, error) {
+	defer metrics.ObserveDBQuery("site", "FindByName")()
+
+	var site Site
+	var affiliateID sql.NullString
+
+	err := r.db.QueryRowContext(ctx,
+		`SELECT id, name, base_url, affiliate_id FROM sites WHERE name = ?`, name,
+	).Scan(&site.ID, &site.Name, &site.BaseURL, &affiliateID)
+	if err != nil {
+		return nil, err
+	}
+
+	site.AffiliateID = affiliateID.String
+	return &site, nil
+}
+
+// List はECサイトをすべて取得する。
+func (r *SiteRepository) List(ctx context.Context) ([]Site, error) {
+	defer metrics.ObserveDBQuery("site", "List")()
+
+	rows, err := r.db.QueryContext(ctx, `SELECT id, name, base_url, affiliate_id FROM sites ORDER BY name`)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var sites []Site
+	for rows.Next() {
+		var site Site
+		var affiliateID sql.NullString
+		if err := rows.Scan(&site.ID, &site.Name, &site.BaseURL, &affiliateID); err != nil {
+			return nil, err
+		}
+		site.AffiliateID = affiliateID.String
+		sites = append(sites, site)
+	}
+	return sites, rows.Err()
+}
+
+// ListCategoryMappings は指定サイトのカテゴリマッピングを取得する。
+func (r *SiteRepository) ListCategoryMappings(ctx context.Context, siteID string) ([]CategoryMapping, error) {
+	defer metrics.ObserveDBQuery("site", "ListCategoryMappings")()
+
+	rows, err := r.db.QueryContext(ctx, `
+		SELECT category_id, site_id, site_specific_category_id
+		FROM site_category_mappings
+		WHERE site_id = ?
+		ORDER BY category_id
+	`, siteID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var mappings []CategoryMapping
+	for rows.Next() {
+		var m CategoryMapping
+		if err := rows.Scan(&m.CategoryID, &m.SiteID, &m.SiteSpecificCategoryID); err != nil {
+			return nil, err
+		}
+		mappings = append(mappings, m)
+	}
+
+	return mappings, rows.Err()
+}
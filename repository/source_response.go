@@ -0,0 +1,45 @@
+package repository
+
+import (
+	"context"
+	"database/sql"
+
+	"github.com/h-hiwatashi/super-business-book-ranking-backend/metrics"
+)
+
+// SourceResponseRepository は取得元ごとに最後に取得できたレスポンスを扱うリポジトリである。
+type SourceResponseRepository struct {
+	db *sql.DB
+}
+
+func NewSourceResponseRepository(db *sql.DB) *SourceResponseRepository {
+	return &SourceResponseRepository{db: db}
+}
+
+// Save は取得元・カテゴリ・期間ごとのレスポンスを保存する。既にある場合は上書きする。
+func (r *SourceResponseRepository) Save(ctx context.Context, response SourceResponse) error {
+	defer metrics.ObserveDBQuery("source_response", "Save")()
+
+	_, err := r.db.ExecContext(ctx, `
+		INSERT INTO source_responses (source, category_id, period_type, body, fetched_at)
+		VALUES (?, ?, ?, ?, ?)
+		ON DUPLICATE KEY UPDATE body = VALUES(body), fetched_at = VALUES(fetched_at)
+	`, response.Source, response.CategoryID, response.PeriodType, response.Body, response.FetchedAt)
+	return err
+}
+
+// Find は最後に保存したレスポンスを取得する。存在しない場合は sql.ErrNoRows を返す。
+func (r *SourceResponseRepository) Find(ctx context.Context, source, categoryID, periodType string) (*SourceResponse, error) {
+	defer metrics.ObserveDBQuery("source_response", "Find")()
+
+	response := SourceResponse{Source: source, CategoryID: categoryID, PeriodType: periodType}
+	err := r.db.QueryRowContext(ctx, `
+		SELECT body, fetched_at
+		FROM source_responses
+		WHERE source = ? AND category_id = ? AND period_type = ?
+	`, source, categoryID, periodType).Scan(&response.Body, &response.FetchedAt)
+	if err != nil {
+		return nil, err
+	}
+	return &response, nil
+}
@@ -0,0 +1,363 @@
+package repository
+
+import (
+	"context"
+	"database/sql"
+	"strings"
+	"time"
+
+	"github.com/h-hiwatashi/super-business-book-ranking-backend/metrics"
+)
+
+// Webhook の配信状況
+const (
+	// WebhookPending は配信待ちか、再試行を待っている通知である。
+	WebhookPending = "pending"
+	// WebhookSucceeded は配信に成功した通知である。
+	WebhookSucceeded = "succeeded"
+	// WebhookDead は再試行の上限に達したか、登録が無効になったため配信をあきらめた通知である。
+	WebhookDead = "dead"
+)
+
+// WebhookRepository は Webhook の登録と配信キューを扱うリポジトリである。
+type WebhookRepository struct {
+	db *sql.DB
+}
+
+func NewWebhookRepository(db *sql.DB) *WebhookRepository {
+	return &WebhookRepository{db: db}
+}
+
+// CreateSubscription は Webhook を登録する。ID と作成日時は設定したうえで sub に書き戻す。
+// CategoryID のカテゴリが存在しない場合は sql.ErrNoRows を返す。
+func (r *WebhookRepository) CreateSubscription(ctx context.Context, sub *WebhookSubscription) error {
+	defer metrics.ObserveDBQuery("webhook", "CreateSubscription")()
+
+	if sub.CategoryID != "" {
+		var exists int
+		err := r.db.QueryRowContext(ctx, `SELECT 1 FROM categories WHERE id = ?`, sub.CategoryID).Scan(&exists)
+		if err != nil {
+			return err
+		}
+	}
+
+	id, err := newID()
+	if err != nil {
+		return err
+	}
+
+	_, err = r.db.ExecContext(ctx, `
+		INSERT INTO webhook_subscriptions (id, name, url, secret, category_id, period_type, events, top_n)
+		VALUES (?, ?, ?, ?, ?, ?, ?, ?)
+	`, id, sub.Name, sub.URL, sub.Secret, nullString(sub.CategoryID), sub.PeriodType, strings.Join(sub.Events, ","), sub.TopN)
+	if err != nil {
+		return err
+	}
+
+	sub.ID = id
+	return r.db.QueryRowContext(ctx, `SELECT created_at FROM webhook_subscriptions WHERE id = ?`, id).Scan(&sub.CreatedAt)
+}
+
+// ListSubscriptions は無効にしたものを含む Webhook の登録を作成日時の新しい順にすべて取得する。
+func (r *WebhookRepository) ListSubscriptions(ctx context.Context) ([]WebhookSubscription, error) {
+	defer metrics.ObserveDBQuery("webhook", "ListSubscriptions")()
+
+	rows, err := r.db.QueryContext(ctx, `
+		SELECT id, name, url, secret, category_id, period_type, events, top_n, created_at, disabled_at
+		FROM webhook_subscriptions
+		ORDER BY created_at DESC, id
+	`)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	return scanWebhookSubscriptions(rows)
+}
+
+// ActiveSubscriptions はカテゴリ・期間の変化を通知する有効な登録を取得する。
+// カテゴリを指定していない登録も含む。
+func (r *WebhookRepository) ActiveSubscriptions(ctx context.Context, categoryID, periodType string) ([]WebhookSubscription, error) {
+	defer metrics.ObserveDBQuery("webhook", "ActiveSubscriptions")()
+
+	rows, err := r.db.QueryContext(ctx, `
+		SELECT id, name, url, secret, category_id, period_type, events, top_n, created_at, disabled_at
+		FROM webhook_subscriptions
+		WHERE disabled_at IS NULL AND period_type = ?
+			AND (category_id IS NULL OR category_id = ?)
+		ORDER BY created_at, id
+	`, periodType, categoryID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	return scanWebhookSubscriptions(rows)
+}
+
+// DisableSubscription は登録を無効にし、配信待ちの通知を dead にする。
+// 存在しないか無効化済みの場合は sql.ErrNoRows を返す。
+func (r *WebhookRepository) DisableSubscription(ctx context.Context, id string) (err error) {
+	defer metrics.ObserveDBQuery("webhook", "DisableSubscription")()
+
+	tx, err := r.db.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer func() {
+		if err != nil {
+			tx.Rollback()
+		}
+	}()
+
+	result, err := tx.ExecContext(ctx, `
+		UPDATE webhook_subscriptions SET disabled_at = CURRENT_TIMESTAMP
+		WHERE id = ? AND disabled_at IS NULL
+	`, id)
+	if err != nil {
+		return err
+	}
+	n, err := result.RowsAffected()
+	if err != nil {
+		return err
+	}
+	if n == 0 {
+		return sql.ErrNoRows
+	}
+
+	_, err = tx.ExecContext(ctx, `
+		UPDATE webhook_deliveries SET status = 'dead', last_error = '登録が無効になりました'
+		WHERE subscription_id = ? AND status = 'pending'
+	`, id)
+	if err != nil {
+		return err
+	}
+	return tx.Commit()
+}
+
+// Enqueue は通知を配信キューに追加し、追加した件数を返す。
+// 同じ登録に同じ EventKey の通知がすでにある場合は追加しない。
+func (r *WebhookRepository) Enqueue(ctx context.Context, deliveries []WebhookDelivery) (int, error) {
+	defer metrics.ObserveDBQuery("webhook", "Enqueue")()
+
+	if len(deliveries) == 0 {
+		return 0, nil
+	}
+
+	placeholders := make([]string, len(deliveries))
+	args := make([]interface{}, 0, len(deliveries)*4)
+	for i, d := range deliveries {
+		placeholders[i] = "(?, ?, ?, ?)"
+		args = append(args, d.SubscriptionID, d.EventType, d.EventKey, string(d.Payload))
+	}
+
+	// 重複した通知は id = id で更新したことにし、影響行数に数えさせない
+	result, err := r.db.ExecContext(ctx, `
+		INSERT INTO webhook_deliveries (subscription_id, event_type, event_key, payload)
+		VALUES `+strings.Join(placeholders, ", ")+`
+		ON DUPLICATE KEY UPDATE id = id
+	`, args...)
+	if err != nil {
+		return 0, err
+	}
+	n, err := result.RowsAffected()
+	return int(n), err
+}
+
+// ClaimDue は配信時刻を過ぎた通知を最大 limit 件取り出す。
+// 複数のインスタンスが同じ通知を配信しないよう、取り出した通知の次の配信時刻を lease だけ先に延ばす。
+// 配信の結果を記録しないまま lease が過ぎた通知は再び取り出される。
+func (r *WebhookRepository) ClaimDue(ctx context.Context, limit int, lease time.Duration) (deliveries []WebhookDelivery, err error) {
+	defer metrics.ObserveDBQuery("webhook", "ClaimDue")()
+
+	tx, err := r.db.BeginTx(ctx, nil)
+	if err != nil {
+		return nil, err
+	}
+	defer func() {
+		if err != nil {
+			tx.Rollback()
+		}
+	}()
+
+	rows, err := tx.QueryContext(ctx, `
+		SELECT d.id, d.subscription_id, d.event_type, d.payload, d.attempts, s.url, s.secret
+		FROM webhook_deliveries d
+		JOIN webhook_subscriptions s ON d.subscription_id = s.id
+		WHERE d.status = 'pending' AND d.next_attempt_at <= CURRENT_TIMESTAMP
+		ORDER BY d.next_attempt_at, d.id
+		LIMIT ?
+		FOR UPDATE OF d SKIP LOCKED
+	`, limit)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	deliveries = []WebhookDelivery{}
+	args := []interface{}{lease.Microseconds()}
+	for rows.Next() {
+		var d WebhookDelivery
+		var payload []byte
+		if err := rows.Scan(&d.ID, &d.SubscriptionID, &d.EventType, &payload, &d.Attempts, &d.URL, &d.Secret); err != nil {
+			return nil, err
+		}
+		d.Payload = payload
+		d.Status = WebhookPending
+		deliveries = append(deliveries, d)
+		args = append(args, d.ID)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	rows.Close()
+	if len(deliveries) == 0 {
+		return deliveries, tx.Commit()
+	}
+
+	_, err = tx.ExecContext(ctx, `
+		UPDATE webhook_deliveries
+		SET next_attempt_at = DATE_ADD(CURRENT_TIMESTAMP, INTERVAL ? MICROSECOND)
+		WHERE id IN (?`+strings.Repeat(", ?", len(deliveries)-1)+`)
+	`, args...)
+	if err != nil {
+		return nil, err
+	}
+	return deliveries, tx.Commit()
+}
+
+// MarkSucceeded は通知の配信に成功したことを記録する。
+func (r *WebhookRepository) MarkSucceeded(ctx context.Context, id int64, statusCode int) error {
+	defer metrics.ObserveDBQuery("webhook", "MarkSucceeded")()
+
+	_, err := r.db.ExecContext(ctx, `
+		UPDATE webhook_deliveries
+		SET status = 'succeeded', attempts = attempts + 1, last_status_code = ?, last_error = '',
+			delivered_at = CURRENT_TIMESTAMP
+		WHERE id = ?
+	`, nullInt(statusCode), id)
+	return err
+}
+
+// MarkFailed は通知の配信に失敗したことを記録し、retryIn 後に再試行させる。
+// statusCode は応答がなかった場合は0とする。
+func (r *WebhookRepository) MarkFailed(ctx context.Context, id int64, statusCode int, lastError string, retryIn time.Duration) error {
+	defer metrics.ObserveDBQuery("webhook", "MarkFailed")()
+
+	_, err := r.db.ExecContext(ctx, `
+		UPDATE webhook_deliveries
+		SET attempts = attempts + 1, last_status_code = ?, last_error = ?,
+			next_attempt_at = DATE_ADD(CURRENT_TIMESTAMP, INTERVAL ? MICROSECOND)
+		WHERE id = ?
+	`, nullInt(statusCode), lastError, retryIn.Microseconds(), id)
+	return err
+}
+
+// MarkDead は通知の配信に失敗したことを記録し、以後の再試行をやめる。
+func (r *WebhookRepository) MarkDead(ctx context.Context, id int64, statusCode int, lastError string) error {
+	defer metrics.ObserveDBQuery("webhook", "MarkDead")()
+
+	_, err := r.db.ExecContext(ctx, `
+		UPDATE webhook_deliveries
+		SET status = 'dead', attempts = attempts + 1, last_status_code = ?, last_error = ?
+		WHERE id = ?
+	`, nullInt(statusCode), lastError, id)
+	return err
+}
+
+// Redeliver は dead になった通知を再試行の回数を戻して配信待ちに戻す。
+// 存在しないか dead でない場合、登録が無効な場合は sql.ErrNoRows を返す。
+func (r *WebhookRepository) Redeliver(ctx context.Context, id int64) error {
+	defer metrics.ObserveDBQuery("webhook", "Redeliver")()
+
+	result, err := r.db.ExecContext(ctx, `
+		UPDATE webhook_deliveries d
+		JOIN webhook_subscriptions s ON d.subscription_id = s.id
+		SET d.status = 'pending', d.attempts = 0, d.next_attempt_at = CURRENT_TIMESTAMP
+		WHERE d.id = ? AND d.status = 'dead' AND s.disabled_at IS NULL
+	`, id)
+	if err != nil {
+		return err
+	}
+	n, err := result.RowsAffected()
+	if err != nil {
+		return err
+	}
+	if n == 0 {
+		return sql.ErrNoRows
+	}
+	return nil
+}
+
+// ListDeliveries は配信ログを作成日時の新しい順に最大 q.Limit 件取得する。
+func (r *WebhookRepository) ListDeliveries(ctx context.Context, q WebhookDeliveryQuery) ([]WebhookDelivery, error) {
+	defer metrics.ObserveDBQuery("webhook", "ListDeliveries")()
+
+	conditions := []string{"1 = 1"}
+	args := []interface{}{}
+	if q.SubscriptionID != "" {
+		conditions = append(conditions, "subscription_id = ?")
+		args = append(args, q.SubscriptionID)
+	}
+	if q.Status != "" {
+		conditions = append(conditions, "status = ?")
+		args = append(args, q.Status)
+	}
+	args = append(args, q.Limit)
+
+	rows, err := r.db.QueryContext(ctx, `
+		SELECT id, subscription_id, event_type, payload, status, attempts, next_attempt_at,
+			last_status_code, last_error, created_at, delivered_at
+		FROM webhook_deliveries
+		WHERE `+strings.Join(conditions, " AND ")+`
+		ORDER BY created_at DESC, id DESC
+		LIMIT ?
+	`, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	deliveries := []WebhookDelivery{}
+	for rows.Next() {
+		var d WebhookDelivery
+		var payload []byte
+		var statusCode sql.NullInt64
+		var deliveredAt sql.NullTime
+		err := rows.Scan(&d.ID, &d.SubscriptionID, &d.EventType, &payload, &d.Status, &d.Attempts, &d.NextAttemptAt,
+			&statusCode, &d.LastError, &d.CreatedAt, &deliveredAt)
+		if err != nil {
+			return nil, err
+		}
+		d.Payload = payload
+		d.LastStatusCode = int(statusCode.Int64)
+		if deliveredAt.Valid {
+			d.DeliveredAt = &deliveredAt.Time
+		}
+		deliveries = append(deliveries, d)
+	}
+	return deliveries, rows.Err()
+}
+
+func scanWebhookSubscriptions(rows *sql.Rows) ([]WebhookSubscription, error) {
+	subs := []WebhookSubscription{}
+	for rows.Next() {
+		var sub WebhookSubscription
+		var categoryID sql.NullString
+		var events string
+		var disabledAt sql.NullTime
+		err := rows.Scan(&sub.ID, &sub.Name, &sub.URL, &sub.Secret, &categoryID, &sub.PeriodType, &events, &sub.TopN,
+			&sub.CreatedAt, &disabledAt)
+		if err != nil {
+			return nil, err
+		}
+		sub.CategoryID = categoryID.String
+		sub.Events = []string{}
+		if events != "" {
+			sub.Events = strings.Split(events, ",")
+		}
+		if disabledAt.Valid {
+			sub.DisabledAt = &disabledAt.Time
+		}
+		subs = append(subs, sub)
+	}
+	return subs, rows.Err()
+}
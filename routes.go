@@ -0,0 +1,134 @@
+package main
+
+import (
+	"net/http"
+	"strings"
+
+	"github.com/gorilla/mux"
+
+	"github.com/h-hiwatashi/super-business-book-ranking-backend/api/admin"
+	"github.com/h-hiwatashi/super-business-book-ranking-backend/api/click"
+	"github.com/h-hiwatashi/super-business-book-ranking-backend/api/docs"
+	"github.com/h-hiwatashi/super-business-book-ranking-backend/api/feed"
+	"github.com/h-hiwatashi/super-business-book-ranking-backend/api/graphql"
+	"github.com/h-hiwatashi/super-business-book-ranking-backend/api/health"
+	"github.com/h-hiwatashi/super-business-book-ranking-backend/api/rakuten"
+	"github.com/h-hiwatashi/super-business-book-ranking-backend/api/ranking"
+	"github.com/h-hiwatashi/super-business-book-ranking-backend/api/stream"
+	"github.com/h-hiwatashi/super-business-book-ranking-backend/auth"
+	"github.com/h-hiwatashi/super-business-book-ranking-backend/metrics"
+	"github.com/h-hiwatashi/super-business-book-ranking-backend/middleware"
+)
+
+// handlers はルーターに登録するハンドラーである。
+type handlers struct {
+	health  *health.HealthHandler
+	ranking *ranking.RankingHandler
+	stream  *stream.StreamHandler
+	rakuten *rakuten.RakutenHandler
+	feed    *feed.FeedHandler
+	// graphql は GraphQL が無効な場合に nil とする。
+	graphql *graphql.GraphQLHandler
+	click   *click.ClickHandler
+	admin   *admin.AdminHandler
+	// docs は API 仕様と Swagger UI を公開しない場合に nil とする。
+	docs *docs.DocsHandler
+}
+
+// routeLimits はルートグループごとのレート制限と、処理時間の上限である。
+type routeLimits struct {
+	// auth はAPIキーを検証する前に掛ける、IPアドレスごとの検証回数の上限である。
+	auth           func(http.Handler) http.Handler
+	public         func(http.Handler) http.Handler
+	search         func(http.Handler) http.Handler
+	admin          func(http.Handler) http.Handler
+	apiTimeout     func(http.Handler) http.Handler
+	rakutenTimeout func(http.Handler) http.Handler
+}
+
+// newRouter はAPIのルーターを返す。
+// ルートを追加した場合は openapi/openapi.yaml にも記載する（routes_test.go で照合する）。
+// legacy はバージョンのないルート（/api/...）に付ける非推奨のヘッダーの内容で、移行先は v1 とする。
+func newRouter(authenticator middleware.Authenticator, h handlers, limits routeLimits, legacy middleware.DeprecationPolicy) *mux.Router {
+	publicLimit, adminLimit, apiTimeout := limits.public, limits.admin, limits.apiTimeout
+
+	// APIキーの利用者はレート制限と管理APIの認可で参照するため、ルートごとのミドルウェアより先に認証する。
+	// 無効なキーの総当たりを防ぐため、認証の前にIPアドレスごとの検証回数を制限する
+	r := mux.NewRouter()
+	r.Use(middleware.Metrics, limits.auth, middleware.Authenticate(authenticator))
+
+	// APIエンドポイント
+	r.HandleFunc("/health", h.health.LivenessHandler).Methods("GET")
+	r.HandleFunc("/health/live", h.health.LivenessHandler).Methods("GET")
+	r.HandleFunc("/health/ready", h.health.ReadinessHandler).Methods("GET")
+	// メトリクスは内部の構成や負荷を明かすため、管理APIと同じく admin のAPIキーとレート制限を必須にする
+	requireMetrics := middleware.RequireScope(auth.ScopeMetricsRead)
+	r.Handle("/metrics", middleware.RequireRole(auth.RoleAdmin)(adminLimit(requireMetrics(metrics.Handler())))).Methods("GET")
+	// バージョンのないルートは既存のクライアントのために v1 の別名として残し、非推奨であることを知らせる。
+	// /api の別名が /api/v1・/api/v2 のパスに一致しないよう、バージョンのあるルートを先に登録する
+	registerV1Routes(r.PathPrefix("/api/v1").Subrouter(), h, limits)
+	registerV2Routes(r.PathPrefix("/api/v2").Subrouter(), h, limits)
+	legacy.Successor = func(path string) string {
+		return "/api/v1/" + strings.TrimPrefix(path, "/api/")
+	}
+	legacyRouter := r.PathPrefix("/api").Subrouter()
+	legacyRouter.Use(middleware.Deprecated(legacy))
+	registerV1Routes(legacyRouter, h, limits)
+
+	r.Handle("/feeds/rankings/{categoryId}.{format:atom|rss}", publicLimit(apiTimeout(http.HandlerFunc(h.feed.RankingFeedHandler)))).Methods("GET")
+	r.Handle("/feeds/rankings/{categoryId}/{kind:new|movers}.{format:atom|rss}", publicLimit(apiTimeout(http.HandlerFunc(h.feed.RankingFeedHandler)))).Methods("GET")
+	if h.graphql != nil {
+		r.Handle("/graphql", publicLimit(apiTimeout(http.HandlerFunc(h.graphql.QueryHandler)))).Methods("POST")
+	}
+	r.Handle("/go/{bookSiteMappingId}", publicLimit(apiTimeout(http.HandlerFunc(h.click.RedirectHandler)))).Methods("GET")
+	if h.docs != nil {
+		r.Handle("/openapi.yaml", publicLimit(http.HandlerFunc(h.docs.SpecYAMLHandler))).Methods("GET")
+		r.Handle("/openapi.json", publicLimit(http.HandlerFunc(h.docs.SpecJSONHandler))).Methods("GET")
+		r.Handle("/docs", publicLimit(http.HandlerFunc(h.docs.UIHandler))).Methods("GET")
+		r.Handle("/docs/{file}", publicLimit(http.HandlerFunc(h.docs.AssetHandler))).Methods("GET")
+	}
+
+	// 管理API
+	adminRouter := r.PathPrefix("/admin").Subrouter()
+	adminRouter.Use(middleware.RequireRole(auth.RoleAdmin), adminLimit, apiTimeout)
+	requireIngest := middleware.RequireScope(auth.ScopeIngestRun)
+	requireKeys := middleware.RequireScope(auth.ScopeKeysManage)
+	requireClicks := middleware.RequireScope(auth.ScopeClicksRead)
+	requireWebhooks := middleware.RequireScope(auth.ScopeWebhooksManage)
+	adminRouter.Handle("/ingest", requireIngest(http.HandlerFunc(h.admin.RunIngestHandler))).Methods("POST")
+	adminRouter.Handle("/api-keys", requireKeys(http.HandlerFunc(h.admin.ListAPIKeysHandler))).Methods("GET")
+	adminRouter.Handle("/api-keys", requireKeys(http.HandlerFunc(h.admin.CreateAPIKeyHandler))).Methods("POST")
+	adminRouter.Handle("/api-keys/{keyId}", requireKeys(http.HandlerFunc(h.admin.RevokeAPIKeyHandler))).Methods("DELETE")
+	adminRouter.Handle("/clicks", requireClicks(http.HandlerFunc(h.admin.ClickReportHandler))).Methods("GET")
+	adminRouter.Handle("/webhooks", requireWebhooks(http.HandlerFunc(h.admin.ListWebhooksHandler))).Methods("GET")
+	adminRouter.Handle("/webhooks", requireWebhooks(http.HandlerFunc(h.admin.CreateWebhookHandler))).Methods("POST")
+	adminRouter.Handle("/webhooks/deliveries", requireWebhooks(http.HandlerFunc(h.admin.ListWebhookDeliveriesHandler))).Methods("GET")
+	adminRouter.Handle("/webhooks/deliveries/{deliveryId}/redeliver", requireWebhooks(http.HandlerFunc(h.admin.RedeliverWebhookHandler))).Methods("POST")
+	adminRouter.Handle("/webhooks/{subscriptionId}", requireWebhooks(http.HandlerFunc(h.admin.DisableWebhookHandler))).Methods("DELETE")
+
+	return r
+}
+
+// registerV1Routes は v1 の API（バージョンを導入する前と同じレスポンス）を r に登録する。
+func registerV1Routes(r *mux.Router, h handlers, limits routeLimits) {
+	publicLimit, searchLimit := limits.public, limits.search
+	apiTimeout, rakutenTimeout := limits.apiTimeout, limits.rakutenTimeout
+
+	r.Handle("/rankings/{categoryId}", publicLimit(apiTimeout(http.HandlerFunc(h.ranking.GetRankingsHandler)))).Methods("GET")
+	// ストリームは接続を保ち続けるため、処理時間の上限を設定しない
+	r.Handle("/rankings/{categoryId}/stream", publicLimit(http.HandlerFunc(h.stream.RankingStreamHandler))).Methods("GET")
+	r.Handle("/books/{bookId}", publicLimit(apiTimeout(http.HandlerFunc(h.ranking.GetBookDetailsHandler)))).Methods("GET")
+	r.Handle("/categories", publicLimit(apiTimeout(http.HandlerFunc(h.ranking.GetCategoriesHandler)))).Methods("GET")
+	r.Handle("/export/rankings", publicLimit(apiTimeout(http.HandlerFunc(h.ranking.ExportRankingsHandler)))).Methods("GET")
+	r.Handle("/rakuten/rankings/{categoryId}", searchLimit(rakutenTimeout(http.HandlerFunc(h.rakuten.GetRakutenBookRankingHandler)))).Methods("GET")
+}
+
+// registerV2Routes は v2 の API を r に登録する。
+// v2 で形を変えたのはランキングだけで、書籍とカテゴリは v1 と同じレスポンスを返す。
+func registerV2Routes(r *mux.Router, h handlers, limits routeLimits) {
+	publicLimit, apiTimeout := limits.public, limits.apiTimeout
+
+	r.Handle("/rankings/{categoryId}", publicLimit(apiTimeout(http.HandlerFunc(h.ranking.GetRankingsV2Handler)))).Methods("GET")
+	r.Handle("/books/{bookId}", publicLimit(apiTimeout(http.HandlerFunc(h.ranking.GetBookV2Handler)))).Methods("GET")
+	r.Handle("/categories", publicLimit(apiTimeout(http.HandlerFunc(h.ranking.GetCategoriesHandler)))).Methods("GET")
+}
@@ -0,0 +1,605 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"database/sql"
+	"encoding/json"
+	"encoding/xml"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"regexp"
+	"sort"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/getkin/kin-openapi/openapi3"
+	"github.com/getkin/kin-openapi/openapi3filter"
+	"github.com/getkin/kin-openapi/routers"
+	"github.com/getkin/kin-openapi/routers/gorillamux"
+	"github.com/gorilla/mux"
+
+	"github.com/h-hiwatashi/super-business-book-ranking-backend/api/admin"
+	"github.com/h-hiwatashi/super-business-book-ranking-backend/api/click"
+	"github.com/h-hiwatashi/super-business-book-ranking-backend/api/docs"
+	"github.com/h-hiwatashi/super-business-book-ranking-backend/api/feed"
+	"github.com/h-hiwatashi/super-business-book-ranking-backend/api/graphql"
+	"github.com/h-hiwatashi/super-business-book-ranking-backend/api/health"
+	"github.com/h-hiwatashi/super-business-book-ranking-backend/api/rakuten"
+	"github.com/h-hiwatashi/super-business-book-ranking-backend/api/ranking"
+	"github.com/h-hiwatashi/super-business-book-ranking-backend/api/stream"
+	"github.com/h-hiwatashi/super-business-book-ranking-backend/auth"
+	"github.com/h-hiwatashi/super-business-book-ranking-backend/middleware"
+	"github.com/h-hiwatashi/super-business-book-ranking-backend/openapi"
+	"github.com/h-hiwatashi/super-business-book-ranking-backend/repository"
+	"github.com/h-hiwatashi/super-business-book-ranking-backend/webhook"
+)
+
+// testAPIKey はすべてのスコープを持つ admin のAPIキーである。
+const testAPIKey = "bkr_test_admin"
+
+var testTime = time.Date(2024, 3, 15, 9, 0, 0, 0, time.UTC)
+
+var testBook = repository.RankedBook{
+	ID: "book-1", Rank: 1, Title: "イシューからはじめよ", Author: "安宅和人", Publisher: "英治出版",
+	ISBN: "9784862760852", PublicationDate: "2010-11-24", ImageURL: "https://example.com/book-1.jpg",
+	Price: 1980, URL: "https://books.rakuten.co.jp/rb/6633144/", Site: "rakuten", BookSiteMappingID: "bsm-1",
+}
+
+// fakeStore は各ハンドラーが使うリポジトリを、データベースなしで再現する。
+type fakeStore struct{}
+
+func (fakeStore) PingContext(ctx context.Context) error { return nil }
+
+func (fakeStore) LastIngestedAt(ctx context.Context) (map[string]time.Time, error) {
+	return map[string]time.Time{"rakuten": time.Now().Add(-time.Minute)}, nil
+}
+
+func (fakeStore) Find(ctx context.Context, q repository.RankingQuery) (*repository.Ranking, error) {
+	return &repository.Ranking{
+		CategoryID: q.CategoryID, CategoryName: "ビジネス書", PeriodType: q.PeriodType,
+		DateFrom: "2024-03-15", DateTo: "2024-03-15",
+		Books: []repository.RankedBook{testBook}, LastModified: testTime,
+	}, nil
+}
+
+func (s fakeStore) FindAll(ctx context.Context, periodType, date string, limit int) (map[string]*repository.Ranking, error) {
+	categories, _ := s.List(ctx)
+	ids := make([]string, len(categories))
+	for i, category := range categories {
+		ids[i] = category.ID
+	}
+	return s.FindLatest(ctx, ids, periodType, limit)
+}
+
+func (s fakeStore) FindLatest(ctx context.Context, categoryIDs []string, periodType string, limit int) (map[string]*repository.Ranking, error) {
+	rankings := make(map[string]*repository.Ranking, len(categoryIDs))
+	for _, id := range categoryIDs {
+		rankings[id], _ = s.Find(ctx, repository.RankingQuery{CategoryID: id, PeriodType: periodType})
+	}
+	return rankings, nil
+}
+
+func (fakeStore) PreviousDate(ctx context.Context, categoryID, periodType string) (string, error) {
+	return "2024-03-14", nil
+}
+
+func (fakeStore) FindByID(ctx context.Context, bookID string) (*repository.Book, error) {
+	return &repository.Book{
+		ID: bookID, Title: testBook.Title, Author: testBook.Author, Publisher: testBook.Publisher,
+		ISBN: testBook.ISBN, PublicationDate: testBook.PublicationDate, ImageURL: testBook.ImageURL, UpdatedAt: testTime,
+	}, nil
+}
+
+func (s fakeStore) FindFields(ctx context.Context, bookID string, fields []string) (*repository.Book, error) {
+	return s.FindByID(ctx, bookID)
+}
+
+func (fakeStore) FindOffers(ctx context.Context, bookIDs []string) (map[string][]repository.Offer, error) {
+	offers := make(map[string][]repository.Offer, len(bookIDs))
+	for _, id := range bookIDs {
+		offers[id] = []repository.Offer{{ID: "bsm-1", BookID: id, Site: "rakuten", Price: testBook.Price, URL: testBook.URL}}
+	}
+	return offers, nil
+}
+
+func (fakeStore) FindCategories(ctx context.Context, bookID string) ([]repository.Category, error) {
+	return []repository.Category{{ID: "001", Name: "ビジネス書"}}, nil
+}
+
+func (fakeStore) FindRankHistory(ctx context.Context, categoryID, periodType, date string, bookIDs []string, limit int) (map[string][]repository.RankPoint, error) {
+	history := make(map[string][]repository.RankPoint, len(bookIDs))
+	for _, id := range bookIDs {
+		history[id] = []repository.RankPoint{{DateTo: "2024-03-15", Rank: 1}, {DateTo: "2024-03-14", Rank: 2}}
+	}
+	return history, nil
+}
+
+func (fakeStore) FindBookHistory(ctx context.Context, bookID string, limit int) ([]repository.BookRankPoint, error) {
+	return []repository.BookRankPoint{{CategoryID: "001", PeriodType: "daily", DateTo: "2024-03-15", Rank: 1}}, nil
+}
+
+func (fakeStore) List(ctx context.Context) ([]repository.Category, error) {
+	parentID := "001"
+	return []repository.Category{{ID: "001", Name: "ビジネス書"}, {ID: "002", Name: "自己啓発", ParentID: &parentID}}, nil
+}
+
+func (fakeStore) FindLink(ctx context.Context, bookSiteMappingID string) (*repository.SiteLink, error) {
+	return &repository.SiteLink{BookSiteMappingID: bookSiteMappingID, BookID: testBook.ID, Site: testBook.Site, URL: testBook.URL}, nil
+}
+
+func (fakeStore) Record(click repository.Click) bool { return true }
+
+func (fakeStore) Hash(ip string) string { return "hashed" }
+
+func (fakeStore) Trigger() {}
+
+func (fakeStore) Report(ctx context.Context, q repository.ClickReportQuery) ([]repository.ClickStat, error) {
+	return []repository.ClickStat{{Date: "2024-03-15", BookID: testBook.ID, Title: testBook.Title, Site: "rakuten", Clicks: 3}}, nil
+}
+
+func (fakeStore) CreateSubscription(ctx context.Context, sub *repository.WebhookSubscription) error {
+	sub.ID = "sub-1"
+	sub.CreatedAt = testTime
+	return nil
+}
+
+func (fakeStore) ListSubscriptions(ctx context.Context) ([]repository.WebhookSubscription, error) {
+	return []repository.WebhookSubscription{{
+		ID: "sub-1", Name: "提携先", URL: "https://partner.example.com/hooks", CategoryID: "001",
+		PeriodType: "daily", Events: []string{webhook.EventNewEntry}, TopN: 10, CreatedAt: testTime, DisabledAt: &testTime,
+	}}, nil
+}
+
+func (fakeStore) DisableSubscription(ctx context.Context, id string) error { return nil }
+
+func (fakeStore) ListDeliveries(ctx context.Context, q repository.WebhookDeliveryQuery) ([]repository.WebhookDelivery, error) {
+	payload, err := json.Marshal(webhook.Payload{SubscriptionID: "sub-1", Event: webhook.Event{
+		Type: webhook.EventPriceDrop, CategoryID: "001", CategoryName: "ビジネス書", PeriodType: "daily",
+		Date: "2024-03-15", Book: testBook, Rank: 1, PreviousRank: 2, PreviousPrice: 2200,
+	}})
+	if err != nil {
+		return nil, err
+	}
+	return []repository.WebhookDelivery{{
+		ID: 1, SubscriptionID: "sub-1", EventType: webhook.EventPriceDrop, Payload: payload, Status: repository.WebhookSucceeded,
+		Attempts: 2, NextAttemptAt: testTime, LastStatusCode: http.StatusNoContent, LastError: "receiver returned 500",
+		CreatedAt: testTime, DeliveredAt: &testTime,
+	}}, nil
+}
+
+func (fakeStore) Redeliver(ctx context.Context, id int64) error { return nil }
+
+// fakeKeys はAPIキーのリポジトリである。カテゴリ一覧と List の型が異なるため fakeStore と分ける。
+type fakeKeys struct{}
+
+func (fakeKeys) FindByHash(ctx context.Context, hash string) (*repository.APIKey, error) {
+	if hash != auth.HashKey(testAPIKey) {
+		return nil, sql.ErrNoRows
+	}
+	return &repository.APIKey{ID: "key-1", Name: "運用者", Role: string(auth.RoleAdmin), Scopes: auth.KnownScopes, CreatedAt: testTime}, nil
+}
+
+func (fakeKeys) Create(ctx context.Context, key *repository.APIKey) error {
+	key.ID = "key-2"
+	key.CreatedAt = testTime
+	return nil
+}
+
+func (fakeKeys) List(ctx context.Context) ([]repository.APIKey, error) {
+	return []repository.APIKey{{
+		ID: "key-1", Name: "運用者", Prefix: "bkr_1a2b3c4d", Role: string(auth.RoleAdmin),
+		Scopes: []string{auth.ScopeKeysManage}, CreatedAt: testTime, RevokedAt: &testTime,
+	}}, nil
+}
+
+func (fakeKeys) Revoke(ctx context.Context, id string) error { return nil }
+
+// newTestRouter は fakeStore のデータを返すルーターを返す。レート制限と処理時間の上限は適用しない。
+func newTestRouter(t *testing.T) *mux.Router {
+	t.Helper()
+	store := fakeStore{}
+	graphqlHandler, err := graphql.NewGraphQLHandler(store, store, store, store, graphql.Limits{MaxDepth: 8, MaxComplexity: 5000})
+	if err != nil {
+		t.Fatal(err)
+	}
+	docsHandler, err := docs.NewDocsHandler(openapi.YAML)
+	if err != nil {
+		t.Fatal(err)
+	}
+	rankingHandler := ranking.NewRankingHandler(store, store, store)
+	rankingHandler.Snapshots = store
+	rankingHandler.Exports = store
+	none := func(next http.Handler) http.Handler { return next }
+	return newRouter(auth.NewAuthenticator(fakeKeys{}), handlers{
+		health:  health.NewHealthHandler(store, store, []string{"rakuten"}, time.Second, time.Hour),
+		ranking: rankingHandler,
+		stream:  stream.NewStreamHandler(store, store, stream.NewBroker(10, 10), time.Hour),
+		rakuten: rakuten.NewRakutenHandler(),
+		feed:    feed.NewFeedHandler(store, store, 10, 5),
+		graphql: graphqlHandler,
+		click:   click.NewClickHandler(store, store, store),
+		admin:   admin.NewAdminHandler(store, fakeKeys{}, store, store),
+		docs:    docsHandler,
+	}, routeLimits{auth: none, public: none, search: none, admin: none, apiTimeout: none, rakutenTimeout: none},
+		middleware.DeprecationPolicy{DeprecatedAt: time.Date(2026, 10, 18, 0, 0, 0, 0, time.UTC)})
+}
+
+// loadTestSpec は servers のホストに関係なくパスで照合できるようにした API 仕様と、そのルーターを返す。
+func loadTestSpec(t *testing.T) (*openapi3.T, routers.Router) {
+	t.Helper()
+	doc, err := openapi.Load()
+	if err != nil {
+		t.Fatal(err)
+	}
+	doc.Servers = openapi3.Servers{{URL: "/"}}
+	router, err := gorillamux.NewRouter(doc)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return doc, router
+}
+
+// mux のパス変数の正規表現（{format:atom|rss} の :atom|rss）
+var pathVariablePattern = regexp.MustCompile(`\{([^}:]+):[^}]+\}`)
+
+func TestRoutesAreDocumented(t *testing.T) {
+	doc, _ := loadTestSpec(t)
+
+	documented := make(map[string]bool)
+	for path, item := range doc.Paths.Map() {
+		for method := range item.Operations() {
+			documented[method+" "+path] = true
+		}
+	}
+
+	routed := make(map[string]bool)
+	err := newTestRouter(t).Walk(func(route *mux.Route, router *mux.Router, ancestors []*mux.Route) error {
+		path, err := route.GetPathTemplate()
+		if err != nil {
+			return nil
+		}
+		methods, err := route.GetMethods()
+		if err != nil {
+			// PathPrefix のサブルーターはメソッドを持たない
+			return nil
+		}
+		for _, method := range methods {
+			routed[method+" "+pathVariablePattern.ReplaceAllString(path, "{$1}")] = true
+		}
+		return nil
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	for route := range routed {
+		if !documented[route] {
+			t.Errorf("%s が openapi.yaml に記載されていません", route)
+		}
+	}
+	for route := range documented {
+		if !routed[route] {
+			t.Errorf("openapi.yaml の %s がルーターに登録されていません", route)
+		}
+	}
+}
+
+func TestVersionedRoutes(t *testing.T) {
+	router := newTestRouter(t)
+
+	testCases := []struct {
+		name            string
+		target          string
+		wantStatusCode  int
+		wantDeprecation string
+		wantLink        string
+	}{
+		{
+			name:            "正常系：バージョンのないルートは v1 へ移行を促す",
+			target:          "/api/rankings/001?period=weekly",
+			wantStatusCode:  http.StatusOK,
+			wantDeprecation: "@1792281600",
+			wantLink:        `</api/v1/rankings/001>; rel="successor-version"`,
+		},
+		{
+			name:           "正常系：v1",
+			target:         "/api/v1/rankings/001",
+			wantStatusCode: http.StatusOK,
+		},
+		{
+			name:           "正常系：v2",
+			target:         "/api/v2/rankings/001",
+			wantStatusCode: http.StatusOK,
+		},
+		{
+			name:           "異常系：v2 にないルートは v1 の別名に一致しない",
+			target:         "/api/v2/export/rankings",
+			wantStatusCode: http.StatusNotFound,
+		},
+		{
+			name:           "異常系：存在しないバージョン",
+			target:         "/api/v3/rankings/001",
+			wantStatusCode: http.StatusNotFound,
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			rr := httptest.NewRecorder()
+			router.ServeHTTP(rr, httptest.NewRequest("GET", tc.target, nil))
+
+			if rr.Code != tc.wantStatusCode {
+				t.Fatalf("status = %v, want %v", rr.Code, tc.wantStatusCode)
+			}
+			if got := rr.Header().Get("Deprecation"); got != tc.wantDeprecation {
+				t.Errorf("Deprecation = %q, want %q", got, tc.wantDeprecation)
+			}
+			if got := rr.Header().Get("Link"); got != tc.wantLink {
+				t.Errorf("Link = %q, want %q", got, tc.wantLink)
+			}
+		})
+	}
+}
+
+func TestMetricsRequiresAdmin(t *testing.T) {
+	router := newTestRouter(t)
+
+	testCases := []struct {
+		name           string
+		apiKey         string
+		wantStatusCode int
+	}{
+		{
+			name:           "正常系：admin のAPIキー",
+			apiKey:         testAPIKey,
+			wantStatusCode: http.StatusOK,
+		},
+		{
+			name:           "異常系：APIキーなし",
+			wantStatusCode: http.StatusUnauthorized,
+		},
+		{
+			name:           "異常系：不正なAPIキー",
+			apiKey:         "bkr_unknown",
+			wantStatusCode: http.StatusUnauthorized,
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			req := httptest.NewRequest("GET", "/metrics", nil)
+			if tc.apiKey != "" {
+				req.Header.Set("X-API-Key", tc.apiKey)
+			}
+			rr := httptest.NewRecorder()
+			router.ServeHTTP(rr, req)
+
+			if rr.Code != tc.wantStatusCode {
+				t.Errorf("status = %v, want %v", rr.Code, tc.wantStatusCode)
+			}
+		})
+	}
+}
+
+// パスパラメーターに使う値。パラメーターを追加した場合はここにも追加する。
+var testPathValues = map[string]string{
+	"categoryId":        "001",
+	"bookId":            "book-1",
+	"bookSiteMappingId": "bsm-1",
+	"format":            "atom",
+	"kind":              "new",
+	"keyId":             "key-1",
+	"subscriptionId":    "sub-1",
+	"deliveryId":        "1",
+	"file":              "swagger-ui.css",
+}
+
+// リクエストボディが必要な操作に送る本文
+var testRequestBodies = map[string]string{
+	"POST /graphql":        `{"query":"{ categories { id name ranking(period: DAILY, limit: 5) { entries { rank book { title offers { site price url } } } } } }"}`,
+	"POST /admin/api-keys": `{"name":"提携先","role":"partner"}`,
+	"POST /admin/webhooks": `{"name":"提携先","url":"https://partner.example.com/hooks","categoryId":"001","events":["new_entry","price_drop"]}`,
+}
+
+// 既定の形式以外も照合するため、追加で送るクエリ
+var testExtraQueries = map[string][]string{
+	"GET /api/rankings/{categoryId}":         {"format=csv", "format=tsv&bom=true"},
+	"GET /api/rakuten/rankings/{categoryId}": {"format=csv", "format=tsv"},
+	"GET /api/export/rankings":               {"format=tsv"},
+	"GET /api/v2/rankings/{categoryId}":      {"fields=title,imageUrl&include=", "include=offers,category,history"},
+	"GET /api/v2/books/{bookId}":             {"fields=title&include=offers,category,history"},
+}
+
+func TestResponsesMatchOpenAPI(t *testing.T) {
+	registerTestBodyDecoders()
+	doc, specRouter := loadTestSpec(t)
+	router := newTestRouter(t)
+
+	paths := doc.Paths.InMatchingOrder()
+	sort.Strings(paths)
+	for _, path := range paths {
+		item := doc.Paths.Value(path)
+		for method, operation := range item.Operations() {
+			name := method + " " + path
+			target := path
+			for _, param := range append(item.Parameters, operation.Parameters...) {
+				if param.Value.In != openapi3.ParameterInPath {
+					continue
+				}
+				value, ok := testPathValues[param.Value.Name]
+				if !ok {
+					t.Errorf("%s: パスパラメーター %s の値が testPathValues にありません", name, param.Value.Name)
+					continue
+				}
+				target = strings.ReplaceAll(target, "{"+param.Value.Name+"}", value)
+			}
+
+			for _, query := range append([]string{""}, testExtraQueries[name]...) {
+				query := query
+				t.Run(strings.TrimSuffix(name+"?"+query, "?"), func(t *testing.T) {
+					url := target
+					if query != "" {
+						url += "?" + query
+					}
+					checkOperation(t, router, specRouter, method, url, testRequestBodies[name], operation.Security != nil && len(*operation.Security) > 0)
+				})
+			}
+		}
+	}
+}
+
+// checkOperation はリクエストを送り、リクエストとレスポンスがともに API 仕様に合うことを確かめる。
+func checkOperation(t *testing.T, router http.Handler, specRouter routers.Router, method, url, body string, authenticated bool) {
+	t.Helper()
+	req := httptest.NewRequest(method, url, strings.NewReader(body))
+	if body != "" {
+		req.Header.Set("Content-Type", "application/json")
+	}
+	if authenticated {
+		req.Header.Set("X-API-Key", testAPIKey)
+	}
+
+	route, pathParams, err := specRouter.FindRoute(req)
+	if err != nil {
+		t.Fatalf("API 仕様のルートが見つかりません: %v", err)
+	}
+	input := &openapi3filter.RequestValidationInput{
+		Request:    req,
+		PathParams: pathParams,
+		Route:      route,
+		Options:    &openapi3filter.Options{AuthenticationFunc: openapi3filter.NoopAuthenticationFunc},
+	}
+	if err := openapi3filter.ValidateRequest(req.Context(), input); err != nil {
+		t.Fatalf("テストのリクエストが API 仕様に合いません: %v", err)
+	}
+
+	// ストリームは接続を閉じるまで送り続けるため、最初のイベントを書いたところで終わらせる
+	ctx, cancel := context.WithCancel(req.Context())
+	if route.Operation.Responses.Status(http.StatusOK) != nil && route.Operation.Responses.Status(http.StatusOK).Value.Content.Get("text/event-stream") != nil {
+		cancel()
+	}
+	defer cancel()
+	rr := httptest.NewRecorder()
+	router.ServeHTTP(rr, req.WithContext(ctx))
+
+	if rr.Code >= 400 {
+		t.Fatalf("status = %v: %s", rr.Code, rr.Body.String())
+	}
+	// 仕様で非推奨としたルートだけが Deprecation ヘッダーを返す
+	if deprecated := rr.Header().Get("Deprecation") != ""; deprecated != route.Operation.Deprecated {
+		t.Errorf("Deprecation ヘッダーの有無 = %v, API 仕様の deprecated = %v", deprecated, route.Operation.Deprecated)
+	}
+	err = openapi3filter.ValidateResponse(req.Context(), &openapi3filter.ResponseValidationInput{
+		RequestValidationInput: input,
+		Status:                 rr.Code,
+		Header:                 rr.Header(),
+		Body:                   io.NopCloser(bytes.NewReader(rr.Body.Bytes())),
+		Options:                &openapi3filter.Options{IncludeResponseStatus: true},
+	})
+	if err != nil {
+		t.Fatalf("レスポンスが API 仕様に合いません: %v\n%s", err, rr.Body.String())
+	}
+
+	// 仕様に記載されていないプロパティは記載漏れとして扱う
+	if !strings.HasPrefix(rr.Header().Get("Content-Type"), "application/json") {
+		return
+	}
+	var value any
+	if err := json.Unmarshal(rr.Body.Bytes(), &value); err != nil {
+		t.Fatal(err)
+	}
+	media := route.Operation.Responses.Status(rr.Code).Value.Content.Get("application/json")
+	for _, property := range undeclaredProperties(media.Schema.Value, value, "$") {
+		t.Errorf("レスポンスの %s が API 仕様に記載されていません", property)
+	}
+}
+
+// undeclaredProperties は value のうち schema に記載されていないプロパティのパスを返す。
+// プロパティを1つも記載していないオブジェクトは、任意の内容を許すものとして扱う。
+func undeclaredProperties(schema *openapi3.Schema, value any, path string) []string {
+	var undeclared []string
+	switch v := value.(type) {
+	case map[string]any:
+		properties := make(map[string]*openapi3.Schema)
+		var additional *openapi3.Schema
+		for _, s := range append([]*openapi3.Schema{schema}, allOf(schema)...) {
+			for name, ref := range s.Properties {
+				properties[name] = ref.Value
+			}
+			if s.AdditionalProperties.Schema != nil {
+				additional = s.AdditionalProperties.Schema.Value
+			}
+		}
+		if len(properties) == 0 && additional == nil {
+			return nil
+		}
+		names := make([]string, 0, len(v))
+		for name := range v {
+			names = append(names, name)
+		}
+		sort.Strings(names)
+		for _, name := range names {
+			child := properties[name]
+			if child == nil {
+				child = additional
+			}
+			if child == nil {
+				undeclared = append(undeclared, path+"."+name)
+				continue
+			}
+			undeclared = append(undeclared, undeclaredProperties(child, v[name], path+"."+name)...)
+		}
+	case []any:
+		if schema.Items == nil {
+			return nil
+		}
+		for i, item := range v {
+			undeclared = append(undeclared, undeclaredProperties(schema.Items.Value, item, fmt.Sprintf("%s[%d]", path, i))...)
+		}
+	}
+	return undeclared
+}
+
+// allOf は schema の allOf のスキーマを、入れ子も含めて返す。
+func allOf(schema *openapi3.Schema) []*openapi3.Schema {
+	var schemas []*openapi3.Schema
+	for _, ref := range schema.AllOf {
+		schemas = append(schemas, ref.Value)
+		schemas = append(schemas, allOf(ref.Value)...)
+	}
+	return schemas
+}
+
+// registerTestBodyDecoders は kin-openapi が読めない形式のレスポンスを、文字列として照合できるようにする。
+// フィードは XML として読めることも確かめる。
+func registerTestBodyDecoders() {
+	text := func(body io.Reader, header http.Header, schema *openapi3.SchemaRef, encFn openapi3filter.EncodingFn) (any, error) {
+		data, err := io.ReadAll(body)
+		return string(data), err
+	}
+	feedXML := func(body io.Reader, header http.Header, schema *openapi3.SchemaRef, encFn openapi3filter.EncodingFn) (any, error) {
+		data, err := io.ReadAll(body)
+		if err != nil {
+			return nil, err
+		}
+		decoder := xml.NewDecoder(bytes.NewReader(data))
+		for {
+			if _, err := decoder.Token(); errors.Is(err, io.EOF) {
+				return string(data), nil
+			} else if err != nil {
+				return nil, err
+			}
+		}
+	}
+	openapi3filter.RegisterBodyDecoder("text/tab-separated-values", text)
+	openapi3filter.RegisterBodyDecoder("text/event-stream", text)
+	openapi3filter.RegisterBodyDecoder("text/html", text)
+	openapi3filter.RegisterBodyDecoder("text/css", text)
+	openapi3filter.RegisterBodyDecoder("application/atom+xml", feedXML)
+	openapi3filter.RegisterBodyDecoder("application/rss+xml", feedXML)
+}
@@ -0,0 +1,61 @@
+// Package version はビルド時に埋め込まれたアプリケーションのバージョンを提供する。
+package version
+
+import (
+	"runtime/debug"
+	"sync"
+)
+
+// Version はビルド時に ldflags で設定するバージョンである。
+//
+//	go build -ldflags "-X github.com/h-hiwatashi/super-business-book-ranking-backend/version.Version=v1.2.3"
+var Version = ""
+
+var (
+	once     sync.Once
+	resolved string
+)
+
+// String はアプリケーションのバージョンを返す。
+// ldflags で設定されていない場合はビルド情報のモジュールバージョン、
+// VCS のリビジョンの順に参照し、いずれもなければ "devel" を返す。
+func String() string {
+	once.Do(func() {
+		resolved = resolve()
+	})
+	return resolved
+}
+
+func resolve() string {
+	if Version != "" {
+		return Version
+	}
+
+	info, ok := debug.ReadBuildInfo()
+	if !ok {
+		return "devel"
+	}
+	if v := info.Main.Version; v != "" && v != "(devel)" {
+		return v
+	}
+
+	var revision, modified string
+	for _, setting := range info.Settings {
+		switch setting.Key {
+		case "vcs.revision":
+			revision = setting.Value
+		case "vcs.modified":
+			modified = setting.Value
+		}
+	}
+	if revision == "" {
+		return "devel"
+	}
+	if len(revision) > 12 {
+		revision = revision[:12]
+	}
+	if modified == "true" {
+		revision += "-dirty"
+	}
+	return revision
+}
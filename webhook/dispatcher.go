@@ -0,0 +1,241 @@
+package webhook
+
+import (
+	"bytes"
+	"context"
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"io"
+	"net/http"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+	"unicode/utf8"
+
+	"github.com/h-hiwatashi/super-business-book-ranking-backend/backoff"
+	"github.com/h-hiwatashi/super-business-book-ranking-backend/logging"
+	"github.com/h-hiwatashi/super-business-book-ranking-backend/metrics"
+	"github.com/h-hiwatashi/super-business-book-ranking-backend/repository"
+	"github.com/h-hiwatashi/super-business-book-ranking-backend/version"
+)
+
+// 通知のリクエストヘッダー
+const (
+	// HeaderDelivery は通知のID（webhook_deliveries.id）で、再試行でも変わらない。受信側の重複排除に使える。
+	HeaderDelivery = "X-Webhook-Delivery"
+	// HeaderEvent はイベントの種類である。
+	HeaderEvent = "X-Webhook-Event"
+	// HeaderTimestamp は送信時刻（UNIX 秒）である。
+	HeaderTimestamp = "X-Webhook-Timestamp"
+	// HeaderSignature は "sha256=" に続く署名である。求め方は Sign を参照。
+	HeaderSignature = "X-Webhook-Signature"
+)
+
+const (
+	// 配信の結果を記録するまでの猶予。送信のタイムアウトに加える
+	leaseMargin = 30 * time.Second
+	// 配信ログに残すエラーメッセージの長さ（文字数）の上限。webhook_deliveries.last_error の長さに合わせる
+	maxErrorLength = 512
+	// エラーメッセージに含める応答本文の長さ（バイト数）の上限
+	maxResponseSnippet = 256
+)
+
+// DeliveryStore は通知の配信キューである。
+type DeliveryStore interface {
+	ClaimDue(ctx context.Context, limit int, lease time.Duration) ([]repository.WebhookDelivery, error)
+	MarkSucceeded(ctx context.Context, id int64, statusCode int) error
+	MarkFailed(ctx context.Context, id int64, statusCode int, lastError string, retryIn time.Duration) error
+	MarkDead(ctx context.Context, id int64, statusCode int, lastError string) error
+}
+
+// Settings は通知の配信の設定である。
+type Settings struct {
+	// PollInterval は配信キューを確認する間隔である。
+	PollInterval time.Duration
+	// BatchSize は1回に取り出して並行に送信する通知の数である。
+	BatchSize int
+	// MaxAttempts は dead にするまでの送信回数の上限である。
+	MaxAttempts int
+	// RetryBaseDelay と RetryMaxDelay は再試行までの待ち時間の初期値と上限である。
+	RetryBaseDelay time.Duration
+	RetryMaxDelay  time.Duration
+	// Timeout は1回の送信にかける時間の上限である。
+	Timeout time.Duration
+}
+
+// Dispatcher は配信キューから通知を取り出し、署名を付けて通知先に送信する。
+// 2xx 以外の応答や接続の失敗は、待ち時間を倍々に延ばしながら MaxAttempts 回まで再試行する。
+type Dispatcher struct {
+	Store    DeliveryStore
+	Client   *http.Client
+	Settings Settings
+
+	now    func() time.Time
+	jitter func(time.Duration) time.Duration
+}
+
+func NewDispatcher(store DeliveryStore, settings Settings) *Dispatcher {
+	return &Dispatcher{
+		Store: store,
+		Client: &http.Client{
+			Timeout: settings.Timeout,
+			// 転送先は登録時に検証していないため、リダイレクトには従わず失敗として扱う
+			CheckRedirect: func(req *http.Request, via []*http.Request) error {
+				return http.ErrUseLastResponse
+			},
+		},
+		Settings: settings,
+		now:      time.Now,
+		jitter:   backoff.FullJitter,
+	}
+}
+
+// Run は ctx が終了するまで PollInterval ごとに配信キューの通知を送信する。
+func (d *Dispatcher) Run(ctx context.Context) {
+	logger := logging.FromContext(ctx)
+
+	ticker := time.NewTicker(d.Settings.PollInterval)
+	defer ticker.Stop()
+
+	for {
+		// 1回で取り出しきれなかった通知は、待たずに続けて送信する
+		for {
+			n, err := d.DispatchOnce(ctx)
+			if err != nil {
+				logger.Error("Webhook の配信エラー", "error", err)
+				break
+			}
+			if n < d.Settings.BatchSize || ctx.Err() != nil {
+				break
+			}
+		}
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// DispatchOnce は配信時刻を過ぎた通知を BatchSize 件まで取り出して送信し、取り出した件数を返す。
+func (d *Dispatcher) DispatchOnce(ctx context.Context) (int, error) {
+	deliveries, err := d.Store.ClaimDue(ctx, d.Settings.BatchSize, d.Settings.Timeout+leaseMargin)
+	if err != nil {
+		return 0, err
+	}
+
+	var wg sync.WaitGroup
+	for _, delivery := range deliveries {
+		wg.Add(1)
+		go func(delivery repository.WebhookDelivery) {
+			defer wg.Done()
+			d.deliver(ctx, delivery)
+		}(delivery)
+	}
+	wg.Wait()
+	return len(deliveries), nil
+}
+
+// deliver は1件の通知を送信し、結果を配信キューに記録する。
+func (d *Dispatcher) deliver(ctx context.Context, delivery repository.WebhookDelivery) {
+	logger := logging.FromContext(ctx).With("deliveryId", delivery.ID, "subscriptionId", delivery.SubscriptionID)
+	// 停止中でも送信済みの通知の結果は記録する
+	recordCtx := context.WithoutCancel(ctx)
+
+	statusCode, err := d.send(ctx, delivery)
+	if err == nil {
+		metrics.WebhookDeliveries.WithLabelValues("succeeded").Inc()
+		if err := d.Store.MarkSucceeded(recordCtx, delivery.ID, statusCode); err != nil {
+			logger.Error("Webhook の配信結果の記録エラー", "error", err)
+		}
+		return
+	}
+
+	if ctx.Err() != nil {
+		// 停止で中断した送信は失敗に数えず、lease が過ぎた後に再び取り出させる
+		logger.Info("停止のため Webhook の送信を中断しました")
+		return
+	}
+
+	message := truncate(err.Error(), maxErrorLength)
+	attempts := delivery.Attempts + 1
+	if attempts >= d.Settings.MaxAttempts {
+		metrics.WebhookDeliveries.WithLabelValues("dead").Inc()
+		logger.Warn("Webhook の配信をあきらめました", "attempts", attempts, "statusCode", statusCode, "error", message)
+		if err := d.Store.MarkDead(recordCtx, delivery.ID, statusCode, message); err != nil {
+			logger.Error("Webhook の配信結果の記録エラー", "error", err)
+		}
+		return
+	}
+
+	retryIn := backoff.Delay(attempts-1, d.Settings.RetryBaseDelay, d.Settings.RetryMaxDelay, d.jitter)
+	metrics.WebhookDeliveries.WithLabelValues("retried").Inc()
+	logger.Info("Webhook の配信に失敗しました", "attempts", attempts, "statusCode", statusCode, "retryIn", retryIn, "error", message)
+	if err := d.Store.MarkFailed(recordCtx, delivery.ID, statusCode, message, retryIn); err != nil {
+		logger.Error("Webhook の配信結果の記録エラー", "error", err)
+	}
+}
+
+// send は通知を POST し、応答のステータスコードを返す。2xx 以外の応答はエラーとする。
+func (d *Dispatcher) send(ctx context.Context, delivery repository.WebhookDelivery) (int, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, delivery.URL, bytes.NewReader(delivery.Payload))
+	if err != nil {
+		return 0, err
+	}
+	timestamp := d.now().Unix()
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("User-Agent", "super-business-book-ranking-webhook/"+version.String())
+	req.Header.Set(HeaderDelivery, strconv.FormatInt(delivery.ID, 10))
+	req.Header.Set(HeaderEvent, delivery.EventType)
+	req.Header.Set(HeaderTimestamp, strconv.FormatInt(timestamp, 10))
+	req.Header.Set(HeaderSignature, "sha256="+Sign(delivery.Secret, timestamp, delivery.Payload))
+
+	resp, err := d.Client.Do(req)
+	if err != nil {
+		return 0, err
+	}
+	defer resp.Body.Close()
+
+	snippet, _ := io.ReadAll(io.LimitReader(resp.Body, maxResponseSnippet))
+	// 接続を再利用できるよう、残りの本文を読み捨てる
+	io.Copy(io.Discard, io.LimitReader(resp.Body, 64<<10))
+
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		return resp.StatusCode, fmt.Errorf("HTTP %d: %s", resp.StatusCode, bytes.TrimSpace(snippet))
+	}
+	return resp.StatusCode, nil
+}
+
+// Sign は通知の署名を16進数で返す。
+// 署名は送信時刻（X-Webhook-Timestamp の値）と本文を "." でつないだ値の、secret を鍵とする HMAC-SHA256 である。
+// 受信側は同じ値を求めて X-Webhook-Signature と比べ、古すぎる送信時刻の通知は再送攻撃として拒否する。
+func Sign(secret string, timestamp int64, body []byte) string {
+	mac := hmac.New(sha256.New, []byte(secret))
+	mac.Write([]byte(strconv.FormatInt(timestamp, 10)))
+	mac.Write([]byte("."))
+	mac.Write(body)
+	return hex.EncodeToString(mac.Sum(nil))
+}
+
+// GenerateSecret は署名の鍵にする乱数の文字列を生成する。
+func GenerateSecret() (string, error) {
+	b := make([]byte, 32)
+	if _, err := rand.Read(b); err != nil {
+		return "", fmt.Errorf("署名の鍵の生成エラー: %w", err)
+	}
+	return "whsec_" + hex.EncodeToString(b), nil
+}
+
+// truncate は s を最大 n 文字に切り詰める。応答本文に含まれる不正な UTF-8 は取り除く。
+func truncate(s string, n int) string {
+	s = strings.ToValidUTF8(s, "")
+	if utf8.RuneCountInString(s) <= n {
+		return s
+	}
+	return string([]rune(s)[:n])
+}
@@ -0,0 +1,252 @@
+package webhook
+
+import (
+	"context"
+	"crypto/hmac"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/h-hiwatashi/super-business-book-ranking-backend/repository"
+)
+
+// fakeQueue は配信キューをメモリ上で再現する。再試行の待ち時間は無視して、すぐに取り出せるようにする。
+type fakeQueue struct {
+	mu         sync.Mutex
+	deliveries map[int64]*repository.WebhookDelivery
+	retryIn    []time.Duration
+}
+
+func newFakeQueue(deliveries ...repository.WebhookDelivery) *fakeQueue {
+	q := &fakeQueue{deliveries: make(map[int64]*repository.WebhookDelivery)}
+	for i := range deliveries {
+		d := deliveries[i]
+		d.Status = repository.WebhookPending
+		q.deliveries[d.ID] = &d
+	}
+	return q
+}
+
+func (q *fakeQueue) ClaimDue(ctx context.Context, limit int, lease time.Duration) ([]repository.WebhookDelivery, error) {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	var due []repository.WebhookDelivery
+	for id := int64(1); len(due) < limit && id <= int64(len(q.deliveries)); id++ {
+		if d := q.deliveries[id]; d.Status == repository.WebhookPending {
+			due = append(due, *d)
+		}
+	}
+	return due, nil
+}
+
+func (q *fakeQueue) MarkSucceeded(ctx context.Context, id int64, statusCode int) error {
+	return q.update(id, repository.WebhookSucceeded, statusCode, "")
+}
+
+func (q *fakeQueue) MarkFailed(ctx context.Context, id int64, statusCode int, lastError string, retryIn time.Duration) error {
+	q.mu.Lock()
+	q.retryIn = append(q.retryIn, retryIn)
+	q.mu.Unlock()
+	return q.update(id, repository.WebhookPending, statusCode, lastError)
+}
+
+func (q *fakeQueue) MarkDead(ctx context.Context, id int64, statusCode int, lastError string) error {
+	return q.update(id, repository.WebhookDead, statusCode, lastError)
+}
+
+func (q *fakeQueue) update(id int64, status string, statusCode int, lastError string) error {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	d := q.deliveries[id]
+	d.Status = status
+	d.Attempts++
+	d.LastStatusCode = statusCode
+	d.LastError = lastError
+	return nil
+}
+
+func (q *fakeQueue) get(id int64) repository.WebhookDelivery {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	return *q.deliveries[id]
+}
+
+// receiver は受け取った通知を記録し、responses の順にステータスコードを返すテスト用の受信側である。
+type receiver struct {
+	mu        sync.Mutex
+	requests  []*http.Request
+	bodies    [][]byte
+	responses []int
+}
+
+func (rv *receiver) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	body, _ := io.ReadAll(r.Body)
+	rv.mu.Lock()
+	defer rv.mu.Unlock()
+	status := http.StatusNoContent
+	if n := len(rv.requests); n < len(rv.responses) {
+		status = rv.responses[n]
+	}
+	rv.requests = append(rv.requests, r)
+	rv.bodies = append(rv.bodies, body)
+	w.WriteHeader(status)
+}
+
+func newTestDispatcher(queue *fakeQueue) *Dispatcher {
+	d := NewDispatcher(queue, Settings{
+		PollInterval:   time.Hour,
+		BatchSize:      10,
+		MaxAttempts:    3,
+		RetryBaseDelay: time.Second,
+		RetryMaxDelay:  time.Minute,
+		Timeout:        time.Second,
+	})
+	d.now = func() time.Time { return time.Unix(1710460800, 0) }
+	d.jitter = func(d time.Duration) time.Duration { return d }
+	return d
+}
+
+func TestDispatcherSignsPayload(t *testing.T) {
+	rv := &receiver{}
+	srv := httptest.NewServer(rv)
+	defer srv.Close()
+
+	payload := []byte(`{"type":"new_entry","book":{"id":"a"}}`)
+	queue := newFakeQueue(repository.WebhookDelivery{
+		ID: 1, SubscriptionID: "sub-1", EventType: EventNewEntry, Payload: payload, URL: srv.URL, Secret: "whsec_test",
+	})
+	d := newTestDispatcher(queue)
+
+	if n, err := d.DispatchOnce(context.Background()); err != nil || n != 1 {
+		t.Fatalf("DispatchOnce() = %d, %v", n, err)
+	}
+
+	if len(rv.requests) != 1 {
+		t.Fatalf("received %d requests, want 1", len(rv.requests))
+	}
+	req := rv.requests[0]
+	if string(rv.bodies[0]) != string(payload) {
+		t.Errorf("body = %s, want %s", rv.bodies[0], payload)
+	}
+	if got := req.Header.Get(HeaderEvent); got != EventNewEntry {
+		t.Errorf("%s = %q, want %q", HeaderEvent, got, EventNewEntry)
+	}
+	if got := req.Header.Get(HeaderDelivery); got != "1" {
+		t.Errorf("%s = %q, want 1", HeaderDelivery, got)
+	}
+	timestamp, err := strconv.ParseInt(req.Header.Get(HeaderTimestamp), 10, 64)
+	if err != nil || timestamp != 1710460800 {
+		t.Errorf("%s = %q", HeaderTimestamp, req.Header.Get(HeaderTimestamp))
+	}
+	// 受信側と同じ手順で署名を検証する
+	want := "sha256=" + Sign("whsec_test", timestamp, rv.bodies[0])
+	if got := req.Header.Get(HeaderSignature); !hmac.Equal([]byte(got), []byte(want)) {
+		t.Errorf("%s = %q, want %q", HeaderSignature, got, want)
+	}
+	if got := req.Header.Get(HeaderSignature); got == "sha256="+Sign("other", timestamp, rv.bodies[0]) {
+		t.Error("別の鍵の署名と一致しました")
+	}
+
+	if got := queue.get(1); got.Status != repository.WebhookSucceeded || got.LastStatusCode != http.StatusNoContent {
+		t.Errorf("delivery = %+v, want succeeded", got)
+	}
+}
+
+func TestDispatcherRetriesAndDeadLetters(t *testing.T) {
+	testCases := []struct {
+		name         string
+		responses    []int
+		wantStatus   string
+		wantAttempts int
+		wantRetryIn  []time.Duration
+	}{
+		{
+			name:         "正常系：再試行で成功",
+			responses:    []int{http.StatusInternalServerError, http.StatusServiceUnavailable, http.StatusOK},
+			wantStatus:   repository.WebhookSucceeded,
+			wantAttempts: 3,
+			wantRetryIn:  []time.Duration{time.Second, 2 * time.Second},
+		},
+		{
+			name:         "異常系：再試行の上限で dead",
+			responses:    []int{http.StatusInternalServerError, http.StatusBadRequest, http.StatusGone, http.StatusOK},
+			wantStatus:   repository.WebhookDead,
+			wantAttempts: 3,
+			wantRetryIn:  []time.Duration{time.Second, 2 * time.Second},
+		},
+		{
+			name:         "異常系：リダイレクトには従わない",
+			responses:    []int{http.StatusFound, http.StatusFound, http.StatusFound},
+			wantStatus:   repository.WebhookDead,
+			wantAttempts: 3,
+			wantRetryIn:  []time.Duration{time.Second, 2 * time.Second},
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			rv := &receiver{responses: tc.responses}
+			srv := httptest.NewServer(rv)
+			defer srv.Close()
+
+			queue := newFakeQueue(repository.WebhookDelivery{
+				ID: 1, SubscriptionID: "sub-1", EventType: EventRankUp, Payload: []byte(`{}`), URL: srv.URL, Secret: "s",
+			})
+			d := newTestDispatcher(queue)
+			for i := 0; i < len(tc.responses); i++ {
+				if _, err := d.DispatchOnce(context.Background()); err != nil {
+					t.Fatal(err)
+				}
+			}
+
+			got := queue.get(1)
+			if got.Status != tc.wantStatus || got.Attempts != tc.wantAttempts {
+				t.Errorf("delivery = %+v, want status %s, attempts %d", got, tc.wantStatus, tc.wantAttempts)
+			}
+			if tc.wantStatus == repository.WebhookDead && got.LastError == "" {
+				t.Error("dead になった通知にエラーが記録されていません")
+			}
+			if len(rv.requests) != tc.wantAttempts {
+				t.Errorf("received %d requests, want %d", len(rv.requests), tc.wantAttempts)
+			}
+			if len(queue.retryIn) != len(tc.wantRetryIn) {
+				t.Fatalf("retryIn = %v, want %v", queue.retryIn, tc.wantRetryIn)
+			}
+			for i := range tc.wantRetryIn {
+				if queue.retryIn[i] != tc.wantRetryIn[i] {
+					t.Errorf("retryIn = %v, want %v", queue.retryIn, tc.wantRetryIn)
+				}
+			}
+		})
+	}
+}
+
+func TestDispatcherConnectionError(t *testing.T) {
+	srv := httptest.NewServer(http.NotFoundHandler())
+	url := srv.URL
+	srv.Close()
+
+	queue := newFakeQueue(repository.WebhookDelivery{ID: 1, EventType: EventDroppedOut, Payload: []byte(`{}`), URL: url, Secret: "s"})
+	d := newTestDispatcher(queue)
+	if _, err := d.DispatchOnce(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+
+	got := queue.get(1)
+	if got.Status != repository.WebhookPending || got.Attempts != 1 || got.LastStatusCode != 0 || got.LastError == "" {
+		t.Errorf("delivery = %+v, want 再試行待ち", got)
+	}
+}
+
+func TestTruncate(t *testing.T) {
+	if got := truncate("あいうえお", 3); got != "あいう" {
+		t.Errorf("truncate() = %q, want あいう", got)
+	}
+	if got := truncate("ok\xff", 10); got != "ok" {
+		t.Errorf("truncate() = %q, want ok", got)
+	}
+}
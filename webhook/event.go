@@ -0,0 +1,152 @@
+// Package webhook はランキングの変化を登録された外部サービスへ Webhook で通知する。
+//
+// 取り込みのたびに前回のスナップショットと比べて通知を配信キュー（webhook_deliveries）に追加し、
+// Dispatcher がキューから取り出して署名付きで送信する。失敗した通知は間隔を空けて再試行し、
+// 上限に達したものは dead として残す。
+package webhook
+
+import (
+	"fmt"
+	"slices"
+	"strings"
+
+	"github.com/h-hiwatashi/super-business-book-ranking-backend/repository"
+)
+
+// イベントの種類
+const (
+	// EventNewEntry は書籍が上位 N 位に入ったことを表す。
+	EventNewEntry = "new_entry"
+	// EventRankUp は上位 N 位の書籍の順位が上がったことを表す。
+	EventRankUp = "rank_up"
+	// EventDroppedOut は書籍が上位 N 位から外れたことを表す。
+	EventDroppedOut = "dropped_out"
+	// EventPriceDrop は上位 N 位の書籍の価格が下がったことを表す。
+	EventPriceDrop = "price_drop"
+)
+
+// EventTypes は登録時に指定できるイベントの種類の一覧である。
+var EventTypes = []string{EventNewEntry, EventRankUp, EventDroppedOut, EventPriceDrop}
+
+// MaxTopN は登録時に指定できる順位の上限である。
+const MaxTopN = 100
+
+// ValidateEvents は events が1つ以上あり、すべて既知の種類かを検証する。
+func ValidateEvents(events []string) error {
+	if len(events) == 0 {
+		return fmt.Errorf("events は1つ以上指定してください（%s）", strings.Join(EventTypes, "、"))
+	}
+	for _, event := range events {
+		if !slices.Contains(EventTypes, event) {
+			return fmt.Errorf("不明なイベントです: %q（%s のいずれかを指定してください）", event, strings.Join(EventTypes, "、"))
+		}
+	}
+	return nil
+}
+
+// Event は Webhook で通知するランキングの変化で、通知の本文になる。
+type Event struct {
+	Type         string `json:"type"`
+	CategoryID   string `json:"categoryId"`
+	CategoryName string `json:"categoryName"`
+	PeriodType   string `json:"periodType"`
+	// Date は変化を検出したランキングの終了日（YYYY-MM-DD）である。
+	Date string                `json:"date"`
+	Book repository.RankedBook `json:"book"`
+	// Rank は現在の順位で、ランキングから外れた場合は0になる。
+	Rank int `json:"rank"`
+	// PreviousRank は前回の順位で、前回のランキングになかった場合は0になる。
+	PreviousRank int `json:"previousRank"`
+	// PreviousPrice は price_drop の値下げ前の価格である。
+	PreviousPrice float64 `json:"previousPrice,omitempty"`
+}
+
+// Key は同じ変化を二重に通知しないためのキーを返す。
+// 同じ日付のスナップショットを取り込み直しても同じキーになる。
+func (e *Event) Key() string {
+	return strings.Join([]string{e.Type, e.CategoryID, e.PeriodType, e.Date, e.Book.ID}, ":")
+}
+
+// Detect は previous から current への変化のうち、上位 topN 位に関わるものを返す。
+// 同じ書籍が複数のECサイトで載っている場合は、上位の方の順位で比べる。
+// 価格は同じECサイトの販売情報どうしで比べる。
+func Detect(previous, current *repository.Ranking, topN int) []Event {
+	date := current.DateTo
+	event := func(eventType string, book repository.RankedBook) Event {
+		return Event{
+			Type:         eventType,
+			CategoryID:   current.CategoryID,
+			CategoryName: current.CategoryName,
+			PeriodType:   current.PeriodType,
+			Date:         date,
+			Book:         book,
+		}
+	}
+
+	before := uniqueBooks(previous.Books)
+	after := uniqueBooks(current.Books)
+	previousByID := make(map[string]repository.RankedBook, len(before))
+	for _, book := range before {
+		previousByID[book.ID] = book
+	}
+	currentByID := make(map[string]repository.RankedBook, len(after))
+	for _, book := range after {
+		currentByID[book.ID] = book
+	}
+	previousPrices := make(map[string]float64, len(previous.Books))
+	for _, book := range previous.Books {
+		previousPrices[book.BookSiteMappingID] = book.Price
+	}
+
+	var events []Event
+	for _, book := range after {
+		if book.Rank > topN {
+			break
+		}
+		old, ok := previousByID[book.ID]
+		switch {
+		case !ok || old.Rank > topN:
+			e := event(EventNewEntry, book)
+			e.Rank = book.Rank
+			e.PreviousRank = old.Rank
+			events = append(events, e)
+		case book.Rank < old.Rank:
+			e := event(EventRankUp, book)
+			e.Rank = book.Rank
+			e.PreviousRank = old.Rank
+			events = append(events, e)
+		}
+		if price, ok := previousPrices[book.BookSiteMappingID]; ok && book.Price > 0 && book.Price < price {
+			e := event(EventPriceDrop, book)
+			e.Rank = book.Rank
+			e.PreviousRank = old.Rank
+			e.PreviousPrice = price
+			events = append(events, e)
+		}
+	}
+	for _, book := range before {
+		if book.Rank > topN {
+			break
+		}
+		if now, ok := currentByID[book.ID]; !ok || now.Rank > topN {
+			e := event(EventDroppedOut, book)
+			e.Rank = now.Rank
+			e.PreviousRank = book.Rank
+			events = append(events, e)
+		}
+	}
+	return events
+}
+
+// uniqueBooks は書籍IDごとに最初（最上位）の1件を残す。
+func uniqueBooks(books []repository.RankedBook) []repository.RankedBook {
+	seen := make(map[string]bool, len(books))
+	unique := make([]repository.RankedBook, 0, len(books))
+	for _, book := range books {
+		if !seen[book.ID] {
+			seen[book.ID] = true
+			unique = append(unique, book)
+		}
+	}
+	return unique
+}
@@ -0,0 +1,112 @@
+package webhook
+
+import (
+	"testing"
+
+	"github.com/h-hiwatashi/super-business-book-ranking-backend/repository"
+)
+
+func book(rank int, id string, price float64) repository.RankedBook {
+	return repository.RankedBook{Rank: rank, ID: id, Title: "書籍" + id, Price: price, Site: "rakuten", BookSiteMappingID: "bsm-" + id}
+}
+
+func ranking(date string, books ...repository.RankedBook) *repository.Ranking {
+	return &repository.Ranking{CategoryID: "001", CategoryName: "ビジネス書", PeriodType: "daily", DateTo: date, Books: books}
+}
+
+func TestDetect(t *testing.T) {
+	previous := ranking("2024-03-14",
+		book(1, "a", 1500), book(2, "b", 1600), book(3, "c", 1700), book(4, "d", 1800), book(5, "e", 1900))
+
+	testCases := []struct {
+		name    string
+		current *repository.Ranking
+		topN    int
+		want    []Event
+	}{
+		{
+			name:    "正常系：変化なし",
+			current: ranking("2024-03-15", book(1, "a", 1500), book(2, "b", 1600), book(3, "c", 1700)),
+			topN:    3,
+		},
+		{
+			name:    "正常系：上位に入った書籍と外れた書籍",
+			current: ranking("2024-03-15", book(1, "a", 1500), book(2, "x", 1000), book(3, "d", 1800), book(4, "b", 1600)),
+			topN:    3,
+			want: []Event{
+				{Type: EventNewEntry, Book: book(2, "x", 1000), Rank: 2},
+				{Type: EventNewEntry, Book: book(3, "d", 1800), Rank: 3, PreviousRank: 4},
+				{Type: EventDroppedOut, Book: book(2, "b", 1600), Rank: 4, PreviousRank: 2},
+				{Type: EventDroppedOut, Book: book(3, "c", 1700), PreviousRank: 3},
+			},
+		},
+		{
+			name:    "正常系：順位の上昇と値下げ",
+			current: ranking("2024-03-15", book(1, "b", 1200), book(2, "a", 1500), book(3, "c", 1800)),
+			topN:    3,
+			want: []Event{
+				{Type: EventRankUp, Book: book(1, "b", 1200), Rank: 1, PreviousRank: 2},
+				{Type: EventPriceDrop, Book: book(1, "b", 1200), Rank: 1, PreviousRank: 2, PreviousPrice: 1600},
+			},
+		},
+		{
+			name:    "正常系：上位の外の変化は通知しない",
+			current: ranking("2024-03-15", book(1, "a", 1500), book(2, "b", 1600), book(3, "e", 100), book(4, "c", 1700)),
+			topN:    2,
+		},
+		{
+			name: "正常系：複数のECサイトに載る書籍は上位の順位で比べる",
+			current: ranking("2024-03-15", book(1, "a", 1500), book(2, "b", 1600),
+				repository.RankedBook{Rank: 3, ID: "a", Site: "amazon", Price: 900, BookSiteMappingID: "bsm-a-amazon"}),
+			topN: 3,
+			want: []Event{
+				{Type: EventDroppedOut, Book: book(3, "c", 1700), PreviousRank: 3},
+			},
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			got := Detect(previous, tc.current, tc.topN)
+			if len(got) != len(tc.want) {
+				t.Fatalf("events = %+v, want %+v", got, tc.want)
+			}
+			for i, want := range tc.want {
+				want.CategoryID = "001"
+				want.CategoryName = "ビジネス書"
+				want.PeriodType = "daily"
+				want.Date = "2024-03-15"
+				if got[i] != want {
+					t.Errorf("events[%d] = %+v, want %+v", i, got[i], want)
+				}
+			}
+		})
+	}
+}
+
+func TestEventKey(t *testing.T) {
+	event := Event{Type: EventNewEntry, CategoryID: "001", PeriodType: "daily", Date: "2024-03-15", Book: book(1, "a", 0)}
+	if got, want := event.Key(), "new_entry:001:daily:2024-03-15:a"; got != want {
+		t.Errorf("Key() = %q, want %q", got, want)
+	}
+}
+
+func TestValidateEvents(t *testing.T) {
+	testCases := []struct {
+		name    string
+		events  []string
+		wantErr bool
+	}{
+		{name: "正常系：すべての種類", events: EventTypes},
+		{name: "異常系：指定なし", events: nil, wantErr: true},
+		{name: "異常系：不明な種類", events: []string{EventNewEntry, "rank_down"}, wantErr: true},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			if err := ValidateEvents(tc.events); (err != nil) != tc.wantErr {
+				t.Errorf("ValidateEvents() error = %v, wantErr %v", err, tc.wantErr)
+			}
+		})
+	}
+}
@@ -0,0 +1,127 @@
+package webhook
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"slices"
+
+	"github.com/h-hiwatashi/super-business-book-ranking-backend/affiliate"
+	"github.com/h-hiwatashi/super-business-book-ranking-backend/logging"
+	"github.com/h-hiwatashi/super-business-book-ranking-backend/repository"
+)
+
+// 変化を比べるランキングの取得件数
+// 同じ書籍が複数のECサイトで載る場合に備え、指定できる順位の上限の2倍まで取得する。
+const compareLimit = MaxTopN * 2
+
+// RankingStore は比べる2つのスナップショットのランキングを取得するリポジトリである。
+// キャッシュを通さず、保存した直後のランキングを読めるものを渡す。
+type RankingStore interface {
+	Find(ctx context.Context, q repository.RankingQuery) (*repository.Ranking, error)
+	PreviousDate(ctx context.Context, categoryID, periodType string) (string, error)
+}
+
+// SubscriptionStore は通知先の登録と配信キューである。
+type SubscriptionStore interface {
+	ActiveSubscriptions(ctx context.Context, categoryID, periodType string) ([]repository.WebhookSubscription, error)
+	Enqueue(ctx context.Context, deliveries []repository.WebhookDelivery) (int, error)
+}
+
+// Payload は通知の本文である。
+type Payload struct {
+	SubscriptionID string `json:"subscriptionId"`
+	Event
+}
+
+// Notifier はスナップショットの保存ごとにランキングの変化を検出し、登録に応じた通知を配信キューに追加する。
+type Notifier struct {
+	Rankings      RankingStore
+	Subscriptions SubscriptionStore
+	// Links が nil の場合は商品URLをそのまま通知する。
+	Links affiliate.URLLinker
+}
+
+func NewNotifier(rankings RankingStore, subscriptions SubscriptionStore) *Notifier {
+	return &Notifier{
+		Rankings:      rankings,
+		Subscriptions: subscriptions,
+	}
+}
+
+// SnapshotSaved は保存したスナップショットと1つ前のスナップショットを比べ、
+// カテゴリ・期間を登録した通知先ごとに、指定されたイベントを配信キューに追加する。
+// 1つ前のスナップショットがない場合は通知しない。
+func (n *Notifier) SnapshotSaved(ctx context.Context, snapshot repository.Snapshot) {
+	logger := logging.FromContext(ctx).With("categoryId", snapshot.CategoryID, "periodType", snapshot.PeriodType)
+
+	subs, err := n.Subscriptions.ActiveSubscriptions(ctx, snapshot.CategoryID, snapshot.PeriodType)
+	if err != nil {
+		logger.Error("Webhook の登録の取得エラー", "error", err)
+		return
+	}
+	if len(subs) == 0 {
+		return
+	}
+
+	previousDate, err := n.Rankings.PreviousDate(ctx, snapshot.CategoryID, snapshot.PeriodType)
+	if errors.Is(err, sql.ErrNoRows) {
+		return
+	}
+	if err != nil {
+		logger.Error("Webhook の比較対象の取得エラー", "error", err)
+		return
+	}
+	current, err := n.find(ctx, snapshot.CategoryID, snapshot.PeriodType, snapshot.DateTo.Format("2006-01-02"))
+	if err != nil {
+		logger.Error("Webhook のランキング取得エラー", "error", err)
+		return
+	}
+	previous, err := n.find(ctx, snapshot.CategoryID, snapshot.PeriodType, previousDate)
+	if err != nil {
+		logger.Error("Webhook のランキング取得エラー", "date", previousDate, "error", err)
+		return
+	}
+
+	var deliveries []repository.WebhookDelivery
+	for _, sub := range subs {
+		for _, event := range Detect(previous, current, sub.TopN) {
+			if !slices.Contains(sub.Events, event.Type) {
+				continue
+			}
+			event.Book.URL = affiliate.LinkURL(n.Links, event.Book.Site, event.Book.URL)
+			payload, err := json.Marshal(Payload{SubscriptionID: sub.ID, Event: event})
+			if err != nil {
+				logger.Error("Webhook の本文の変換エラー", "subscriptionId", sub.ID, "error", err)
+				continue
+			}
+			deliveries = append(deliveries, repository.WebhookDelivery{
+				SubscriptionID: sub.ID,
+				EventType:      event.Type,
+				EventKey:       event.Key(),
+				Payload:        payload,
+			})
+		}
+	}
+	if len(deliveries) == 0 {
+		return
+	}
+
+	added, err := n.Subscriptions.Enqueue(ctx, deliveries)
+	if err != nil {
+		logger.Error("Webhook の通知の登録エラー", "deliveries", len(deliveries), "error", err)
+		return
+	}
+	logger.Info("Webhook の通知を登録しました", "deliveries", added)
+}
+
+func (n *Notifier) find(ctx context.Context, categoryID, periodType, date string) (*repository.Ranking, error) {
+	return n.Rankings.Find(ctx, repository.RankingQuery{
+		CategoryID: categoryID,
+		PeriodType: periodType,
+		Date:       date,
+		Page:       1,
+		Limit:      compareLimit,
+	})
+}
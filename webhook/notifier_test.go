@@ -0,0 +1,109 @@
+package webhook
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"testing"
+	"time"
+
+	"github.com/h-hiwatashi/super-business-book-ranking-backend/repository"
+)
+
+// fakeRankings は日付ごとのランキングを返す。
+type fakeRankings struct {
+	byDate       map[string]*repository.Ranking
+	previousDate string
+}
+
+func (f *fakeRankings) Find(ctx context.Context, q repository.RankingQuery) (*repository.Ranking, error) {
+	if r, ok := f.byDate[q.Date]; ok {
+		return r, nil
+	}
+	return &repository.Ranking{}, nil
+}
+
+func (f *fakeRankings) PreviousDate(ctx context.Context, categoryID, periodType string) (string, error) {
+	if f.previousDate == "" {
+		return "", sql.ErrNoRows
+	}
+	return f.previousDate, nil
+}
+
+type fakeSubscriptions struct {
+	subs     []repository.WebhookSubscription
+	enqueued []repository.WebhookDelivery
+}
+
+func (f *fakeSubscriptions) ActiveSubscriptions(ctx context.Context, categoryID, periodType string) ([]repository.WebhookSubscription, error) {
+	return f.subs, nil
+}
+
+func (f *fakeSubscriptions) Enqueue(ctx context.Context, deliveries []repository.WebhookDelivery) (int, error) {
+	f.enqueued = append(f.enqueued, deliveries...)
+	return len(deliveries), nil
+}
+
+func TestNotifierSnapshotSaved(t *testing.T) {
+	snapshot := repository.Snapshot{CategoryID: "001", PeriodType: "daily", DateTo: time.Date(2024, 3, 15, 0, 0, 0, 0, time.UTC)}
+	rankings := &fakeRankings{
+		byDate: map[string]*repository.Ranking{
+			"2024-03-14": ranking("2024-03-14", book(1, "a", 1500), book(2, "b", 1600)),
+			"2024-03-15": ranking("2024-03-15", book(1, "b", 1600), book(2, "c", 1700)),
+		},
+	}
+
+	testCases := []struct {
+		name         string
+		previousDate string
+		subs         []repository.WebhookSubscription
+		wantEvents   []string
+	}{
+		{
+			name: "正常系：登録ごとに指定したイベントを通知",
+			subs: []repository.WebhookSubscription{
+				{ID: "sub-1", Events: []string{EventNewEntry, EventDroppedOut}, TopN: 2},
+				// 1位の書籍は前回2位のため、上位1位では順位の上昇ではなく新たに入ったことになる
+				{ID: "sub-2", Events: []string{EventRankUp, EventNewEntry}, TopN: 1},
+			},
+			previousDate: "2024-03-14",
+			wantEvents:   []string{"sub-1:" + EventNewEntry, "sub-1:" + EventDroppedOut, "sub-2:" + EventNewEntry},
+		},
+		{
+			name:         "正常系：前回のスナップショットがない",
+			subs:         []repository.WebhookSubscription{{ID: "sub-1", Events: EventTypes, TopN: 2}},
+			previousDate: "",
+		},
+		{
+			name:         "正常系：登録がない",
+			previousDate: "2024-03-14",
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			rankings.previousDate = tc.previousDate
+			subs := &fakeSubscriptions{subs: tc.subs}
+			n := NewNotifier(rankings, subs)
+
+			n.SnapshotSaved(context.Background(), snapshot)
+
+			if len(subs.enqueued) != len(tc.wantEvents) {
+				t.Fatalf("enqueued = %+v, want %v", subs.enqueued, tc.wantEvents)
+			}
+			for i, want := range tc.wantEvents {
+				d := subs.enqueued[i]
+				if got := d.SubscriptionID + ":" + d.EventType; got != want {
+					t.Errorf("enqueued[%d] = %s, want %s", i, got, want)
+				}
+				var payload Payload
+				if err := json.Unmarshal(d.Payload, &payload); err != nil {
+					t.Fatal(err)
+				}
+				if payload.SubscriptionID != d.SubscriptionID || payload.Type != d.EventType || d.EventKey != payload.Key() {
+					t.Errorf("payload = %+v, key = %q", payload, d.EventKey)
+				}
+			}
+		})
+	}
+}